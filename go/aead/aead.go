@@ -34,6 +34,10 @@ func init() {
 		panic(fmt.Sprintf("aead.init() failed: %v", err))
 	}
 
+	if err := registry.RegisterKeyManager(newAESGCMSIVKeyManager()); err != nil {
+		panic(fmt.Sprintf("aead.init() failed: %v", err))
+	}
+
 	if err := registry.RegisterKeyManager(newChaCha20Poly1305KeyManager()); err != nil {
 		panic(fmt.Sprintf("aead.init() failed: %v", err))
 	}
@@ -44,4 +48,7 @@ func init() {
 	if err := registry.RegisterKeyManager(newKMSEnvelopeAEADKeyManager()); err != nil {
 		panic(fmt.Sprintf("aead.init() failed: %v", err))
 	}
+	if err := registry.RegisterKeyTemplates(SupportedTemplates()); err != nil {
+		panic(fmt.Sprintf("aead.init() failed: %v", err))
+	}
 }
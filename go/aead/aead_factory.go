@@ -17,18 +17,130 @@
 package aead
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/google/tink/go/core/cryptofmt"
 	"github.com/google/tink/go/core/primitiveset"
 	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/internal/bufpool"
+	"github.com/google/tink/go/internal/trialdecrypt"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/tink"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
+var (
+	// ErrNoMatchingKey is returned by the Decrypt method of the AEAD
+	// primitives returned by New if no key of the keyset was tried, i.e. the
+	// ciphertext prefix matches no key and there are no RAW keys to try. This
+	// can mean that the ciphertext was encrypted with a key that is missing
+	// from the keyset.
+	ErrNoMatchingKey = errors.New("aead_factory: decryption failed: no matching key")
+	// ErrDecryptionFailed is returned by the Decrypt method of the AEAD
+	// primitives returned by New if at least one key was tried, but none of
+	// them decrypted the ciphertext. If the keyset has RAW keys, this is also
+	// returned for ciphertexts whose prefix matches no key.
+	ErrDecryptionFailed = errors.New("aead_factory: decryption failed")
+)
+
+// PooledAEAD is implemented by the AEAD primitives returned by New.
+//
+// EncryptPooled is like Encrypt, but returns the ciphertext in a buffer borrowed
+// from a pool, which saves an allocation per call. The caller must call
+// release exactly once and must neither use nor retain ct afterwards; use
+// Encrypt if the ciphertext has to outlive the call.
+type PooledAEAD interface {
+	tink.AEAD
+	EncryptPooled(pt, ad []byte) (ct []byte, release func(), err error)
+}
+
+// Assert that wrappedAead implements the PooledAEAD interface.
+var _ PooledAEAD = (*wrappedAead)(nil)
+
+// KeyIDAEAD is implemented by the AEAD primitives returned by New.
+//
+// EncryptWithKeyID is like Encrypt, but also returns the ID of the key that
+// encrypted the plaintext, i.e. the ID of the primary key. For TINK and
+// LEGACY keys, this is the ID encoded in the prefix of the ciphertext.
+type KeyIDAEAD interface {
+	tink.AEAD
+	EncryptWithKeyID(pt, ad []byte) (ct []byte, keyID uint32, err error)
+}
+
+// Assert that wrappedAead implements the KeyIDAEAD interface.
+var _ KeyIDAEAD = (*wrappedAead)(nil)
+
+// MinCiphertextLenAEAD is implemented by the AEAD primitives returned by New.
+//
+// MinCiphertextLen returns the length of the shortest ciphertext that Decrypt
+// can accept for any key of the keyset, including its output prefix, so that
+// shorter inputs can be rejected without calling Decrypt. For keys whose
+// primitive does not report its minimum ciphertext length, e.g. KMS envelope
+// keys, only the output prefix is counted.
+type MinCiphertextLenAEAD interface {
+	tink.AEAD
+	MinCiphertextLen() int
+}
+
+// Assert that wrappedAead implements the MinCiphertextLenAEAD interface.
+var _ MinCiphertextLenAEAD = (*wrappedAead)(nil)
+
+// minCiphertextLener is implemented by the subtle AEADs that know the length of
+// their shortest ciphertext.
+type minCiphertextLener interface {
+	MinCiphertextLen() int
+}
+
+// Option configures the AEAD primitive returned by New.
+type Option func(*wrappedAead)
+
+// WithoutRawTrialDecryption makes Decrypt only consider keys whose TINK or
+// LEGACY prefix matches the ciphertext, instead of also trying every RAW key
+// in the keyset. This bounds the work done for garbage ciphertexts, which
+// otherwise grows with the number of RAW keys. Use it only if all ciphertexts
+// carry a prefix; New fails if the primary key has a RAW prefix.
+func WithoutRawTrialDecryption() Option {
+	return func(a *wrappedAead) {
+		a.skipRawEntries = true
+	}
+}
+
+// WithConcurrentTrialDecryption makes Decrypt try the candidate keys for a
+// ciphertext on up to maxWorkers goroutines at once, instead of one after the
+// other. This helps keysets with many RAW keys whose AEADs are slow, e.g.
+// because they call a KMS. The result is the same as with serial trial
+// decryption: if several keys decrypt the ciphertext, the plaintext of the
+// first of them in the serial order is returned. Once a key succeeds, no
+// further trials are started, but trials already running are not
+// interrupted. New fails if maxWorkers is less than 1.
+func WithConcurrentTrialDecryption(maxWorkers int) Option {
+	return func(a *wrappedAead) {
+		a.trialWorkers = maxWorkers
+	}
+}
+
 // New returns an AEAD primitive from the given keyset handle.
-func New(h *keyset.Handle) (tink.AEAD, error) {
-	return NewWithKeyManager(h, nil /*keyManager*/)
+func New(h *keyset.Handle, opts ...Option) (tink.AEAD, error) {
+	ps, err := h.Primitives()
+	if err != nil {
+		return nil, fmt.Errorf("aead_factory: cannot obtain primitive set: %s", err)
+	}
+	a, err := newWrappedAead(ps, h.UsagePolicy())
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.skipRawEntries && ps.Primary.PrefixType == tinkpb.OutputPrefixType_RAW {
+		return nil, fmt.Errorf("aead_factory: primary key has RAW prefix and raw trial decryption is disabled")
+	}
+	if a.trialWorkers < 1 {
+		return nil, fmt.Errorf("aead_factory: invalid number of trial decryption workers: %d", a.trialWorkers)
+	}
+	return a, nil
 }
 
 // NewWithKeyManager returns an AEAD primitive from the given keyset handle and custom key manager.
@@ -39,16 +151,65 @@ func NewWithKeyManager(h *keyset.Handle, km registry.KeyManager) (tink.AEAD, err
 		return nil, fmt.Errorf("aead_factory: cannot obtain primitive set: %s", err)
 	}
 
-	return newWrappedAead(ps)
+	return newWrappedAead(ps, h.UsagePolicy())
+}
+
+// NewWithHotKeys returns an AEAD primitive from the given keyset handle that,
+// when decrypting, tries the keys with IDs in hotKeyIDs before the other keys
+// with the same ciphertext prefix, in the order given. This only changes the
+// order of trial decryption: it is useful for keysets with many RAW keys of
+// which a few decrypt most of the traffic. Unknown key IDs are ignored.
+func NewWithHotKeys(h *keyset.Handle, hotKeyIDs []uint32) (tink.AEAD, error) {
+	ps, err := h.Primitives()
+	if err != nil {
+		return nil, fmt.Errorf("aead_factory: cannot obtain primitive set: %s", err)
+	}
+	prioritizeEntries(ps, hotKeyIDs)
+	return newWrappedAead(ps, h.UsagePolicy())
+}
+
+// prioritizeEntries reorders the entries of ps so that, for every prefix,
+// the entries of hotKeyIDs come first. The relative order of other entries
+// is preserved.
+func prioritizeEntries(ps *primitiveset.PrimitiveSet, hotKeyIDs []uint32) {
+	rank := make(map[uint32]int)
+	for i, id := range hotKeyIDs {
+		if _, ok := rank[id]; !ok {
+			rank[id] = i
+		}
+	}
+	for prefix, entries := range ps.Entries {
+		sorted := make([]*primitiveset.Entry, len(entries))
+		copy(sorted, entries)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			ri, iHot := rank[sorted[i].KeyID]
+			rj, jHot := rank[sorted[j].KeyID]
+			if iHot && jHot {
+				return ri < rj
+			}
+			return iHot
+		})
+		ps.Entries[prefix] = sorted
+	}
 }
 
 // wrappedAead is an AEAD implementation that uses the underlying primitive set for encryption
 // and decryption.
 type wrappedAead struct {
 	ps *primitiveset.PrimitiveSet
+
+	// skipRawEntries disables trial decryption with RAW keys.
+	skipRawEntries bool
+
+	// trialWorkers is the maximal number of concurrent trial decryptions.
+	trialWorkers int
+
+	// policy is consulted before each use of a key, see
+	// keyset.Handle.WithUsagePolicy.
+	policy keyset.UsagePolicy
 }
 
-func newWrappedAead(ps *primitiveset.PrimitiveSet) (*wrappedAead, error) {
+func newWrappedAead(ps *primitiveset.PrimitiveSet, policy keyset.UsagePolicy) (*wrappedAead, error) {
 	if _, ok := (ps.Primary.Primitive).(tink.AEAD); !ok {
 		return nil, fmt.Errorf("aead_factory: not an AEAD primitive")
 	}
@@ -63,6 +224,8 @@ func newWrappedAead(ps *primitiveset.PrimitiveSet) (*wrappedAead, error) {
 
 	ret := new(wrappedAead)
 	ret.ps = ps
+	ret.trialWorkers = 1
+	ret.policy = policy
 
 	return ret, nil
 }
@@ -70,23 +233,59 @@ func newWrappedAead(ps *primitiveset.PrimitiveSet) (*wrappedAead, error) {
 // Encrypt encrypts the given plaintext with the given additional authenticated data.
 // It returns the concatenation of the primary's identifier and the ciphertext.
 func (a *wrappedAead) Encrypt(pt, ad []byte) ([]byte, error) {
+	ct, _, err := a.EncryptWithKeyID(pt, ad)
+	return ct, err
+}
+
+// EncryptWithKeyID is like Encrypt, but also returns the ID of the primary key,
+// see KeyIDAEAD.
+func (a *wrappedAead) EncryptWithKeyID(pt, ad []byte) ([]byte, uint32, error) {
 	primary := a.ps.Primary
 	p, ok := (primary.Primitive).(tink.AEAD)
 	if !ok {
-		return nil, fmt.Errorf("aead_factory: not an AEAD primitive")
+		return nil, 0, fmt.Errorf("aead_factory: not an AEAD primitive")
+	}
+	if err := keyset.CheckUsage(a.policy, primary.KeyID, keyset.OpEncrypt); err != nil {
+		return nil, 0, err
 	}
 
 	ct, err := p.Encrypt(pt, ad)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	return append([]byte(primary.Prefix), ct...), primary.KeyID, nil
+}
+
+// EncryptPooled is like Encrypt, but stores the result in a pooled buffer, see
+// PooledAEAD.
+func (a *wrappedAead) EncryptPooled(pt, ad []byte) ([]byte, func(), error) {
+	primary := a.ps.Primary
+	p, ok := (primary.Primitive).(tink.AEAD)
+	if !ok {
+		return nil, nil, fmt.Errorf("aead_factory: not an AEAD primitive")
+	}
+	if err := keyset.CheckUsage(a.policy, primary.KeyID, keyset.OpEncrypt); err != nil {
+		return nil, nil, err
 	}
-	return append([]byte(primary.Prefix), ct...), nil
+
+	ct, err := p.Encrypt(pt, ad)
+	if err != nil {
+		return nil, nil, err
+	}
+	buf := bufpool.Get(len(primary.Prefix) + len(ct))
+	buf.B = append(buf.B, primary.Prefix...)
+	buf.B = append(buf.B, ct...)
+	return buf.B, buf.ReleaseFunc(), nil
 }
 
 // Decrypt decrypts the given ciphertext and authenticates it with the given
 // additional authenticated data. It returns the corresponding plaintext if the
 // ciphertext is authenticated.
 func (a *wrappedAead) Decrypt(ct, ad []byte) ([]byte, error) {
+	// matched is true if a key was tried.
+	matched := false
+	// denied is the first error of the usage policy.
+	var denied error
 	// try non-raw keys
 	prefixSize := cryptofmt.NonRawPrefixSize
 	if len(ct) > prefixSize {
@@ -94,34 +293,94 @@ func (a *wrappedAead) Decrypt(ct, ad []byte) ([]byte, error) {
 		ctNoPrefix := ct[prefixSize:]
 		entries, err := a.ps.EntriesForPrefix(string(prefix))
 		if err == nil {
-			for i := 0; i < len(entries); i++ {
-				p, ok := (entries[i].Primitive).(tink.AEAD)
-				if !ok {
-					return nil, fmt.Errorf("aead_factory: not an AEAD primitive")
-				}
-
-				pt, err := p.Decrypt(ctNoPrefix, ad)
-				if err == nil {
-					return pt, nil
-				}
+			matched = len(entries) > 0
+			pt, ok, deniedErr := a.tryDecrypt(entries, ctNoPrefix, ad)
+			if ok {
+				return pt, nil
 			}
+			denied = deniedErr
 		}
 	}
+	if a.skipRawEntries {
+		return nil, decryptionError(matched, denied)
+	}
 	// try raw keys
 	entries, err := a.ps.RawEntries()
 	if err == nil {
-		for i := 0; i < len(entries); i++ {
-			p, ok := (entries[i].Primitive).(tink.AEAD)
-			if !ok {
-				return nil, fmt.Errorf("aead_factory: not an AEAD primitive")
-			}
+		matched = matched || len(entries) > 0
+		pt, ok, deniedErr := a.tryDecrypt(entries, ct, ad)
+		if ok {
+			return pt, nil
+		}
+		if denied == nil {
+			denied = deniedErr
+		}
+	}
+	// nothing worked
+	return nil, decryptionError(matched, denied)
+}
 
-			pt, err := p.Decrypt(ct, ad)
-			if err == nil {
-				return pt, nil
+// MinCiphertextLen returns the length of the shortest ciphertext that Decrypt
+// can accept, see MinCiphertextLenAEAD.
+func (a *wrappedAead) MinCiphertextLen() int {
+	min := -1
+	for _, entries := range a.ps.Entries {
+		for _, e := range entries {
+			if a.skipRawEntries && e.PrefixType == tinkpb.OutputPrefixType_RAW {
+				continue
+			}
+			n := len(e.Prefix)
+			if p, ok := e.Primitive.(minCiphertextLener); ok {
+				n += p.MinCiphertextLen()
+			}
+			if min < 0 || n < min {
+				min = n
 			}
 		}
 	}
-	// nothing worked
-	return nil, fmt.Errorf("aead_factory: decryption failed")
+	if min < 0 {
+		return 0
+	}
+	return min
+}
+
+// decryptionError returns denied if it is not nil, ErrDecryptionFailed if a
+// key was tried, and ErrNoMatchingKey otherwise.
+func decryptionError(matched bool, denied error) error {
+	if denied != nil {
+		return denied
+	}
+	if matched {
+		return ErrDecryptionFailed
+	}
+	return ErrNoMatchingKey
+}
+
+// tryDecrypt returns the plaintext of the first entry that decrypts ct, trying
+// up to a.trialWorkers entries at once. Entries denied by the usage policy are
+// skipped; if no entry decrypts ct, the first denial is returned.
+func (a *wrappedAead) tryDecrypt(entries []*primitiveset.Entry, ct, ad []byte) ([]byte, bool, error) {
+	// Each call of the closure writes only its own element, and First waits
+	// for all of them.
+	denials := make([]error, len(entries))
+	pt, ok := trialdecrypt.First(len(entries), a.trialWorkers, func(i int) ([]byte, error) {
+		p, ok := (entries[i].Primitive).(tink.AEAD)
+		if !ok {
+			return nil, fmt.Errorf("aead_factory: not an AEAD primitive")
+		}
+		if err := keyset.CheckUsage(a.policy, entries[i].KeyID, keyset.OpDecrypt); err != nil {
+			denials[i] = err
+			return nil, err
+		}
+		return p.Decrypt(ct, ad)
+	})
+	if ok {
+		return pt, true, nil
+	}
+	for _, err := range denials {
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return nil, false, nil
 }
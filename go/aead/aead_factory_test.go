@@ -20,13 +20,18 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/google/tink/go/aead"
 	"github.com/google/tink/go/core/cryptofmt"
+	"github.com/google/tink/go/core/registry"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/signature"
 	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/testing/fakekms"
 	"github.com/google/tink/go/testkeyset"
 	"github.com/google/tink/go/testutil"
 	"github.com/google/tink/go/tink"
@@ -164,3 +169,589 @@ func TestFactoryWithValidPrimitiveSetType(t *testing.T) {
 		t.Fatalf("calling New() with good *keyset.Handle failed: %s", err)
 	}
 }
+
+// newRawAESGCMKeys returns a keyset with n RAW AES-GCM keys, together with one
+// ciphertext of pt per key.
+func newRawAESGCMKeys(tb testing.TB, n int, pt, ad []byte) (*keyset.Handle, []uint32, [][]byte) {
+	tb.Helper()
+	kt := aead.AES128GCMKeyTemplate()
+	kt.OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	ksm := keyset.NewManager()
+	var ids []uint32
+	var cts [][]byte
+	for i := 0; i < n; i++ {
+		id, err := ksm.RotateKey(kt)
+		if err != nil {
+			tb.Fatalf("ksm.RotateKey() err = %v", err)
+		}
+		h, err := ksm.Handle()
+		if err != nil {
+			tb.Fatalf("ksm.Handle() err = %v", err)
+		}
+		a, err := aead.New(h)
+		if err != nil {
+			tb.Fatalf("aead.New() err = %v", err)
+		}
+		ct, err := a.Encrypt(pt, ad)
+		if err != nil {
+			tb.Fatalf("a.Encrypt() err = %v", err)
+		}
+		ids = append(ids, id)
+		cts = append(cts, ct)
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		tb.Fatalf("ksm.Handle() err = %v", err)
+	}
+	return h, ids, cts
+}
+
+func TestNewWithHotKeys(t *testing.T) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	h, ids, cts := newRawAESGCMKeys(t, 5, pt, ad)
+	for _, hot := range [][]uint32{
+		nil,
+		{ids[4]},
+		{ids[2], ids[0]},
+		{ids[1], ids[1], 12345},
+		ids,
+	} {
+		a, err := aead.NewWithHotKeys(h, hot)
+		if err != nil {
+			t.Fatalf("aead.NewWithHotKeys() err = %v", err)
+		}
+		for i, ct := range cts {
+			got, err := a.Decrypt(ct, ad)
+			if err != nil {
+				t.Errorf("hot keys %v: a.Decrypt() of ciphertext %d err = %v", hot, i, err)
+				continue
+			}
+			if !bytes.Equal(got, pt) {
+				t.Errorf("hot keys %v: a.Decrypt() = %q, want %q", hot, got, pt)
+			}
+		}
+		if _, err := a.Decrypt(random.GetRandomBytes(40), ad); err == nil {
+			t.Errorf("hot keys %v: a.Decrypt() of random bytes err = nil, want error", hot)
+		}
+	}
+}
+
+func BenchmarkDecryptWithHotKeys(b *testing.B) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	h, ids, cts := newRawAESGCMKeys(b, 50, pt, ad)
+	// The last key is tried last by default.
+	hotID, ct := ids[len(ids)-1], cts[len(cts)-1]
+	plain, err := aead.New(h)
+	if err != nil {
+		b.Fatalf("aead.New() err = %v", err)
+	}
+	hot, err := aead.NewWithHotKeys(h, []uint32{hotID})
+	if err != nil {
+		b.Fatalf("aead.NewWithHotKeys() err = %v", err)
+	}
+	for _, bc := range []struct {
+		name string
+		a    tink.AEAD
+	}{
+		{"Default", plain},
+		{"HotKey", hot},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.a.Decrypt(ct, ad); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newTinkPrimaryWithRawKeys returns a keyset with numRaw RAW AES-GCM keys and a
+// TINK primary, along with a ciphertext of pt from the first RAW key.
+func newTinkPrimaryWithRawKeys(tb testing.TB, numRaw int, pt, ad []byte) (*keyset.Handle, []byte) {
+	tb.Helper()
+	h, _, cts := newRawAESGCMKeys(tb, numRaw, pt, ad)
+	ksm := keyset.NewManagerFromHandle(h)
+	if _, err := ksm.RotateKey(aead.AES128GCMKeyTemplate()); err != nil {
+		tb.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		tb.Fatalf("ksm.Handle() err = %v", err)
+	}
+	return h, cts[0]
+}
+
+func TestWithoutRawTrialDecryption(t *testing.T) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	h, rawCT := newTinkPrimaryWithRawKeys(t, 3, pt, ad)
+	a, err := aead.New(h, aead.WithoutRawTrialDecryption())
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	ct, err := a.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v", err)
+	}
+	if got, err := a.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("a.Decrypt() = %q, %v, want %q, nil", got, err, pt)
+	}
+	if _, err := a.Decrypt(rawCT, ad); err == nil {
+		t.Error("a.Decrypt() of RAW ciphertext err = nil, want error")
+	}
+
+	// The default still tries RAW keys.
+	a, err = aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	if got, err := a.Decrypt(rawCT, ad); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("a.Decrypt() of RAW ciphertext = %q, %v, want %q, nil", got, err, pt)
+	}
+}
+
+func TestWithoutRawTrialDecryptionRejectsRawPrimary(t *testing.T) {
+	h, err := keyset.NewHandle(aead.AES256GCMNoPrefixKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	if _, err := aead.New(h, aead.WithoutRawTrialDecryption()); err == nil {
+		t.Error("aead.New() with RAW primary err = nil, want error")
+	}
+}
+
+func BenchmarkDecryptGarbageWithRawKeys(b *testing.B) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	h, _ := newTinkPrimaryWithRawKeys(b, 50, pt, ad)
+	garbage := random.GetRandomBytes(64)
+	for _, bc := range []struct {
+		name string
+		opts []aead.Option
+	}{
+		{"Default", nil},
+		{"WithoutRawTrialDecryption", []aead.Option{aead.WithoutRawTrialDecryption()}},
+	} {
+		a, err := aead.New(h, bc.opts...)
+		if err != nil {
+			b.Fatalf("aead.New() err = %v", err)
+		}
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := a.Decrypt(garbage, ad); err == nil {
+					b.Fatal("a.Decrypt() of garbage err = nil, want error")
+				}
+			}
+		})
+	}
+}
+
+func TestWithConcurrentTrialDecryption(t *testing.T) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	h, _, rawCTs := newRawAESGCMKeys(t, 10, pt, ad)
+	ksm := keyset.NewManagerFromHandle(h)
+	if _, err := ksm.RotateKey(aead.AES128GCMKeyTemplate()); err != nil {
+		t.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	for _, workers := range []int{1, 3, 100} {
+		a, err := aead.New(h, aead.WithConcurrentTrialDecryption(workers))
+		if err != nil {
+			t.Fatalf("aead.New() err = %v", err)
+		}
+		ct, err := a.Encrypt(pt, ad)
+		if err != nil {
+			t.Fatalf("a.Encrypt() err = %v", err)
+		}
+		for i, ct := range append(rawCTs, ct) {
+			if got, err := a.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+				t.Errorf("workers %d: a.Decrypt() of ciphertext %d = %q, %v, want %q, nil", workers, i, got, err, pt)
+			}
+		}
+		if _, err := a.Decrypt(random.GetRandomBytes(40), ad); err == nil {
+			t.Errorf("workers %d: a.Decrypt() of random bytes err = nil, want error", workers)
+		}
+		if _, err := a.Decrypt(rawCTs[0], []byte("wrong ad")); err == nil {
+			t.Errorf("workers %d: a.Decrypt() with wrong associated data err = nil, want error", workers)
+		}
+	}
+}
+
+func TestWithConcurrentTrialDecryptionRejectsInvalidWorkers(t *testing.T) {
+	h, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	for _, workers := range []int{0, -1} {
+		if _, err := aead.New(h, aead.WithConcurrentTrialDecryption(workers)); err == nil {
+			t.Errorf("aead.New() with %d workers err = nil, want error", workers)
+		}
+	}
+}
+
+// newSlowKMSEnvelopeKeys returns a keyset with n KMS envelope AEAD keys whose
+// KEKs are each held by a fake KMS with the given latency, and a ciphertext
+// of pt from the last key, which is tried last.
+func newSlowKMSEnvelopeKeys(b *testing.B, n int, latency time.Duration, pt, ad []byte) (*keyset.Handle, []byte) {
+	b.Helper()
+	client, err := fakekms.NewClient("fake-kms://", fakekms.WithLatency(latency))
+	if err != nil {
+		b.Fatalf("fakekms.NewClient() err = %v", err)
+	}
+	registry.RegisterKMSClient(client)
+	ksm := keyset.NewManager()
+	var ct []byte
+	for i := 0; i < n; i++ {
+		kekURI, err := fakekms.NewKeyURI()
+		if err != nil {
+			b.Fatalf("fakekms.NewKeyURI() err = %v", err)
+		}
+		if _, err := ksm.RotateKey(aead.KMSEnvelopeAEADKeyTemplate(kekURI, aead.AES128GCMKeyTemplate())); err != nil {
+			b.Fatalf("ksm.RotateKey() err = %v", err)
+		}
+		if i == n-1 {
+			h, err := ksm.Handle()
+			if err != nil {
+				b.Fatalf("ksm.Handle() err = %v", err)
+			}
+			a, err := aead.New(h)
+			if err != nil {
+				b.Fatalf("aead.New() err = %v", err)
+			}
+			if ct, err = a.Encrypt(pt, ad); err != nil {
+				b.Fatalf("a.Encrypt() err = %v", err)
+			}
+		}
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		b.Fatalf("ksm.Handle() err = %v", err)
+	}
+	return h, ct
+}
+
+func BenchmarkDecryptWithSlowRawKeys(b *testing.B) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	h, ct := newSlowKMSEnvelopeKeys(b, 20, time.Millisecond, pt, ad)
+	defer registry.RemoveKMSClient("fake-kms://")
+	for _, bc := range []struct {
+		name string
+		opts []aead.Option
+	}{
+		{"Serial", nil},
+		{"Concurrent4", []aead.Option{aead.WithConcurrentTrialDecryption(4)}},
+		{"Concurrent20", []aead.Option{aead.WithConcurrentTrialDecryption(20)}},
+	} {
+		a, err := aead.New(h, bc.opts...)
+		if err != nil {
+			b.Fatalf("aead.New() err = %v", err)
+		}
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := a.Decrypt(ct, ad); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptPooled(t *testing.T) {
+	for _, prefixType := range []tinkpb.OutputPrefixType{tinkpb.OutputPrefixType_TINK, tinkpb.OutputPrefixType_RAW} {
+		kh, err := testkeyset.NewHandle(testutil.NewTestAESGCMKeyset(prefixType))
+		if err != nil {
+			t.Fatalf("testkeyset.NewHandle failed: %s", err)
+		}
+		a, err := aead.New(kh)
+		if err != nil {
+			t.Fatalf("aead.New failed: %s", err)
+		}
+		pooled, ok := a.(aead.PooledAEAD)
+		if !ok {
+			t.Fatal("aead.New did not return an aead.PooledAEAD")
+		}
+		pt, ad := []byte("plaintext"), []byte("ad")
+		ct, release, err := pooled.EncryptPooled(pt, ad)
+		if err != nil {
+			t.Fatalf("EncryptPooled failed: %s", err)
+		}
+		got, err := a.Decrypt(ct, ad)
+		if err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("%s: Decrypt = %q, %v, want %q, nil", prefixType, got, err, pt)
+		}
+		release()
+	}
+}
+
+func TestEncryptPooledConcurrently(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle failed: %s", err)
+	}
+	a, err := aead.New(kh)
+	if err != nil {
+		t.Fatalf("aead.New failed: %s", err)
+	}
+	pooled := a.(aead.PooledAEAD)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pt := []byte(fmt.Sprintf("plaintext %d", i))
+			for j := 0; j < 100; j++ {
+				ct, release, err := pooled.EncryptPooled(pt, nil)
+				if err != nil {
+					t.Errorf("EncryptPooled failed: %s", err)
+					return
+				}
+				got, err := a.Decrypt(ct, nil)
+				release()
+				if err != nil || !bytes.Equal(got, pt) {
+					t.Errorf("Decrypt = %q, %v, want %q, nil", got, err, pt)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	kh, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		b.Fatalf("keyset.NewHandle failed: %s", err)
+	}
+	a, err := aead.New(kh)
+	if err != nil {
+		b.Fatalf("aead.New failed: %s", err)
+	}
+	pt := make([]byte, 256)
+	b.Run("Encrypt", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := a.Encrypt(pt, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("EncryptPooled", func(b *testing.B) {
+		b.ReportAllocs()
+		pooled := a.(aead.PooledAEAD)
+		for i := 0; i < b.N; i++ {
+			_, release, err := pooled.EncryptPooled(pt, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			release()
+		}
+	})
+}
+
+func BenchmarkNew(b *testing.B) {
+	kh, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		b.Fatalf("keyset.NewHandle failed: %s", err)
+	}
+	ks := testkeyset.KeysetMaterial(kh)
+	b.Run("FreshHandle", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			h, err := testkeyset.NewHandle(ks)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := aead.New(h); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("SameHandle", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := aead.New(kh); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestEncryptWithKeyID(t *testing.T) {
+	for _, prefixType := range []tinkpb.OutputPrefixType{tinkpb.OutputPrefixType_TINK, tinkpb.OutputPrefixType_LEGACY, tinkpb.OutputPrefixType_RAW} {
+		ks := testutil.NewTestAESGCMKeyset(prefixType)
+		kh, err := testkeyset.NewHandle(ks)
+		if err != nil {
+			t.Fatalf("testkeyset.NewHandle failed: %s", err)
+		}
+		a, err := aead.New(kh)
+		if err != nil {
+			t.Fatalf("aead.New failed: %s", err)
+		}
+		withKeyID, ok := a.(aead.KeyIDAEAD)
+		if !ok {
+			t.Fatal("aead.New did not return an aead.KeyIDAEAD")
+		}
+		pt, ad := []byte("plaintext"), []byte("ad")
+		ct, keyID, err := withKeyID.EncryptWithKeyID(pt, ad)
+		if err != nil {
+			t.Fatalf("EncryptWithKeyID failed: %s", err)
+		}
+		if keyID != ks.PrimaryKeyId {
+			t.Errorf("%s: EncryptWithKeyID returned key ID %d, want %d", prefixType, keyID, ks.PrimaryKeyId)
+		}
+		if prefixType != tinkpb.OutputPrefixType_RAW {
+			prefixID, prefixIDType, err := cryptofmt.KeyIDFromPrefix(ct)
+			if err != nil {
+				t.Fatalf("cryptofmt.KeyIDFromPrefix failed: %s", err)
+			}
+			if prefixID != keyID || prefixIDType != prefixType {
+				t.Errorf("%s: ciphertext prefix has key ID %d of type %s, want %d of type %s", prefixType, prefixID, prefixIDType, keyID, prefixType)
+			}
+		}
+		got, err := a.Decrypt(ct, ad)
+		if err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("%s: Decrypt = %q, %v, want %q, nil", prefixType, got, err, pt)
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	newAEAD := func(template *tinkpb.KeyTemplate) tink.AEAD {
+		t.Helper()
+		h, err := keyset.NewHandle(template)
+		if err != nil {
+			t.Fatalf("keyset.NewHandle() err = %v", err)
+		}
+		a, err := aead.New(h)
+		if err != nil {
+			t.Fatalf("aead.New() err = %v", err)
+		}
+		return a
+	}
+	a := newAEAD(aead.AES128GCMKeyTemplate())
+	other := newAEAD(aead.AES128GCMKeyTemplate())
+	raw := newAEAD(aead.AES256GCMNoPrefixKeyTemplate())
+	ad := []byte("ad")
+	ct, err := a.Encrypt([]byte("plaintext"), ad)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v", err)
+	}
+	tampered := append([]byte{}, ct...)
+	tampered[len(tampered)-1] ^= 1
+
+	var testCases = []struct {
+		name string
+		a    tink.AEAD
+		ct   []byte
+		ad   []byte
+		want error
+	}{
+		{name: "tampered ciphertext", a: a, ct: tampered, ad: ad, want: aead.ErrDecryptionFailed},
+		{name: "wrong associated data", a: a, ct: ct, ad: []byte("other ad"), want: aead.ErrDecryptionFailed},
+		{name: "unknown key", a: other, ct: ct, ad: ad, want: aead.ErrNoMatchingKey},
+		{name: "short ciphertext", a: a, ct: ct[:cryptofmt.NonRawPrefixSize], ad: ad, want: aead.ErrNoMatchingKey},
+		{name: "unknown key with RAW keys", a: raw, ct: ct, ad: ad, want: aead.ErrDecryptionFailed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.a.Decrypt(tc.ct, tc.ad); err != tc.want {
+				t.Errorf("Decrypt() err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestMinCiphertextLen(t *testing.T) {
+	templates := []*tinkpb.KeyTemplate{
+		aead.AES128GCMKeyTemplate(),
+		aead.AES256GCMNoPrefixKeyTemplate(),
+		aead.AES128GCMSIVKeyTemplate(),
+		aead.AES128CTRHMACSHA256KeyTemplate(),
+		aead.ChaCha20Poly1305KeyTemplate(),
+		aead.XChaCha20Poly1305KeyTemplate(),
+	}
+	for _, kt := range templates {
+		for _, prefix := range []tinkpb.OutputPrefixType{tinkpb.OutputPrefixType_TINK, tinkpb.OutputPrefixType_LEGACY, tinkpb.OutputPrefixType_RAW} {
+			kt := proto.Clone(kt).(*tinkpb.KeyTemplate)
+			kt.OutputPrefixType = prefix
+			name := fmt.Sprintf("%s/%s", kt.TypeUrl, prefix)
+			h, err := keyset.NewHandle(kt)
+			if err != nil {
+				t.Fatalf("%s: keyset.NewHandle() err = %v", name, err)
+			}
+			a, err := aead.New(h)
+			if err != nil {
+				t.Fatalf("%s: aead.New() err = %v", name, err)
+			}
+			ct, err := a.Encrypt(nil, nil)
+			if err != nil {
+				t.Fatalf("%s: Encrypt() err = %v", name, err)
+			}
+			if got := a.(aead.MinCiphertextLenAEAD).MinCiphertextLen(); got != len(ct) {
+				t.Errorf("%s: MinCiphertextLen() = %d, want %d", name, got, len(ct))
+			}
+			if _, err := a.Decrypt(ct[:len(ct)-1], nil); err == nil {
+				t.Errorf("%s: Decrypt() of ciphertext shorter than MinCiphertextLen() err = nil, want error", name)
+			}
+		}
+	}
+}
+
+func TestMinCiphertextLenIsMinimumOverKeys(t *testing.T) {
+	ksm := keyset.NewManager()
+	// A RAW AES-GCM key has the shortest ciphertexts.
+	for _, kt := range []*tinkpb.KeyTemplate{aead.XChaCha20Poly1305KeyTemplate(), aead.AES256GCMNoPrefixKeyTemplate(), aead.AES128CTRHMACSHA256KeyTemplate()} {
+		if err := ksm.Rotate(kt); err != nil {
+			t.Fatalf("ksm.Rotate() err = %v", err)
+		}
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	want := subtle.AESGCMIVSize + subtle.AESGCMTagSize
+	if got := a.(aead.MinCiphertextLenAEAD).MinCiphertextLen(); got != want {
+		t.Errorf("MinCiphertextLen() = %d, want %d", got, want)
+	}
+
+	// RAW keys are not tried if raw trial decryption is disabled.
+	a, err = aead.New(h, aead.WithoutRawTrialDecryption())
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	// The AES-CTR-HMAC template uses 16-byte IVs and tags.
+	want = cryptofmt.NonRawPrefixSize + 16 + 16
+	if got := a.(aead.MinCiphertextLenAEAD).MinCiphertextLen(); got != want {
+		t.Errorf("MinCiphertextLen() without raw trial decryption = %d, want %d", got, want)
+	}
+}
+
+func TestMinCiphertextLenOfKMSEnvelopeKey(t *testing.T) {
+	client, err := fakekms.NewClient("fake-kms://")
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() err = %v", err)
+	}
+	registry.RegisterKMSClient(client)
+	kekURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() err = %v", err)
+	}
+	h, err := keyset.NewHandle(aead.KMSEnvelopeAEADKeyTemplate(kekURI, aead.AES128GCMKeyTemplate()))
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	// The length of the encrypted DEK is unknown, so only the prefix counts,
+	// which is empty for the RAW KMS envelope template.
+	if got, want := a.(aead.MinCiphertextLenAEAD).MinCiphertextLen(), 0; got != want {
+		t.Errorf("MinCiphertextLen() = %d, want %d", got, want)
+	}
+}
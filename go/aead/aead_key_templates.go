@@ -21,6 +21,7 @@ import (
 	ctrpb "github.com/google/tink/go/proto/aes_ctr_go_proto"
 	ctrhmacpb "github.com/google/tink/go/proto/aes_ctr_hmac_aead_go_proto"
 	gcmpb "github.com/google/tink/go/proto/aes_gcm_go_proto"
+	gcmsivpb "github.com/google/tink/go/proto/aes_gcm_siv_go_proto"
 	commonpb "github.com/google/tink/go/proto/common_go_proto"
 	hmacpb "github.com/google/tink/go/proto/hmac_go_proto"
 	kmsenvpb "github.com/google/tink/go/proto/kms_envelope_go_proto"
@@ -30,6 +31,24 @@ import (
 // This file contains pre-generated KeyTemplates for AEAD keys. One can use these templates
 // to generate new Keysets.
 
+// SupportedTemplates returns the AEAD key templates of this package by their
+// canonical names, such as "AES128_GCM". These are the names used by Tinkey.
+// Each call returns a new map. The KMS envelope template is not included, since
+// it depends on a key URI.
+func SupportedTemplates() map[string]func() *tinkpb.KeyTemplate {
+	return map[string]func() *tinkpb.KeyTemplate{
+		"AES128_GCM":             AES128GCMKeyTemplate,
+		"AES256_GCM":             AES256GCMKeyTemplate,
+		"AES256_GCM_RAW":         AES256GCMNoPrefixKeyTemplate,
+		"AES128_GCM_SIV":         AES128GCMSIVKeyTemplate,
+		"AES256_GCM_SIV":         AES256GCMSIVKeyTemplate,
+		"AES128_CTR_HMAC_SHA256": AES128CTRHMACSHA256KeyTemplate,
+		"AES256_CTR_HMAC_SHA256": AES256CTRHMACSHA256KeyTemplate,
+		"CHACHA20_POLY1305":      ChaCha20Poly1305KeyTemplate,
+		"XCHACHA20_POLY1305":     XChaCha20Poly1305KeyTemplate,
+	}
+}
+
 // AES128GCMKeyTemplate is a KeyTemplate that generates an AES-GCM key with the following parameters:
 //   - Key size: 16 bytes
 //   - Output prefix type: TINK
@@ -51,6 +70,20 @@ func AES256GCMNoPrefixKeyTemplate() *tinkpb.KeyTemplate {
 	return createAESGCMKeyTemplate(32, tinkpb.OutputPrefixType_RAW)
 }
 
+// AES128GCMSIVKeyTemplate is a KeyTemplate that generates an AES-GCM-SIV key with the following parameters:
+//   - Key size: 16 bytes
+//   - Output prefix type: TINK
+func AES128GCMSIVKeyTemplate() *tinkpb.KeyTemplate {
+	return createAESGCMSIVKeyTemplate(16, tinkpb.OutputPrefixType_TINK)
+}
+
+// AES256GCMSIVKeyTemplate is a KeyTemplate that generates an AES-GCM-SIV key with the following parameters:
+//   - Key size: 32 bytes
+//   - Output prefix type: TINK
+func AES256GCMSIVKeyTemplate() *tinkpb.KeyTemplate {
+	return createAESGCMSIVKeyTemplate(32, tinkpb.OutputPrefixType_TINK)
+}
+
 // AES128CTRHMACSHA256KeyTemplate is a KeyTemplate that generates an AES-CTR-HMAC-AEAD key with the following parameters:
 //  - AES key size: 16 bytes
 //  - AES CTR IV size: 16 bytes
@@ -121,6 +154,20 @@ func createAESGCMKeyTemplate(keySize uint32, outputPrefixType tinkpb.OutputPrefi
 	}
 }
 
+// createAESGCMSIVKeyTemplate creates a new AES-GCM-SIV key template with the
+// given key size in bytes.
+func createAESGCMSIVKeyTemplate(keySize uint32, outputPrefixType tinkpb.OutputPrefixType) *tinkpb.KeyTemplate {
+	format := &gcmsivpb.AesGcmSivKeyFormat{
+		KeySize: keySize,
+	}
+	serializedFormat, _ := proto.Marshal(format)
+	return &tinkpb.KeyTemplate{
+		TypeUrl:          aesGCMSIVTypeURL,
+		Value:            serializedFormat,
+		OutputPrefixType: outputPrefixType,
+	}
+}
+
 func createAESCTRHMACAEADKeyTemplate(aesKeySize, ivSize, hmacKeySize, tagSize uint32, hash commonpb.HashType) *tinkpb.KeyTemplate {
 	format := &ctrhmacpb.AesCtrHmacAeadKeyFormat{
 		AesCtrKeyFormat: &ctrpb.AesCtrKeyFormat{
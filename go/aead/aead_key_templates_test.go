@@ -19,6 +19,7 @@ package aead_test
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
@@ -42,6 +43,12 @@ func TestKeyTemplates(t *testing.T) {
 		}, {
 			name:     "AES256_GCM",
 			template: aead.AES256GCMKeyTemplate(),
+		}, {
+			name:     "AES128_GCM_SIV",
+			template: aead.AES128GCMSIVKeyTemplate(),
+		}, {
+			name:     "AES256_GCM_SIV",
+			template: aead.AES256GCMSIVKeyTemplate(),
 		}, {
 			name:     "AES128_CTR_HMAC_SHA256",
 			template: aead.AES128CTRHMACSHA256KeyTemplate(),
@@ -106,6 +113,7 @@ func TestKMSEnvelopeAEADKeyTemplate(t *testing.T) {
 		t.Fatalf("fakekms.NewClient('fake-kms://') failed: %v", err)
 	}
 	registry.RegisterKMSClient(fakeKmsClient)
+	defer registry.RemoveKMSClient("fake-kms://")
 
 	fixedKeyURI := "fake-kms://CM2b3_MDElQKSAowdHlwZS5nb29nbGVhcGlzLmNvbS9nb29nbGUuY3J5cHRvLnRpbmsuQWVzR2NtS2V5EhIaEIK75t5L-adlUwVhWvRuWUwYARABGM2b3_MDIAE"
 	newKeyURI, err := fakekms.NewKeyURI()
@@ -144,6 +152,7 @@ func TestKMSEnvelopeAEADKeyTemplateMultipleKeysSameKEK(t *testing.T) {
 		t.Fatalf("fakekms.NewClient('fake-kms://') failed: %v", err)
 	}
 	registry.RegisterKMSClient(fakeKmsClient)
+	defer registry.RemoveKMSClient("fake-kms://")
 
 	fixedKeyURI := "fake-kms://CM2b3_MDElQKSAowdHlwZS5nb29nbGVhcGlzLmNvbS9nb29nbGUuY3J5cHRvLnRpbmsuQWVzR2NtS2V5EhIaEIK75t5L-adlUwVhWvRuWUwYARABGM2b3_MDIAE"
 	template1 := aead.KMSEnvelopeAEADKeyTemplate(fixedKeyURI, aead.AES128GCMKeyTemplate())
@@ -251,3 +260,19 @@ func testEncryptDecrypt(template *tinkpb.KeyTemplate) error {
 	}
 	return nil
 }
+
+func TestSupportedTemplates(t *testing.T) {
+	templates := aead.SupportedTemplates()
+	if got, want := templates["AES128_GCM"](), aead.AES128GCMKeyTemplate(); !proto.Equal(got, want) {
+		t.Errorf("SupportedTemplates()[%q]() = %s, want %s", "AES128_GCM", got, want)
+	}
+	for name, newTemplate := range templates {
+		template := newTemplate()
+		if _, err := keyset.NewHandle(template); err != nil {
+			t.Errorf("keyset.NewHandle(SupportedTemplates()[%q]()) failed: %s", name, err)
+		}
+		if wantRAW := strings.HasSuffix(name, "_RAW"); wantRAW != (template.OutputPrefixType == tinkpb.OutputPrefixType_RAW) {
+			t.Errorf("SupportedTemplates()[%q] has output prefix type %s", name, template.OutputPrefixType)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/testutil"
+	gcmsivpb "github.com/google/tink/go/proto/aes_gcm_siv_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+
+func TestAESGCMSIVGetPrimitiveBasic(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	for _, keySize := range keySizes {
+		key := newAESGCMSIVKey(testutil.AESGCMSIVKeyVersion, uint32(keySize))
+		serializedKey, _ := proto.Marshal(key)
+		p, err := keyManager.Primitive(serializedKey)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if err := validateAESGCMSIVPrimitive(p, key); err != nil {
+			t.Errorf("%s", err)
+		}
+	}
+}
+
+func TestAESGCMSIVGetPrimitiveWithInvalidInput(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	// invalid AESGCMSIVKey
+	testKeys := genInvalidAESGCMSIVKeys()
+	for i := 0; i < len(testKeys); i++ {
+		serializedKey, _ := proto.Marshal(testKeys[i])
+		if _, err := keyManager.Primitive(serializedKey); err == nil {
+			t.Errorf("expect an error in test case %d", i)
+		}
+	}
+	// nil
+	if _, err := keyManager.Primitive(nil); err == nil {
+		t.Errorf("expect an error when input is nil")
+	}
+	// empty array
+	if _, err := keyManager.Primitive([]byte{}); err == nil {
+		t.Errorf("expect an error when input is empty")
+	}
+}
+
+func TestAESGCMSIVNewKeyMultipleTimes(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	format := newAESGCMSIVKeyFormat(32)
+	serializedFormat, _ := proto.Marshal(format)
+	keys := make(map[string]bool)
+	nTest := 26
+	for i := 0; i < nTest; i++ {
+		key, _ := keyManager.NewKey(serializedFormat)
+		serializedKey, _ := proto.Marshal(key)
+		keys[string(serializedKey)] = true
+
+		keyData, _ := keyManager.NewKeyData(serializedFormat)
+		serializedKey = keyData.Value
+		keys[string(serializedKey)] = true
+	}
+	if len(keys) != nTest*2 {
+		t.Errorf("key is repeated")
+	}
+}
+
+func TestAESGCMSIVNewKeyBasic(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	for _, keySize := range keySizes {
+		format := newAESGCMSIVKeyFormat(uint32(keySize))
+		serializedFormat, _ := proto.Marshal(format)
+		m, err := keyManager.NewKey(serializedFormat)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		key := m.(*gcmsivpb.AesGcmSivKey)
+		if err := validateAESGCMSIVKey(key, format); err != nil {
+			t.Errorf("%s", err)
+		}
+	}
+}
+
+func TestAESGCMSIVNewKeyWithInvalidInput(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	// bad format
+	badFormats := genInvalidAESGCMSIVKeyFormats()
+	for i := 0; i < len(badFormats); i++ {
+		serializedFormat, _ := proto.Marshal(badFormats[i])
+		if _, err := keyManager.NewKey(serializedFormat); err == nil {
+			t.Errorf("expect an error in test case %d", i)
+		}
+	}
+	// nil
+	if _, err := keyManager.NewKey(nil); err == nil {
+		t.Errorf("expect an error when input is nil")
+	}
+	// empty array
+	if _, err := keyManager.NewKey([]byte{}); err == nil {
+		t.Errorf("expect an error when input is empty")
+	}
+}
+
+func TestAESGCMSIVNewKeyDataBasic(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	for _, keySize := range keySizes {
+		format := newAESGCMSIVKeyFormat(uint32(keySize))
+		serializedFormat, _ := proto.Marshal(format)
+		keyData, err := keyManager.NewKeyData(serializedFormat)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if keyData.TypeUrl != testutil.AESGCMSIVTypeURL {
+			t.Errorf("incorrect type url")
+		}
+		if keyData.KeyMaterialType != tinkpb.KeyData_SYMMETRIC {
+			t.Errorf("incorrect key material type")
+		}
+		key := new(gcmsivpb.AesGcmSivKey)
+		if err := proto.Unmarshal(keyData.Value, key); err != nil {
+			t.Errorf("incorrect key value")
+		}
+		if err := validateAESGCMSIVKey(key, format); err != nil {
+			t.Errorf("%s", err)
+		}
+	}
+}
+
+func TestAESGCMSIVNewKeyDataWithInvalidInput(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	badFormats := genInvalidAESGCMSIVKeyFormats()
+	for i := 0; i < len(badFormats); i++ {
+		serializedFormat, _ := proto.Marshal(badFormats[i])
+		if _, err := keyManager.NewKeyData(serializedFormat); err == nil {
+			t.Errorf("expect an error in test case %d", i)
+		}
+	}
+	// nil input
+	if _, err := keyManager.NewKeyData(nil); err == nil {
+		t.Errorf("expect an error when input is nil")
+	}
+	// empty input
+	if _, err := keyManager.NewKeyData([]byte{}); err == nil {
+		t.Errorf("expect an error when input is empty")
+	}
+}
+
+func TestAESGCMSIVDoesSupport(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	if !keyManager.DoesSupport(testutil.AESGCMSIVTypeURL) {
+		t.Errorf("AESGCMSIVKeyManager must support %s", testutil.AESGCMSIVTypeURL)
+	}
+	if keyManager.DoesSupport("some bad type") {
+		t.Errorf("AESGCMSIVKeyManager must support only %s", testutil.AESGCMSIVTypeURL)
+	}
+}
+
+func TestAESGCMSIVTypeURL(t *testing.T) {
+	keyManager, err := registry.GetKeyManager(testutil.AESGCMSIVTypeURL)
+	if err != nil {
+		t.Errorf("cannot obtain AES-GCM-SIV key manager: %s", err)
+	}
+	if keyManager.TypeURL() != testutil.AESGCMSIVTypeURL {
+		t.Errorf("incorrect key type")
+	}
+}
+
+func genInvalidAESGCMSIVKeys() []proto.Message {
+	return []proto.Message{
+		// not a AESGCMSIVKey
+		newAESGCMSIVKeyFormat(32),
+		// bad key size
+		newAESGCMSIVKey(testutil.AESGCMSIVKeyVersion, 17),
+		newAESGCMSIVKey(testutil.AESGCMSIVKeyVersion, 25),
+		newAESGCMSIVKey(testutil.AESGCMSIVKeyVersion, 33),
+		// bad version
+		newAESGCMSIVKey(testutil.AESGCMSIVKeyVersion+1, 16),
+	}
+}
+
+func genInvalidAESGCMSIVKeyFormats() []proto.Message {
+	return []proto.Message{
+		// not AESGCMSIVKeyFormat
+		newAESGCMSIVKey(testutil.AESGCMSIVKeyVersion, 16),
+		// invalid key size
+		newAESGCMSIVKeyFormat(uint32(15)),
+		newAESGCMSIVKeyFormat(uint32(23)),
+		newAESGCMSIVKeyFormat(uint32(31)),
+	}
+}
+
+func validateAESGCMSIVKey(key *gcmsivpb.AesGcmSivKey, format *gcmsivpb.AesGcmSivKeyFormat) error {
+	if uint32(len(key.KeyValue)) != format.KeySize {
+		return fmt.Errorf("incorrect key size")
+	}
+	if key.Version != testutil.AESGCMSIVKeyVersion {
+		return fmt.Errorf("incorrect key version")
+	}
+	// try to encrypt and decrypt
+	p, err := subtle.NewAESGCMSIV(key.KeyValue)
+	if err != nil {
+		return fmt.Errorf("invalid key")
+	}
+	return validateAESGCMSIVPrimitive(p, key)
+}
+
+func validateAESGCMSIVPrimitive(p interface{}, key *gcmsivpb.AesGcmSivKey) error {
+	cipher := p.(*subtle.AESGCMSIV)
+	if !bytes.Equal(cipher.Key, key.KeyValue) {
+		return fmt.Errorf("key and primitive don't match")
+	}
+	// try to encrypt and decrypt
+	pt := random.GetRandomBytes(32)
+	aad := random.GetRandomBytes(32)
+	ct, err := cipher.Encrypt(pt, aad)
+	if err != nil {
+		return fmt.Errorf("encryption failed")
+	}
+	decrypted, err := cipher.Decrypt(ct, aad)
+	if err != nil {
+		return fmt.Errorf("decryption failed")
+	}
+	if !bytes.Equal(decrypted, pt) {
+		return fmt.Errorf("decryption failed")
+	}
+	return nil
+}
+
+func newAESGCMSIVKey(keyVersion uint32, keySize uint32) *gcmsivpb.AesGcmSivKey {
+	return &gcmsivpb.AesGcmSivKey{
+		Version:  keyVersion,
+		KeyValue: random.GetRandomBytes(keySize),
+	}
+}
+
+func newAESGCMSIVKeyFormat(keySize uint32) *gcmsivpb.AesGcmSivKeyFormat {
+	return &gcmsivpb.AesGcmSivKeyFormat{
+		KeySize: keySize,
+	}
+}
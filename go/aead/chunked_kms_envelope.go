@@ -0,0 +1,214 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/tink"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+const (
+	chunkedEnvelopeVersion = 1
+
+	// MaxEnvelopeChunks is the largest number of chunks in a chunked KMS
+	// envelope, one per uint32 chunk index. It is a uint64 since it does not
+	// fit in an int on 32-bit platforms.
+	MaxEnvelopeChunks uint64 = 1 << 32
+)
+
+// ChunkedKMSEnvelopeEncrypter encrypts a large object as a sequence of chunks
+// under a single DEK, which is wrapped once with the remote AEAD and stored in
+// the header. Each chunk is sealed independently with the DEK; its index, a
+// flag marking the last chunk, the header and the associated data are bound
+// to it as associated data, so that ChunkedKMSEnvelopeDecrypter detects
+// reordered, dropped, duplicated and truncated chunks.
+//
+// The encrypter keeps no state besides the DEK, so an interrupted upload can
+// be resumed by creating a new encrypter with
+// ResumeChunkedKMSEnvelopeEncrypter from the stored header and continuing
+// with the chunk after the last acknowledged one. Every chunk gets a fresh
+// nonce from the DEK AEAD, so encrypting a chunk again after a failure is
+// safe. The DEK template must be safe for encrypting the expected number of
+// chunks with random nonces.
+type ChunkedKMSEnvelopeEncrypter struct {
+	dek    tink.AEAD
+	header []byte
+	aad    []byte
+}
+
+// NewChunkedKMSEnvelopeEncrypter generates a DEK from kt, wraps it with remote
+// and returns an encrypter for a new chunked envelope with associated data aad.
+func NewChunkedKMSEnvelopeEncrypter(kt *tinkpb.KeyTemplate, remote tink.AEAD, aad []byte) (*ChunkedKMSEnvelopeEncrypter, error) {
+	if kt == nil || remote == nil {
+		return nil, errors.New("chunked_kms_envelope: invalid DEK template or remote AEAD")
+	}
+	a := NewKMSEnvelopeAEAD2(kt, remote)
+	dek, encryptedDEK, err := a.newDEK(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("chunked_kms_envelope: cannot create DEK: %s", err)
+	}
+	header, err := buildCipherText(encryptedDEK, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ChunkedKMSEnvelopeEncrypter{
+		dek:    dek,
+		header: append([]byte{chunkedEnvelopeVersion}, header...),
+		aad:    aad,
+	}, nil
+}
+
+// ResumeChunkedKMSEnvelopeEncrypter returns an encrypter that continues the
+// chunked envelope with the given header, unwrapping its DEK with remote. kt
+// and aad must be the ones the envelope was created with.
+func ResumeChunkedKMSEnvelopeEncrypter(kt *tinkpb.KeyTemplate, remote tink.AEAD, header, aad []byte) (*ChunkedKMSEnvelopeEncrypter, error) {
+	dek, err := openChunkedEnvelopeHeader(kt, remote, header)
+	if err != nil {
+		return nil, err
+	}
+	return &ChunkedKMSEnvelopeEncrypter{
+		dek:    dek,
+		header: append([]byte{}, header...),
+		aad:    aad,
+	}, nil
+}
+
+// Header returns the header of the envelope, which contains the wrapped DEK.
+// It must be stored with the chunks and is needed to resume encryption and to
+// decrypt.
+func (e *ChunkedKMSEnvelopeEncrypter) Header() []byte {
+	return append([]byte{}, e.header...)
+}
+
+// EncryptChunk encrypts pt as the chunk with the given index, counting from
+// zero. last must be true for the final chunk of the object and only for it.
+// A chunk must always be encrypted with the same plaintext; resuming with
+// different data yields an object that mixes both versions.
+func (e *ChunkedKMSEnvelopeEncrypter) EncryptChunk(index uint32, pt []byte, last bool) ([]byte, error) {
+	return e.dek.Encrypt(pt, chunkAssociatedData(e.header, index, last, e.aad))
+}
+
+// ChunkedKMSEnvelopeDecrypter decrypts the chunks of an envelope created by a
+// ChunkedKMSEnvelopeEncrypter, in order.
+type ChunkedKMSEnvelopeDecrypter struct {
+	dek    tink.AEAD
+	header []byte
+	aad    []byte
+
+	next uint64
+	done bool
+}
+
+// NewChunkedKMSEnvelopeDecrypter returns a decrypter for the chunked envelope
+// with the given header, unwrapping its DEK with remote.
+func NewChunkedKMSEnvelopeDecrypter(kt *tinkpb.KeyTemplate, remote tink.AEAD, header, aad []byte) (*ChunkedKMSEnvelopeDecrypter, error) {
+	dek, err := openChunkedEnvelopeHeader(kt, remote, header)
+	if err != nil {
+		return nil, err
+	}
+	return &ChunkedKMSEnvelopeDecrypter{
+		dek:    dek,
+		header: append([]byte{}, header...),
+		aad:    aad,
+	}, nil
+}
+
+// DecryptChunk decrypts the next chunk. It fails if ct is not the chunk that
+// follows the previously decrypted one, or if the last chunk has already been
+// decrypted.
+func (d *ChunkedKMSEnvelopeDecrypter) DecryptChunk(ct []byte) ([]byte, error) {
+	if d.done {
+		return nil, errors.New("chunked_kms_envelope: chunk after the last chunk")
+	}
+	if d.next >= MaxEnvelopeChunks {
+		return nil, errors.New("chunked_kms_envelope: too many chunks")
+	}
+	index := uint32(d.next)
+	if pt, err := d.dek.Decrypt(ct, chunkAssociatedData(d.header, index, false, d.aad)); err == nil {
+		d.next++
+		return pt, nil
+	}
+	pt, err := d.dek.Decrypt(ct, chunkAssociatedData(d.header, index, true, d.aad))
+	if err != nil {
+		return nil, fmt.Errorf("chunked_kms_envelope: cannot decrypt chunk %d", index)
+	}
+	d.next++
+	d.done = true
+	return pt, nil
+}
+
+// Finish returns an error unless the last chunk has been decrypted. Plaintext
+// returned by DecryptChunk must not be considered complete before Finish
+// succeeds.
+func (d *ChunkedKMSEnvelopeDecrypter) Finish() error {
+	if !d.done {
+		return fmt.Errorf("chunked_kms_envelope: truncated envelope, last chunk missing after %d chunks", d.next)
+	}
+	return nil
+}
+
+// openChunkedEnvelopeHeader parses header and returns the DEK primitive
+// unwrapped with remote.
+func openChunkedEnvelopeHeader(kt *tinkpb.KeyTemplate, remote tink.AEAD, header []byte) (tink.AEAD, error) {
+	if kt == nil || remote == nil {
+		return nil, errors.New("chunked_kms_envelope: invalid DEK template or remote AEAD")
+	}
+	if len(header) == 0 || header[0] != chunkedEnvelopeVersion {
+		return nil, errors.New("chunked_kms_envelope: invalid header")
+	}
+	encryptedDEK, rest, err := parseCipherText(header[1:])
+	if err != nil || len(rest) != 0 {
+		return nil, errors.New("chunked_kms_envelope: invalid header")
+	}
+	a := NewKMSEnvelopeAEAD2(kt, remote)
+	dek, err := a.remoteDecrypt(context.Background(), encryptedDEK)
+	if err != nil {
+		return nil, fmt.Errorf("chunked_kms_envelope: cannot unwrap DEK: %s", err)
+	}
+	p, err := registry.Primitive(kt.TypeUrl, dek)
+	if err != nil {
+		return nil, fmt.Errorf("chunked_kms_envelope: %s", err)
+	}
+	primitive, ok := p.(tink.AEAD)
+	if !ok {
+		return nil, errors.New("chunked_kms_envelope: failed to convert AEAD primitive")
+	}
+	return primitive, nil
+}
+
+// chunkAssociatedData returns header || index || last || aad. The header is
+// self-delimiting and index and last have a fixed length, so the encoding is
+// unambiguous.
+func chunkAssociatedData(header []byte, index uint32, last bool, aad []byte) []byte {
+	var b bytes.Buffer
+	b.Write(header)
+	var buf [5]byte
+	binary.BigEndian.PutUint32(buf[:4], index)
+	if last {
+		buf[4] = 1
+	}
+	b.Write(buf[:])
+	b.Write(aad)
+	return b.Bytes()
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/tink"
+)
+
+func newChunkedEnvelopeKEK(t *testing.T) tink.AEAD {
+	t.Helper()
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	kek, err := aead.New(kh)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	return kek
+}
+
+func splitChunks(data []byte, size int) [][]byte {
+	var chunks [][]byte
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+func encryptChunks(t *testing.T, e *aead.ChunkedKMSEnvelopeEncrypter, chunks [][]byte, from int) [][]byte {
+	t.Helper()
+	var cts [][]byte
+	for i := from; i < len(chunks); i++ {
+		ct, err := e.EncryptChunk(uint32(i), chunks[i], i == len(chunks)-1)
+		if err != nil {
+			t.Fatalf("EncryptChunk(%d) err = %v, want nil", i, err)
+		}
+		cts = append(cts, ct)
+	}
+	return cts
+}
+
+func decryptChunks(header []byte, kek tink.AEAD, aad []byte, cts [][]byte) ([]byte, error) {
+	d, err := aead.NewChunkedKMSEnvelopeDecrypter(aead.AES128GCMKeyTemplate(), kek, header, aad)
+	if err != nil {
+		return nil, err
+	}
+	var pt []byte
+	for _, ct := range cts {
+		p, err := d.DecryptChunk(ct)
+		if err != nil {
+			return nil, err
+		}
+		pt = append(pt, p...)
+	}
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	return pt, nil
+}
+
+func TestChunkedKMSEnvelopeRoundTrip(t *testing.T) {
+	kek := newChunkedEnvelopeKEK(t)
+	aad := []byte("object name")
+	for _, size := range []int{0, 1, 100, 1000, 1024} {
+		data := random.GetRandomBytes(uint32(size))
+		e, err := aead.NewChunkedKMSEnvelopeEncrypter(aead.AES128GCMKeyTemplate(), kek, aad)
+		if err != nil {
+			t.Fatalf("NewChunkedKMSEnvelopeEncrypter() err = %v, want nil", err)
+		}
+		cts := encryptChunks(t, e, splitChunks(data, 256), 0)
+		got, err := decryptChunks(e.Header(), kek, aad, cts)
+		if err != nil {
+			t.Fatalf("size %d: decryption err = %v, want nil", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("size %d: decrypted data differs from the original", size)
+		}
+	}
+}
+
+func TestChunkedKMSEnvelopeResume(t *testing.T) {
+	kek := newChunkedEnvelopeKEK(t)
+	aad := []byte("object name")
+	data := random.GetRandomBytes(1000)
+	chunks := splitChunks(data, 100)
+
+	e, err := aead.NewChunkedKMSEnvelopeEncrypter(aead.AES128GCMKeyTemplate(), kek, aad)
+	if err != nil {
+		t.Fatalf("NewChunkedKMSEnvelopeEncrypter() err = %v, want nil", err)
+	}
+	header := e.Header()
+	// The upload fails after 4 chunks have been acknowledged. Chunk 4 was
+	// encrypted but not stored.
+	uploaded := encryptChunks(t, e, chunks[:5], 0)[:4]
+
+	resumed, err := aead.ResumeChunkedKMSEnvelopeEncrypter(aead.AES128GCMKeyTemplate(), kek, header, aad)
+	if err != nil {
+		t.Fatalf("ResumeChunkedKMSEnvelopeEncrypter() err = %v, want nil", err)
+	}
+	if !bytes.Equal(resumed.Header(), header) {
+		t.Errorf("resumed.Header() differs from the header of the envelope")
+	}
+	uploaded = append(uploaded, encryptChunks(t, resumed, chunks, 4)...)
+
+	got, err := decryptChunks(header, kek, aad, uploaded)
+	if err != nil {
+		t.Fatalf("decryption err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("decrypted data differs from the original")
+	}
+}
+
+func TestChunkedKMSEnvelopeDetectsModifiedChunks(t *testing.T) {
+	kek := newChunkedEnvelopeKEK(t)
+	aad := []byte("object name")
+	e, err := aead.NewChunkedKMSEnvelopeEncrypter(aead.AES128GCMKeyTemplate(), kek, aad)
+	if err != nil {
+		t.Fatalf("NewChunkedKMSEnvelopeEncrypter() err = %v, want nil", err)
+	}
+	cts := encryptChunks(t, e, splitChunks(random.GetRandomBytes(400), 100), 0)
+	other, err := aead.NewChunkedKMSEnvelopeEncrypter(aead.AES128GCMKeyTemplate(), kek, aad)
+	if err != nil {
+		t.Fatalf("NewChunkedKMSEnvelopeEncrypter() err = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name   string
+		header []byte
+		aad    []byte
+		cts    [][]byte
+	}{
+		{"reordered", e.Header(), aad, [][]byte{cts[0], cts[2], cts[1], cts[3]}},
+		{"dropped", e.Header(), aad, [][]byte{cts[0], cts[2], cts[3]}},
+		{"duplicated", e.Header(), aad, [][]byte{cts[0], cts[1], cts[1], cts[2], cts[3]}},
+		{"truncated", e.Header(), aad, cts[:3]},
+		{"after last", e.Header(), aad, append(append([][]byte{}, cts...), cts[3])},
+		{"no chunks", e.Header(), aad, nil},
+		{"wrong aad", e.Header(), []byte("other name"), cts},
+		{"other header", other.Header(), aad, cts},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decryptChunks(tc.header, kek, tc.aad, tc.cts); err == nil {
+				t.Errorf("decryption err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestChunkedKMSEnvelopeInvalidHeader(t *testing.T) {
+	kek := newChunkedEnvelopeKEK(t)
+	e, err := aead.NewChunkedKMSEnvelopeEncrypter(aead.AES128GCMKeyTemplate(), kek, nil)
+	if err != nil {
+		t.Fatalf("NewChunkedKMSEnvelopeEncrypter() err = %v, want nil", err)
+	}
+	header := e.Header()
+	wrongVersion := append([]byte{}, header...)
+	wrongVersion[0] = 2
+	for _, h := range [][]byte{nil, header[:1], header[:len(header)-1], append(header, 0), wrongVersion} {
+		if _, err := aead.NewChunkedKMSEnvelopeDecrypter(aead.AES128GCMKeyTemplate(), kek, h, nil); err == nil {
+			t.Errorf("NewChunkedKMSEnvelopeDecrypter(%x) err = nil, want error", h)
+		}
+		if _, err := aead.ResumeChunkedKMSEnvelopeEncrypter(aead.AES128GCMKeyTemplate(), kek, h, nil); err == nil {
+			t.Errorf("ResumeChunkedKMSEnvelopeEncrypter(%x) err = nil, want error", h)
+		}
+	}
+	if _, err := aead.NewChunkedKMSEnvelopeDecrypter(aead.AES128GCMKeyTemplate(), newChunkedEnvelopeKEK(t), header, nil); err == nil {
+		t.Errorf("NewChunkedKMSEnvelopeDecrypter() with another KEK err = nil, want error")
+	}
+}
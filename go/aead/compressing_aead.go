@@ -0,0 +1,161 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/google/tink/go/tink"
+)
+
+// DefaultMaxDecompressedSize is the largest plaintext, in bytes, that a
+// CompressingAEAD created with NewCompressingAEAD decompresses.
+const DefaultMaxDecompressedSize = 64 << 20
+
+var errDecompressedTooLarge = errors.New("compressing_aead: decompressed plaintext exceeds maximum size")
+
+// CompressionCodec is a compression algorithm usable with CompressingAEAD.
+type CompressionCodec interface {
+	// ID returns the format byte that identifies this codec in ciphertexts.
+	// Different codecs must use different IDs.
+	ID() byte
+
+	// Compress returns the compressed form of data.
+	Compress(data []byte) ([]byte, error)
+
+	// NewReader returns a reader that decompresses the data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCodec is a CompressionCodec using gzip (RFC 1952) at the default
+// compression level.
+var GzipCodec CompressionCodec = gzipCodec{}
+
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte { return 1 }
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// CompressingAEAD is an AEAD that compresses plaintexts before encrypting them
+// with an inner AEAD.
+//
+// Compression makes the ciphertext length depend on the content of the
+// plaintext. If a plaintext mixes secret data with data that an attacker can
+// influence, the attacker may learn the secret by observing ciphertext lengths
+// (as in the CRIME and BREACH attacks). Only use CompressingAEAD when that
+// cannot happen, e.g. when every plaintext is entirely produced by a trusted
+// party.
+//
+// The inner AEAD encrypts the codec's format byte followed by the compressed
+// plaintext, so the format byte is authenticated.
+type CompressingAEAD struct {
+	inner               tink.AEAD
+	codec               CompressionCodec
+	maxDecompressedSize int64
+}
+
+// Assert that CompressingAEAD implements the AEAD interface.
+var _ tink.AEAD = (*CompressingAEAD)(nil)
+
+// NewCompressingAEAD creates a CompressingAEAD that compresses with codec and
+// encrypts with inner. Decrypt refuses plaintexts larger than
+// DefaultMaxDecompressedSize.
+func NewCompressingAEAD(inner tink.AEAD, codec CompressionCodec) (*CompressingAEAD, error) {
+	return NewCompressingAEADWithMaxSize(inner, codec, DefaultMaxDecompressedSize)
+}
+
+// NewCompressingAEADWithMaxSize is like NewCompressingAEAD, but Decrypt refuses
+// plaintexts that decompress to more than maxDecompressedSize bytes.
+func NewCompressingAEADWithMaxSize(inner tink.AEAD, codec CompressionCodec, maxDecompressedSize int64) (*CompressingAEAD, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("compressing_aead: inner AEAD must not be nil")
+	}
+	if codec == nil {
+		return nil, fmt.Errorf("compressing_aead: codec must not be nil")
+	}
+	if maxDecompressedSize <= 0 {
+		return nil, fmt.Errorf("compressing_aead: maximum decompressed size must be positive")
+	}
+	return &CompressingAEAD{
+		inner:               inner,
+		codec:               codec,
+		maxDecompressedSize: maxDecompressedSize,
+	}, nil
+}
+
+// Encrypt compresses plaintext and encrypts it with additionalData as
+// additional authenticated data.
+func (a *CompressingAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	compressed, err := a.codec.Compress(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("compressing_aead: compression failed: %s", err)
+	}
+	payload := make([]byte, 0, 1+len(compressed))
+	payload = append(payload, a.codec.ID())
+	payload = append(payload, compressed...)
+	return a.inner.Encrypt(payload, additionalData)
+}
+
+// Decrypt decrypts ciphertext with additionalData as additional authenticated
+// data and decompresses the result.
+func (a *CompressingAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	payload, err := a.inner.Decrypt(ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("compressing_aead: missing format byte")
+	}
+	if payload[0] != a.codec.ID() {
+		return nil, fmt.Errorf("compressing_aead: unsupported format byte %d", payload[0])
+	}
+	r, err := a.codec.NewReader(bytes.NewReader(payload[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("compressing_aead: decompression failed: %s", err)
+	}
+	defer r.Close()
+	// Read one byte more than allowed to detect oversized plaintexts without
+	// decompressing all of them.
+	pt, err := ioutil.ReadAll(io.LimitReader(r, a.maxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("compressing_aead: decompression failed: %s", err)
+	}
+	if int64(len(pt)) > a.maxDecompressedSize {
+		return nil, errDecompressedTooLarge
+	}
+	return pt, nil
+}
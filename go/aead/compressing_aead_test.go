@@ -0,0 +1,135 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/tink"
+)
+
+func newInnerAEAD(t *testing.T) tink.AEAD {
+	t.Helper()
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	a, err := aead.New(kh)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	return a
+}
+
+func TestCompressingAEADEncryptDecrypt(t *testing.T) {
+	a, err := aead.NewCompressingAEAD(newInnerAEAD(t), aead.GzipCodec)
+	if err != nil {
+		t.Fatalf("aead.NewCompressingAEAD() err = %v", err)
+	}
+	ad := []byte("log shipping")
+	for _, pt := range [][]byte{
+		{},
+		[]byte("a"),
+		bytes.Repeat([]byte("INFO request served in 3ms\n"), 1000),
+		random.GetRandomBytes(4096),
+	} {
+		ct, err := a.Encrypt(pt, ad)
+		if err != nil {
+			t.Fatalf("a.Encrypt() err = %v", err)
+		}
+		got, err := a.Decrypt(ct, ad)
+		if err != nil {
+			t.Fatalf("a.Decrypt() err = %v", err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("a.Decrypt() = %q, want %q", got, pt)
+		}
+		if _, err := a.Decrypt(ct, []byte("wrong ad")); err == nil {
+			t.Error("a.Decrypt() with wrong additional data err = nil, want error")
+		}
+	}
+}
+
+func TestCompressingAEADCompresses(t *testing.T) {
+	inner := newInnerAEAD(t)
+	a, err := aead.NewCompressingAEAD(inner, aead.GzipCodec)
+	if err != nil {
+		t.Fatalf("aead.NewCompressingAEAD() err = %v", err)
+	}
+	pt := bytes.Repeat([]byte("x"), 1<<16)
+	ct, err := a.Encrypt(pt, nil)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v", err)
+	}
+	if len(ct) >= len(pt)/10 {
+		t.Errorf("len(ct) = %d, want much less than %d", len(ct), len(pt))
+	}
+	// Ciphertexts without the format byte are rejected.
+	rawCT, err := inner.Encrypt(pt, nil)
+	if err != nil {
+		t.Fatalf("inner.Encrypt() err = %v", err)
+	}
+	if _, err := a.Decrypt(rawCT, nil); err == nil {
+		t.Error("a.Decrypt() of uncompressed ciphertext err = nil, want error")
+	}
+}
+
+func TestCompressingAEADRejectsDecompressionBomb(t *testing.T) {
+	inner := newInnerAEAD(t)
+	const maxSize = 1 << 16
+	a, err := aead.NewCompressingAEADWithMaxSize(inner, aead.GzipCodec, maxSize)
+	if err != nil {
+		t.Fatalf("aead.NewCompressingAEADWithMaxSize() err = %v", err)
+	}
+	unbounded, err := aead.NewCompressingAEAD(inner, aead.GzipCodec)
+	if err != nil {
+		t.Fatalf("aead.NewCompressingAEAD() err = %v", err)
+	}
+
+	bomb, err := unbounded.Encrypt(make([]byte, 16<<20), nil)
+	if err != nil {
+		t.Fatalf("unbounded.Encrypt() err = %v", err)
+	}
+	if _, err := a.Decrypt(bomb, nil); err == nil {
+		t.Error("a.Decrypt() of oversized plaintext err = nil, want error")
+	}
+
+	atLimit, err := unbounded.Encrypt(make([]byte, maxSize), nil)
+	if err != nil {
+		t.Fatalf("unbounded.Encrypt() err = %v", err)
+	}
+	if _, err := a.Decrypt(atLimit, nil); err != nil {
+		t.Errorf("a.Decrypt() of plaintext at the limit err = %v, want nil", err)
+	}
+}
+
+func TestNewCompressingAEADWithInvalidArguments(t *testing.T) {
+	inner := newInnerAEAD(t)
+	if _, err := aead.NewCompressingAEAD(nil, aead.GzipCodec); err == nil {
+		t.Error("aead.NewCompressingAEAD() with nil AEAD err = nil, want error")
+	}
+	if _, err := aead.NewCompressingAEAD(inner, nil); err == nil {
+		t.Error("aead.NewCompressingAEAD() with nil codec err = nil, want error")
+	}
+	if _, err := aead.NewCompressingAEADWithMaxSize(inner, aead.GzipCodec, 0); err == nil {
+		t.Error("aead.NewCompressingAEADWithMaxSize() with zero size err = nil, want error")
+	}
+}
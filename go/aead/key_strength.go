@@ -0,0 +1,156 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/tink"
+	aeadpb "github.com/google/tink/go/proto/aes_ctr_hmac_aead_go_proto"
+	gcmpb "github.com/google/tink/go/proto/aes_gcm_go_proto"
+	gcmsivpb "github.com/google/tink/go/proto/aes_gcm_siv_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// chaCha20KeyBits is the key size of ChaCha20-Poly1305 and XChaCha20-Poly1305
+// in bits.
+const chaCha20KeyBits = 256
+
+// NewStrict is like New, but fails if the primary key of h is weaker than
+// minKeyBits bits, e.g. if it is an AES128-GCM key and minKeyBits is 256.
+// This lets services reject keysets that violate a key strength policy at
+// startup instead of when encrypting.
+//
+// The strength of AES-GCM and AES-GCM-SIV keys is their key size; the
+// strength of AES-CTR-HMAC keys is the smaller of the AES and HMAC key sizes;
+// ChaCha20-Poly1305 and XChaCha20-Poly1305 keys have 256 bits. NewStrict fails
+// for other key types, such as KMS envelope keys, whose strength it cannot
+// determine. Only the primary key is checked, since it is the one used for
+// encryption.
+func NewStrict(h *keyset.Handle, minKeyBits int, opts ...Option) (tink.AEAD, error) {
+	if h == nil {
+		return nil, fmt.Errorf("aead_factory: nil keyset handle")
+	}
+	var typeURL string
+	info := h.KeysetInfo()
+	for _, key := range info.GetKeyInfo() {
+		if key.GetKeyId() == info.GetPrimaryKeyId() {
+			typeURL = key.GetTypeUrl()
+		}
+	}
+	// The key manager is given the serialized key of every ENABLED key of the
+	// primary's type, so the key material is not read from the keyset.
+	ps, err := h.PrimitivesWithKeyManager(&keyStrengthKeyManager{typeURL: typeURL})
+	if err != nil {
+		return nil, fmt.Errorf("aead_factory: %s", err)
+	}
+	if ps.Primary == nil {
+		return nil, fmt.Errorf("aead_factory: keyset has no ENABLED primary key")
+	}
+	bits, ok := ps.Primary.Primitive.(keyStrengthBits)
+	if !ok {
+		return nil, fmt.Errorf("aead_factory: cannot determine the strength of keys of type %q", typeURL)
+	}
+	if int(bits) < minKeyBits {
+		return nil, fmt.Errorf("aead_factory: primary key %d has %d bits, want at least %d", ps.Primary.KeyID, bits, minKeyBits)
+	}
+	return New(h, opts...)
+}
+
+// keyStrengthBits is the primitive of keyStrengthKeyManager.
+type keyStrengthBits int
+
+// keyStrengthKeyManager is a key manager for Handle.PrimitivesWithKeyManager
+// whose primitives are the keyStrengthBits of the keys of typeURL. It supports
+// typeURL only if keyStrength knows its keys.
+type keyStrengthKeyManager struct {
+	typeURL string
+}
+
+var _ registry.KeyManager = (*keyStrengthKeyManager)(nil)
+
+func (km *keyStrengthKeyManager) Primitive(serializedKey []byte) (interface{}, error) {
+	bits, err := keyStrength(km.typeURL, serializedKey)
+	if err != nil {
+		return nil, err
+	}
+	return keyStrengthBits(bits), nil
+}
+
+func (km *keyStrengthKeyManager) NewKey(serializedKeyFormat []byte) (proto.Message, error) {
+	return nil, errKeyStrengthNotSupported
+}
+
+func (km *keyStrengthKeyManager) NewKeyData(serializedKeyFormat []byte) (*tinkpb.KeyData, error) {
+	return nil, errKeyStrengthNotSupported
+}
+
+func (km *keyStrengthKeyManager) DoesSupport(typeURL string) bool {
+	if typeURL != km.typeURL {
+		return false
+	}
+	switch typeURL {
+	case aesGCMTypeURL, aesGCMSIVTypeURL, aesCTRHMACAEADTypeURL, chaCha20Poly1305TypeURL, xChaCha20Poly1305TypeURL:
+		return true
+	default:
+		return false
+	}
+}
+
+func (km *keyStrengthKeyManager) TypeURL() string {
+	return km.typeURL
+}
+
+var errKeyStrengthNotSupported = errors.New("aead_factory: not supported")
+
+// keyStrength returns the strength in bits of serializedKey, an AEAD key of
+// the given type.
+func keyStrength(typeURL string, serializedKey []byte) (int, error) {
+	switch typeURL {
+	case aesGCMTypeURL:
+		key := new(gcmpb.AesGcmKey)
+		if err := proto.Unmarshal(serializedKey, key); err != nil {
+			return 0, fmt.Errorf("invalid AES-GCM key: %s", err)
+		}
+		return 8 * len(key.KeyValue), nil
+	case aesGCMSIVTypeURL:
+		key := new(gcmsivpb.AesGcmSivKey)
+		if err := proto.Unmarshal(serializedKey, key); err != nil {
+			return 0, fmt.Errorf("invalid AES-GCM-SIV key: %s", err)
+		}
+		return 8 * len(key.KeyValue), nil
+	case aesCTRHMACAEADTypeURL:
+		key := new(aeadpb.AesCtrHmacAeadKey)
+		if err := proto.Unmarshal(serializedKey, key); err != nil {
+			return 0, fmt.Errorf("invalid AES-CTR-HMAC key: %s", err)
+		}
+		aesBits := 8 * len(key.GetAesCtrKey().GetKeyValue())
+		hmacBits := 8 * len(key.GetHmacKey().GetKeyValue())
+		if hmacBits < aesBits {
+			return hmacBits, nil
+		}
+		return aesBits, nil
+	case chaCha20Poly1305TypeURL, xChaCha20Poly1305TypeURL:
+		return chaCha20KeyBits, nil
+	default:
+		return 0, fmt.Errorf("cannot determine the strength of keys of type %q", typeURL)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+func TestNewStrict(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		template *tinkpb.KeyTemplate
+		bits     int
+	}{
+		{name: "AES128_GCM", template: aead.AES128GCMKeyTemplate(), bits: 128},
+		{name: "AES256_GCM", template: aead.AES256GCMKeyTemplate(), bits: 256},
+		{name: "AES128_GCM_SIV", template: aead.AES128GCMSIVKeyTemplate(), bits: 128},
+		{name: "AES256_GCM_SIV", template: aead.AES256GCMSIVKeyTemplate(), bits: 256},
+		{name: "AES128_CTR_HMAC_SHA256", template: aead.AES128CTRHMACSHA256KeyTemplate(), bits: 128},
+		{name: "AES256_CTR_HMAC_SHA256", template: aead.AES256CTRHMACSHA256KeyTemplate(), bits: 256},
+		{name: "CHACHA20_POLY1305", template: aead.ChaCha20Poly1305KeyTemplate(), bits: 256},
+		{name: "XCHACHA20_POLY1305", template: aead.XChaCha20Poly1305KeyTemplate(), bits: 256},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := keyset.NewHandle(tc.template)
+			if err != nil {
+				t.Fatalf("keyset.NewHandle() err = %v", err)
+			}
+			a, err := aead.NewStrict(h, tc.bits)
+			if err != nil {
+				t.Fatalf("aead.NewStrict(h, %d) err = %v", tc.bits, err)
+			}
+			pt, ad := []byte("plaintext"), []byte("ad")
+			ct, err := a.Encrypt(pt, ad)
+			if err != nil {
+				t.Fatalf("a.Encrypt() err = %v", err)
+			}
+			if got, err := a.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+				t.Errorf("a.Decrypt() = %q, %v, want %q, nil", got, err, pt)
+			}
+			if _, err := aead.NewStrict(h, tc.bits+1); err == nil {
+				t.Errorf("aead.NewStrict(h, %d) err = nil, want error", tc.bits+1)
+			}
+		})
+	}
+}
+
+func TestNewStrictChecksPrimaryKey(t *testing.T) {
+	ksm := keyset.NewManager()
+	if err := ksm.Rotate(aead.AES256GCMKeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	if err := ksm.Rotate(aead.AES128GCMKeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	if _, err := aead.NewStrict(h, 256); err == nil {
+		t.Error("aead.NewStrict(h, 256) with an AES128-GCM primary key err = nil, want error")
+	}
+	if err := ksm.Rotate(aead.AES256GCMKeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	if _, err := aead.NewStrict(h, 256); err != nil {
+		t.Errorf("aead.NewStrict(h, 256) with an AES256-GCM primary key err = %v", err)
+	}
+}
+
+func TestNewStrictFails(t *testing.T) {
+	envelope, err := keyset.NewHandle(aead.KMSEnvelopeAEADKeyTemplate("fake-kms://key", aead.AES256GCMKeyTemplate()))
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	macHandle, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	for name, h := range map[string]*keyset.Handle{"KMS envelope": envelope, "MAC": macHandle, "nil": nil} {
+		if _, err := aead.NewStrict(h, 128); err == nil {
+			t.Errorf("aead.NewStrict() with %s keyset err = nil, want error", name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"fmt"
+
+	kwpsubtle "github.com/google/tink/go/kwp/subtle"
+)
+
+// WrapKey wraps key with the AES key encryption key kek, using AES-KWP as
+// specified in RFC 5649 and NIST SP 800 38F. kek must be 16, 24 or 32 bytes
+// long, and key between kwpsubtle.MinWrapSize and kwpsubtle.MaxWrapSize
+// bytes.
+//
+// This is meant for exchanging keys with systems that use AES key wrapping.
+// Unlike an AEAD, key wrapping is deterministic and takes no associated data.
+func WrapKey(kek, key []byte) ([]byte, error) {
+	kwp, err := kwpsubtle.NewKWP(kek)
+	if err != nil {
+		return nil, fmt.Errorf("key_wrap: %s", err)
+	}
+	wrapped, err := kwp.Wrap(key)
+	if err != nil {
+		return nil, fmt.Errorf("key_wrap: %s", err)
+	}
+	return wrapped, nil
+}
+
+// UnwrapKey unwraps a key that was wrapped with WrapKey, or any other
+// implementation of AES-KWP, under the key encryption key kek. It returns an
+// error if wrapped is malformed or was not wrapped under kek.
+func UnwrapKey(kek, wrapped []byte) ([]byte, error) {
+	kwp, err := kwpsubtle.NewKWP(kek)
+	if err != nil {
+		return nil, fmt.Errorf("key_wrap: %s", err)
+	}
+	key, err := kwp.Unwrap(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("key_wrap: %s", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/subtle/random"
+)
+
+func TestWrapUnwrapKey(t *testing.T) {
+	for _, kekSize := range []uint32{16, 24, 32} {
+		kek := random.GetRandomBytes(kekSize)
+		key := random.GetRandomBytes(32)
+		wrapped, err := aead.WrapKey(kek, key)
+		if err != nil {
+			t.Fatalf("aead.WrapKey() with a %d-byte KEK failed: %s", kekSize, err)
+		}
+		got, err := aead.UnwrapKey(kek, wrapped)
+		if err != nil {
+			t.Fatalf("aead.UnwrapKey() with a %d-byte KEK failed: %s", kekSize, err)
+		}
+		if !bytes.Equal(got, key) {
+			t.Errorf("aead.UnwrapKey() = %x, want %x", got, key)
+		}
+		if _, err := aead.UnwrapKey(random.GetRandomBytes(kekSize), wrapped); err == nil {
+			t.Errorf("aead.UnwrapKey() with a wrong KEK succeeded, want error")
+		}
+		if _, err := aead.UnwrapKey(kek, wrapped[1:]); err == nil {
+			t.Errorf("aead.UnwrapKey() of a truncated wrapped key succeeded, want error")
+		}
+	}
+}
+
+func TestWrapKeyRFC5649Vector(t *testing.T) {
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	key, _ := hex.DecodeString("c37b7e6492584340bed12207808941155068f738")
+	want, _ := hex.DecodeString("138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a")
+	got, err := aead.WrapKey(kek, key)
+	if err != nil {
+		t.Fatalf("aead.WrapKey() failed: %s", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("aead.WrapKey() = %x, want %x", got, want)
+	}
+}
+
+func TestWrapKeyInvalidInput(t *testing.T) {
+	for _, kekSize := range []uint32{0, 8, 15, 17, 31, 33, 64} {
+		if _, err := aead.WrapKey(make([]byte, kekSize), random.GetRandomBytes(16)); err == nil {
+			t.Errorf("aead.WrapKey() with a %d-byte KEK succeeded, want error", kekSize)
+		}
+		if _, err := aead.UnwrapKey(make([]byte, kekSize), make([]byte, 24)); err == nil {
+			t.Errorf("aead.UnwrapKey() with a %d-byte KEK succeeded, want error", kekSize)
+		}
+	}
+	if _, err := aead.WrapKey(random.GetRandomBytes(16), random.GetRandomBytes(15)); err == nil {
+		t.Errorf("aead.WrapKey() of a 15-byte key succeeded, want error")
+	}
+	for _, size := range []int{0, 8, 16, 23, 25} {
+		if _, err := aead.UnwrapKey(random.GetRandomBytes(16), make([]byte, size)); err == nil {
+			t.Errorf("aead.UnwrapKey() of %d bytes succeeded, want error", size)
+		}
+	}
+}
@@ -18,24 +18,45 @@ package aead
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead/subtle"
 	"github.com/google/tink/go/core/registry"
 	"github.com/google/tink/go/tink"
+	gcmpb "github.com/google/tink/go/proto/aes_gcm_go_proto"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
 const (
 	lenDEK = 4
+
+	// MaxMessagesPerDEK is the largest number of messages that a KMSEnvelopeAEAD
+	// created with NewKMSEnvelopeAEADWithDEKReuse encrypts under a single DEK.
+	MaxMessagesPerDEK = 1 << 20
 )
 
 // KMSEnvelopeAEAD represents an instance of Envelope AEAD.
+//
+// If the remote AEAD implements tink.AEADWithContext, EncryptContext and
+// DecryptContext pass their context on to the remote AEAD. Otherwise they only
+// check that the context is not done before calling the remote AEAD.
 type KMSEnvelopeAEAD struct {
 	dekTemplate *tinkpb.KeyTemplate
 	remote      tink.AEAD
+
+	// maxMessagesPerDEK is the number of messages encrypted under the same DEK.
+	// Values <= 1 mean that a fresh DEK is used for every message.
+	maxMessagesPerDEK uint32
+
+	mu           sync.Mutex
+	dek          tink.AEAD
+	encryptedDEK []byte
+	dekUses      uint32
 }
 
 // NewKMSEnvelopeAEAD creates an new instance of KMSEnvelopeAEAD.
@@ -55,60 +76,145 @@ func NewKMSEnvelopeAEAD2(kt *tinkpb.KeyTemplate, remote tink.AEAD) *KMSEnvelopeA
 	}
 }
 
+// Assert that KMSEnvelopeAEAD implements the AEADWithContext interface.
+var _ tink.AEADWithContext = (*KMSEnvelopeAEAD)(nil)
+
+// NewKMSEnvelopeAEADWithDEKReuse creates an new instance of KMSEnvelopeAEAD
+// which encrypts up to maxMessagesPerDEK messages under the same DEK before
+// generating and wrapping a new one. This saves one call to the remote AEAD
+// per message when encrypting many small messages.
+//
+// maxMessagesPerDEK must be in the range [1..MaxMessagesPerDEK], and kt must
+// be an AES-GCM key template. Instead of a random IV, the payload of the n-th
+// message under a DEK uses the counter n as IV, see
+// subtle.AESGCMCounterNonce, so the nonces under a DEK are unique however
+// many messages it encrypts. The ciphertext format is unchanged: every
+// ciphertext still carries its wrapped DEK, and AES-GCM decryption accepts
+// any IV, so it can be decrypted by any KMSEnvelopeAEAD with the same remote
+// AEAD.
+//
+// Reusing a DEK weakens the isolation between messages: all messages
+// encrypted under the same DEK are compromised together if that DEK leaks,
+// and the IVs reveal which messages share a DEK and in which order they were
+// encrypted.
+func NewKMSEnvelopeAEADWithDEKReuse(kt *tinkpb.KeyTemplate, remote tink.AEAD, maxMessagesPerDEK uint32) (*KMSEnvelopeAEAD, error) {
+	if maxMessagesPerDEK < 1 || maxMessagesPerDEK > MaxMessagesPerDEK {
+		return nil, fmt.Errorf("kms_envelope_aead: maxMessagesPerDEK must be in the range [1..%d], got %d", MaxMessagesPerDEK, maxMessagesPerDEK)
+	}
+	if kt == nil || kt.TypeUrl != aesGCMTypeURL {
+		return nil, errors.New("kms_envelope_aead: DEK reuse requires an AES-GCM key template")
+	}
+	return &KMSEnvelopeAEAD{
+		remote:            remote,
+		dekTemplate:       kt,
+		maxMessagesPerDEK: maxMessagesPerDEK,
+	}, nil
+}
+
 // Encrypt implements the tink.AEAD interface for encryption.
 func (a *KMSEnvelopeAEAD) Encrypt(pt, aad []byte) ([]byte, error) {
-	dekM, err := registry.NewKey(a.dekTemplate)
+	return a.EncryptContext(context.Background(), pt, aad)
+}
+
+// EncryptContext implements the tink.AEADWithContext interface for encryption.
+// ctx only applies to the call to the remote AEAD that wraps a new DEK.
+func (a *KMSEnvelopeAEAD) EncryptContext(ctx context.Context, pt, aad []byte) ([]byte, error) {
+	primitive, encryptedDEK, err := a.nextDEK(ctx)
 	if err != nil {
 		return nil, err
 	}
-	dek, err := proto.Marshal(dekM)
+	payload, err := primitive.Encrypt(pt, aad)
 	if err != nil {
 		return nil, err
 	}
-	encryptedDEK, err := a.remote.Encrypt(dek, []byte{})
+	return buildCipherText(encryptedDEK, payload)
+}
+
+// nextDEK returns the DEK primitive and the wrapped DEK to be used for the next
+// encryption, generating a new DEK if the current one has been used up. The
+// new DEK is wrapped without holding a.mu, so that a slow remote AEAD only
+// delays the callers that need a new DEK.
+func (a *KMSEnvelopeAEAD) nextDEK(ctx context.Context) (tink.AEAD, []byte, error) {
+	if a.maxMessagesPerDEK <= 1 {
+		return a.newDEK(ctx)
+	}
+	a.mu.Lock()
+	if a.dek != nil && a.dekUses < a.maxMessagesPerDEK {
+		a.dekUses++
+		primitive, encryptedDEK := a.dek, a.encryptedDEK
+		a.mu.Unlock()
+		return primitive, encryptedDEK, nil
+	}
+	a.mu.Unlock()
+
+	primitive, encryptedDEK, err := a.newDEK(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Another caller may have installed a new DEK in the meantime. Then this
+	// DEK only encrypts the current message.
+	if a.dek == nil || a.dekUses >= a.maxMessagesPerDEK {
+		a.dek = primitive
+		a.encryptedDEK = encryptedDEK
+		a.dekUses = 1
+	}
+	return primitive, encryptedDEK, nil
+}
+
+// newDEK generates a fresh DEK and wraps it with the remote AEAD. If DEKs are
+// reused, the returned primitive is a subtle.AESGCMCounterNonce.
+func (a *KMSEnvelopeAEAD) newDEK(ctx context.Context) (tink.AEAD, []byte, error) {
+	dekM, err := registry.NewKey(a.dekTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+	dek, err := proto.Marshal(dekM)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedDEK, err := a.remoteEncrypt(ctx, dek)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.maxMessagesPerDEK > 1 {
+		key := new(gcmpb.AesGcmKey)
+		if err := proto.Unmarshal(dek, key); err != nil {
+			return nil, nil, fmt.Errorf("kms_envelope_aead: invalid DEK: %s", err)
+		}
+		primitive, err := subtle.NewAESGCMCounterNonce(key.KeyValue, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("kms_envelope_aead: %s", err)
+		}
+		return primitive, encryptedDEK, nil
 	}
 	p, err := registry.Primitive(a.dekTemplate.TypeUrl, dek)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	primitive, ok := p.(tink.AEAD)
 	if !ok {
-		return nil, errors.New("kms_envelope_aead: failed to convert AEAD primitive")
+		return nil, nil, errors.New("kms_envelope_aead: failed to convert AEAD primitive")
 	}
-
-	payload, err := primitive.Encrypt(pt, aad)
-	if err != nil {
-		return nil, err
-	}
-	return buildCipherText(encryptedDEK, payload)
-
+	return primitive, encryptedDEK, nil
 }
 
 // Decrypt implements the tink.AEAD interface for decryption.
 func (a *KMSEnvelopeAEAD) Decrypt(ct, aad []byte) ([]byte, error) {
-	// Verify we have enough bytes for the length of the encrypted DEK.
-	if len(ct) <= lenDEK {
-		return nil, errors.New("kms_envelope_aead: invalid ciphertext")
-	}
-
-	// Extract length of encrypted DEK and advance past that length.
-	ed := int(binary.BigEndian.Uint32(ct[:lenDEK]))
-	ct = ct[lenDEK:]
+	return a.DecryptContext(context.Background(), ct, aad)
+}
 
-	// Verify we have enough bytes for the encrypted DEK.
-	if ed <= 0 || len(ct) < ed {
-		return nil, errors.New("kms_envelope_aead: invalid ciphertext")
+// DecryptContext implements the tink.AEADWithContext interface for decryption.
+// ctx applies to the call to the remote AEAD that unwraps the DEK.
+func (a *KMSEnvelopeAEAD) DecryptContext(ctx context.Context, ct, aad []byte) ([]byte, error) {
+	encryptedDEK, payload, err := parseCipherText(ct)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract the encrypted DEK and the payload.
-	encryptedDEK := ct[:ed]
-	payload := ct[ed:]
-	ct = nil
-
 	// Decrypt the DEK.
-	dek, err := a.remote.Decrypt(encryptedDEK, []byte{})
+	dek, err := a.remoteDecrypt(ctx, encryptedDEK)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +233,74 @@ func (a *KMSEnvelopeAEAD) Decrypt(ct, aad []byte) ([]byte, error) {
 	return primitive.Decrypt(payload, aad)
 }
 
+// remoteEncrypt wraps dek with the remote AEAD, honoring ctx.
+func (a *KMSEnvelopeAEAD) remoteEncrypt(ctx context.Context, dek []byte) ([]byte, error) {
+	if r, ok := a.remote.(tink.AEADWithContext); ok {
+		return r.EncryptContext(ctx, dek, []byte{})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.remote.Encrypt(dek, []byte{})
+}
+
+// remoteDecrypt unwraps encryptedDEK with the remote AEAD, honoring ctx.
+func (a *KMSEnvelopeAEAD) remoteDecrypt(ctx context.Context, encryptedDEK []byte) ([]byte, error) {
+	if r, ok := a.remote.(tink.AEADWithContext); ok {
+		return r.DecryptContext(ctx, encryptedDEK, []byte{})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.remote.Decrypt(encryptedDEK, []byte{})
+}
+
+// RewrapEnvelope replaces the wrapped DEK in ct, a ciphertext of a
+// KMSEnvelopeAEAD without output prefix, by the DEK unwrapped with oldKEK and
+// wrapped again with newKEK. The encrypted payload is copied unchanged, so
+// this is much cheaper than decrypting and encrypting again when the KEK is
+// rotated. The result decrypts to the same plaintext with a KMSEnvelopeAEAD
+// whose remote AEAD is newKEK.
+func RewrapEnvelope(ct []byte, oldKEK, newKEK tink.AEAD) ([]byte, error) {
+	if oldKEK == nil || newKEK == nil {
+		return nil, errors.New("kms_envelope_aead: invalid KEK")
+	}
+	encryptedDEK, payload, err := parseCipherText(ct)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := oldKEK.Decrypt(encryptedDEK, []byte{})
+	if err != nil {
+		return nil, fmt.Errorf("kms_envelope_aead: cannot unwrap DEK: %s", err)
+	}
+	rewrappedDEK, err := newKEK.Encrypt(dek, []byte{})
+	if err != nil {
+		return nil, fmt.Errorf("kms_envelope_aead: cannot wrap DEK: %s", err)
+	}
+	return buildCipherText(rewrappedDEK, payload)
+}
+
+// parseCipherText splits a cipher text built by buildCipherText into the
+// encrypted DEK and the encrypted payload.
+func parseCipherText(ct []byte) ([]byte, []byte, error) {
+	// Verify we have enough bytes for the length of the encrypted DEK.
+	if len(ct) <= lenDEK {
+		return nil, nil, errors.New("kms_envelope_aead: invalid ciphertext")
+	}
+
+	// Extract length of encrypted DEK and advance past that length.
+	ed := int(binary.BigEndian.Uint32(ct[:lenDEK]))
+	ct = ct[lenDEK:]
+
+	// Verify we have enough bytes for the encrypted DEK.
+	if ed <= 0 || len(ct) < ed {
+		return nil, nil, errors.New("kms_envelope_aead: invalid ciphertext")
+	}
+
+	// Extract the encrypted DEK and the payload.
+	return ct[:ed], ct[ed:], nil
+}
+
 // buildCipherText builds the cipher text by appending the length DEK, encrypted DEK
 // and the encrypted payload.
 func buildCipherText(encryptedDEK, payload []byte) ([]byte, error) {
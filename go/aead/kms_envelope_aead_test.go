@@ -17,10 +17,17 @@
 package aead_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/tink/go/aead"
 	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/testing/fakekms"
 	"github.com/google/tink/go/tink"
 )
 
@@ -70,3 +77,309 @@ func TestKMSEnvelopeShortCiphertext(t *testing.T) {
 	}
 
 }
+
+// countingAEAD counts the calls to Encrypt of the wrapped AEAD.
+type countingAEAD struct {
+	tink.AEAD
+	encryptions int
+}
+
+func (a *countingAEAD) Encrypt(pt, aad []byte) ([]byte, error) {
+	a.encryptions++
+	return a.AEAD.Encrypt(pt, aad)
+}
+
+func encryptedDEK(t *testing.T, ct []byte) []byte {
+	t.Helper()
+	if len(ct) < 4 {
+		t.Fatalf("ciphertext too short: %d bytes", len(ct))
+	}
+	n := int(binary.BigEndian.Uint32(ct[:4]))
+	if len(ct) < 4+n {
+		t.Fatalf("ciphertext too short for a wrapped DEK of %d bytes", n)
+	}
+	return ct[4 : 4+n]
+}
+
+func TestKMSEnvelopeWithDEKReuse(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("failed to create new handle: %v", err)
+	}
+	parentAEAD, err := aead.New(kh)
+	if err != nil {
+		t.Fatalf("failed to create parent AEAD: %v", err)
+	}
+	remote := &countingAEAD{AEAD: parentAEAD}
+	const maxMessages = 3
+	a, err := aead.NewKMSEnvelopeAEADWithDEKReuse(aead.AES256GCMKeyTemplate(), remote, maxMessages)
+	if err != nil {
+		t.Fatalf("aead.NewKMSEnvelopeAEADWithDEKReuse() failed: %v", err)
+	}
+	// A fresh KMSEnvelopeAEAD with the same remote must be able to decrypt.
+	decrypter := aead.NewKMSEnvelopeAEAD2(aead.AES256GCMKeyTemplate(), parentAEAD)
+
+	var cts [][]byte
+	for i := 0; i < 2*maxMessages+1; i++ {
+		pt := []byte(fmt.Sprintf("message %d", i))
+		ct, err := a.Encrypt(pt, []byte("aad"))
+		if err != nil {
+			t.Fatalf("a.Encrypt() failed: %v", err)
+		}
+		got, err := decrypter.Decrypt(ct, []byte("aad"))
+		if err != nil {
+			t.Fatalf("decrypter.Decrypt() failed: %v", err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q; want %q", pt, got, pt)
+		}
+		cts = append(cts, ct)
+	}
+	if remote.encryptions != 3 {
+		t.Errorf("remote AEAD was called %d times, want 3", remote.encryptions)
+	}
+	for i := range cts {
+		sameDEK := bytes.Equal(encryptedDEK(t, cts[i]), encryptedDEK(t, cts[i-i%maxMessages]))
+		if !sameDEK {
+			t.Errorf("ciphertext %d does not reuse the DEK of ciphertext %d", i, i-i%maxMessages)
+		}
+		if i%maxMessages == 0 && i > 0 && bytes.Equal(encryptedDEK(t, cts[i]), encryptedDEK(t, cts[i-1])) {
+			t.Errorf("ciphertext %d reuses the DEK after %d messages, want a new DEK", i, maxMessages)
+		}
+	}
+}
+
+func TestKMSEnvelopeWithDEKReuseInvalidLimit(t *testing.T) {
+	kh, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("failed to create new handle: %v", err)
+	}
+	parentAEAD, err := aead.New(kh)
+	if err != nil {
+		t.Fatalf("failed to create parent AEAD: %v", err)
+	}
+	for _, n := range []uint32{0, aead.MaxMessagesPerDEK + 1} {
+		if _, err := aead.NewKMSEnvelopeAEADWithDEKReuse(aead.AES256GCMKeyTemplate(), parentAEAD, n); err == nil {
+			t.Errorf("aead.NewKMSEnvelopeAEADWithDEKReuse(..., %d) succeeded, want error", n)
+		}
+	}
+}
+
+func TestKMSEnvelopeWithDEKReuseRejectsOtherKeyTypes(t *testing.T) {
+	kek := newFakeKMSAEAD(t)
+	if _, err := aead.NewKMSEnvelopeAEADWithDEKReuse(aead.ChaCha20Poly1305KeyTemplate(), kek, 2); err == nil {
+		t.Error("aead.NewKMSEnvelopeAEADWithDEKReuse() with a ChaCha20-Poly1305 template succeeded, want error")
+	}
+}
+
+func TestKMSEnvelopeWithDEKReuseUsesCounterNonces(t *testing.T) {
+	const maxMessages = 3
+	a, err := aead.NewKMSEnvelopeAEADWithDEKReuse(aead.AES128GCMKeyTemplate(), newFakeKMSAEAD(t), maxMessages)
+	if err != nil {
+		t.Fatalf("aead.NewKMSEnvelopeAEADWithDEKReuse() err = %v", err)
+	}
+	for i := 0; i < 2*maxMessages; i++ {
+		ct, err := a.Encrypt([]byte("pt"), nil)
+		if err != nil {
+			t.Fatalf("a.Encrypt() err = %v", err)
+		}
+		want := make([]byte, 12)
+		binary.BigEndian.PutUint64(want[4:], uint64(i%maxMessages))
+		if iv := envelopePayload(t, ct)[:12]; !bytes.Equal(iv, want) {
+			t.Errorf("IV of message %d = %x, want %x", i, iv, want)
+		}
+	}
+}
+
+// blockingAEAD blocks in the first call to EncryptContext until its context
+// is done, and otherwise behaves like the wrapped AEAD.
+type blockingAEAD struct {
+	tink.AEAD
+	blocked chan struct{}
+	once    sync.Once
+}
+
+func (a *blockingAEAD) EncryptContext(ctx context.Context, pt, aad []byte) ([]byte, error) {
+	block := false
+	a.once.Do(func() { block = true })
+	if block {
+		close(a.blocked)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return a.AEAD.Encrypt(pt, aad)
+}
+
+func (a *blockingAEAD) DecryptContext(ctx context.Context, ct, aad []byte) ([]byte, error) {
+	return a.AEAD.Decrypt(ct, aad)
+}
+
+func TestKMSEnvelopeWithDEKReuseDoesNotBlockOnRemoteCall(t *testing.T) {
+	remote := &blockingAEAD{AEAD: newFakeKMSAEAD(t), blocked: make(chan struct{})}
+	a, err := aead.NewKMSEnvelopeAEADWithDEKReuse(aead.AES128GCMKeyTemplate(), remote, 2)
+	if err != nil {
+		t.Fatalf("aead.NewKMSEnvelopeAEADWithDEKReuse() err = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blockedErr := make(chan error, 1)
+	go func() {
+		_, err := a.EncryptContext(ctx, []byte("pt"), nil)
+		blockedErr <- err
+	}()
+	<-remote.blocked
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.Encrypt([]byte("pt"), nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("a.Encrypt() err = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("a.Encrypt() is blocked by a concurrent wrap of a new DEK")
+	}
+	cancel()
+	if err := <-blockedErr; err != context.Canceled {
+		t.Errorf("a.EncryptContext() err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func newFakeKMSAEAD(t *testing.T) tink.AEAD {
+	t.Helper()
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() err = %v", err)
+	}
+	client, err := fakekms.NewClient(keyURI)
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() err = %v", err)
+	}
+	kek, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v", err)
+	}
+	return kek
+}
+
+// envelopePayload returns the encrypted payload of an envelope ciphertext.
+func envelopePayload(t *testing.T, ct []byte) []byte {
+	t.Helper()
+	if len(ct) < 4 {
+		t.Fatalf("ciphertext too short")
+	}
+	return ct[4+binary.BigEndian.Uint32(ct[:4]):]
+}
+
+func TestRewrapEnvelope(t *testing.T) {
+	oldKEK, newKEK := newFakeKMSAEAD(t), newFakeKMSAEAD(t)
+	oldEnvelope := aead.NewKMSEnvelopeAEAD2(aead.AES128GCMKeyTemplate(), oldKEK)
+	newEnvelope := aead.NewKMSEnvelopeAEAD2(aead.AES128GCMKeyTemplate(), newKEK)
+	pt, ad := []byte("a large payload"), []byte("ad")
+	ct, err := oldEnvelope.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("oldEnvelope.Encrypt() err = %v", err)
+	}
+
+	rewrapped, err := aead.RewrapEnvelope(ct, oldKEK, newKEK)
+	if err != nil {
+		t.Fatalf("aead.RewrapEnvelope() err = %v", err)
+	}
+	if got, err := newEnvelope.Decrypt(rewrapped, ad); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("newEnvelope.Decrypt() = %q, %v, want %q, nil", got, err, pt)
+	}
+	if _, err := oldEnvelope.Decrypt(rewrapped, ad); err == nil {
+		t.Error("oldEnvelope.Decrypt() of rewrapped ciphertext err = nil, want error")
+	}
+	if !bytes.Equal(envelopePayload(t, rewrapped), envelopePayload(t, ct)) {
+		t.Error("aead.RewrapEnvelope() changed the encrypted payload")
+	}
+
+	// Only the old KEK can unwrap the DEK of the original ciphertext.
+	if _, err := aead.RewrapEnvelope(ct, newKEK, oldKEK); err == nil {
+		t.Error("aead.RewrapEnvelope() with wrong old KEK err = nil, want error")
+	}
+}
+
+func TestRewrapEnvelopeWithInvalidInput(t *testing.T) {
+	oldKEK, newKEK := newFakeKMSAEAD(t), newFakeKMSAEAD(t)
+	ct, err := aead.NewKMSEnvelopeAEAD2(aead.AES128GCMKeyTemplate(), oldKEK).Encrypt([]byte("pt"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+	for name, invalid := range map[string][]byte{
+		"empty":             nil,
+		"too short":         {0, 0, 0, 1},
+		"zero DEK length":   {0, 0, 0, 0, 1},
+		"DEK length beyond": {0, 0, 1, 0, 1, 2, 3},
+	} {
+		if _, err := aead.RewrapEnvelope(invalid, oldKEK, newKEK); err == nil {
+			t.Errorf("%s: aead.RewrapEnvelope() err = nil, want error", name)
+		}
+	}
+	if _, err := aead.RewrapEnvelope(ct, nil, newKEK); err == nil {
+		t.Error("aead.RewrapEnvelope() with nil old KEK err = nil, want error")
+	}
+	if _, err := aead.RewrapEnvelope(ct, oldKEK, nil); err == nil {
+		t.Error("aead.RewrapEnvelope() with nil new KEK err = nil, want error")
+	}
+}
+
+func TestKMSEnvelopeContextCancelsRemoteCall(t *testing.T) {
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() err = %v", err)
+	}
+	latency := 10 * time.Second
+	client, err := fakekms.NewClient(keyURI, fakekms.WithLatency(latency))
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() err = %v", err)
+	}
+	kek, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v", err)
+	}
+	a := aead.NewKMSEnvelopeAEAD2(aead.AES128GCMKeyTemplate(), kek)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := a.EncryptContext(ctx, []byte("pt"), nil); err != context.DeadlineExceeded {
+		t.Errorf("a.EncryptContext() err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := a.DecryptContext(ctx, []byte{0, 0, 0, 1, 0, 1}, nil); err != context.DeadlineExceeded {
+		t.Errorf("a.DecryptContext() err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed >= latency {
+		t.Errorf("a.EncryptContext() and a.DecryptContext() took %v, want less than %v", elapsed, latency)
+	}
+}
+
+func TestKMSEnvelopeContextWithRemoteWithoutContext(t *testing.T) {
+	// countingAEAD hides the context methods of the fake KMS AEAD.
+	kek := &countingAEAD{AEAD: newFakeKMSAEAD(t)}
+	a := aead.NewKMSEnvelopeAEAD2(aead.AES128GCMKeyTemplate(), kek)
+	ct, err := a.EncryptContext(context.Background(), []byte("pt"), []byte("ad"))
+	if err != nil {
+		t.Fatalf("a.EncryptContext() err = %v", err)
+	}
+	if got, err := a.DecryptContext(context.Background(), ct, []byte("ad")); err != nil || !bytes.Equal(got, []byte("pt")) {
+		t.Errorf("a.DecryptContext() = %q, %v, want %q, nil", got, err, "pt")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	encryptions := kek.encryptions
+	if _, err := a.EncryptContext(ctx, []byte("pt"), nil); err != context.Canceled {
+		t.Errorf("a.EncryptContext() err = %v, want %v", err, context.Canceled)
+	}
+	if _, err := a.DecryptContext(ctx, ct, []byte("ad")); err != context.Canceled {
+		t.Errorf("a.DecryptContext() err = %v, want %v", err, context.Canceled)
+	}
+	if kek.encryptions != encryptions {
+		t.Errorf("remote AEAD encrypted %d times with a cancelled context, want 0", kek.encryptions-encryptions)
+	}
+}
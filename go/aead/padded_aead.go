@@ -0,0 +1,105 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/tink/go/tink"
+)
+
+const (
+	paddingLengthSize = 4
+
+	// MaxPaddingBlockSize is the largest block size of a PaddedAEAD.
+	MaxPaddingBlockSize = 1 << 20
+)
+
+var errInvalidPadding = errors.New("padded_aead: invalid padding")
+
+// PaddedAEAD is an AEAD that hides the exact length of plaintexts by padding
+// them to a multiple of a block size before encrypting them with an inner
+// AEAD. Ciphertexts of plaintexts that differ in length, but pad to the same
+// number of blocks, have the same length.
+//
+// The inner AEAD encrypts the 4-byte big-endian plaintext length, followed by
+// the plaintext and as many zero bytes as needed to make the total a multiple
+// of the block size. A plaintext of n bytes thus pads to
+// ceil((n+4)/blockSize)*blockSize bytes; the 4 bytes of the length always
+// take up room, so e.g. a plaintext of exactly one block pads to two blocks.
+// The padding is authenticated by the inner AEAD, and Decrypt rejects a
+// length that does not fit or padding bytes that are not zero.
+type PaddedAEAD struct {
+	inner     tink.AEAD
+	blockSize int
+}
+
+// Assert that PaddedAEAD implements the AEAD interface.
+var _ tink.AEAD = (*PaddedAEAD)(nil)
+
+// NewPaddedAEAD creates a PaddedAEAD that pads plaintexts to a multiple of
+// blockSize bytes, which must be in the range [1..MaxPaddingBlockSize], and
+// encrypts them with inner.
+func NewPaddedAEAD(inner tink.AEAD, blockSize int) (*PaddedAEAD, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("padded_aead: inner AEAD must not be nil")
+	}
+	if blockSize < 1 || blockSize > MaxPaddingBlockSize {
+		return nil, fmt.Errorf("padded_aead: block size must be in the range [1..%d], got %d", MaxPaddingBlockSize, blockSize)
+	}
+	return &PaddedAEAD{inner: inner, blockSize: blockSize}, nil
+}
+
+// Encrypt pads plaintext and encrypts it with the inner AEAD.
+func (a *PaddedAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	if uint64(len(plaintext)) > math.MaxUint32 {
+		return nil, errors.New("padded_aead: plaintext too long")
+	}
+	n := paddingLengthSize + len(plaintext)
+	if r := n % a.blockSize; r != 0 {
+		n += a.blockSize - r
+	}
+	padded := make([]byte, n)
+	binary.BigEndian.PutUint32(padded, uint32(len(plaintext)))
+	copy(padded[paddingLengthSize:], plaintext)
+	return a.inner.Encrypt(padded, additionalData)
+}
+
+// Decrypt decrypts ciphertext with the inner AEAD and removes the padding.
+func (a *PaddedAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	padded, err := a.inner.Decrypt(ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	if len(padded) < paddingLengthSize || len(padded)%a.blockSize != 0 {
+		return nil, errInvalidPadding
+	}
+	n := uint64(binary.BigEndian.Uint32(padded))
+	if n > uint64(len(padded)-paddingLengthSize) {
+		return nil, errInvalidPadding
+	}
+	end := paddingLengthSize + int(n)
+	for _, b := range padded[end:] {
+		if b != 0 {
+			return nil, errInvalidPadding
+		}
+	}
+	return padded[paddingLengthSize:end], nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/subtle/random"
+)
+
+func TestPaddedAEADRoundTrip(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	const blockSize = 32
+	a, err := aead.NewPaddedAEAD(inner, blockSize)
+	if err != nil {
+		t.Fatalf("aead.NewPaddedAEAD() err = %v", err)
+	}
+	ad := []byte("ad")
+	tests := []struct {
+		ptSize     int
+		paddedSize int
+	}{
+		{0, 32},
+		{1, 32},
+		{28, 32},
+		{29, 64},
+		{32, 64},
+		{60, 64},
+		{61, 96},
+		{1000, 1024},
+	}
+	for _, tc := range tests {
+		pt := random.GetRandomBytes(uint32(tc.ptSize))
+		ct, err := a.Encrypt(pt, ad)
+		if err != nil {
+			t.Fatalf("a.Encrypt() of %d bytes err = %v", tc.ptSize, err)
+		}
+		if want := tc.paddedSize + subtle.AESGCMIVSize + subtle.AESGCMTagSize; len(ct) != want {
+			t.Errorf("len(a.Encrypt()) of %d bytes = %d, want %d", tc.ptSize, len(ct), want)
+		}
+		got, err := a.Decrypt(ct, ad)
+		if err != nil {
+			t.Fatalf("a.Decrypt() of %d bytes err = %v", tc.ptSize, err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("a.Decrypt() of %d bytes = %x, want %x", tc.ptSize, got, pt)
+		}
+		if _, err := a.Decrypt(ct, []byte("other ad")); err == nil {
+			t.Errorf("a.Decrypt() of %d bytes with wrong additional data err = nil, want error", tc.ptSize)
+		}
+	}
+}
+
+func TestPaddedAEADBlockSizeOne(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	a, err := aead.NewPaddedAEAD(inner, 1)
+	if err != nil {
+		t.Fatalf("aead.NewPaddedAEAD() err = %v", err)
+	}
+	pt := []byte("hello")
+	ct, err := a.Encrypt(pt, nil)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v", err)
+	}
+	if got, err := a.Decrypt(ct, nil); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("a.Decrypt() = %q, %v, want %q, nil", got, err, pt)
+	}
+}
+
+func TestPaddedAEADRejectsInvalidPadding(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	a, err := aead.NewPaddedAEAD(inner, 16)
+	if err != nil {
+		t.Fatalf("aead.NewPaddedAEAD() err = %v", err)
+	}
+	withLength := func(n uint32, size int) []byte {
+		b := make([]byte, size)
+		binary.BigEndian.PutUint32(b, n)
+		return b
+	}
+	nonZero := withLength(2, 16)
+	nonZero[15] = 1
+	tests := []struct {
+		name   string
+		padded []byte
+	}{
+		{"empty", nil},
+		{"not a multiple of the block size", withLength(0, 20)},
+		{"length too large", withLength(13, 16)},
+		{"non-zero padding", nonZero},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Encrypt with the inner AEAD to produce authentic but malformed
+			// padded plaintexts.
+			ct, err := inner.Encrypt(tc.padded, nil)
+			if err != nil {
+				t.Fatalf("inner.Encrypt() err = %v", err)
+			}
+			if _, err := a.Decrypt(ct, nil); err == nil {
+				t.Errorf("a.Decrypt() err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestNewPaddedAEADInvalidParameters(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	if _, err := aead.NewPaddedAEAD(nil, 16); err == nil {
+		t.Errorf("aead.NewPaddedAEAD(nil, 16) err = nil, want error")
+	}
+	for _, blockSize := range []int{-1, 0, aead.MaxPaddingBlockSize + 1} {
+		if _, err := aead.NewPaddedAEAD(inner, blockSize); err == nil {
+			t.Errorf("aead.NewPaddedAEAD(inner, %d) err = nil, want error", blockSize)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/tink/go/core/cryptofmt"
+	"github.com/google/tink/go/keyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// CanDecryptPrefix parses the TINK or LEGACY prefix of ciphertext and reports
+// whether h contains an ENABLED key with that prefix, i.e. a key that the
+// AEAD returned by New would try first. It also returns the key ID encoded
+// in the prefix. It neither creates primitives nor attempts decryption, so it
+// is cheap enough to audit stored ciphertexts for ones whose key has been
+// disabled or removed.
+//
+// It returns an error if ciphertext does not start with a TINK or LEGACY
+// prefix; ciphertexts of RAW keys carry no key ID and cannot be checked.
+func CanDecryptPrefix(h *keyset.Handle, ciphertext []byte) (bool, uint32, error) {
+	if h == nil {
+		return false, 0, errors.New("aead.CanDecryptPrefix: invalid keyset handle")
+	}
+	keyID, _, err := cryptofmt.KeyIDFromPrefix(ciphertext)
+	if err != nil {
+		return false, 0, fmt.Errorf("aead.CanDecryptPrefix: %s", err)
+	}
+	prefix := string(ciphertext[:cryptofmt.NonRawPrefixSize])
+	for _, info := range h.KeysetInfo().KeyInfo {
+		if info.Status != tinkpb.KeyStatusType_ENABLED || info.KeyId != keyID {
+			continue
+		}
+		p, err := cryptofmt.OutputPrefix(&tinkpb.Keyset_Key{KeyId: info.KeyId, OutputPrefixType: info.OutputPrefixType})
+		if err != nil {
+			continue
+		}
+		if p == prefix {
+			return true, keyID, nil
+		}
+	}
+	return false, keyID, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/testkeyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+func TestCanDecryptPrefix(t *testing.T) {
+	legacyTemplate := aead.AES128GCMKeyTemplate()
+	legacyTemplate.OutputPrefixType = tinkpb.OutputPrefixType_LEGACY
+	templates := []*tinkpb.KeyTemplate{
+		aead.AES128GCMKeyTemplate(), // stays enabled
+		aead.AES128GCMKeyTemplate(), // gets disabled
+		aead.AES128GCMKeyTemplate(), // gets removed
+		legacyTemplate,              // stays enabled
+	}
+	ksm := keyset.NewManager()
+	var ids []uint32
+	var cts [][]byte
+	for _, kt := range templates {
+		id, err := ksm.RotateKey(kt)
+		if err != nil {
+			t.Fatalf("ksm.RotateKey() err = %v", err)
+		}
+		h, err := ksm.Handle()
+		if err != nil {
+			t.Fatalf("ksm.Handle() err = %v", err)
+		}
+		a, err := aead.New(h)
+		if err != nil {
+			t.Fatalf("aead.New() err = %v", err)
+		}
+		ct, err := a.Encrypt([]byte("plaintext"), nil)
+		if err != nil {
+			t.Fatalf("a.Encrypt() err = %v", err)
+		}
+		ids = append(ids, id)
+		cts = append(cts, ct)
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	ks := proto.Clone(testkeyset.KeysetMaterial(h)).(*tinkpb.Keyset)
+	ks.Key[1].Status = tinkpb.KeyStatusType_DISABLED
+	ks.Key = append(ks.Key[:2], ks.Key[3])
+	h, err = testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v", err)
+	}
+
+	wrongPrefixType := append([]byte{0}, cts[0][1:]...)
+	for _, tc := range []struct {
+		name   string
+		ct     []byte
+		want   bool
+		wantID uint32
+	}{
+		{"enabled key", cts[0], true, ids[0]},
+		{"disabled key", cts[1], false, ids[1]},
+		{"removed key", cts[2], false, ids[2]},
+		{"enabled legacy key", cts[3], true, ids[3]},
+		{"wrong prefix type", wrongPrefixType, false, ids[0]},
+	} {
+		got, gotID, err := aead.CanDecryptPrefix(h, tc.ct)
+		if err != nil {
+			t.Errorf("%s: aead.CanDecryptPrefix() err = %v, want nil", tc.name, err)
+			continue
+		}
+		if got != tc.want || gotID != tc.wantID {
+			t.Errorf("%s: aead.CanDecryptPrefix() = %t, %d, want %t, %d", tc.name, got, gotID, tc.want, tc.wantID)
+		}
+	}
+}
+
+func TestCanDecryptPrefixFailsWithoutPrefix(t *testing.T) {
+	h, err := keyset.NewHandle(aead.AES256GCMNoPrefixKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	rawCT, err := a.Encrypt([]byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v", err)
+	}
+	rawCT[0] = 0xff // make sure the ciphertext does not look like it had a prefix
+	for name, ct := range map[string][]byte{
+		"raw ciphertext": rawCT,
+		"too short":      {1, 2, 3, 4},
+		"empty":          nil,
+	} {
+		if _, _, err := aead.CanDecryptPrefix(h, ct); err == nil {
+			t.Errorf("%s: aead.CanDecryptPrefix() err = nil, want error", name)
+		}
+	}
+	if _, _, err := aead.CanDecryptPrefix(nil, rawCT); err == nil {
+		t.Error("aead.CanDecryptPrefix() with nil handle err = nil, want error")
+	}
+}
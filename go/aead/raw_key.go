@@ -0,0 +1,100 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/subtle/random"
+	gcmpb "github.com/google/tink/go/proto/aes_gcm_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// RawKeyOption configures KeysetHandleFromRawAESKey.
+type RawKeyOption func(*rawKeyOptions)
+
+type rawKeyOptions struct {
+	derivedKeyID bool
+}
+
+// WithKeyIDFromKeyMaterial makes KeysetHandleFromRawAESKey use the key ID
+// derived from the key material, see keyset.DerivedKeyID, instead of a random
+// one. Importing the same raw key twice then gives the same key ID, and thus
+// the same TINK or LEGACY ciphertext prefix.
+func WithKeyIDFromKeyMaterial() RawKeyOption {
+	return func(o *rawKeyOptions) {
+		o.derivedKeyID = true
+	}
+}
+
+// KeysetHandleFromRawAESKey returns a handle for a keyset that consists of a
+// single ENABLED AES-GCM key with the given key material and output prefix
+// type. key must be 16 or 32 bytes long; Tink does not support 24-byte
+// AES-GCM keys.
+//
+// With a RAW prefix, the AEAD obtained from the handle is compatible with
+// other AES-GCM implementations that use 12-byte IVs and 16-byte tags: its
+// ciphertexts are IV || ciphertext || tag.
+//
+// The key material is wrapped without any encryption, so this should only be
+// used to import keys from systems that hand out raw AES keys.
+func KeysetHandleFromRawAESKey(key []byte, prefix tinkpb.OutputPrefixType, opts ...RawKeyOption) (*keyset.Handle, error) {
+	var o rawKeyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := subtle.ValidateAESKeySize(uint32(len(key))); err != nil {
+		return nil, fmt.Errorf("aead: %s", err)
+	}
+	serializedKey, err := proto.Marshal(&gcmpb.AesGcmKey{
+		Version:  aesGCMKeyVersion,
+		KeyValue: key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aead: cannot serialize key: %s", err)
+	}
+	keyData := &tinkpb.KeyData{
+		TypeUrl:         aesGCMTypeURL,
+		Value:           serializedKey,
+		KeyMaterialType: tinkpb.KeyData_SYMMETRIC,
+	}
+	var keyID uint32
+	if o.derivedKeyID {
+		keyID = keyset.DerivedKeyID(keyData)
+	} else {
+		for keyID == 0 {
+			keyID = random.GetRandomUint32()
+		}
+	}
+	ks := &tinkpb.Keyset{
+		PrimaryKeyId: keyID,
+		Key: []*tinkpb.Keyset_Key{{
+			KeyData:          keyData,
+			Status:           tinkpb.KeyStatusType_ENABLED,
+			KeyId:            keyID,
+			OutputPrefixType: prefix,
+		}},
+	}
+	if err := keyset.Validate(ks); err != nil {
+		return nil, fmt.Errorf("aead: invalid keyset: %s", err)
+	}
+	return insecurecleartextkeyset.KeysetHandle(ks), nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/core/cryptofmt"
+	"github.com/google/tink/go/subtle/random"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// stdlibSeal encrypts pt with AES-GCM from the standard library and returns
+// IV || ciphertext || tag.
+func stdlibSeal(t *testing.T, key, pt, ad []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("aes.NewCipher() err = %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("cipher.NewGCM() err = %v", err)
+	}
+	iv := random.GetRandomBytes(uint32(gcm.NonceSize()))
+	return gcm.Seal(iv, iv, pt, ad)
+}
+
+func TestKeysetHandleFromRawAESKey(t *testing.T) {
+	for _, keySize := range []uint32{16, 32} {
+		key := random.GetRandomBytes(keySize)
+		h, err := aead.KeysetHandleFromRawAESKey(key, tinkpb.OutputPrefixType_RAW)
+		if err != nil {
+			t.Fatalf("aead.KeysetHandleFromRawAESKey() with a %d-byte key err = %v", keySize, err)
+		}
+		info := h.KeysetInfo()
+		if len(info.KeyInfo) != 1 || info.KeyInfo[0].TypeUrl != "type.googleapis.com/google.crypto.tink.AesGcmKey" {
+			t.Errorf("h.KeysetInfo() = %v, want a single AesGcmKey", info)
+		}
+		a, err := aead.New(h)
+		if err != nil {
+			t.Fatalf("aead.New() err = %v", err)
+		}
+
+		pt, ad := []byte("plaintext"), []byte("ad")
+		ct := stdlibSeal(t, key, pt, ad)
+		if got, err := a.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("a.Decrypt() of a %d-byte key standard library ciphertext = %q, %v, want %q, nil", keySize, got, err, pt)
+		}
+		ct, err = a.Encrypt(pt, ad)
+		if err != nil {
+			t.Fatalf("a.Encrypt() err = %v", err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("aes.NewCipher() err = %v", err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatalf("cipher.NewGCM() err = %v", err)
+		}
+		n := gcm.NonceSize()
+		if got, err := gcm.Open(nil, ct[:n], ct[n:], ad); err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("gcm.Open() of a %d-byte key Tink ciphertext = %q, %v, want %q, nil", keySize, got, err, pt)
+		}
+	}
+}
+
+func TestKeysetHandleFromRawAESKeyWithTinkPrefix(t *testing.T) {
+	key := random.GetRandomBytes(32)
+	h, err := aead.KeysetHandleFromRawAESKey(key, tinkpb.OutputPrefixType_TINK)
+	if err != nil {
+		t.Fatalf("aead.KeysetHandleFromRawAESKey() err = %v", err)
+	}
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	pt, ad := []byte("plaintext"), []byte("ad")
+	ct, err := a.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v", err)
+	}
+	keyID, prefixType, err := cryptofmt.KeyIDFromPrefix(ct)
+	if err != nil {
+		t.Fatalf("cryptofmt.KeyIDFromPrefix() err = %v", err)
+	}
+	if want := h.KeysetInfo().PrimaryKeyId; keyID != want || prefixType != tinkpb.OutputPrefixType_TINK {
+		t.Errorf("cryptofmt.KeyIDFromPrefix() = %d, %s, want %d, TINK", keyID, prefixType, want)
+	}
+	// Without a RAW key, ciphertexts must carry the prefix.
+	if got, err := a.Decrypt(stdlibSeal(t, key, pt, ad), ad); err == nil {
+		t.Errorf("a.Decrypt() of an unprefixed ciphertext = %q, want error", got)
+	}
+}
+
+func TestKeysetHandleFromRawAESKeyWithKeyIDFromKeyMaterial(t *testing.T) {
+	key := random.GetRandomBytes(32)
+	h1, err := aead.KeysetHandleFromRawAESKey(key, tinkpb.OutputPrefixType_TINK, aead.WithKeyIDFromKeyMaterial())
+	if err != nil {
+		t.Fatalf("aead.KeysetHandleFromRawAESKey() err = %v", err)
+	}
+	h2, err := aead.KeysetHandleFromRawAESKey(append([]byte{}, key...), tinkpb.OutputPrefixType_TINK, aead.WithKeyIDFromKeyMaterial())
+	if err != nil {
+		t.Fatalf("aead.KeysetHandleFromRawAESKey() err = %v", err)
+	}
+	id1, id2 := h1.KeysetInfo().PrimaryKeyId, h2.KeysetInfo().PrimaryKeyId
+	if id1 != id2 {
+		t.Errorf("key IDs of handles from the same raw key = %d, %d, want equal", id1, id2)
+	}
+	other, err := aead.KeysetHandleFromRawAESKey(random.GetRandomBytes(32), tinkpb.OutputPrefixType_TINK, aead.WithKeyIDFromKeyMaterial())
+	if err != nil {
+		t.Fatalf("aead.KeysetHandleFromRawAESKey() err = %v", err)
+	}
+	if id := other.KeysetInfo().PrimaryKeyId; id == id1 {
+		t.Errorf("key IDs of handles from different raw keys are both %d", id)
+	}
+
+	a1, err := aead.New(h1)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	a2, err := aead.New(h2)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	pt, ad := []byte("plaintext"), []byte("ad")
+	ct, err := a1.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a1.Encrypt() err = %v", err)
+	}
+	if got, err := a2.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("a2.Decrypt() = %q, %v, want %q, nil", got, err, pt)
+	}
+}
+
+func TestKeysetHandleFromRawAESKeyWithInvalidInput(t *testing.T) {
+	for _, keySize := range []uint32{0, 15, 24, 33} {
+		if _, err := aead.KeysetHandleFromRawAESKey(random.GetRandomBytes(keySize), tinkpb.OutputPrefixType_RAW); err == nil {
+			t.Errorf("aead.KeysetHandleFromRawAESKey() with a %d-byte key err = nil, want error", keySize)
+		}
+	}
+	if _, err := aead.KeysetHandleFromRawAESKey(random.GetRandomBytes(32), tinkpb.OutputPrefixType_UNKNOWN_PREFIX); err == nil {
+		t.Error("aead.KeysetHandleFromRawAESKey() with UNKNOWN_PREFIX err = nil, want error")
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/google/tink/go/tink"
+)
+
+const sequenceNumberSize = 8
+
+var (
+	// ErrSequenceReplay is returned by SequencedAEAD.Decrypt for a ciphertext
+	// whose sequence number has already been accepted.
+	ErrSequenceReplay = errors.New("sequenced_aead: replayed or out-of-order sequence number")
+
+	// ErrSequenceGap is returned by SequencedAEAD.Decrypt for a ciphertext whose
+	// sequence number is larger than the expected one, i.e. when messages were
+	// lost or reordered.
+	ErrSequenceGap = errors.New("sequenced_aead: gap in sequence numbers")
+
+	// ErrSequenceExhausted is returned by SequencedAEAD once all sequence
+	// numbers have been used.
+	ErrSequenceExhausted = errors.New("sequenced_aead: sequence numbers exhausted")
+)
+
+// SequencedAEAD is an AEAD for messages on an in-order channel that binds a
+// sequence number to every message. Encrypt uses the numbers 0, 1, 2, ... and
+// Decrypt only accepts the next expected number, so replayed, dropped and
+// reordered messages are rejected.
+//
+// The ciphertext is the 8-byte big-endian sequence number followed by the
+// ciphertext of the inner AEAD, whose associated data is the sequence number
+// followed by the additional data. The sequence number is sent in clear so
+// that Decrypt can tell replays from gaps.
+//
+// The counters are kept in memory and are per SequencedAEAD: the encryption
+// and the decryption counter are independent, and a new SequencedAEAD starts
+// both at zero. Use one instance per direction of a channel, and a fresh key
+// whenever the counters would be reset, otherwise old messages can be
+// replayed. SequencedAEAD is not suited for channels that may reorder or drop
+// messages.
+type SequencedAEAD struct {
+	inner tink.AEAD
+
+	encMu   sync.Mutex
+	nextEnc uint64
+
+	decMu   sync.Mutex
+	nextDec uint64
+}
+
+// Assert that SequencedAEAD implements the AEAD interface.
+var _ tink.AEAD = (*SequencedAEAD)(nil)
+
+// NewSequencedAEAD creates a SequencedAEAD that encrypts with inner.
+func NewSequencedAEAD(inner tink.AEAD) (*SequencedAEAD, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("sequenced_aead: inner AEAD must not be nil")
+	}
+	return &SequencedAEAD{inner: inner}, nil
+}
+
+// Encrypt encrypts plaintext with the next sequence number. The sequence
+// number is only used up if the inner AEAD succeeds.
+func (a *SequencedAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	a.encMu.Lock()
+	defer a.encMu.Unlock()
+	if a.nextEnc == math.MaxUint64 {
+		return nil, ErrSequenceExhausted
+	}
+	seq := sequenceNumber(a.nextEnc)
+	ct, err := a.inner.Encrypt(plaintext, sequencedAssociatedData(seq, additionalData))
+	if err != nil {
+		return nil, err
+	}
+	a.nextEnc++
+	return append(seq, ct...), nil
+}
+
+// Decrypt decrypts ciphertext if it carries the next expected sequence number.
+// It returns ErrSequenceReplay for smaller and ErrSequenceGap for larger
+// sequence numbers. The expected sequence number only advances if decryption
+// succeeds, so corrupted ciphertexts do not desynchronize the channel.
+func (a *SequencedAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < sequenceNumberSize {
+		return nil, errors.New("sequenced_aead: ciphertext too short")
+	}
+	seq := ciphertext[:sequenceNumberSize]
+	n := binary.BigEndian.Uint64(seq)
+	a.decMu.Lock()
+	defer a.decMu.Unlock()
+	switch {
+	case a.nextDec == math.MaxUint64:
+		return nil, ErrSequenceExhausted
+	case n < a.nextDec:
+		return nil, ErrSequenceReplay
+	case n > a.nextDec:
+		return nil, ErrSequenceGap
+	}
+	pt, err := a.inner.Decrypt(ciphertext[sequenceNumberSize:], sequencedAssociatedData(seq, additionalData))
+	if err != nil {
+		return nil, err
+	}
+	a.nextDec++
+	return pt, nil
+}
+
+func sequenceNumber(n uint64) []byte {
+	seq := make([]byte, sequenceNumberSize)
+	binary.BigEndian.PutUint64(seq, n)
+	return seq
+}
+
+// sequencedAssociatedData returns seq || additionalData. seq has a fixed
+// length, so the encoding is unambiguous.
+func sequencedAssociatedData(seq, additionalData []byte) []byte {
+	ad := make([]byte, 0, len(seq)+len(additionalData))
+	ad = append(ad, seq...)
+	return append(ad, additionalData...)
+}
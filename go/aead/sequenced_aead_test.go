@@ -0,0 +1,138 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/subtle/random"
+)
+
+func newSequencedAEADPair(t *testing.T) (*aead.SequencedAEAD, *aead.SequencedAEAD) {
+	t.Helper()
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	sender, err := aead.NewSequencedAEAD(inner)
+	if err != nil {
+		t.Fatalf("aead.NewSequencedAEAD() err = %v", err)
+	}
+	receiver, err := aead.NewSequencedAEAD(inner)
+	if err != nil {
+		t.Fatalf("aead.NewSequencedAEAD() err = %v", err)
+	}
+	return sender, receiver
+}
+
+func encryptSequence(t *testing.T, a *aead.SequencedAEAD, n int, ad []byte) [][]byte {
+	t.Helper()
+	var cts [][]byte
+	for i := 0; i < n; i++ {
+		ct, err := a.Encrypt([]byte(fmt.Sprintf("message %d", i)), ad)
+		if err != nil {
+			t.Fatalf("a.Encrypt() #%d err = %v", i, err)
+		}
+		cts = append(cts, ct)
+	}
+	return cts
+}
+
+func TestSequencedAEADInOrder(t *testing.T) {
+	sender, receiver := newSequencedAEADPair(t)
+	ad := []byte("ad")
+	for i, ct := range encryptSequence(t, sender, 5, ad) {
+		if got := binary.BigEndian.Uint64(ct); got != uint64(i) {
+			t.Errorf("sequence number of ciphertext #%d = %d, want %d", i, got, i)
+		}
+		want := []byte(fmt.Sprintf("message %d", i))
+		if got, err := receiver.Decrypt(ct, ad); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("receiver.Decrypt() #%d = %q, %v, want %q, nil", i, got, err, want)
+		}
+	}
+}
+
+func TestSequencedAEADRejectsReplay(t *testing.T) {
+	sender, receiver := newSequencedAEADPair(t)
+	ad := []byte("ad")
+	cts := encryptSequence(t, sender, 3, ad)
+	for i, ct := range cts[:2] {
+		if _, err := receiver.Decrypt(ct, ad); err != nil {
+			t.Fatalf("receiver.Decrypt() #%d err = %v", i, err)
+		}
+	}
+	for i, ct := range cts[:2] {
+		if _, err := receiver.Decrypt(ct, ad); err != aead.ErrSequenceReplay {
+			t.Errorf("receiver.Decrypt() of replayed #%d err = %v, want %v", i, err, aead.ErrSequenceReplay)
+		}
+	}
+	// The replays do not disturb the channel.
+	if _, err := receiver.Decrypt(cts[2], ad); err != nil {
+		t.Errorf("receiver.Decrypt() #2 err = %v", err)
+	}
+}
+
+func TestSequencedAEADDetectsGap(t *testing.T) {
+	sender, receiver := newSequencedAEADPair(t)
+	ad := []byte("ad")
+	cts := encryptSequence(t, sender, 3, ad)
+	if _, err := receiver.Decrypt(cts[0], ad); err != nil {
+		t.Fatalf("receiver.Decrypt() #0 err = %v", err)
+	}
+	if _, err := receiver.Decrypt(cts[2], ad); err != aead.ErrSequenceGap {
+		t.Errorf("receiver.Decrypt() #2 before #1 err = %v, want %v", err, aead.ErrSequenceGap)
+	}
+	if _, err := receiver.Decrypt(cts[1], ad); err != nil {
+		t.Errorf("receiver.Decrypt() #1 err = %v", err)
+	}
+}
+
+func TestSequencedAEADRejectsModifiedSequenceNumber(t *testing.T) {
+	sender, receiver := newSequencedAEADPair(t)
+	ad := []byte("ad")
+	cts := encryptSequence(t, sender, 2, ad)
+	if _, err := receiver.Decrypt(cts[0], ad); err != nil {
+		t.Fatalf("receiver.Decrypt() #0 err = %v", err)
+	}
+	// Relabel ciphertext #0 as #1.
+	relabeled := append([]byte{}, cts[0]...)
+	binary.BigEndian.PutUint64(relabeled, 1)
+	if _, err := receiver.Decrypt(relabeled, ad); err == nil {
+		t.Errorf("receiver.Decrypt() of relabeled ciphertext err = nil, want error")
+	}
+	if _, err := receiver.Decrypt(cts[1], []byte("other ad")); err == nil {
+		t.Errorf("receiver.Decrypt() with wrong additional data err = nil, want error")
+	}
+	// Failed decryptions do not advance the expected sequence number.
+	if _, err := receiver.Decrypt(cts[1], ad); err != nil {
+		t.Errorf("receiver.Decrypt() #1 err = %v", err)
+	}
+	if _, err := receiver.Decrypt([]byte{1, 2, 3}, ad); err == nil {
+		t.Errorf("receiver.Decrypt() of short ciphertext err = nil, want error")
+	}
+}
+
+func TestNewSequencedAEADNilInner(t *testing.T) {
+	if _, err := aead.NewSequencedAEAD(nil); err == nil {
+		t.Errorf("aead.NewSequencedAEAD(nil) err = nil, want error")
+	}
+}
@@ -90,6 +90,12 @@ func (a *AESGCM) Decrypt(ct, aad []byte) ([]byte, error) {
 	return pt, nil
 }
 
+// MinCiphertextLen returns the length of the shortest ciphertext that Decrypt
+// accepts, which is the length of the encryption of an empty plaintext.
+func (a *AESGCM) MinCiphertextLen() int {
+	return AESGCMIVSize + AESGCMTagSize
+}
+
 // newIV creates a new IV for encryption.
 func (a *AESGCM) newIV() []byte {
 	return random.GetRandomBytes(AESGCMIVSize)
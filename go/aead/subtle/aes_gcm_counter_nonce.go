@@ -0,0 +1,123 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/google/tink/go/tink"
+)
+
+// ErrCounterNonceExhausted is returned by AESGCMCounterNonce.Encrypt once the
+// nonce counter has reached its maximum value.
+var ErrCounterNonceExhausted = errors.New("aes_gcm_counter_nonce: nonce counter exhausted")
+
+// AESGCMCounterNonce is an AES-GCM AEAD whose Encrypt uses a counter instead of
+// a random IV, for protocols that require strictly increasing nonces. The IV
+// is 4 zero bytes followed by the counter as a big-endian 64-bit integer, and
+// the counter is incremented after each encryption. Ciphertexts have the same
+// format as those of AESGCM: IV || ciphertext || tag. Decrypt accepts any IV,
+// so AESGCMCounterNonce and AESGCM can decrypt each other's ciphertexts.
+//
+// WARNING: This is insecure for general use. Reusing a nonce with AES-GCM
+// reveals the authentication key and the XOR of the plaintexts, and a counter
+// only guarantees unique nonces if exactly one AESGCMCounterNonce encrypts
+// with the key, and if it never starts again from a counter value that was
+// already used, e.g. after a restart. Use AESGCM unless the protocol dictates
+// the nonces. No key manager of Tink creates an AESGCMCounterNonce.
+type AESGCMCounterNonce struct {
+	aead cipher.AEAD
+
+	mu sync.Mutex
+	// counter is the nonce of the next encryption.
+	counter uint64
+	// exhausted is true once the nonce math.MaxUint64 has been used.
+	exhausted bool
+}
+
+// Assert that AESGCMCounterNonce implements the AEAD interface.
+var _ tink.AEAD = (*AESGCMCounterNonce)(nil)
+
+// NewAESGCMCounterNonce returns an AESGCMCounterNonce whose first encryption
+// uses initialCounter as nonce. The key argument should be the AES key, either
+// 16 or 32 bytes to select AES-128 or AES-256.
+func NewAESGCMCounterNonce(key []byte, initialCounter uint64) (*AESGCMCounterNonce, error) {
+	if err := ValidateAESKeySize(uint32(len(key))); err != nil {
+		return nil, fmt.Errorf("aes_gcm_counter_nonce: %s", err)
+	}
+	aesCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes_gcm_counter_nonce: initializing cipher failed")
+	}
+	aead, err := cipher.NewGCM(aesCipher)
+	if err != nil {
+		return nil, fmt.Errorf("aes_gcm_counter_nonce: initializing cipher failed")
+	}
+	return &AESGCMCounterNonce{aead: aead, counter: initialCounter}, nil
+}
+
+// Encrypt encrypts pt with aad as additional authenticated data, using the
+// next counter value as nonce. It returns ErrCounterNonceExhausted once all
+// counter values have been used.
+func (a *AESGCMCounterNonce) Encrypt(pt, aad []byte) ([]byte, error) {
+	if len(pt) > maxPtSize() {
+		return nil, fmt.Errorf("aes_gcm_counter_nonce: plaintext too long")
+	}
+	iv, err := a.nextIV()
+	if err != nil {
+		return nil, err
+	}
+	ct := a.aead.Seal(nil, iv, pt, aad)
+	return append(iv, ct...), nil
+}
+
+// nextIV returns the IV for the current counter value and increments the
+// counter.
+func (a *AESGCMCounterNonce) nextIV() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.exhausted {
+		return nil, ErrCounterNonceExhausted
+	}
+	iv := make([]byte, AESGCMIVSize)
+	binary.BigEndian.PutUint64(iv[AESGCMIVSize-8:], a.counter)
+	if a.counter == math.MaxUint64 {
+		a.exhausted = true
+	} else {
+		a.counter++
+	}
+	return iv, nil
+}
+
+// Decrypt decrypts ct with aad as the additional authenticated data. The IV of
+// ct may be any value.
+func (a *AESGCMCounterNonce) Decrypt(ct, aad []byte) ([]byte, error) {
+	if len(ct) < AESGCMIVSize+AESGCMTagSize {
+		return nil, fmt.Errorf("aes_gcm_counter_nonce: ciphertext too short")
+	}
+	pt, err := a.aead.Open(nil, ct[:AESGCMIVSize], ct[AESGCMIVSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("aes_gcm_counter_nonce: %s", err)
+	}
+	return pt, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/subtle/random"
+)
+
+// counterOf returns the counter in the IV of ct, and fails the test if the
+// first 4 bytes of the IV are not zero.
+func counterOf(t *testing.T, ct []byte) uint64 {
+	t.Helper()
+	if !bytes.Equal(ct[:4], []byte{0, 0, 0, 0}) {
+		t.Fatalf("IV = %x, want 4 zero bytes followed by the counter", ct[:subtle.AESGCMIVSize])
+	}
+	return binary.BigEndian.Uint64(ct[4:subtle.AESGCMIVSize])
+}
+
+func TestAESGCMCounterNonceIncrementsNonce(t *testing.T) {
+	for _, keySize := range []int{16, 32} {
+		key := random.GetRandomBytes(uint32(keySize))
+		a, err := subtle.NewAESGCMCounterNonce(key, 42)
+		if err != nil {
+			t.Fatalf("subtle.NewAESGCMCounterNonce() err = %v", err)
+		}
+		gcm, err := subtle.NewAESGCM(key)
+		if err != nil {
+			t.Fatalf("subtle.NewAESGCM() err = %v", err)
+		}
+		pt := []byte("plaintext")
+		ad := []byte("ad")
+		for i := uint64(0); i < 5; i++ {
+			ct, err := a.Encrypt(pt, ad)
+			if err != nil {
+				t.Fatalf("Encrypt() err = %v", err)
+			}
+			if got, want := counterOf(t, ct), 42+i; got != want {
+				t.Errorf("counter = %d, want %d", got, want)
+			}
+			if len(ct) != subtle.AESGCMIVSize+len(pt)+subtle.AESGCMTagSize {
+				t.Errorf("len(ct) = %d, want %d", len(ct), subtle.AESGCMIVSize+len(pt)+subtle.AESGCMTagSize)
+			}
+			// The ciphertexts are regular AES-GCM ciphertexts.
+			for _, d := range []interface {
+				Decrypt(ct, ad []byte) ([]byte, error)
+			}{a, gcm} {
+				got, err := d.Decrypt(ct, ad)
+				if err != nil || !bytes.Equal(got, pt) {
+					t.Errorf("Decrypt() = %q, %v, want %q, nil", got, err, pt)
+				}
+			}
+		}
+
+		// Decrypt accepts any valid nonce, e.g. a random one.
+		ct, err := gcm.Encrypt(pt, ad)
+		if err != nil {
+			t.Fatalf("gcm.Encrypt() err = %v", err)
+		}
+		if got, err := a.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("Decrypt() of AESGCM ciphertext = %q, %v, want %q, nil", got, err, pt)
+		}
+		ct[len(ct)-1] ^= 1
+		if _, err := a.Decrypt(ct, ad); err == nil {
+			t.Errorf("Decrypt() of modified ciphertext err = nil, want error")
+		}
+		if _, err := a.Decrypt(ct[:subtle.AESGCMIVSize+subtle.AESGCMTagSize-1], ad); err == nil {
+			t.Errorf("Decrypt() of short ciphertext err = nil, want error")
+		}
+	}
+}
+
+func TestAESGCMCounterNonceOverflow(t *testing.T) {
+	a, err := subtle.NewAESGCMCounterNonce(random.GetRandomBytes(16), math.MaxUint64-1)
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCMCounterNonce() err = %v", err)
+	}
+	for _, want := range []uint64{math.MaxUint64 - 1, math.MaxUint64} {
+		ct, err := a.Encrypt([]byte("plaintext"), nil)
+		if err != nil {
+			t.Fatalf("Encrypt() err = %v", err)
+		}
+		if got := counterOf(t, ct); got != want {
+			t.Errorf("counter = %d, want %d", got, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Encrypt([]byte("plaintext"), nil); err != subtle.ErrCounterNonceExhausted {
+			t.Errorf("Encrypt() after the last counter value err = %v, want %v", err, subtle.ErrCounterNonceExhausted)
+		}
+	}
+}
+
+func TestAESGCMCounterNonceConcurrentEncrypt(t *testing.T) {
+	a, err := subtle.NewAESGCMCounterNonce(random.GetRandomBytes(16), 0)
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCMCounterNonce() err = %v", err)
+	}
+	const n = 100
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		counters = make(map[uint64]bool)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ct, err := a.Encrypt([]byte("plaintext"), nil)
+			if err != nil {
+				t.Errorf("Encrypt() err = %v", err)
+				return
+			}
+			mu.Lock()
+			counters[binary.BigEndian.Uint64(ct[4:subtle.AESGCMIVSize])] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for i := uint64(0); i < n; i++ {
+		if !counters[i] {
+			t.Errorf("counter %d was not used", i)
+		}
+	}
+}
+
+func TestNewAESGCMCounterNonceInvalidKeySize(t *testing.T) {
+	for _, keySize := range []int{0, 15, 24, 33} {
+		if _, err := subtle.NewAESGCMCounterNonce(make([]byte, keySize), 0); err == nil {
+			t.Errorf("subtle.NewAESGCMCounterNonce() with %d-byte key err = nil, want error", keySize)
+		}
+	}
+}
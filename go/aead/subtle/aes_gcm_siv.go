@@ -18,12 +18,11 @@ package subtle
 
 import (
 	"crypto/aes"
-	"crypto/subtle"
 	"encoding/binary"
 	"fmt"
 	"math"
 
-	// Placeholder for internal crypto/subtle allowlist, please ignore. // to allow import of "crypto/subte"
+	"github.com/google/tink/go/subtle"
 	"github.com/google/tink/go/subtle/random"
 )
 
@@ -79,7 +78,12 @@ func (a *AESGCMSIV) Encrypt(pt, aad []byte) ([]byte, error) {
 		return nil, fmt.Errorf("aes_gcm_siv: additional-data too long")
 	}
 
-	nonce := random.GetRandomBytes(uint32(AESGCMSIVNonceSize))
+	return a.encryptWithNonce(random.GetRandomBytes(uint32(AESGCMSIVNonceSize)), pt, aad)
+}
+
+// encryptWithNonce is Encrypt with a caller-supplied nonce. It exists so that
+// tests can exercise the behaviour of AES-GCM-SIV under nonce reuse.
+func (a *AESGCMSIV) encryptWithNonce(nonce, pt, aad []byte) ([]byte, error) {
 	authKey, encKey, err := a.deriveKeys(nonce)
 	if err != nil {
 		return nil, err
@@ -143,13 +147,19 @@ func (a *AESGCMSIV) Decrypt(ct, aad []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	if subtle.ConstantTimeCompare(expectedTag, tag) != 1 {
+	if !subtle.ConstantTimeCompare(expectedTag, tag) {
 		return nil, fmt.Errorf("aes_gcm_siv: message authentication failure")
 	}
 
 	return pt, nil
 }
 
+// MinCiphertextLen returns the length of the shortest ciphertext that Decrypt
+// accepts, which is the length of the encryption of an empty plaintext.
+func (a *AESGCMSIV) MinCiphertextLen() int {
+	return AESGCMSIVNonceSize + aesgcmsivTagSize
+}
+
 // The KDF as described by the RFC #8452. This uses the AES-GCM-SIV key and
 // nonce to generate the authentication key and the encryption key.
 func (a *AESGCMSIV) deriveKeys(nonce []byte) ([]byte, []byte, error) {
@@ -18,6 +18,8 @@ package subtle_test
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
 	"encoding/hex"
 	"fmt"
 	"testing"
@@ -94,6 +96,94 @@ func TestAESGCMSIVModifyCiphertext(t *testing.T) {
 	}
 }
 
+func TestAESGCMSIVNonceReuseOnlyLeaksEquality(t *testing.T) {
+	for _, keySize := range subtle.AESGCMSIVKeySizes {
+		a, err := subtle.NewAESGCMSIV(random.GetRandomBytes(keySize))
+		if err != nil {
+			t.Fatalf("cannot create aead, error: %v", err)
+		}
+		nonce := random.GetRandomBytes(subtle.AESGCMSIVNonceSize)
+		aad := random.GetRandomBytes(16)
+		pt1 := random.GetRandomBytes(64)
+		pt2 := make([]byte, len(pt1))
+		copy(pt2, pt1)
+		pt2[len(pt2)-1] ^= 1
+
+		ct1, err := a.EncryptWithNonce(nonce, pt1, aad)
+		if err != nil {
+			t.Fatalf("EncryptWithNonce() err = %v", err)
+		}
+		ct1Again, err := a.EncryptWithNonce(nonce, pt1, aad)
+		if err != nil {
+			t.Fatalf("EncryptWithNonce() err = %v", err)
+		}
+		ct2, err := a.EncryptWithNonce(nonce, pt2, aad)
+		if err != nil {
+			t.Fatalf("EncryptWithNonce() err = %v", err)
+		}
+		ct3, err := a.EncryptWithNonce(nonce, pt1, []byte("other aad"))
+		if err != nil {
+			t.Fatalf("EncryptWithNonce() err = %v", err)
+		}
+
+		// Encryption is deterministic for a fixed nonce, so repeating a nonce
+		// reveals whether two (plaintext, aad) pairs are equal ...
+		if !bytes.Equal(ct1, ct1Again) {
+			t.Errorf("key size %d: same nonce, plaintext and aad produced different ciphertexts", keySize)
+		}
+		// ... but nothing more: a one-bit change in the plaintext or a different
+		// aad yields a different synthetic IV and therefore an unrelated
+		// keystream.
+		if bytes.Equal(ciphertextBody(ct1), ciphertextBody(ct3)) {
+			t.Errorf("key size %d: different aad produced the same ciphertext", keySize)
+		}
+		if bytes.Equal(xorBytes(ciphertextBody(ct1), ciphertextBody(ct2)), xorBytes(pt1, pt2)) {
+			t.Errorf("key size %d: ciphertexts under a reused nonce leak the xor of the plaintexts", keySize)
+		}
+		for _, ct := range [][]byte{ct1, ct2} {
+			if _, err := a.Decrypt(ct, aad); err != nil {
+				t.Errorf("key size %d: Decrypt() err = %v", keySize, err)
+			}
+		}
+	}
+}
+
+// TestAESGCMNonceReuseLeaksXor documents the failure mode AES-GCM-SIV
+// protects against: with plain AES-GCM a repeated nonce means a repeated
+// keystream, so the xor of two ciphertexts is the xor of their plaintexts.
+func TestAESGCMNonceReuseLeaksXor(t *testing.T) {
+	block, err := aes.NewCipher(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("aes.NewCipher() err = %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("cipher.NewGCM() err = %v", err)
+	}
+	nonce := random.GetRandomBytes(uint32(gcm.NonceSize()))
+	pt1 := random.GetRandomBytes(64)
+	pt2 := random.GetRandomBytes(64)
+	ct1 := gcm.Seal(nil, nonce, pt1, nil)
+	ct2 := gcm.Seal(nil, nonce, pt2, nil)
+	tagSize := gcm.Overhead()
+	if !bytes.Equal(xorBytes(ct1[:len(ct1)-tagSize], ct2[:len(ct2)-tagSize]), xorBytes(pt1, pt2)) {
+		t.Error("expected AES-GCM with a reused nonce to leak the xor of the plaintexts")
+	}
+}
+
+// ciphertextBody strips the nonce and the tag from an AES-GCM-SIV ciphertext.
+func ciphertextBody(ct []byte) []byte {
+	return ct[subtle.AESGCMSIVNonceSize : len(ct)-16]
+}
+
+func xorBytes(a, b []byte) []byte {
+	ret := make([]byte, len(a))
+	for i := range a {
+		ret[i] = a[i] ^ b[i]
+	}
+	return ret
+}
+
 func TestAESGCMSIVWycheproofCases(t *testing.T) {
 	testutil.SkipTestIfTestSrcDirIsNotSet(t)
 	suite := new(AEADSuite)
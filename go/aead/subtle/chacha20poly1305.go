@@ -83,6 +83,12 @@ func (ca *ChaCha20Poly1305) Decrypt(ct []byte, aad []byte) ([]byte, error) {
 	return pt, nil
 }
 
+// MinCiphertextLen returns the length of the shortest ciphertext that Decrypt
+// accepts, which is the length of the encryption of an empty plaintext.
+func (ca *ChaCha20Poly1305) MinCiphertextLen() int {
+	return chacha20poly1305.NonceSize + poly1305TagSize
+}
+
 // newNonce creates a new nonce for encryption.
 func (ca *ChaCha20Poly1305) newNonce() []byte {
 	return random.GetRandomBytes(chacha20poly1305.NonceSize)
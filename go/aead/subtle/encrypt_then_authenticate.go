@@ -28,6 +28,10 @@ import (
 // and additional authenticated data (aad). The MAC is computed over (aad ||
 // ciphertext || size of aad). This implementation is based on
 // http://tools.ietf.org/html/draft-mcgrew-aead-aes-cbc-hmac-sha2-05.
+//
+// When built from an AESCTR cipher, ciphertexts are laid out as
+// IV || AES-CTR ciphertext || tag, which is the format produced by the
+// AES-CTR-HMAC-AEAD key type.
 type EncryptThenAuthenticate struct {
 	indCPACipher INDCPACipher
 	mac          tink.MAC
@@ -36,6 +40,9 @@ type EncryptThenAuthenticate struct {
 
 const (
 	minTagSizeInBytes = 10
+	// maxTagSizeInBytes is the output size of the largest supported MAC
+	// (HMAC-SHA512).
+	maxTagSizeInBytes = 64
 )
 
 // Assert that EncryptThenAuthenticate implements the AEAD interface.
@@ -49,10 +56,18 @@ func uint64ToByte(n uint64) []byte {
 }
 
 // NewEncryptThenAuthenticate returns a new instance of EncryptThenAuthenticate.
+// tagSize must be the size of the tags produced by mac, and must be between 10
+// and 64 bytes.
 func NewEncryptThenAuthenticate(indCPACipher INDCPACipher, mac tink.MAC, tagSize int) (*EncryptThenAuthenticate, error) {
+	if indCPACipher == nil || mac == nil {
+		return nil, fmt.Errorf("encrypt_then_authenticate: cipher and MAC must not be nil")
+	}
 	if tagSize < minTagSizeInBytes {
 		return nil, fmt.Errorf("encrypt_then_authenticate: tag size too small")
 	}
+	if tagSize > maxTagSizeInBytes {
+		return nil, fmt.Errorf("encrypt_then_authenticate: tag size too large")
+	}
 	return &EncryptThenAuthenticate{indCPACipher, mac, tagSize}, nil
 }
 
@@ -70,11 +85,7 @@ func (e *EncryptThenAuthenticate) Encrypt(plaintext, additionalData []byte) ([]b
 		return nil, fmt.Errorf("encrypt_then_authenticate: %v", err)
 	}
 
-	toAuthData := append(additionalData, ciphertext...)
-	aadSizeInBits := uint64(len(additionalData)) * 8
-	toAuthData = append(toAuthData, uint64ToByte(aadSizeInBits)...)
-
-	tag, err := e.mac.ComputeMAC(toAuthData)
+	tag, err := e.mac.ComputeMAC(authData(additionalData, ciphertext))
 	if err != nil {
 		return nil, fmt.Errorf("encrypt_then_authenticate: %v", err)
 	}
@@ -97,13 +108,7 @@ func (e *EncryptThenAuthenticate) Decrypt(ciphertext, additionalData []byte) ([]
 	// payload contains everything except the tag.
 	payload := ciphertext[:len(ciphertext)-e.tagSize]
 
-	// Authenticate the following data:
-	// additionalData || payload || aadSizeInBits
-	toAuthData := append(additionalData, payload...)
-	aadSizeInBits := uint64(len(additionalData)) * 8
-	toAuthData = append(toAuthData, uint64ToByte(aadSizeInBits)...)
-
-	err := e.mac.VerifyMAC(ciphertext[len(ciphertext)-e.tagSize:], toAuthData)
+	err := e.mac.VerifyMAC(ciphertext[len(ciphertext)-e.tagSize:], authData(additionalData, payload))
 	if err != nil {
 		return nil, fmt.Errorf("encrypt_then_authenticate: %v", err)
 	}
@@ -115,3 +120,24 @@ func (e *EncryptThenAuthenticate) Decrypt(ciphertext, additionalData []byte) ([]
 
 	return plaintext, nil
 }
+
+// MinCiphertextLen returns the length of the shortest ciphertext that Decrypt
+// accepts, which is the length of the encryption of an empty plaintext. If the
+// IND-CPA cipher is not an AESCTR, its IV size is unknown and only the tag is
+// counted.
+func (e *EncryptThenAuthenticate) MinCiphertextLen() int {
+	if c, ok := e.indCPACipher.(*AESCTR); ok {
+		return c.IVSize + e.tagSize
+	}
+	return e.tagSize
+}
+
+// authData returns the data to authenticate:
+// additionalData || payload || aadSizeInBits.
+// It always allocates, so that additionalData is never written to.
+func authData(additionalData, payload []byte) []byte {
+	ret := make([]byte, 0, len(additionalData)+len(payload)+8)
+	ret = append(ret, additionalData...)
+	ret = append(ret, payload...)
+	return append(ret, uint64ToByte(uint64(len(additionalData))*8)...)
+}
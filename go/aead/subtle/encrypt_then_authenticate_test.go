@@ -18,6 +18,9 @@ package subtle_test
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"testing"
 
@@ -250,6 +253,94 @@ func TestETAInvalidTagSize(t *testing.T) {
 	}
 }
 
+func TestETATooLargeTagSize(t *testing.T) {
+	ctr, err := subtle.NewAESCTR(random.GetRandomBytes(16), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac, err := subtleMac.NewHMAC("SHA512", random.GetRandomBytes(32), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := subtle.NewEncryptThenAuthenticate(ctr, mac, 64); err != nil {
+		t.Errorf("NewEncryptThenAuthenticate() with 64 byte tag err = %v", err)
+	}
+	if _, err := subtle.NewEncryptThenAuthenticate(ctr, mac, 65); err == nil {
+		t.Error("NewEncryptThenAuthenticate() with 65 byte tag err = nil, want error")
+	}
+	if _, err := subtle.NewEncryptThenAuthenticate(nil, mac, 32); err == nil {
+		t.Error("NewEncryptThenAuthenticate() with nil cipher err = nil, want error")
+	}
+	if _, err := subtle.NewEncryptThenAuthenticate(ctr, nil, 32); err == nil {
+		t.Error("NewEncryptThenAuthenticate() with nil MAC err = nil, want error")
+	}
+}
+
+// TestETACiphertextLayout checks the IV || ciphertext || tag layout against an
+// independent HMAC-SHA256 computation.
+func TestETACiphertextLayout(t *testing.T) {
+	const ivSize = 16
+	const tagSize = 16
+	encKey := random.GetRandomBytes(16)
+	macKey := random.GetRandomBytes(32)
+	cipher, err := createAEADWithKeys(encKey, ivSize, "SHA256", macKey, tagSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := []byte("interop plaintext")
+	aad := []byte("interop aad")
+	ct, err := cipher.Encrypt(pt, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ct) != ivSize+len(pt)+tagSize {
+		t.Fatalf("len(ct) = %d, want %d", len(ct), ivSize+len(pt)+tagSize)
+	}
+
+	payload := ct[:len(ct)-tagSize]
+	m := hmac.New(sha256.New, macKey)
+	m.Write(aad)
+	m.Write(payload)
+	aadBits := make([]byte, 8)
+	binary.BigEndian.PutUint64(aadBits, uint64(len(aad))*8)
+	m.Write(aadBits)
+	if want := m.Sum(nil)[:tagSize]; !bytes.Equal(ct[len(ct)-tagSize:], want) {
+		t.Errorf("tag = %x, want %x", ct[len(ct)-tagSize:], want)
+	}
+
+	ctr, err := subtle.NewAESCTR(encKey, ivSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ctr.Decrypt(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pt) {
+		t.Errorf("AES-CTR decryption of payload = %q, want %q", got, pt)
+	}
+}
+
+func TestETADoesNotModifyAdditionalData(t *testing.T) {
+	cipher, err := createAEAD(16, 12, "SHA256", 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backing := make([]byte, 8, 64)
+	aad := backing[:4]
+	want := append([]byte{}, backing[:cap(backing)]...)
+	ct, err := cipher.Encrypt([]byte("plaintext"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cipher.Decrypt(ct, aad); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backing[:cap(backing)], want) {
+		t.Error("Encrypt or Decrypt wrote past the end of additionalData")
+	}
+}
+
 func TestETADecryptModifiedCiphertext(t *testing.T) {
 	const keySize = 16
 	const ivSize = 12
@@ -0,0 +1,134 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/tink/go/tink"
+)
+
+// ErrNonceReuse is returned by NonceTrackingAEAD when it observes a repeated
+// nonce.
+var ErrNonceReuse = errors.New("nonce_tracking_aead: nonce reuse detected")
+
+// NonceTrackingAEAD is an AEAD that detects nonce reuse of an inner AEAD whose
+// ciphertexts start with the nonce, such as AESGCM, ChaCha20Poly1305 and
+// XChaCha20Poly1305.
+//
+// Encrypt fails with ErrNonceReuse if the inner AEAD returns a ciphertext
+// whose nonce was seen before. Decrypt fails with ErrNonceReuse if it
+// authenticates a ciphertext whose nonce was seen before in a different
+// ciphertext; decrypting the same ciphertext twice is not an error.
+//
+// NonceTrackingAEAD is a debugging aid for tests of code that chooses nonces
+// itself. It must not be used in production: it keeps a digest of up to
+// maxTracked of the most recent ciphertexts in memory, serializes all calls,
+// and only detects reuse within these ciphertexts.
+type NonceTrackingAEAD struct {
+	inner      tink.AEAD
+	nonceSize  int
+	maxTracked int
+
+	mu sync.Mutex
+	// seen maps the nonces of the tracked ciphertexts to the ciphertexts'
+	// digests.
+	seen map[string][sha256.Size]byte
+	// order holds the tracked nonces from the oldest to the newest.
+	order []string
+}
+
+// Assert that NonceTrackingAEAD implements the AEAD interface.
+var _ tink.AEAD = (*NonceTrackingAEAD)(nil)
+
+// NewNonceTrackingAEAD returns a NonceTrackingAEAD that tracks the nonceSize
+// byte nonces of up to maxTracked ciphertexts of inner.
+func NewNonceTrackingAEAD(inner tink.AEAD, nonceSize, maxTracked int) (*NonceTrackingAEAD, error) {
+	if inner == nil {
+		return nil, errors.New("nonce_tracking_aead: invalid inner AEAD")
+	}
+	if nonceSize <= 0 {
+		return nil, fmt.Errorf("nonce_tracking_aead: invalid nonce size: %d", nonceSize)
+	}
+	if maxTracked <= 0 {
+		return nil, fmt.Errorf("nonce_tracking_aead: invalid number of tracked nonces: %d", maxTracked)
+	}
+	return &NonceTrackingAEAD{
+		inner:      inner,
+		nonceSize:  nonceSize,
+		maxTracked: maxTracked,
+		seen:       make(map[string][sha256.Size]byte),
+	}, nil
+}
+
+// Encrypt encrypts pt with the inner AEAD. It returns ErrNonceReuse instead of
+// the ciphertext if the nonce of the ciphertext was seen before.
+func (a *NonceTrackingAEAD) Encrypt(pt, ad []byte) ([]byte, error) {
+	ct, err := a.inner.Encrypt(pt, ad)
+	if err != nil {
+		return nil, err
+	}
+	if len(ct) < a.nonceSize {
+		return nil, errors.New("nonce_tracking_aead: ciphertext too short")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	nonce := string(ct[:a.nonceSize])
+	if _, ok := a.seen[nonce]; ok {
+		return nil, ErrNonceReuse
+	}
+	a.track(nonce, sha256.Sum256(ct))
+	return ct, nil
+}
+
+// Decrypt decrypts ct with the inner AEAD. It returns ErrNonceReuse if ct is
+// authentic and its nonce was seen before in a different ciphertext.
+func (a *NonceTrackingAEAD) Decrypt(ct, ad []byte) ([]byte, error) {
+	pt, err := a.inner.Decrypt(ct, ad)
+	if err != nil {
+		return nil, err
+	}
+	if len(ct) < a.nonceSize {
+		return nil, errors.New("nonce_tracking_aead: ciphertext too short")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	nonce := string(ct[:a.nonceSize])
+	digest := sha256.Sum256(ct)
+	if d, ok := a.seen[nonce]; ok {
+		if d != digest {
+			return nil, ErrNonceReuse
+		}
+		return pt, nil
+	}
+	a.track(nonce, digest)
+	return pt, nil
+}
+
+// track records nonce, evicting the oldest nonce if maxTracked nonces are
+// tracked. It must be called with a.mu held.
+func (a *NonceTrackingAEAD) track(nonce string, digest [sha256.Size]byte) {
+	if len(a.order) == a.maxTracked {
+		delete(a.seen, a.order[0])
+		a.order = a.order[1:]
+	}
+	a.seen[nonce] = digest
+	a.order = append(a.order, nonce)
+}
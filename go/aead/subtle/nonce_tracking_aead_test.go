@@ -0,0 +1,178 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/subtle/random"
+)
+
+// fixedNonceAEAD encrypts with a nonce that the test controls.
+type fixedNonceAEAD struct {
+	*subtle.XChaCha20Poly1305InsecureNonce
+	nonce []byte
+}
+
+func (a *fixedNonceAEAD) Encrypt(pt, ad []byte) ([]byte, error) {
+	return a.EncryptWithNonce(a.nonce, pt, ad)
+}
+
+func newFixedNonceAEAD(t *testing.T) *fixedNonceAEAD {
+	t.Helper()
+	x, err := subtle.NewXChaCha20Poly1305InsecureNonce(random.GetRandomBytes(chacha20poly1305.KeySize))
+	if err != nil {
+		t.Fatalf("subtle.NewXChaCha20Poly1305InsecureNonce() err = %v, want nil", err)
+	}
+	return &fixedNonceAEAD{
+		XChaCha20Poly1305InsecureNonce: x,
+		nonce:                          make([]byte, chacha20poly1305.NonceSizeX),
+	}
+}
+
+func TestNonceTrackingAEADDetectsReuseOnEncrypt(t *testing.T) {
+	inner := newFixedNonceAEAD(t)
+	a, err := subtle.NewNonceTrackingAEAD(inner, chacha20poly1305.NonceSizeX, 10)
+	if err != nil {
+		t.Fatalf("subtle.NewNonceTrackingAEAD() err = %v, want nil", err)
+	}
+	ct, err := a.Encrypt([]byte("first"), nil)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v, want nil", err)
+	}
+	if _, err := a.Encrypt([]byte("second"), nil); err != subtle.ErrNonceReuse {
+		t.Errorf("a.Encrypt() with a repeated nonce err = %v, want %v", err, subtle.ErrNonceReuse)
+	}
+	// A fresh nonce is fine.
+	inner.nonce = bytes.Repeat([]byte{1}, chacha20poly1305.NonceSizeX)
+	if _, err := a.Encrypt([]byte("third"), nil); err != nil {
+		t.Errorf("a.Encrypt() with a fresh nonce err = %v, want nil", err)
+	}
+	// Decrypting a ciphertext that was encrypted by a is not reuse.
+	if got, err := a.Decrypt(ct, nil); err != nil || !bytes.Equal(got, []byte("first")) {
+		t.Errorf("a.Decrypt() = %q, %v, want %q, nil", got, err, "first")
+	}
+}
+
+func TestNonceTrackingAEADDetectsReuseOnDecrypt(t *testing.T) {
+	inner := newFixedNonceAEAD(t)
+	ct1, err := inner.Encrypt([]byte("first"), nil)
+	if err != nil {
+		t.Fatalf("inner.Encrypt() err = %v, want nil", err)
+	}
+	ct2, err := inner.Encrypt([]byte("second"), nil)
+	if err != nil {
+		t.Fatalf("inner.Encrypt() err = %v, want nil", err)
+	}
+
+	a, err := subtle.NewNonceTrackingAEAD(inner, chacha20poly1305.NonceSizeX, 10)
+	if err != nil {
+		t.Fatalf("subtle.NewNonceTrackingAEAD() err = %v, want nil", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Decrypt(ct1, nil); err != nil {
+			t.Errorf("a.Decrypt(ct1) err = %v, want nil", err)
+		}
+	}
+	if _, err := a.Decrypt(ct2, nil); err != subtle.ErrNonceReuse {
+		t.Errorf("a.Decrypt(ct2) err = %v, want %v", err, subtle.ErrNonceReuse)
+	}
+
+	// Unauthentic ciphertexts are not tracked.
+	b, err := subtle.NewNonceTrackingAEAD(inner, chacha20poly1305.NonceSizeX, 10)
+	if err != nil {
+		t.Fatalf("subtle.NewNonceTrackingAEAD() err = %v, want nil", err)
+	}
+	modified := append([]byte{}, ct1...)
+	modified[len(modified)-1] ^= 1
+	if _, err := b.Decrypt(modified, nil); err == nil || err == subtle.ErrNonceReuse {
+		t.Errorf("b.Decrypt() of a modified ciphertext err = %v, want an authentication error", err)
+	}
+	if _, err := b.Decrypt(ct1, nil); err != nil {
+		t.Errorf("b.Decrypt(ct1) err = %v, want nil", err)
+	}
+}
+
+func TestNonceTrackingAEADForgetsOldestNonces(t *testing.T) {
+	inner := newFixedNonceAEAD(t)
+	a, err := subtle.NewNonceTrackingAEAD(inner, chacha20poly1305.NonceSizeX, 2)
+	if err != nil {
+		t.Fatalf("subtle.NewNonceTrackingAEAD() err = %v, want nil", err)
+	}
+	for i := byte(0); i < 3; i++ {
+		inner.nonce = bytes.Repeat([]byte{i}, chacha20poly1305.NonceSizeX)
+		if _, err := a.Encrypt([]byte("pt"), nil); err != nil {
+			t.Fatalf("a.Encrypt() with nonce %d err = %v, want nil", i, err)
+		}
+	}
+	// Only the nonces 1 and 2 are still tracked.
+	inner.nonce = bytes.Repeat([]byte{0}, chacha20poly1305.NonceSizeX)
+	if _, err := a.Encrypt([]byte("pt"), nil); err != nil {
+		t.Errorf("a.Encrypt() with evicted nonce err = %v, want nil", err)
+	}
+	inner.nonce = bytes.Repeat([]byte{2}, chacha20poly1305.NonceSizeX)
+	if _, err := a.Encrypt([]byte("pt"), nil); err != subtle.ErrNonceReuse {
+		t.Errorf("a.Encrypt() with tracked nonce err = %v, want %v", err, subtle.ErrNonceReuse)
+	}
+}
+
+func TestNonceTrackingAEADWithRandomNonces(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v, want nil", err)
+	}
+	a, err := subtle.NewNonceTrackingAEAD(inner, subtle.AESGCMIVSize, 1000)
+	if err != nil {
+		t.Fatalf("subtle.NewNonceTrackingAEAD() err = %v, want nil", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ct, err := a.Encrypt([]byte("plaintext"), nil)
+				if err != nil {
+					t.Errorf("a.Encrypt() err = %v, want nil", err)
+					return
+				}
+				if _, err := a.Decrypt(ct, nil); err != nil {
+					t.Errorf("a.Decrypt() err = %v, want nil", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewNonceTrackingAEADWithInvalidArguments(t *testing.T) {
+	inner := newFixedNonceAEAD(t)
+	if _, err := subtle.NewNonceTrackingAEAD(nil, 12, 10); err == nil {
+		t.Error("subtle.NewNonceTrackingAEAD() with nil inner AEAD err = nil, want error")
+	}
+	if _, err := subtle.NewNonceTrackingAEAD(inner, 0, 10); err == nil {
+		t.Error("subtle.NewNonceTrackingAEAD() with nonce size 0 err = nil, want error")
+	}
+	if _, err := subtle.NewNonceTrackingAEAD(inner, 12, 0); err == nil {
+		t.Error("subtle.NewNonceTrackingAEAD() with maxTracked 0 err = nil, want error")
+	}
+}
@@ -50,14 +50,18 @@ func (x *XChaCha20Poly1305) Encrypt(pt []byte, aad []byte) ([]byte, error) {
 	if len(pt) > maxInt-chacha20poly1305.NonceSizeX-poly1305TagSize {
 		return nil, fmt.Errorf("xchacha20poly1305: plaintext too long")
 	}
-	c, err := chacha20poly1305.NewX(x.Key)
+	return encryptXChaCha20Poly1305(x.Key, x.newNonce(), pt, aad)
+}
+
+// encryptXChaCha20Poly1305 encrypts pt with the given key and nonce and
+// returns the nonce followed by the actual ciphertext.
+func encryptXChaCha20Poly1305(key, nonce, pt, aad []byte) ([]byte, error) {
+	c, err := chacha20poly1305.NewX(key)
 	if err != nil {
 		return nil, err
 	}
-
-	n := x.newNonce()
-	ct := c.Seal(nil, n, pt, aad)
-	return append(n, ct...), nil
+	ct := c.Seal(nil, nonce, pt, aad)
+	return append(nonce, ct...), nil
 }
 
 // Decrypt decrypts {@code ct} with {@code aad} as the additionalauthenticated data.
@@ -79,7 +83,53 @@ func (x *XChaCha20Poly1305) Decrypt(ct []byte, aad []byte) ([]byte, error) {
 	return pt, nil
 }
 
+// MinCiphertextLen returns the length of the shortest ciphertext that Decrypt
+// accepts, which is the length of the encryption of an empty plaintext.
+func (x *XChaCha20Poly1305) MinCiphertextLen() int {
+	return chacha20poly1305.NonceSizeX + poly1305TagSize
+}
+
 // newNonce creates a new nonce for encryption.
 func (x *XChaCha20Poly1305) newNonce() []byte {
 	return random.GetRandomBytes(chacha20poly1305.NonceSizeX)
 }
+
+// XChaCha20Poly1305InsecureNonce is XChaCha20Poly1305 with caller-supplied
+// nonces. It is meant for known-answer tests and for interoperating with
+// systems that derive nonces deterministically.
+//
+// It is insecure unless the caller guarantees that a nonce is never used
+// twice with the same key: reusing a nonce reveals the XOR of the plaintexts
+// and allows forgeries. It does not implement tink.AEAD and is not used by any
+// key manager. Its ciphertexts can be decrypted by XChaCha20Poly1305.
+type XChaCha20Poly1305InsecureNonce struct {
+	Key []byte
+}
+
+// NewXChaCha20Poly1305InsecureNonce returns an XChaCha20Poly1305InsecureNonce
+// instance. The key argument should be a 32-bytes key.
+func NewXChaCha20Poly1305InsecureNonce(key []byte) (*XChaCha20Poly1305InsecureNonce, error) {
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, errors.New("xchacha20poly1305: bad key length")
+	}
+
+	return &XChaCha20Poly1305InsecureNonce{Key: key}, nil
+}
+
+// EncryptWithNonce encrypts pt with aad as additional authenticated data,
+// using the given 24-byte nonce. The resulting ciphertext has the same format
+// as the one of XChaCha20Poly1305.Encrypt.
+func (x *XChaCha20Poly1305InsecureNonce) EncryptWithNonce(nonce, pt, aad []byte) ([]byte, error) {
+	if len(nonce) != chacha20poly1305.NonceSizeX {
+		return nil, fmt.Errorf("xchacha20poly1305: bad nonce length")
+	}
+	if len(pt) > maxInt-chacha20poly1305.NonceSizeX-poly1305TagSize {
+		return nil, fmt.Errorf("xchacha20poly1305: plaintext too long")
+	}
+	return encryptXChaCha20Poly1305(x.Key, append([]byte{}, nonce...), pt, aad)
+}
+
+// Decrypt decrypts ct with aad as the additional authenticated data.
+func (x *XChaCha20Poly1305InsecureNonce) Decrypt(ct, aad []byte) ([]byte, error) {
+	return (&XChaCha20Poly1305{Key: x.Key}).Decrypt(ct, aad)
+}
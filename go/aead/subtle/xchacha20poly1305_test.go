@@ -25,6 +25,7 @@ import (
 
 	"golang.org/x/crypto/chacha20poly1305"
 	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/internal/randomsource"
 	"github.com/google/tink/go/subtle/random"
 	"github.com/google/tink/go/testutil"
 )
@@ -200,6 +201,86 @@ func TestXChaCha20Poly1305RandomNonce(t *testing.T) {
 	}
 }
 
+func TestXChaCha20Poly1305NonceFromRandomReader(t *testing.T) {
+	key := random.GetRandomBytes(chacha20poly1305.KeySize)
+	x, err := subtle.NewXChaCha20Poly1305(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := bytes.Repeat([]byte{0x42}, chacha20poly1305.NonceSizeX)
+	randomsource.SetReader(bytes.NewReader(nonce))
+	ct, err := x.Encrypt([]byte("plaintext"), nil)
+	randomsource.Reset()
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+	if !bytes.HasPrefix(ct, nonce) {
+		t.Errorf("ciphertext %x does not start with nonce %x", ct, nonce)
+	}
+}
+
+func TestXChaCha20Poly1305InsecureNonce(t *testing.T) {
+	key := random.GetRandomBytes(chacha20poly1305.KeySize)
+	x, err := subtle.NewXChaCha20Poly1305InsecureNonce(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := []byte("plaintext")
+	aad := []byte("aad")
+	nonce := random.GetRandomBytes(chacha20poly1305.NonceSizeX)
+
+	ct, err := x.EncryptWithNonce(nonce, pt, aad)
+	if err != nil {
+		t.Fatalf("EncryptWithNonce() err = %v", err)
+	}
+	if !bytes.HasPrefix(ct, nonce) {
+		t.Errorf("ciphertext %x does not start with nonce %x", ct, nonce)
+	}
+	ct2, err := x.EncryptWithNonce(nonce, pt, aad)
+	if err != nil {
+		t.Fatalf("EncryptWithNonce() err = %v", err)
+	}
+	if !bytes.Equal(ct, ct2) {
+		t.Errorf("EncryptWithNonce() is not deterministic")
+	}
+
+	// The ciphertext is the one XChaCha20Poly1305 produces for the same nonce.
+	a, err := subtle.NewXChaCha20Poly1305(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	randomsource.SetReader(bytes.NewReader(nonce))
+	want, err := a.Encrypt(pt, aad)
+	randomsource.Reset()
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+	if !bytes.Equal(ct, want) {
+		t.Errorf("EncryptWithNonce() = %x, want %x", ct, want)
+	}
+	for _, d := range []interface {
+		Decrypt(ct, aad []byte) ([]byte, error)
+	}{a, x} {
+		got, err := d.Decrypt(ct, aad)
+		if err != nil {
+			t.Fatalf("Decrypt() err = %v", err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("Decrypt() = %q, want %q", got, pt)
+		}
+	}
+
+	for _, size := range []int{0, 12, 23, 25} {
+		if _, err := x.EncryptWithNonce(make([]byte, size), pt, aad); err == nil {
+			t.Errorf("EncryptWithNonce() with a %d-byte nonce succeeded", size)
+		}
+	}
+	if _, err := subtle.NewXChaCha20Poly1305InsecureNonce(key[1:]); err == nil {
+		t.Errorf("NewXChaCha20Poly1305InsecureNonce() with a short key succeeded")
+	}
+}
+
 func TestXChaCha20Poly1305WycheproofCases(t *testing.T) {
 	testutil.SkipTestIfTestSrcDirIsNotSet(t)
 	suite := new(AEADSuite)
@@ -236,6 +317,19 @@ func runXChaCha20Poly1305WycheproofCase(t *testing.T, tc *AEADCase) {
 		t.Fatalf("unexpected encryption error: %s", err)
 	}
 
+	// Encrypting with the nonce of the test case must reproduce its ciphertext.
+	insecure, err := subtle.NewXChaCha20Poly1305InsecureNonce(tc.Key)
+	if err != nil {
+		t.Fatalf("cannot create new instance of XChaCha20Poly1305InsecureNonce: %s", err)
+	}
+	ct, err := insecure.EncryptWithNonce(tc.Iv, tc.Msg, tc.Aad)
+	if err != nil {
+		t.Fatalf("unexpected encryption error: %s", err)
+	}
+	if tc.Result == "valid" && !bytes.Equal(ct, combinedCt) {
+		t.Errorf("EncryptWithNonce() = %x, want %x", ct, combinedCt)
+	}
+
 	decrypted, err := ca.Decrypt(combinedCt, tc.Aad)
 	if err != nil {
 		if tc.Result == "valid" {
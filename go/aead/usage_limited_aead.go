@@ -0,0 +1,90 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/tink/go/tink"
+)
+
+// ErrUsageLimitReached is returned by UsageLimitedAEAD.Encrypt once the inner
+// AEAD has encrypted the maximum number of messages.
+var ErrUsageLimitReached = errors.New("usage_limited_aead: maximum number of encryptions reached, rotate the key")
+
+// UsageLimitedAEAD is an AEAD that encrypts at most a fixed number of messages
+// with an inner AEAD. This bounds the use of a key whose security degrades with
+// the number of messages, e.g. AES-GCM with random nonces, and prompts a key
+// rotation once the bound is hit. Decryption is not limited.
+//
+// The counter is kept in memory: it is per UsageLimitedAEAD and per process,
+// and is not persisted. A new UsageLimitedAEAD for the same key starts over
+// at zero, so the limit only holds if a single instance is used per key.
+type UsageLimitedAEAD struct {
+	// encryptions is accessed atomically. It is the first field to be 64-bit
+	// aligned on 32-bit platforms.
+	encryptions    uint64
+	maxEncryptions uint64
+	inner          tink.AEAD
+}
+
+// Assert that UsageLimitedAEAD implements the AEAD interface.
+var _ tink.AEAD = (*UsageLimitedAEAD)(nil)
+
+// NewUsageLimitedAEAD creates a UsageLimitedAEAD that encrypts at most
+// maxEncryptions messages with inner.
+func NewUsageLimitedAEAD(inner tink.AEAD, maxEncryptions uint64) (*UsageLimitedAEAD, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("usage_limited_aead: inner AEAD must not be nil")
+	}
+	if maxEncryptions == 0 {
+		return nil, fmt.Errorf("usage_limited_aead: maximum number of encryptions must be positive")
+	}
+	return &UsageLimitedAEAD{
+		maxEncryptions: maxEncryptions,
+		inner:          inner,
+	}, nil
+}
+
+// Encrypt encrypts plaintext with the inner AEAD, or returns
+// ErrUsageLimitReached if it has already been called maxEncryptions times.
+// Calls that fail in the inner AEAD count as well.
+func (a *UsageLimitedAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	if atomic.AddUint64(&a.encryptions, 1) > a.maxEncryptions {
+		// Undo the increment so that the counter cannot wrap around.
+		atomic.AddUint64(&a.encryptions, ^uint64(0))
+		return nil, ErrUsageLimitReached
+	}
+	return a.inner.Encrypt(plaintext, additionalData)
+}
+
+// Decrypt decrypts ciphertext with the inner AEAD.
+func (a *UsageLimitedAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	return a.inner.Decrypt(ciphertext, additionalData)
+}
+
+// Encryptions returns the number of calls to Encrypt that were not rejected
+// because of the limit.
+func (a *UsageLimitedAEAD) Encryptions() uint64 {
+	// Rejected calls increment the counter briefly.
+	if n := atomic.LoadUint64(&a.encryptions); n < a.maxEncryptions {
+		return n
+	}
+	return a.maxEncryptions
+}
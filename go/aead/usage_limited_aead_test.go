@@ -0,0 +1,104 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package aead_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/subtle/random"
+)
+
+func TestUsageLimitedAEAD(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	a, err := aead.NewUsageLimitedAEAD(inner, 3)
+	if err != nil {
+		t.Fatalf("aead.NewUsageLimitedAEAD() err = %v", err)
+	}
+	pt, ad := []byte("plaintext"), []byte("ad")
+	var cts [][]byte
+	for i := 0; i < 3; i++ {
+		ct, err := a.Encrypt(pt, ad)
+		if err != nil {
+			t.Fatalf("a.Encrypt() #%d err = %v", i, err)
+		}
+		cts = append(cts, ct)
+	}
+	if _, err := a.Encrypt(pt, ad); err != aead.ErrUsageLimitReached {
+		t.Errorf("a.Encrypt() after the limit err = %v, want %v", err, aead.ErrUsageLimitReached)
+	}
+	if got := a.Encryptions(); got != 3 {
+		t.Errorf("a.Encryptions() = %d, want 3", got)
+	}
+	// Decryption still works after the limit is reached.
+	for i, ct := range cts {
+		if got, err := a.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("a.Decrypt() #%d = %q, %v, want %q, nil", i, got, err, pt)
+		}
+	}
+}
+
+func TestUsageLimitedAEADConcurrentEncryptions(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	const limit, goroutines, perGoroutine = 50, 8, 20
+	a, err := aead.NewUsageLimitedAEAD(inner, limit)
+	if err != nil {
+		t.Fatalf("aead.NewUsageLimitedAEAD() err = %v", err)
+	}
+	var mu sync.Mutex
+	succeeded := 0
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				if _, err := a.Encrypt([]byte("pt"), nil); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != limit {
+		t.Errorf("%d concurrent encryptions succeeded, want %d", succeeded, limit)
+	}
+}
+
+func TestNewUsageLimitedAEADWithInvalidInput(t *testing.T) {
+	inner, err := subtle.NewAESGCM(random.GetRandomBytes(16))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() err = %v", err)
+	}
+	if _, err := aead.NewUsageLimitedAEAD(nil, 1); err == nil {
+		t.Error("aead.NewUsageLimitedAEAD() with nil inner AEAD err = nil, want error")
+	}
+	if _, err := aead.NewUsageLimitedAEAD(inner, 0); err == nil {
+		t.Error("aead.NewUsageLimitedAEAD() with zero limit err = nil, want error")
+	}
+}
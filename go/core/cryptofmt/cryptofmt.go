@@ -71,3 +71,24 @@ func createOutputPrefix(size int, startByte byte, keyID uint32) string {
 	binary.BigEndian.PutUint32(prefix[1:], keyID)
 	return string(prefix)
 }
+
+// KeyIDFromPrefix is the reverse of OutputPrefix. It parses the first
+// NonRawPrefixSize bytes of prefix, which may be a whole ciphertext or
+// signature, and returns the key ID and the output prefix type they encode.
+// Since LEGACY and CRUNCHY prefixes are identical, both are reported as
+// LEGACY. RAW prefixes carry no key ID and cannot be parsed.
+func KeyIDFromPrefix(prefix []byte) (uint32, tinkpb.OutputPrefixType, error) {
+	if len(prefix) < NonRawPrefixSize {
+		return 0, tinkpb.OutputPrefixType_UNKNOWN_PREFIX, fmt.Errorf("crypto_format: prefix too short")
+	}
+	var prefixType tinkpb.OutputPrefixType
+	switch prefix[0] {
+	case TinkStartByte:
+		prefixType = tinkpb.OutputPrefixType_TINK
+	case LegacyStartByte:
+		prefixType = tinkpb.OutputPrefixType_LEGACY
+	default:
+		return 0, tinkpb.OutputPrefixType_UNKNOWN_PREFIX, fmt.Errorf("crypto_format: unknown prefix start byte %d", prefix[0])
+	}
+	return binary.BigEndian.Uint32(prefix[1:NonRawPrefixSize]), prefixType, nil
+}
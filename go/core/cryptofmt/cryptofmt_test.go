@@ -83,3 +83,57 @@ func validatePrefix(prefix string, startByte byte, key string) bool {
 	}
 	return prefix[1:] == key
 }
+
+func TestKeyIDFromPrefix(t *testing.T) {
+	key := new(tinkpb.Keyset_Key)
+	for i, test := range tests {
+		key.KeyId = test.keyID
+		for _, prefixType := range []tinkpb.OutputPrefixType{
+			tinkpb.OutputPrefixType_TINK,
+			tinkpb.OutputPrefixType_LEGACY,
+		} {
+			key.OutputPrefixType = prefixType
+			prefix, err := cryptofmt.OutputPrefix(key)
+			if err != nil {
+				t.Fatalf("cryptofmt.OutputPrefix() err = %v", err)
+			}
+			// Trailing ciphertext bytes must be ignored.
+			ct := append([]byte(prefix), "ciphertext"...)
+			keyID, gotType, err := cryptofmt.KeyIDFromPrefix(ct)
+			if err != nil {
+				t.Errorf("test %d, %s: cryptofmt.KeyIDFromPrefix() err = %v", i, prefixType, err)
+				continue
+			}
+			if keyID != test.keyID || gotType != prefixType {
+				t.Errorf("test %d: cryptofmt.KeyIDFromPrefix() = %d, %s, want %d, %s", i, keyID, gotType, test.keyID, prefixType)
+			}
+		}
+	}
+}
+
+func TestKeyIDFromPrefixCrunchyIsReportedAsLegacy(t *testing.T) {
+	key := &tinkpb.Keyset_Key{KeyId: 42, OutputPrefixType: tinkpb.OutputPrefixType_CRUNCHY}
+	prefix, err := cryptofmt.OutputPrefix(key)
+	if err != nil {
+		t.Fatalf("cryptofmt.OutputPrefix() err = %v", err)
+	}
+	keyID, prefixType, err := cryptofmt.KeyIDFromPrefix([]byte(prefix))
+	if err != nil || keyID != 42 || prefixType != tinkpb.OutputPrefixType_LEGACY {
+		t.Errorf("cryptofmt.KeyIDFromPrefix() = %d, %s, %v, want 42, LEGACY, nil", keyID, prefixType, err)
+	}
+}
+
+func TestKeyIDFromPrefixWithMalformedInput(t *testing.T) {
+	for _, prefix := range [][]byte{
+		nil,
+		[]byte(cryptofmt.RawPrefix),
+		{cryptofmt.TinkStartByte, 0, 0, 1},
+		{cryptofmt.LegacyStartByte},
+		{2, 0, 0, 0, 1},
+		{0xff, 0, 0, 0, 1, 0},
+	} {
+		if _, _, err := cryptofmt.KeyIDFromPrefix(prefix); err == nil {
+			t.Errorf("cryptofmt.KeyIDFromPrefix(%x) err = nil, want error", prefix)
+		}
+	}
+}
@@ -68,6 +68,9 @@ type PrimitiveSet struct {
 	// primitives sharing the prefix). This allows quickly retrieving the
 	// primitives sharing some particular prefix.
 	Entries map[string][]*Entry
+
+	// All entries, in the order in which they were added.
+	ordered []*Entry
 }
 
 // New returns an empty instance of PrimitiveSet.
@@ -92,6 +95,47 @@ func (ps *PrimitiveSet) EntriesForPrefix(prefix string) ([]*Entry, error) {
 	return result, nil
 }
 
+// PrimaryEntry returns the primary entry of the set.
+func (ps *PrimitiveSet) PrimaryEntry() (*Entry, error) {
+	if ps.Primary == nil {
+		return nil, fmt.Errorf("primitive_set: no primary entry")
+	}
+	return ps.Primary, nil
+}
+
+// AllEntries returns all entries added to the set with Add, in the order in
+// which they were added. For a primitive set obtained from a keyset handle
+// this is the order of the keys in the keyset.
+func (ps *PrimitiveSet) AllEntries() []*Entry {
+	return append([]*Entry{}, ps.ordered...)
+}
+
+// Copy returns a primitive set with the same entries as ps, whose Entries map
+// and entry slices can be modified without affecting ps. The entries and their
+// primitives are shared.
+func (ps *PrimitiveSet) Copy() *PrimitiveSet {
+	ret := &PrimitiveSet{
+		Primary: ps.Primary,
+		Entries: make(map[string][]*Entry, len(ps.Entries)),
+		ordered: append([]*Entry{}, ps.ordered...),
+	}
+	for prefix, entries := range ps.Entries {
+		ret.Entries[prefix] = append([]*Entry{}, entries...)
+	}
+	return ret
+}
+
+// EntryByKeyID returns the entry of the key with the given ID. If several
+// keys share that ID, the first of them in keyset order is returned.
+func (ps *PrimitiveSet) EntryByKeyID(id uint32) (*Entry, error) {
+	for _, e := range ps.ordered {
+		if e.KeyID == id {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("primitive_set: no entry with key ID %d", id)
+}
+
 // Add creates a new entry in the primitive set and returns the added entry.
 func (ps *PrimitiveSet) Add(p interface{}, key *tinkpb.Keyset_Key) (*Entry, error) {
 	if key == nil || p == nil {
@@ -106,5 +150,6 @@ func (ps *PrimitiveSet) Add(p interface{}, key *tinkpb.Keyset_Key) (*Entry, erro
 	}
 	e := newEntry(key.KeyId, p, prefix, key.OutputPrefixType, key.Status)
 	ps.Entries[prefix] = append(ps.Entries[prefix], e)
+	ps.ordered = append(ps.ordered, e)
 	return e, nil
 }
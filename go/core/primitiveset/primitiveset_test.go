@@ -175,3 +175,110 @@ func validateEntry(entry *primitiveset.Entry,
 	}
 	return true
 }
+
+func TestPrimitiveSetAccessors(t *testing.T) {
+	ps := primitiveset.New()
+	if _, err := ps.PrimaryEntry(); err == nil {
+		t.Errorf("ps.PrimaryEntry() succeeded on an empty set, want error")
+	}
+	if got := ps.AllEntries(); len(got) != 0 {
+		t.Errorf("len(ps.AllEntries()) = %d on an empty set, want 0", len(got))
+	}
+	keys := createKeyset()
+	entries := make([]*primitiveset.Entry, len(keys))
+	for i, key := range keys {
+		var err error
+		entries[i], err = ps.Add(testutil.DummyMAC{Name: fmt.Sprintf("Mac#%d", i)}, key)
+		if err != nil {
+			t.Fatalf("ps.Add() failed: %s", err)
+		}
+	}
+	ps.Primary = entries[2]
+
+	primary, err := ps.PrimaryEntry()
+	if err != nil {
+		t.Fatalf("ps.PrimaryEntry() failed: %s", err)
+	}
+	if primary != entries[2] {
+		t.Errorf("ps.PrimaryEntry() = %v, want %v", primary, entries[2])
+	}
+
+	all := ps.AllEntries()
+	if len(all) != len(entries) {
+		t.Fatalf("len(ps.AllEntries()) = %d, want %d", len(all), len(entries))
+	}
+	for i, e := range all {
+		if e != entries[i] {
+			t.Errorf("ps.AllEntries()[%d] = %v, want %v", i, e, entries[i])
+		}
+	}
+	// The returned slice is a copy.
+	all[0] = nil
+	if ps.AllEntries()[0] != entries[0] {
+		t.Errorf("modifying the result of ps.AllEntries() modified the set")
+	}
+
+	for _, i := range []int{0, 1, 3, 4} {
+		e, err := ps.EntryByKeyID(keys[i].KeyId)
+		if err != nil {
+			t.Errorf("ps.EntryByKeyID(%d) failed: %s", keys[i].KeyId, err)
+			continue
+		}
+		if e != entries[i] {
+			t.Errorf("ps.EntryByKeyID(%d) = %v, want %v", keys[i].KeyId, e, entries[i])
+		}
+	}
+	// keys[2] shares its ID with keys[1], and keys[5] with keys[0].
+	if e, _ := ps.EntryByKeyID(keys[2].KeyId); e != entries[1] {
+		t.Errorf("ps.EntryByKeyID(%d) did not return the first entry with that ID", keys[2].KeyId)
+	}
+	if _, err := ps.EntryByKeyID(42); err == nil {
+		t.Errorf("ps.EntryByKeyID(42) succeeded, want error")
+	}
+}
+
+func TestPrimitiveSetCopy(t *testing.T) {
+	ps := primitiveset.New()
+	keys := createKeyset()
+	for i, key := range keys {
+		if _, err := ps.Add(testutil.DummyMAC{Name: fmt.Sprintf("Mac#%d", i)}, key); err != nil {
+			t.Fatalf("ps.Add() failed: %s", err)
+		}
+	}
+	ps.Primary = ps.AllEntries()[0]
+
+	cp := ps.Copy()
+	if cp.Primary != ps.Primary {
+		t.Errorf("cp.Primary = %v, want %v", cp.Primary, ps.Primary)
+	}
+	if !reflect.DeepEqual(cp.Entries, ps.Entries) {
+		t.Errorf("cp.Entries = %v, want %v", cp.Entries, ps.Entries)
+	}
+	if !reflect.DeepEqual(cp.AllEntries(), ps.AllEntries()) {
+		t.Errorf("cp.AllEntries() = %v, want %v", cp.AllEntries(), ps.AllEntries())
+	}
+
+	// Modifying the copy leaves ps unchanged.
+	want := len(ps.Entries)
+	for prefix, entries := range cp.Entries {
+		entries[0] = nil
+		delete(cp.Entries, prefix)
+		break
+	}
+	if _, err := cp.Add(testutil.DummyMAC{Name: "extra"}, testutil.NewDummyKey(42, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_RAW)); err != nil {
+		t.Fatalf("cp.Add() failed: %s", err)
+	}
+	if len(ps.Entries) != want {
+		t.Errorf("len(ps.Entries) = %d after modifying the copy, want %d", len(ps.Entries), want)
+	}
+	for prefix, entries := range ps.Entries {
+		for _, e := range entries {
+			if e == nil {
+				t.Errorf("ps.Entries[%q] contains nil after modifying the copy", prefix)
+			}
+		}
+	}
+	if len(ps.AllEntries()) != len(keys) {
+		t.Errorf("len(ps.AllEntries()) = %d after modifying the copy, want %d", len(ps.AllEntries()), len(keys))
+	}
+}
@@ -16,7 +16,11 @@
 
 package registry
 
-import "github.com/google/tink/go/tink"
+import (
+	"fmt"
+
+	"github.com/google/tink/go/tink"
+)
 
 // KMSClient knows how to produce primitives backed by keys stored in remote KMS services.
 type KMSClient interface {
@@ -26,3 +30,78 @@ type KMSClient interface {
 	// GetAEAD  gets an AEAD backend by keyURI.
 	GetAEAD(keyURI string) (tink.AEAD, error)
 }
+
+// failoverKMSClient is a KMSClient composed of several clients that support
+// the same key URIs. Its AEADs try the clients in order until one succeeds.
+type failoverKMSClient struct {
+	clients []KMSClient
+}
+
+var _ KMSClient = (*failoverKMSClient)(nil)
+
+// Supported returns true if any of the underlying clients supports keyURI.
+func (c *failoverKMSClient) Supported(keyURI string) bool {
+	for _, k := range c.clients {
+		if k.Supported(keyURI) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAEAD returns an AEAD backed by every underlying client that can provide
+// one for keyURI.
+func (c *failoverKMSClient) GetAEAD(keyURI string) (tink.AEAD, error) {
+	var aeads []tink.AEAD
+	var lastErr error
+	for _, k := range c.clients {
+		if !k.Supported(keyURI) {
+			continue
+		}
+		a, err := k.GetAEAD(keyURI)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		aeads = append(aeads, a)
+	}
+	switch len(aeads) {
+	case 0:
+		if lastErr == nil {
+			return nil, fmt.Errorf("registry: KMS client supporting %s not found", keyURI)
+		}
+		return nil, fmt.Errorf("registry: no KMS client could provide an AEAD for %s: %s", keyURI, lastErr)
+	case 1:
+		return aeads[0], nil
+	default:
+		return &failoverAEAD{aeads: aeads}, nil
+	}
+}
+
+// failoverAEAD is a tink.AEAD that tries each of its AEADs in order and
+// returns the result of the first one that succeeds.
+type failoverAEAD struct {
+	aeads []tink.AEAD
+}
+
+func (a *failoverAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	var err error
+	for _, p := range a.aeads {
+		var ct []byte
+		if ct, err = p.Encrypt(plaintext, additionalData); err == nil {
+			return ct, nil
+		}
+	}
+	return nil, fmt.Errorf("registry: encryption failed on all %d KMS backends, last error: %s", len(a.aeads), err)
+}
+
+func (a *failoverAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	var err error
+	for _, p := range a.aeads {
+		var pt []byte
+		if pt, err = p.Decrypt(ciphertext, additionalData); err == nil {
+			return pt, nil
+		}
+	}
+	return nil, fmt.Errorf("registry: decryption failed on all %d KMS backends, last error: %s", len(a.aeads), err)
+}
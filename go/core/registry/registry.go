@@ -32,7 +32,10 @@ package registry
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
@@ -43,6 +46,12 @@ var (
 	keyManagers   = make(map[string]KeyManager) // typeURL -> KeyManager
 	kmsClientsMu  sync.RWMutex
 	kmsClients    = []KMSClient{}
+
+	keyTemplatesMu sync.RWMutex
+	keyTemplates   = make(map[string]func() *tinkpb.KeyTemplate) // name -> template constructor
+
+	primitiveFilterMu sync.RWMutex
+	primitiveFilter   func(typeURL string) error
 )
 
 // RegisterKeyManager registers the given key manager.
@@ -69,6 +78,50 @@ func GetKeyManager(typeURL string) (KeyManager, error) {
 	return km, nil
 }
 
+// RegisterKeyTemplates registers the given key template constructors by their
+// canonical names, such as "AES128_GCM". Primitive packages call it in their
+// init function with the result of their SupportedTemplates function. Does not
+// allow to overwrite existing names; if any name is already registered,
+// nothing is registered.
+func RegisterKeyTemplates(templates map[string]func() *tinkpb.KeyTemplate) error {
+	keyTemplatesMu.Lock()
+	defer keyTemplatesMu.Unlock()
+	for name := range templates {
+		if _, existed := keyTemplates[name]; existed {
+			return fmt.Errorf("registry.RegisterKeyTemplates: key template %s already registered", name)
+		}
+	}
+	for name, newTemplate := range templates {
+		keyTemplates[name] = newTemplate
+	}
+	return nil
+}
+
+// KeyTemplate returns a new key template with the given canonical name. Only
+// the templates of the primitive packages that are linked into the binary are
+// known. The error for an unknown name lists the known ones.
+func KeyTemplate(name string) (*tinkpb.KeyTemplate, error) {
+	keyTemplatesMu.RLock()
+	newTemplate, existed := keyTemplates[name]
+	keyTemplatesMu.RUnlock()
+	if !existed {
+		return nil, fmt.Errorf("registry.KeyTemplate: unknown key template %q, want one of: %s", name, strings.Join(KeyTemplateNames(), ", "))
+	}
+	return newTemplate(), nil
+}
+
+// KeyTemplateNames returns the sorted names of the registered key templates.
+func KeyTemplateNames() []string {
+	keyTemplatesMu.RLock()
+	defer keyTemplatesMu.RUnlock()
+	names := make([]string, 0, len(keyTemplates))
+	for name := range keyTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // NewKeyData generates a new KeyData for the given key template.
 func NewKeyData(kt *tinkpb.KeyTemplate) (*tinkpb.KeyData, error) {
 	if kt == nil {
@@ -107,6 +160,9 @@ func Primitive(typeURL string, sk []byte) (interface{}, error) {
 	if len(sk) == 0 {
 		return nil, fmt.Errorf("registry.Primitive: invalid serialized key")
 	}
+	if err := CheckPrimitiveFilter(typeURL); err != nil {
+		return nil, err
+	}
 	km, err := GetKeyManager(typeURL)
 	if err != nil {
 		return nil, err
@@ -114,6 +170,62 @@ func Primitive(typeURL string, sk []byte) (interface{}, error) {
 	return km.Primitive(sk)
 }
 
+// SetPrimitiveFilter installs a process-wide filter that is consulted with the
+// type URL of every key before a primitive is created for it. If the filter
+// returns an error, primitive creation fails with that error. This allows
+// enforcing a policy, e.g. to only allow FIPS-approved algorithms, for all
+// primitives regardless of where they are created. Passing nil removes the
+// filter, which is the default and allows all key types.
+func SetPrimitiveFilter(filter func(typeURL string) error) {
+	primitiveFilterMu.Lock()
+	defer primitiveFilterMu.Unlock()
+	primitiveFilter = filter
+}
+
+// CheckPrimitiveFilter returns an error if the filter installed with
+// SetPrimitiveFilter rejects the given typeURL. Code that caches primitives
+// calls it before handing out a cached primitive, so that a filter installed
+// later also applies to it.
+func CheckPrimitiveFilter(typeURL string) error {
+	primitiveFilterMu.RLock()
+	filter := primitiveFilter
+	primitiveFilterMu.RUnlock()
+	if filter == nil {
+		return nil
+	}
+	if err := filter(typeURL); err != nil {
+		return fmt.Errorf("registry: primitive for key type %s rejected by filter: %s", typeURL, err)
+	}
+	return nil
+}
+
+// PrimitiveWithTiming is like PrimitiveFromKeyData, but also returns how long
+// the key manager took to construct the primitive. This lets applications
+// find key types that are expensive to instantiate, e.g. to log them at
+// startup. The duration is zero if the primitive was not constructed.
+func PrimitiveWithTiming(kd *tinkpb.KeyData) (interface{}, time.Duration, error) {
+	if kd == nil {
+		return nil, 0, fmt.Errorf("registry.PrimitiveWithTiming: invalid key data")
+	}
+	if len(kd.Value) == 0 {
+		return nil, 0, fmt.Errorf("registry.PrimitiveWithTiming: invalid serialized key")
+	}
+	if err := CheckPrimitiveFilter(kd.TypeUrl); err != nil {
+		return nil, 0, err
+	}
+	km, err := GetKeyManager(kd.TypeUrl)
+	if err != nil {
+		return nil, 0, err
+	}
+	start := time.Now()
+	p, err := km.Primitive(kd.Value)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, elapsed, nil
+}
+
 // RegisterKMSClient is used to register a new KMS client
 func RegisterKMSClient(k KMSClient) {
 	kmsClientsMu.Lock()
@@ -122,15 +234,41 @@ func RegisterKMSClient(k KMSClient) {
 }
 
 // GetKMSClient fetches a KMSClient by a given URI.
+//
+// If several registered clients support keyURI, the returned client fails
+// over between them in registration order: its AEADs try each backend in
+// turn until one of them succeeds.
 func GetKMSClient(keyURI string) (KMSClient, error) {
 	kmsClientsMu.RLock()
 	defer kmsClientsMu.RUnlock()
+	var supported []KMSClient
 	for _, k := range kmsClients {
 		if k.Supported(keyURI) {
-			return k, nil
+			supported = append(supported, k)
+		}
+	}
+	switch len(supported) {
+	case 0:
+		return nil, fmt.Errorf("KMS client supporting %s not found", keyURI)
+	case 1:
+		return supported[0], nil
+	default:
+		return &failoverKMSClient{clients: supported}, nil
+	}
+}
+
+// RemoveKMSClient removes all registered KMS clients that support the given
+// URI prefix, i.e. those for which Supported(uriPrefix) returns true.
+func RemoveKMSClient(uriPrefix string) {
+	kmsClientsMu.Lock()
+	defer kmsClientsMu.Unlock()
+	remaining := []KMSClient{}
+	for _, k := range kmsClients {
+		if !k.Supported(uriPrefix) {
+			remaining = append(remaining, k)
 		}
 	}
-	return nil, fmt.Errorf("KMS client supporting %s not found", keyURI)
+	kmsClients = remaining
 }
 
 // ClearKMSClients removes all registered KMS clients.
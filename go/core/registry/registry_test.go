@@ -17,11 +17,16 @@
 package registry_test
 
 import (
+	"bytes"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/google/tink/go/aead"
 	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/mac"
 	"github.com/google/tink/go/mac/subtle"
 	"github.com/google/tink/go/testing/fakekms"
@@ -172,6 +177,139 @@ func TestPrimitive(t *testing.T) {
 	}
 }
 
+// slowKeyManager is a key manager whose primitive construction takes at least
+// delay.
+type slowKeyManager struct {
+	testutil.DummyAEADKeyManager
+	delay time.Duration
+}
+
+const slowKeyManagerTypeURL = "type.googleapis.com/google.crypto.tink.SlowKey"
+
+func (km *slowKeyManager) Primitive(serializedKey []byte) (interface{}, error) {
+	time.Sleep(km.delay)
+	return km.DummyAEADKeyManager.Primitive(serializedKey)
+}
+
+func (km *slowKeyManager) DoesSupport(typeURL string) bool {
+	return typeURL == slowKeyManagerTypeURL
+}
+
+func (km *slowKeyManager) TypeURL() string {
+	return slowKeyManagerTypeURL
+}
+
+func TestPrimitiveWithTiming(t *testing.T) {
+	delay := 20 * time.Millisecond
+	if err := registry.RegisterKeyManager(&slowKeyManager{delay: delay}); err != nil {
+		t.Fatalf("registry.RegisterKeyManager() err = %v, want nil", err)
+	}
+	keyData := testutil.NewKeyData(slowKeyManagerTypeURL, []byte{0x01}, tinkpb.KeyData_SYMMETRIC)
+	p, elapsed, err := registry.PrimitiveWithTiming(keyData)
+	if err != nil {
+		t.Fatalf("registry.PrimitiveWithTiming() err = %v, want nil", err)
+	}
+	if _, ok := p.(*testutil.DummyAEAD); !ok {
+		t.Errorf("registry.PrimitiveWithTiming() returned %T, want *testutil.DummyAEAD", p)
+	}
+	if elapsed < delay {
+		t.Errorf("registry.PrimitiveWithTiming() elapsed = %s, want at least %s", elapsed, delay)
+	}
+
+	// The result must match the untimed path.
+	hmacKeyData := testutil.NewHMACKeyData(commonpb.HashType_SHA256, 16)
+	p, elapsed, err = registry.PrimitiveWithTiming(hmacKeyData)
+	if err != nil {
+		t.Fatalf("registry.PrimitiveWithTiming() err = %v, want nil", err)
+	}
+	var _ *subtle.HMAC = p.(*subtle.HMAC)
+	if elapsed <= 0 {
+		t.Errorf("registry.PrimitiveWithTiming() elapsed = %s, want > 0", elapsed)
+	}
+}
+
+func TestPrimitiveWithTimingFailures(t *testing.T) {
+	if _, _, err := registry.PrimitiveWithTiming(nil); err == nil {
+		t.Errorf("registry.PrimitiveWithTiming(nil) err = nil, want error")
+	}
+	keyData := testutil.NewHMACKeyData(commonpb.HashType_SHA256, 16)
+	keyData.TypeUrl = "some url"
+	if _, _, err := registry.PrimitiveWithTiming(keyData); err == nil {
+		t.Errorf("registry.PrimitiveWithTiming() with unregistered type URL err = nil, want error")
+	}
+	keyData.TypeUrl = testutil.AESGCMTypeURL
+	_, elapsed, err := registry.PrimitiveWithTiming(keyData)
+	if err == nil {
+		t.Errorf("registry.PrimitiveWithTiming() with mismatched key err = nil, want error")
+	}
+	if elapsed != 0 {
+		t.Errorf("registry.PrimitiveWithTiming() elapsed = %s on failure, want 0", elapsed)
+	}
+}
+
+func TestPrimitiveFilter(t *testing.T) {
+	xchachaTypeURL := aead.XChaCha20Poly1305KeyTemplate().TypeUrl
+	errForbidden := fmt.Errorf("not FIPS-approved")
+	registry.SetPrimitiveFilter(func(typeURL string) error {
+		if typeURL == xchachaTypeURL {
+			return errForbidden
+		}
+		return nil
+	})
+	defer registry.SetPrimitiveFilter(nil)
+
+	xchacha, err := keyset.NewHandle(aead.XChaCha20Poly1305KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	if _, err := aead.New(xchacha); err == nil {
+		t.Errorf("aead.New() with filtered key type err = nil, want error")
+	}
+	keyData, err := registry.NewKeyData(aead.XChaCha20Poly1305KeyTemplate())
+	if err != nil {
+		t.Fatalf("registry.NewKeyData() err = %v, want nil", err)
+	}
+	if _, err := registry.PrimitiveFromKeyData(keyData); err == nil {
+		t.Errorf("registry.PrimitiveFromKeyData() with filtered key type err = nil, want error")
+	}
+	if _, _, err := registry.PrimitiveWithTiming(keyData); err == nil {
+		t.Errorf("registry.PrimitiveWithTiming() with filtered key type err = nil, want error")
+	}
+
+	gcm, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	if _, err := aead.New(gcm); err != nil {
+		t.Errorf("aead.New() with allowed key type err = %v, want nil", err)
+	}
+
+	registry.SetPrimitiveFilter(nil)
+	if _, err := aead.New(xchacha); err != nil {
+		t.Errorf("aead.New() after removing the filter err = %v, want nil", err)
+	}
+}
+
+func TestPrimitiveFilterConcurrentAccess(t *testing.T) {
+	defer registry.SetPrimitiveFilter(nil)
+	keyData := testutil.NewHMACKeyData(commonpb.HashType_SHA256, 16)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			registry.SetPrimitiveFilter(func(string) error { return nil })
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := registry.PrimitiveFromKeyData(keyData); err != nil {
+				t.Errorf("registry.PrimitiveFromKeyData() err = %v, want nil", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestRegisterKmsClient(t *testing.T) {
 	c1, err := fakekms.NewClient("fake-kms://prefix1")
 	if err != nil {
@@ -181,6 +319,8 @@ func TestRegisterKmsClient(t *testing.T) {
 	if err != nil {
 		t.Fatalf("fakekms.NewClient('fake-kms://prefix2') failed: %v", err)
 	}
+	registry.ClearKMSClients()
+	defer registry.ClearKMSClients()
 	registry.RegisterKMSClient(c1)
 	registry.RegisterKMSClient(c2)
 	output1, err := registry.GetKMSClient("fake-kms://prefix1-postfix")
@@ -206,3 +346,205 @@ func TestRegisterKmsClient(t *testing.T) {
 		t.Errorf("registry.GetKMSClient('bad-kms://unknown-prefix') succeeded, want fail")
 	}
 }
+
+func TestRemoveKMSClient(t *testing.T) {
+	registry.ClearKMSClients()
+	defer registry.ClearKMSClients()
+	c1, err := fakekms.NewClient("fake-kms://prefix1")
+	if err != nil {
+		t.Fatalf("fakekms.NewClient('fake-kms://prefix1') failed: %v", err)
+	}
+	c2, err := fakekms.NewClient("fake-kms://prefix2")
+	if err != nil {
+		t.Fatalf("fakekms.NewClient('fake-kms://prefix2') failed: %v", err)
+	}
+	registry.RegisterKMSClient(c1)
+	registry.RegisterKMSClient(c2)
+
+	registry.RemoveKMSClient("fake-kms://prefix1")
+	if _, err := registry.GetKMSClient("fake-kms://prefix1-postfix"); err == nil {
+		t.Errorf("registry.GetKMSClient('fake-kms://prefix1-postfix') succeeded after removal, want fail")
+	}
+	output2, err := registry.GetKMSClient("fake-kms://prefix2-postfix")
+	if err != nil {
+		t.Errorf("registry.GetKMSClient('fake-kms://prefix2-postfix') failed: %v", err)
+	}
+	if output2 != c2 {
+		t.Errorf("registry.GetKMSClient('fake-kms://prefix2-postfix') did not return c2")
+	}
+}
+
+func TestRemoveKMSClientMakesAEADUnresolvable(t *testing.T) {
+	registry.ClearKMSClients()
+	defer registry.ClearKMSClients()
+	c, err := fakekms.NewClient("fake-kms://")
+	if err != nil {
+		t.Fatalf("fakekms.NewClient('fake-kms://') failed: %v", err)
+	}
+	registry.RegisterKMSClient(c)
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() failed: %v", err)
+	}
+	client, err := registry.GetKMSClient(keyURI)
+	if err != nil {
+		t.Fatalf("registry.GetKMSClient(keyURI) failed: %v", err)
+	}
+	if _, err := client.GetAEAD(keyURI); err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+
+	registry.RemoveKMSClient("fake-kms://")
+	if _, err := registry.GetKMSClient(keyURI); err == nil {
+		t.Errorf("registry.GetKMSClient(keyURI) succeeded after removal, want fail")
+	}
+}
+
+func TestKMSClientsConcurrentAccess(t *testing.T) {
+	registry.ClearKMSClients()
+	defer registry.ClearKMSClients()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prefix := fmt.Sprintf("fake-kms://prefix%d", i)
+			c, err := fakekms.NewClient(prefix)
+			if err != nil {
+				t.Errorf("fakekms.NewClient(%q) failed: %v", prefix, err)
+				return
+			}
+			registry.RegisterKMSClient(c)
+			registry.GetKMSClient(prefix)
+			registry.RemoveKMSClient(prefix)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGetKMSClientFailsOverInRegistrationOrder(t *testing.T) {
+	registry.ClearKMSClients()
+	defer registry.ClearKMSClients()
+	// The primary fails its first two operations, the secondary never fails.
+	primary, err := fakekms.NewClient("fake-kms://", fakekms.WithFailureSequence([]bool{true, true}))
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() failed: %v", err)
+	}
+	secondary, err := fakekms.NewClient("fake-kms://")
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() failed: %v", err)
+	}
+	registry.RegisterKMSClient(primary)
+	registry.RegisterKMSClient(secondary)
+
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() failed: %v", err)
+	}
+	client, err := registry.GetKMSClient(keyURI)
+	if err != nil {
+		t.Fatalf("registry.GetKMSClient(keyURI) failed: %v", err)
+	}
+	if client == primary || client == secondary {
+		t.Fatalf("registry.GetKMSClient(keyURI) returned a single client, want a composite")
+	}
+	if !client.Supported(keyURI) {
+		t.Errorf("client.Supported(keyURI) = false, want true")
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+
+	pt := []byte("plaintext")
+	ad := []byte("additional data")
+	// Served by the secondary, since the primary fails.
+	ct, err := a.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a.Encrypt() failed: %v", err)
+	}
+	got, err := a.Decrypt(ct, ad)
+	if err != nil {
+		t.Fatalf("a.Decrypt() failed: %v", err)
+	}
+	if !bytes.Equal(got, pt) {
+		t.Errorf("a.Decrypt() = %q, want %q", got, pt)
+	}
+	// The primary has recovered and serves the following calls directly.
+	primaryAEAD, err := primary.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("primary.GetAEAD(keyURI) failed: %v", err)
+	}
+	ct, err = a.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a.Encrypt() failed: %v", err)
+	}
+	if _, err := primaryAEAD.Decrypt(ct, ad); err != nil {
+		t.Errorf("primaryAEAD.Decrypt() failed: %v", err)
+	}
+}
+
+func TestGetKMSClientFailoverFailsIfAllBackendsFail(t *testing.T) {
+	registry.ClearKMSClients()
+	defer registry.ClearKMSClients()
+	for i := 0; i < 2; i++ {
+		c, err := fakekms.NewClient("fake-kms://", fakekms.WithFailureRate(1, 0))
+		if err != nil {
+			t.Fatalf("fakekms.NewClient() failed: %v", err)
+		}
+		registry.RegisterKMSClient(c)
+	}
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() failed: %v", err)
+	}
+	client, err := registry.GetKMSClient(keyURI)
+	if err != nil {
+		t.Fatalf("registry.GetKMSClient(keyURI) failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	if _, err := a.Encrypt([]byte("plaintext"), nil); err == nil {
+		t.Errorf("a.Encrypt() succeeded, want error")
+	}
+	if _, err := a.Decrypt([]byte("ciphertext"), nil); err == nil {
+		t.Errorf("a.Decrypt() succeeded, want error")
+	}
+}
+
+func TestRegisterKeyTemplates(t *testing.T) {
+	if got, err := registry.KeyTemplate("AES128_GCM"); err != nil || !proto.Equal(got, aead.AES128GCMKeyTemplate()) {
+		t.Errorf("registry.KeyTemplate(\"AES128_GCM\") = %v, %v, want %v, nil", got, err, aead.AES128GCMKeyTemplate())
+	}
+	if err := registry.RegisterKeyTemplates(map[string]func() *tinkpb.KeyTemplate{
+		"TEST_REGISTRY_HMAC": mac.HMACSHA256Tag256KeyTemplate,
+	}); err != nil {
+		t.Fatalf("registry.RegisterKeyTemplates() err = %v", err)
+	}
+	if got, err := registry.KeyTemplate("TEST_REGISTRY_HMAC"); err != nil || !proto.Equal(got, mac.HMACSHA256Tag256KeyTemplate()) {
+		t.Errorf("registry.KeyTemplate(\"TEST_REGISTRY_HMAC\") = %v, %v, want %v, nil", got, err, mac.HMACSHA256Tag256KeyTemplate())
+	}
+
+	// Registration is all or nothing.
+	if err := registry.RegisterKeyTemplates(map[string]func() *tinkpb.KeyTemplate{
+		"TEST_REGISTRY_NEW":  mac.HMACSHA256Tag256KeyTemplate,
+		"TEST_REGISTRY_HMAC": mac.HMACSHA512Tag512KeyTemplate,
+	}); err == nil {
+		t.Error("registry.RegisterKeyTemplates() with a registered name err = nil, want error")
+	}
+	if _, err := registry.KeyTemplate("TEST_REGISTRY_NEW"); err == nil {
+		t.Error("registry.KeyTemplate(\"TEST_REGISTRY_NEW\") err = nil, want error")
+	}
+
+	found := false
+	for _, name := range registry.KeyTemplateNames() {
+		if name == "TEST_REGISTRY_HMAC" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("registry.KeyTemplateNames() = %v, want it to contain TEST_REGISTRY_HMAC", registry.KeyTemplateNames())
+	}
+}
@@ -30,4 +30,7 @@ func init() {
 	if err := registry.RegisterKeyManager(newAESSIVKeyManager()); err != nil {
 		panic(fmt.Sprintf("daead.init() failed: %v", err))
 	}
+	if err := registry.RegisterKeyTemplates(SupportedTemplates()); err != nil {
+		panic(fmt.Sprintf("daead.init() failed: %v", err))
+	}
 }
@@ -22,6 +22,15 @@ import (
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
+// SupportedTemplates returns the deterministic AEAD key templates of this
+// package by their canonical names, such as "AES256_SIV". These are the names
+// used by Tinkey. Each call returns a new map.
+func SupportedTemplates() map[string]func() *tinkpb.KeyTemplate {
+	return map[string]func() *tinkpb.KeyTemplate{
+		"AES256_SIV": AESSIVKeyTemplate,
+	}
+}
+
 // AESSIVKeyTemplate is a KeyTemplate that generates a AES-SIV key.
 func AESSIVKeyTemplate() *tinkpb.KeyTemplate {
 	format := &aspb.AesSivKeyFormat{
@@ -124,3 +124,16 @@ func testEncryptDecrypt(template *tinkpb.KeyTemplate) error {
 	}
 	return nil
 }
+
+func TestSupportedTemplates(t *testing.T) {
+	templates := daead.SupportedTemplates()
+	if got, want := templates["AES256_SIV"](), daead.AESSIVKeyTemplate(); !proto.Equal(got, want) {
+		t.Errorf("SupportedTemplates()[%q]() = %s, want %s", "AES256_SIV", got, want)
+	}
+	for name, newTemplate := range templates {
+		template := newTemplate()
+		if _, err := keyset.NewHandle(template); err != nil {
+			t.Errorf("keyset.NewHandle(SupportedTemplates()[%q]()) failed: %s", name, err)
+		}
+	}
+}
@@ -3,11 +3,11 @@ module github.com/google/tink/go
 go 1.12
 
 require (
-  github.com/aws/aws-sdk-go v1.36.29 // indirect
-  github.com/golang/protobuf v1.4.3
-  github.com/hashicorp/vault/api v1.0.4 // indirect
-  github.com/stretchr/testify v1.6.1 // indirect
-  golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0 // indirect
-  golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43 // indirect
-  google.golang.org/api v0.32.0 // indirect
+	github.com/aws/aws-sdk-go v1.36.29
+	github.com/golang/protobuf v1.4.3
+	github.com/hashicorp/vault/api v1.0.4
+	github.com/stretchr/testify v1.6.1 // indirect
+	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0
+	golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43
+	google.golang.org/api v0.32.0
 )
@@ -0,0 +1,221 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package hybrid
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/hybrid/subtle"
+	"github.com/google/tink/go/keyset"
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	eahpb "github.com/google/tink/go/proto/ecies_aead_hkdf_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+const compactPublicKeyVersion = 1
+
+var errInvalidCompactPublicKey = errors.New("compact_public_key: invalid compact public key")
+
+// compactDEMTemplates are the DEM templates that a compact public key can
+// refer to, by their position plus one.
+var compactDEMTemplates = []func() *tinkpb.KeyTemplate{
+	aead.AES128GCMKeyTemplate,
+	aead.AES256GCMKeyTemplate,
+	aead.AES128CTRHMACSHA256KeyTemplate,
+	aead.AES256CTRHMACSHA256KeyTemplate,
+}
+
+// PublicKeyToCompactString encodes the primary key of h, an ECIES-AEAD-HKDF
+// keyset, as a short URL-safe string without padding, e.g. for a QR code.
+// If h contains private keys, the corresponding public key is encoded. Only
+// the primary key is encoded; PublicKeyFromCompactString turns the string
+// back into a keyset handle for encryption with this key.
+//
+// The string is the unpadded base64url encoding of
+//
+//   version (1) || output prefix type (1) || key ID (4) || curve (1) ||
+//   HKDF hash (1) || ciphertext point format (1) || DEM (1) ||
+//   salt length (1) || HKDF salt || compressed public point
+//
+// where the DEM is one of the AES-GCM and AES-CTR-HMAC AEAD templates of
+// the aead package. Keys with other DEMs or salts longer than 255 bytes
+// cannot be encoded. A P-256 key with an empty salt takes 59 characters.
+func PublicKeyToCompactString(h *keyset.Handle) (string, error) {
+	key, err := primaryECIESPublicKey(h)
+	if err != nil {
+		return "", err
+	}
+	pub := new(eahpb.EciesAeadHkdfPublicKey)
+	if err := proto.Unmarshal(key.KeyData.Value, pub); err != nil {
+		return "", fmt.Errorf("compact_public_key: cannot parse public key: %s", err)
+	}
+	if err := newECIESAEADHKDFPublicKeyKeyManager().validateKey(pub); err != nil {
+		return "", fmt.Errorf("compact_public_key: %s", err)
+	}
+	params := pub.Params
+	dem := 0
+	for i, t := range compactDEMTemplates {
+		if proto.Equal(t(), params.DemParams.AeadDem) {
+			dem = i + 1
+			break
+		}
+	}
+	if dem == 0 {
+		return "", errors.New("compact_public_key: DEM is not supported")
+	}
+	salt := params.KemParams.HkdfSalt
+	if len(salt) > 255 {
+		return "", errors.New("compact_public_key: HKDF salt too long")
+	}
+	curve, err := subtle.GetCurve(params.KemParams.CurveType.String())
+	if err != nil {
+		return "", fmt.Errorf("compact_public_key: %s", err)
+	}
+	point, err := subtle.PointEncode(curve, commonpb.EcPointFormat_COMPRESSED.String(), subtle.ECPoint{
+		X: new(big.Int).SetBytes(pub.X),
+		Y: new(big.Int).SetBytes(pub.Y),
+	})
+	if err != nil {
+		return "", fmt.Errorf("compact_public_key: invalid public point: %s", err)
+	}
+
+	b := []byte{compactPublicKeyVersion, byte(key.OutputPrefixType)}
+	var id [4]byte
+	binary.BigEndian.PutUint32(id[:], key.KeyId)
+	b = append(b, id[:]...)
+	b = append(b,
+		byte(params.KemParams.CurveType),
+		byte(params.KemParams.HkdfHashType),
+		byte(params.EcPointFormat),
+		byte(dem),
+		byte(len(salt)))
+	b = append(b, salt...)
+	b = append(b, point...)
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PublicKeyFromCompactString returns a handle for a keyset that contains the
+// public key encoded in s by PublicKeyToCompactString, with its key ID and
+// output prefix type, as its primary key. It fails if s is malformed or the
+// public point is not on the curve.
+func PublicKeyFromCompactString(s string) (*keyset.Handle, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) < 11 || b[0] != compactPublicKeyVersion {
+		return nil, errInvalidCompactPublicKey
+	}
+	prefixType := tinkpb.OutputPrefixType(b[1])
+	switch prefixType {
+	case tinkpb.OutputPrefixType_TINK, tinkpb.OutputPrefixType_LEGACY, tinkpb.OutputPrefixType_RAW, tinkpb.OutputPrefixType_CRUNCHY:
+	default:
+		return nil, errInvalidCompactPublicKey
+	}
+	keyID := binary.BigEndian.Uint32(b[2:6])
+	curveType := commonpb.EllipticCurveType(b[6])
+	hashType := commonpb.HashType(b[7])
+	pointFormat := commonpb.EcPointFormat(b[8])
+	dem := int(b[9])
+	saltLen := int(b[10])
+	b = b[11:]
+	if dem < 1 || dem > len(compactDEMTemplates) || len(b) < saltLen {
+		return nil, errInvalidCompactPublicKey
+	}
+	salt, encodedPoint := b[:saltLen], b[saltLen:]
+	curve, err := subtle.GetCurve(curveType.String())
+	if err != nil {
+		return nil, errInvalidCompactPublicKey
+	}
+	point, err := subtle.PointDecode(curve, commonpb.EcPointFormat_COMPRESSED.String(), encodedPoint)
+	if err != nil || !curve.IsOnCurve(point.X, point.Y) {
+		return nil, errInvalidCompactPublicKey
+	}
+
+	pub := &eahpb.EciesAeadHkdfPublicKey{
+		Version: eciesAEADHKDFPublicKeyKeyVersion,
+		Params: &eahpb.EciesAeadHkdfParams{
+			KemParams: &eahpb.EciesHkdfKemParams{
+				CurveType:    curveType,
+				HkdfHashType: hashType,
+				HkdfSalt:     append([]byte{}, salt...),
+			},
+			DemParams: &eahpb.EciesAeadDemParams{
+				AeadDem: compactDEMTemplates[dem-1](),
+			},
+			EcPointFormat: pointFormat,
+		},
+		X: point.X.Bytes(),
+		Y: point.Y.Bytes(),
+	}
+	if err := newECIESAEADHKDFPublicKeyKeyManager().validateKey(pub); err != nil {
+		return nil, errInvalidCompactPublicKey
+	}
+	serialized, err := proto.Marshal(pub)
+	if err != nil {
+		return nil, err
+	}
+	ks := &tinkpb.Keyset{
+		PrimaryKeyId: keyID,
+		Key: []*tinkpb.Keyset_Key{{
+			KeyData: &tinkpb.KeyData{
+				TypeUrl:         eciesAEADHKDFPublicKeyTypeURL,
+				Value:           serialized,
+				KeyMaterialType: tinkpb.KeyData_ASYMMETRIC_PUBLIC,
+			},
+			Status:           tinkpb.KeyStatusType_ENABLED,
+			KeyId:            keyID,
+			OutputPrefixType: prefixType,
+		}},
+	}
+	return keyset.NewHandleWithNoSecrets(ks)
+}
+
+// primaryECIESPublicKey returns the primary key of h, or of its public
+// keyset if h contains private keys, which must be an ECIES-AEAD-HKDF public
+// key.
+func primaryECIESPublicKey(h *keyset.Handle) (*tinkpb.Keyset_Key, error) {
+	if h == nil {
+		return nil, errors.New("compact_public_key: nil handle")
+	}
+	hasSecrets, err := h.HasSecrets()
+	if err != nil {
+		return nil, fmt.Errorf("compact_public_key: %s", err)
+	}
+	if hasSecrets {
+		if h, err = h.Public(); err != nil {
+			return nil, fmt.Errorf("compact_public_key: cannot get public keyset: %s", err)
+		}
+	}
+	mem := &keyset.MemReaderWriter{}
+	if err := h.WriteWithNoSecrets(mem); err != nil {
+		return nil, fmt.Errorf("compact_public_key: %s", err)
+	}
+	for _, key := range mem.Keyset.Key {
+		if key.KeyId != mem.Keyset.PrimaryKeyId {
+			continue
+		}
+		if key.KeyData.GetTypeUrl() != eciesAEADHKDFPublicKeyTypeURL {
+			return nil, fmt.Errorf("compact_public_key: primary key has type %s, want %s", key.KeyData.GetTypeUrl(), eciesAEADHKDFPublicKeyTypeURL)
+		}
+		return key, nil
+	}
+	return nil, errors.New("compact_public_key: keyset has no primary key")
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package hybrid_test
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/hybrid"
+	"github.com/google/tink/go/keyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+func TestPublicKeyCompactStringRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		template *tinkpb.KeyTemplate
+	}{
+		{"AES128-GCM", hybrid.ECIESHKDFAES128GCMKeyTemplate()},
+		{"AES128-CTR-HMAC-SHA256", hybrid.ECIESHKDFAES128CTRHMACSHA256KeyTemplate()},
+		{"AES128-GCM with salt", hybrid.ECIESHKDFAES128GCMKeyTemplateWithSalt([]byte("some salt"))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			priv, err := keyset.NewHandle(tc.template)
+			if err != nil {
+				t.Fatalf("keyset.NewHandle() err = %v", err)
+			}
+			pub, err := priv.Public()
+			if err != nil {
+				t.Fatalf("priv.Public() err = %v", err)
+			}
+			s, err := hybrid.PublicKeyToCompactString(pub)
+			if err != nil {
+				t.Fatalf("hybrid.PublicKeyToCompactString() err = %v", err)
+			}
+			// Private keysets are encoded as their public keyset.
+			if fromPriv, err := hybrid.PublicKeyToCompactString(priv); err != nil || fromPriv != s {
+				t.Errorf("hybrid.PublicKeyToCompactString(priv) = %q, %v, want %q, nil", fromPriv, err, s)
+			}
+			mem := &keyset.MemReaderWriter{}
+			if err := pub.WriteWithNoSecrets(mem); err != nil {
+				t.Fatalf("pub.WriteWithNoSecrets() err = %v", err)
+			}
+			serialized, err := proto.Marshal(mem.Keyset)
+			if err != nil {
+				t.Fatalf("proto.Marshal() err = %v", err)
+			}
+			if len(s) >= len(serialized)/2 {
+				t.Errorf("len(compact string) = %d, want less than half of the %d bytes of the serialized keyset", len(s), len(serialized))
+			}
+
+			imported, err := hybrid.PublicKeyFromCompactString(s)
+			if err != nil {
+				t.Fatalf("hybrid.PublicKeyFromCompactString() err = %v", err)
+			}
+			if got, want := imported.KeysetInfo().String(), pub.KeysetInfo().String(); got != want {
+				t.Errorf("imported.KeysetInfo() = %s, want %s", got, want)
+			}
+			enc, err := hybrid.NewHybridEncrypt(imported)
+			if err != nil {
+				t.Fatalf("hybrid.NewHybridEncrypt() err = %v", err)
+			}
+			dec, err := hybrid.NewHybridDecrypt(priv)
+			if err != nil {
+				t.Fatalf("hybrid.NewHybridDecrypt() err = %v", err)
+			}
+			pt, contextInfo := []byte("plaintext"), []byte("context info")
+			ct, err := enc.Encrypt(pt, contextInfo)
+			if err != nil {
+				t.Fatalf("enc.Encrypt() err = %v", err)
+			}
+			if got, err := dec.Decrypt(ct, contextInfo); err != nil || !bytes.Equal(got, pt) {
+				t.Errorf("dec.Decrypt() = %q, %v, want %q, nil", got, err, pt)
+			}
+		})
+	}
+}
+
+// offCurveX returns the smallest x > 0 that is not the x-coordinate of a
+// point on c.
+func offCurveX(c elliptic.Curve) *big.Int {
+	p, b := c.Params().P, c.Params().B
+	for x := big.NewInt(1); ; x.Add(x, big.NewInt(1)) {
+		// y² = x³ - 3x + b
+		rhs := new(big.Int).Exp(x, big.NewInt(3), p)
+		rhs.Sub(rhs, new(big.Int).Mul(big.NewInt(3), x))
+		rhs.Add(rhs, b)
+		rhs.Mod(rhs, p)
+		if new(big.Int).ModSqrt(rhs, p) == nil {
+			return x
+		}
+	}
+}
+
+func TestPublicKeyFromCompactStringRejectsMalformedInput(t *testing.T) {
+	priv, err := keyset.NewHandle(hybrid.ECIESHKDFAES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	s, err := hybrid.PublicKeyToCompactString(priv)
+	if err != nil {
+		t.Fatalf("hybrid.PublicKeyToCompactString() err = %v", err)
+	}
+	valid, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("base64.RawURLEncoding.DecodeString() err = %v", err)
+	}
+	modified := func(i int, v byte) string {
+		b := append([]byte{}, valid...)
+		b[i] = v
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	// Replace the x-coordinate of the point, the last 32 bytes, by one that
+	// is not on P-256.
+	offCurve := append([]byte{}, valid...)
+	offCurveX(elliptic.P256()).FillBytes(offCurve[len(offCurve)-32:])
+
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"empty", ""},
+		{"not base64url", s[:10] + "+/" + s[12:]},
+		{"padded", s + "="},
+		{"truncated", base64.RawURLEncoding.EncodeToString(valid[:len(valid)-1])},
+		{"trailing data", base64.RawURLEncoding.EncodeToString(append(append([]byte{}, valid...), 0))},
+		{"wrong version", modified(0, 2)},
+		{"unknown prefix type", modified(1, 0)},
+		{"unknown curve", modified(6, 0)},
+		{"unknown hash", modified(7, 0)},
+		{"unknown point format", modified(8, 0)},
+		{"unknown DEM", modified(9, 0)},
+		{"salt longer than input", modified(10, 200)},
+		{"invalid point tag", modified(11, 4)},
+		{"point not on curve", base64.RawURLEncoding.EncodeToString(offCurve)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := hybrid.PublicKeyFromCompactString(tc.s); err == nil {
+				t.Errorf("hybrid.PublicKeyFromCompactString() err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestPublicKeyToCompactStringRejectsOtherKeys(t *testing.T) {
+	if _, err := hybrid.PublicKeyToCompactString(nil); err == nil {
+		t.Errorf("hybrid.PublicKeyToCompactString(nil) err = nil, want error")
+	}
+	otherSalt := make([]byte, 256)
+	h, err := keyset.NewHandle(hybrid.ECIESHKDFAES128GCMKeyTemplateWithSalt(otherSalt))
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	if _, err := hybrid.PublicKeyToCompactString(h); err == nil {
+		t.Errorf("hybrid.PublicKeyToCompactString() with 256-byte salt err = nil, want error")
+	}
+}
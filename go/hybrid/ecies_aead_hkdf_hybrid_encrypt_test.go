@@ -107,3 +107,60 @@ func TestECAESSIVEncrypt(t *testing.T) {
 	basicMultipleEncrypts(t, "NIST_P521", daead.AESSIVKeyTemplate())
 	basicMultipleEncrypts(t, "NIST_P224", daead.AESSIVKeyTemplate())
 }
+
+func TestECIESWithLargeContextReader(t *testing.T) {
+	curve, err := subtle.GetCurve("NIST_P256")
+	if err != nil {
+		t.Fatalf("error getting NIST_P256 curve: %s ", err)
+	}
+	pvt, err := subtle.GenerateECDHKeyPair(curve)
+	if err != nil {
+		t.Fatalf("error generating ECDH key pair: %s", err)
+	}
+	salt := []byte("some salt")
+	pt := random.GetRandomBytes(20)
+	header := random.GetRandomBytes(4 << 20)
+	// AES256_CTR_HMAC_SHA256 needs more than one HKDF block of key material.
+	for _, k := range []*tinkpb.KeyTemplate{aead.AES128GCMKeyTemplate(), aead.AES256CTRHMACSHA256KeyTemplate(), daead.AESSIVKeyTemplate()} {
+		rDem, err := newRegisterECIESAEADHKDFDemHelper(k)
+		if err != nil {
+			t.Fatalf("error generating a DEM helper :%s", err)
+		}
+		e, err := subtle.NewECIESAEADHKDFHybridEncrypt(&pvt.PublicKey, salt, "SHA256", "UNCOMPRESSED", rDem)
+		if err != nil {
+			t.Fatalf("error generating an encryption construct :%s", err)
+		}
+		d, err := subtle.NewECIESAEADHKDFHybridDecrypt(pvt, salt, "SHA256", "UNCOMPRESSED", rDem)
+		if err != nil {
+			t.Fatalf("error generating an decryption construct :%s", err)
+		}
+
+		ct, err := e.EncryptWithContextReader(pt, bytes.NewReader(header))
+		if err != nil {
+			t.Fatalf("EncryptWithContextReader() failed: %s", err)
+		}
+		if got, err := d.DecryptWithContextReader(ct, bytes.NewReader(header)); err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("DecryptWithContextReader() = %x, %v, want %x, nil", got, err, pt)
+		}
+		// The binding is the same as with the context info as a byte slice.
+		if got, err := d.Decrypt(ct, header); err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("Decrypt() = %x, %v, want %x, nil", got, err, pt)
+		}
+		ct2, err := e.Encrypt(pt, header)
+		if err != nil {
+			t.Fatalf("Encrypt() failed: %s", err)
+		}
+		if got, err := d.DecryptWithContextReader(ct2, bytes.NewReader(header)); err != nil || !bytes.Equal(got, pt) {
+			t.Errorf("DecryptWithContextReader() of Encrypt() output = %x, %v, want %x, nil", got, err, pt)
+		}
+
+		modified := append([]byte{}, header...)
+		modified[len(modified)-1] ^= 1
+		if _, err := d.DecryptWithContextReader(ct, bytes.NewReader(modified)); err == nil {
+			t.Error("DecryptWithContextReader() with modified context info succeeded, want error")
+		}
+		if _, err := d.DecryptWithContextReader(ct, bytes.NewReader(header[:len(header)-1])); err == nil {
+			t.Error("DecryptWithContextReader() with truncated context info succeeded, want error")
+		}
+	}
+}
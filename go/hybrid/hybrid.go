@@ -44,4 +44,7 @@ func init() {
 	if err := registry.RegisterKeyManager(newECIESAEADHKDFPublicKeyKeyManager()); err != nil {
 		panic(fmt.Sprintf("hybrid.init() failed: %v", err))
 	}
+	if err := registry.RegisterKeyTemplates(SupportedTemplates()); err != nil {
+		panic(fmt.Sprintf("hybrid.init() failed: %v", err))
+	}
 }
@@ -17,18 +17,78 @@
 package hybrid
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/google/tink/go/core/cryptofmt"
 	"github.com/google/tink/go/core/primitiveset"
 	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/internal/trialdecrypt"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/tink"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
+var (
+	// ErrNoMatchingKey is returned by the Decrypt method of the HybridDecrypt
+	// primitives returned by NewHybridDecrypt if no key of the keyset was
+	// tried, i.e. the ciphertext prefix matches no key and there are no RAW
+	// keys to try.
+	ErrNoMatchingKey = errors.New("hybrid_factory: decryption failed: no matching key")
+	// ErrDecryptionFailed is returned by the Decrypt method of the
+	// HybridDecrypt primitives returned by NewHybridDecrypt if at least one key
+	// was tried, but none of them decrypted the ciphertext.
+	ErrDecryptionFailed = errors.New("hybrid_factory: decryption failed")
+)
+
+// DecryptOption configures the HybridDecrypt primitive returned by
+// NewHybridDecrypt.
+type DecryptOption func(*wrappedHybridDecrypt)
+
+// WithoutRawTrialDecryption makes Decrypt only consider keys whose TINK or
+// LEGACY prefix matches the ciphertext, instead of also trying every RAW key
+// in the keyset. This bounds the work done for garbage ciphertexts, which
+// otherwise grows with the number of RAW keys. Use it only if all ciphertexts
+// carry a prefix; NewHybridDecrypt fails if the primary key has a RAW prefix.
+func WithoutRawTrialDecryption() DecryptOption {
+	return func(d *wrappedHybridDecrypt) {
+		d.skipRawEntries = true
+	}
+}
+
+// WithConcurrentTrialDecryption makes Decrypt try the candidate keys for a
+// ciphertext on up to maxWorkers goroutines at once, instead of one after the
+// other. The result is the same as with serial trial decryption: if several
+// keys decrypt the ciphertext, the plaintext of the first of them in the
+// serial order is returned. Once a key succeeds, no further trials are
+// started, but trials already running are not interrupted. NewHybridDecrypt
+// fails if maxWorkers is less than 1.
+func WithConcurrentTrialDecryption(maxWorkers int) DecryptOption {
+	return func(d *wrappedHybridDecrypt) {
+		d.trialWorkers = maxWorkers
+	}
+}
+
 // NewHybridDecrypt returns an HybridDecrypt primitive from the given keyset handle.
-func NewHybridDecrypt(h *keyset.Handle) (tink.HybridDecrypt, error) {
-	return NewHybridDecryptWithKeyManager(h, nil /*keyManager*/)
+func NewHybridDecrypt(h *keyset.Handle, opts ...DecryptOption) (tink.HybridDecrypt, error) {
+	ps, err := h.Primitives()
+	if err != nil {
+		return nil, fmt.Errorf("hybrid_factory: cannot obtain primitive set: %s", err)
+	}
+	d, err := newWrappedHybridDecrypt(ps)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.skipRawEntries && ps.Primary.PrefixType == tinkpb.OutputPrefixType_RAW {
+		return nil, fmt.Errorf("hybrid_factory: primary key has RAW prefix and raw trial decryption is disabled")
+	}
+	if d.trialWorkers < 1 {
+		return nil, fmt.Errorf("hybrid_factory: invalid number of trial decryption workers: %d", d.trialWorkers)
+	}
+	return d, nil
 }
 
 // NewHybridDecryptWithKeyManager returns an HybridDecrypt primitive from the given keyset handle
@@ -47,6 +107,12 @@ func NewHybridDecryptWithKeyManager(h *keyset.Handle, km registry.KeyManager) (t
 // for decryption.
 type wrappedHybridDecrypt struct {
 	ps *primitiveset.PrimitiveSet
+
+	// skipRawEntries disables trial decryption with RAW keys.
+	skipRawEntries bool
+
+	// trialWorkers is the maximal number of concurrent trial decryptions.
+	trialWorkers int
 }
 
 func newWrappedHybridDecrypt(ps *primitiveset.PrimitiveSet) (*wrappedHybridDecrypt, error) {
@@ -54,16 +120,15 @@ func newWrappedHybridDecrypt(ps *primitiveset.PrimitiveSet) (*wrappedHybridDecry
 		return nil, fmt.Errorf("hybrid_factory: not a HybridDecrypt primitive")
 	}
 
-	for _, primitives := range ps.Entries {
-		for _, p := range primitives {
-			if _, ok := (p.Primitive).(tink.HybridDecrypt); !ok {
-				return nil, fmt.Errorf("hybrid_factory: not a HybridDecrypt primitive")
-			}
+	for _, p := range ps.AllEntries() {
+		if _, ok := (p.Primitive).(tink.HybridDecrypt); !ok {
+			return nil, fmt.Errorf("hybrid_factory: not a HybridDecrypt primitive")
 		}
 	}
 
 	ret := new(wrappedHybridDecrypt)
 	ret.ps = ps
+	ret.trialWorkers = 1
 
 	return ret, nil
 }
@@ -72,6 +137,8 @@ func newWrappedHybridDecrypt(ps *primitiveset.PrimitiveSet) (*wrappedHybridDecry
 // additional authenticated data. It returns the corresponding plaintext if the
 // ciphertext is authenticated.
 func (a *wrappedHybridDecrypt) Decrypt(ct, ad []byte) ([]byte, error) {
+	// matched is true if a key was tried.
+	matched := false
 	// try non-raw keys
 	prefixSize := cryptofmt.NonRawPrefixSize
 	if len(ct) > prefixSize {
@@ -79,36 +146,47 @@ func (a *wrappedHybridDecrypt) Decrypt(ct, ad []byte) ([]byte, error) {
 		ctNoPrefix := ct[prefixSize:]
 		entries, err := a.ps.EntriesForPrefix(string(prefix))
 		if err == nil {
-			for i := 0; i < len(entries); i++ {
-				p, ok := (entries[i].Primitive).(tink.HybridDecrypt)
-				if !ok {
-					return nil, fmt.Errorf("hybrid_factory: not a HybridDecrypt primitive")
-				}
-
-				pt, err := p.Decrypt(ctNoPrefix, ad)
-				if err == nil {
-					return pt, nil
-				}
+			matched = len(entries) > 0
+			if pt, ok := a.tryDecrypt(entries, ctNoPrefix, ad); ok {
+				return pt, nil
 			}
 		}
 	}
 
+	if a.skipRawEntries {
+		return nil, decryptionError(matched)
+	}
+
 	// try raw keys
 	entries, err := a.ps.RawEntries()
 	if err == nil {
-		for i := 0; i < len(entries); i++ {
-			p, ok := (entries[i].Primitive).(tink.HybridDecrypt)
-			if !ok {
-				return nil, fmt.Errorf("hybrid_factory: not a HybridDecrypt primitive")
-			}
-
-			pt, err := p.Decrypt(ct, ad)
-			if err == nil {
-				return pt, nil
-			}
+		matched = matched || len(entries) > 0
+		if pt, ok := a.tryDecrypt(entries, ct, ad); ok {
+			return pt, nil
 		}
 	}
 
 	// nothing worked
-	return nil, fmt.Errorf("hybrid_factory: decryption failed")
+	return nil, decryptionError(matched)
+}
+
+// decryptionError returns ErrDecryptionFailed if a key was tried, and
+// ErrNoMatchingKey otherwise.
+func decryptionError(matched bool) error {
+	if matched {
+		return ErrDecryptionFailed
+	}
+	return ErrNoMatchingKey
+}
+
+// tryDecrypt returns the plaintext of the first entry that decrypts ct, trying
+// up to a.trialWorkers entries at once.
+func (a *wrappedHybridDecrypt) tryDecrypt(entries []*primitiveset.Entry, ct, ad []byte) ([]byte, bool) {
+	return trialdecrypt.First(len(entries), a.trialWorkers, func(i int) ([]byte, error) {
+		p, ok := (entries[i].Primitive).(tink.HybridDecrypt)
+		if !ok {
+			return nil, fmt.Errorf("hybrid_factory: not a HybridDecrypt primitive")
+		}
+		return p.Decrypt(ct, ad)
+	})
 }
@@ -51,11 +51,9 @@ func newEncryptPrimitiveSet(ps *primitiveset.PrimitiveSet) (*wrappedHybridEncryp
 		return nil, fmt.Errorf("hybrid_factory: not a HybridEncrypt primitive")
 	}
 
-	for _, primitives := range ps.Entries {
-		for _, p := range primitives {
-			if _, ok := (p.Primitive).(tink.HybridEncrypt); !ok {
-				return nil, fmt.Errorf("hybrid_factory: not a HybridEncrypt primitive")
-			}
+	for _, p := range ps.AllEntries() {
+		if _, ok := (p.Primitive).(tink.HybridEncrypt); !ok {
+			return nil, fmt.Errorf("hybrid_factory: not a HybridEncrypt primitive")
 		}
 	}
 
@@ -27,6 +27,7 @@ import (
 	"github.com/google/tink/go/subtle/random"
 	"github.com/google/tink/go/testkeyset"
 	"github.com/google/tink/go/testutil"
+	"github.com/google/tink/go/tink"
 	commonpb "github.com/google/tink/go/proto/common_go_proto"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
@@ -141,3 +142,167 @@ func TestFactoryWithValidPrimitiveSetType(t *testing.T) {
 		t.Fatalf("calling NewHybridDecrypt() with good *keyset.Handle failed %s", err)
 	}
 }
+
+func TestHybridDecryptWithoutRawTrialDecryption(t *testing.T) {
+	pt, ad := []byte("plaintext"), []byte("context info")
+	rawTemplate := ECIESHKDFAES128GCMKeyTemplate()
+	rawTemplate.OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	ksm := keyset.NewManager()
+	if err := ksm.Rotate(rawTemplate); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	rawCT := encryptWithPrimary(t, ksm, pt, ad)
+	if err := ksm.Rotate(ECIESHKDFAES128GCMKeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	tinkCT := encryptWithPrimary(t, ksm, pt, ad)
+	kh, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+
+	d, err := NewHybridDecrypt(kh, WithoutRawTrialDecryption())
+	if err != nil {
+		t.Fatalf("NewHybridDecrypt() err = %v", err)
+	}
+	if got, err := d.Decrypt(tinkCT, ad); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("d.Decrypt() = %q, %v, want %q, nil", got, err, pt)
+	}
+	if _, err := d.Decrypt(rawCT, ad); err == nil {
+		t.Error("d.Decrypt() of RAW ciphertext err = nil, want error")
+	}
+
+	d, err = NewHybridDecrypt(kh)
+	if err != nil {
+		t.Fatalf("NewHybridDecrypt() err = %v", err)
+	}
+	if got, err := d.Decrypt(rawCT, ad); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("d.Decrypt() of RAW ciphertext = %q, %v, want %q, nil", got, err, pt)
+	}
+}
+
+func TestHybridDecryptWithoutRawTrialDecryptionRejectsRawPrimary(t *testing.T) {
+	kt := ECIESHKDFAES128GCMKeyTemplate()
+	kt.OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	kh, err := keyset.NewHandle(kt)
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	if _, err := NewHybridDecrypt(kh, WithoutRawTrialDecryption()); err == nil {
+		t.Error("NewHybridDecrypt() with RAW primary err = nil, want error")
+	}
+}
+
+func TestHybridDecryptWithConcurrentTrialDecryption(t *testing.T) {
+	pt, ad := []byte("plaintext"), []byte("context info")
+	rawTemplate := ECIESHKDFAES128GCMKeyTemplate()
+	rawTemplate.OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	ksm := keyset.NewManager()
+	var cts [][]byte
+	for i := 0; i < 5; i++ {
+		if err := ksm.Rotate(rawTemplate); err != nil {
+			t.Fatalf("ksm.Rotate() err = %v", err)
+		}
+		cts = append(cts, encryptWithPrimary(t, ksm, pt, ad))
+	}
+	if err := ksm.Rotate(ECIESHKDFAES128GCMKeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	cts = append(cts, encryptWithPrimary(t, ksm, pt, ad))
+	kh, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+
+	for _, workers := range []int{1, 2, 10} {
+		d, err := NewHybridDecrypt(kh, WithConcurrentTrialDecryption(workers))
+		if err != nil {
+			t.Fatalf("NewHybridDecrypt() err = %v", err)
+		}
+		for i, ct := range cts {
+			if got, err := d.Decrypt(ct, ad); err != nil || !bytes.Equal(got, pt) {
+				t.Errorf("workers %d: d.Decrypt() of ciphertext %d = %q, %v, want %q, nil", workers, i, got, err, pt)
+			}
+		}
+		if _, err := d.Decrypt(random.GetRandomBytes(100), ad); err == nil {
+			t.Errorf("workers %d: d.Decrypt() of random bytes err = nil, want error", workers)
+		}
+	}
+	if _, err := NewHybridDecrypt(kh, WithConcurrentTrialDecryption(0)); err == nil {
+		t.Error("NewHybridDecrypt() with 0 workers err = nil, want error")
+	}
+}
+
+func encryptWithPrimary(t *testing.T, ksm *keyset.Manager, pt, ad []byte) []byte {
+	t.Helper()
+	kh, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	pub, err := kh.Public()
+	if err != nil {
+		t.Fatalf("kh.Public() err = %v", err)
+	}
+	e, err := NewHybridEncrypt(pub)
+	if err != nil {
+		t.Fatalf("NewHybridEncrypt() err = %v", err)
+	}
+	ct, err := e.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("e.Encrypt() err = %v", err)
+	}
+	return ct
+}
+
+func TestHybridDecryptErrors(t *testing.T) {
+	newPrimitives := func(template *tinkpb.KeyTemplate) (tink.HybridEncrypt, tink.HybridDecrypt) {
+		t.Helper()
+		priv, err := keyset.NewHandle(template)
+		if err != nil {
+			t.Fatalf("keyset.NewHandle() err = %v", err)
+		}
+		pub, err := priv.Public()
+		if err != nil {
+			t.Fatalf("priv.Public() err = %v", err)
+		}
+		enc, err := NewHybridEncrypt(pub)
+		if err != nil {
+			t.Fatalf("NewHybridEncrypt() err = %v", err)
+		}
+		dec, err := NewHybridDecrypt(priv)
+		if err != nil {
+			t.Fatalf("NewHybridDecrypt() err = %v", err)
+		}
+		return enc, dec
+	}
+	rawTemplate := ECIESHKDFAES128GCMKeyTemplate()
+	rawTemplate.OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	enc, dec := newPrimitives(ECIESHKDFAES128GCMKeyTemplate())
+	_, other := newPrimitives(ECIESHKDFAES128GCMKeyTemplate())
+	_, raw := newPrimitives(rawTemplate)
+	contextInfo := []byte("context info")
+	ct, err := enc.Encrypt([]byte("plaintext"), contextInfo)
+	if err != nil {
+		t.Fatalf("enc.Encrypt() err = %v", err)
+	}
+	tampered := append([]byte{}, ct...)
+	tampered[len(tampered)-1] ^= 1
+
+	var testCases = []struct {
+		name string
+		dec  tink.HybridDecrypt
+		ct   []byte
+		want error
+	}{
+		{name: "tampered ciphertext", dec: dec, ct: tampered, want: ErrDecryptionFailed},
+		{name: "unknown key", dec: other, ct: ct, want: ErrNoMatchingKey},
+		{name: "unknown key with RAW keys", dec: raw, ct: ct, want: ErrDecryptionFailed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.dec.Decrypt(tc.ct, contextInfo); err != tc.want {
+				t.Errorf("Decrypt() err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
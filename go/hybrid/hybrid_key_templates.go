@@ -27,6 +27,17 @@ import (
 // This file contains pre-generated KeyTemplates for HybridEncrypt keys. One can use these templates
 // to generate new Keysets.
 
+// SupportedTemplates returns the hybrid encryption key templates of this
+// package by their canonical names, such as
+// "ECIES_P256_HKDF_HMAC_SHA256_AES128_GCM". These are the names used by Tinkey.
+// Each call returns a new map.
+func SupportedTemplates() map[string]func() *tinkpb.KeyTemplate {
+	return map[string]func() *tinkpb.KeyTemplate{
+		"ECIES_P256_HKDF_HMAC_SHA256_AES128_GCM":             ECIESHKDFAES128GCMKeyTemplate,
+		"ECIES_P256_HKDF_HMAC_SHA256_AES128_CTR_HMAC_SHA256": ECIESHKDFAES128CTRHMACSHA256KeyTemplate,
+	}
+}
+
 // ECIESHKDFAES128GCMKeyTemplate is a KeyTemplate that generates an ECDH P-256 and decapsulation key AES128-GCM key with the following parameters:
 //  - KEM: ECDH over NIST P-256
 //  - DEM: AES128-GCM
@@ -49,6 +60,22 @@ func ECIESHKDFAES128CTRHMACSHA256KeyTemplate() *tinkpb.KeyTemplate {
 	return createECIESAEADHKDFKeyTemplate(commonpb.EllipticCurveType_NIST_P256, commonpb.HashType_SHA256, commonpb.EcPointFormat_UNCOMPRESSED, aead.AES128CTRHMACSHA256KeyTemplate(), empty)
 }
 
+// ECIESHKDFAES128GCMKeyTemplateWithSalt is like ECIESHKDFAES128GCMKeyTemplate,
+// but uses salt as the HKDF salt. Keys with different salts derive different
+// DEM keys, so their ciphertexts are not interchangeable; this can be used for
+// domain separation.
+func ECIESHKDFAES128GCMKeyTemplateWithSalt(salt []byte) *tinkpb.KeyTemplate {
+	s := append([]byte{}, salt...)
+	return createECIESAEADHKDFKeyTemplate(commonpb.EllipticCurveType_NIST_P256, commonpb.HashType_SHA256, commonpb.EcPointFormat_UNCOMPRESSED, aead.AES128GCMKeyTemplate(), s)
+}
+
+// ECIESHKDFAES128CTRHMACSHA256KeyTemplateWithSalt is like
+// ECIESHKDFAES128CTRHMACSHA256KeyTemplate, but uses salt as the HKDF salt.
+func ECIESHKDFAES128CTRHMACSHA256KeyTemplateWithSalt(salt []byte) *tinkpb.KeyTemplate {
+	s := append([]byte{}, salt...)
+	return createECIESAEADHKDFKeyTemplate(commonpb.EllipticCurveType_NIST_P256, commonpb.HashType_SHA256, commonpb.EcPointFormat_UNCOMPRESSED, aead.AES128CTRHMACSHA256KeyTemplate(), s)
+}
+
 // createEciesAEADHKDFKeyTemplate creates a new ECIES-AEAD-HKDF key template with the given key
 // size in bytes.
 func createECIESAEADHKDFKeyTemplate(c commonpb.EllipticCurveType, ht commonpb.HashType, ptfmt commonpb.EcPointFormat, dekT *tinkpb.KeyTemplate, salt []byte) *tinkpb.KeyTemplate {
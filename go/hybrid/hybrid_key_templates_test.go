@@ -21,8 +21,10 @@ import (
 	"testing"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/insecurecleartextkeyset"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/testutil"
+	eciespb "github.com/google/tink/go/proto/ecies_aead_hkdf_go_proto"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
@@ -122,3 +124,82 @@ func TestKeyTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportedTemplates(t *testing.T) {
+	templates := SupportedTemplates()
+	if got, want := templates["ECIES_P256_HKDF_HMAC_SHA256_AES128_GCM"](), ECIESHKDFAES128GCMKeyTemplate(); !proto.Equal(got, want) {
+		t.Errorf("SupportedTemplates()[%q]() = %s, want %s", "ECIES_P256_HKDF_HMAC_SHA256_AES128_GCM", got, want)
+	}
+	for name, newTemplate := range templates {
+		template := newTemplate()
+		if _, err := keyset.NewHandle(template); err != nil {
+			t.Errorf("keyset.NewHandle(SupportedTemplates()[%q]()) failed: %s", name, err)
+		}
+	}
+}
+
+func TestKeyTemplatesWithSalt(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		newTemplate func(salt []byte) *tinkpb.KeyTemplate
+	}{
+		{name: "AES128_GCM", newTemplate: ECIESHKDFAES128GCMKeyTemplateWithSalt},
+		{name: "AES128_CTR_HMAC_SHA256", newTemplate: ECIESHKDFAES128CTRHMACSHA256KeyTemplateWithSalt},
+	}
+	plaintext := []byte("this data needs to be encrypted")
+	contextInfo := []byte("encryption context")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			privateHandle, err := keyset.NewHandle(tc.newTemplate([]byte("salt A")))
+			if err != nil {
+				t.Fatalf("keyset.NewHandle() failed: %s", err)
+			}
+			publicHandle, err := privateHandle.Public()
+			if err != nil {
+				t.Fatalf("privateHandle.Public() failed: %s", err)
+			}
+			enc, err := NewHybridEncrypt(publicHandle)
+			if err != nil {
+				t.Fatalf("NewHybridEncrypt(publicHandle) failed: %s", err)
+			}
+			dec, err := NewHybridDecrypt(privateHandle)
+			if err != nil {
+				t.Fatalf("NewHybridDecrypt(privateHandle) failed: %s", err)
+			}
+			ciphertext, err := enc.Encrypt(plaintext, contextInfo)
+			if err != nil {
+				t.Fatalf("enc.Encrypt() failed: %s", err)
+			}
+			decrypted, err := dec.Decrypt(ciphertext, contextInfo)
+			if err != nil {
+				t.Fatalf("dec.Decrypt() failed: %s", err)
+			}
+			if !bytes.Equal(plaintext, decrypted) {
+				t.Errorf("decrypted data doesn't match plaintext, got: %q, want: %q", decrypted, plaintext)
+			}
+
+			// A keyset with the same key material but a different salt cannot
+			// decrypt the ciphertext.
+			ks := insecurecleartextkeyset.KeysetMaterial(privateHandle)
+			key := new(eciespb.EciesAeadHkdfPrivateKey)
+			if err := proto.Unmarshal(ks.Key[0].KeyData.Value, key); err != nil {
+				t.Fatalf("proto.Unmarshal() failed: %s", err)
+			}
+			if got, want := key.PublicKey.Params.KemParams.HkdfSalt, []byte("salt A"); !bytes.Equal(got, want) {
+				t.Errorf("HkdfSalt = %q, want %q", got, want)
+			}
+			key.PublicKey.Params.KemParams.HkdfSalt = []byte("salt B")
+			ks.Key[0].KeyData.Value, err = proto.Marshal(key)
+			if err != nil {
+				t.Fatalf("proto.Marshal() failed: %s", err)
+			}
+			otherDec, err := NewHybridDecrypt(insecurecleartextkeyset.KeysetHandle(ks))
+			if err != nil {
+				t.Fatalf("NewHybridDecrypt() failed: %s", err)
+			}
+			if _, err := otherDec.Decrypt(ciphertext, contextInfo); err == nil {
+				t.Error("Decrypt() with a different salt succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package hybrid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/tink"
+)
+
+const (
+	// multiRecipientDEKSize is the size of the data encryption key of a
+	// multi-recipient ciphertext.
+	multiRecipientDEKSize = 32
+	// multiRecipientLengthSize is the size of the big-endian length fields of
+	// a multi-recipient ciphertext.
+	multiRecipientLengthSize = 4
+	// multiRecipientCommitmentSize is the size of the key commitment of a
+	// multi-recipient ciphertext.
+	multiRecipientCommitmentSize = sha256.Size
+
+	// Labels of the values derived from the DEK with HMAC-SHA256.
+	multiRecipientPayloadKeyLabel = "multi_recipient_hybrid payload key"
+	multiRecipientCommitmentLabel = "multi_recipient_hybrid key commitment"
+)
+
+var errMultiRecipientDecryptionFailed = errors.New("multi_recipient_hybrid: decryption failed")
+
+// multiRecipientEncrypt is a HybridEncrypt that encrypts a plaintext once for
+// several recipients.
+type multiRecipientEncrypt struct {
+	recipients []tink.HybridEncrypt
+}
+
+// Assert that multiRecipientEncrypt implements the HybridEncrypt interface.
+var _ tink.HybridEncrypt = (*multiRecipientEncrypt)(nil)
+
+// NewMultiRecipientEncrypt returns a HybridEncrypt primitive whose ciphertexts
+// can be decrypted by each of the recipients, given by their public keyset
+// handles, using a HybridDecrypt returned by NewMultiRecipientDecrypt.
+//
+// Encrypt generates a fresh 32-byte data encryption key (DEK) and encrypts it
+// with the HybridEncrypt primitive of each recipient and contextInfo. From
+// the DEK, it derives an AES-256-GCM payload key, which encrypts the
+// plaintext, and a key commitment, as HMAC-SHA256(DEK, label) with distinct
+// labels. The ciphertext is
+//
+//	n || len(wrapped DEK 1) || wrapped DEK 1 || ... || len(wrapped DEK n) ||
+//	wrapped DEK n || commitment || AES-256-GCM ciphertext of the plaintext
+//
+// where n and the lengths are 4-byte big-endian integers and the associated
+// data of the AES-256-GCM ciphertext is everything that precedes it. The
+// ciphertext reveals the number of recipients, but not who they are.
+//
+// AES-GCM does not commit to its key, so without the commitment a malicious
+// sender could wrap different DEKs for different recipients under which the
+// same payload decrypts to different plaintexts. Since recipients check the
+// commitment of their DEK, all recipients that decrypt a ciphertext get the
+// same plaintext. Recipients get confidentiality, but no sender
+// authentication: anyone who knows their public keys can create ciphertexts
+// for them. Nor is it guaranteed that every recipient in a ciphertext can
+// decrypt it, since a sender can wrap an invalid DEK for some of them.
+func NewMultiRecipientEncrypt(recipients []*keyset.Handle) (tink.HybridEncrypt, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("multi_recipient_hybrid: no recipients")
+	}
+	ret := &multiRecipientEncrypt{recipients: make([]tink.HybridEncrypt, len(recipients))}
+	for i, h := range recipients {
+		if h == nil {
+			return nil, fmt.Errorf("multi_recipient_hybrid: recipient %d: nil handle", i)
+		}
+		e, err := NewHybridEncrypt(h)
+		if err != nil {
+			return nil, fmt.Errorf("multi_recipient_hybrid: recipient %d: %s", i, err)
+		}
+		ret.recipients[i] = e
+	}
+	return ret, nil
+}
+
+// Encrypt encrypts plaintext for all recipients, binding contextInfo to the
+// resulting ciphertext.
+func (e *multiRecipientEncrypt) Encrypt(plaintext, contextInfo []byte) ([]byte, error) {
+	dek := random.GetRandomBytes(multiRecipientDEKSize)
+	header := make([]byte, multiRecipientLengthSize)
+	binary.BigEndian.PutUint32(header, uint32(len(e.recipients)))
+	for i, r := range e.recipients {
+		wrapped, err := r.Encrypt(dek, contextInfo)
+		if err != nil {
+			return nil, fmt.Errorf("multi_recipient_hybrid: recipient %d: %s", i, err)
+		}
+		var length [multiRecipientLengthSize]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(wrapped)))
+		header = append(header, length[:]...)
+		header = append(header, wrapped...)
+	}
+	payloadKey, commitment := deriveMultiRecipientKeys(dek)
+	header = append(header, commitment...)
+	a, err := subtle.NewAESGCM(payloadKey)
+	if err != nil {
+		return nil, fmt.Errorf("multi_recipient_hybrid: %s", err)
+	}
+	ct, err := a.Encrypt(plaintext, header)
+	if err != nil {
+		return nil, fmt.Errorf("multi_recipient_hybrid: %s", err)
+	}
+	return append(header, ct...), nil
+}
+
+// multiRecipientDecrypt is a HybridDecrypt for the ciphertexts of
+// multiRecipientEncrypt.
+type multiRecipientDecrypt struct {
+	decrypter tink.HybridDecrypt
+}
+
+// Assert that multiRecipientDecrypt implements the HybridDecrypt interface.
+var _ tink.HybridDecrypt = (*multiRecipientDecrypt)(nil)
+
+// NewMultiRecipientDecrypt returns a HybridDecrypt primitive that decrypts
+// the ciphertexts of NewMultiRecipientEncrypt with the given private keyset
+// handle of one of the recipients. It tries the keyset on each wrapped DEK of
+// a ciphertext until one can be decrypted.
+func NewMultiRecipientDecrypt(h *keyset.Handle, opts ...DecryptOption) (tink.HybridDecrypt, error) {
+	d, err := NewHybridDecrypt(h, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("multi_recipient_hybrid: %s", err)
+	}
+	return &multiRecipientDecrypt{decrypter: d}, nil
+}
+
+// Decrypt decrypts ciphertext, verifying the integrity of contextInfo.
+func (d *multiRecipientDecrypt) Decrypt(ciphertext, contextInfo []byte) ([]byte, error) {
+	wrappedDEKs, commitment, headerSize, err := parseMultiRecipientHeader(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	for _, wrapped := range wrappedDEKs {
+		dek, err := d.decrypter.Decrypt(wrapped, contextInfo)
+		if err != nil || len(dek) != multiRecipientDEKSize {
+			continue
+		}
+		payloadKey, want := deriveMultiRecipientKeys(dek)
+		if !hmac.Equal(commitment, want) {
+			continue
+		}
+		a, err := subtle.NewAESGCM(payloadKey)
+		if err != nil {
+			return nil, errMultiRecipientDecryptionFailed
+		}
+		// The DEK matches the commitment, so any other wrapped DEK that does
+		// would yield the same payload key. A payload that does not decrypt
+		// has been modified, and trying further DEKs is pointless. This does
+		// not authenticate the sender.
+		pt, err := a.Decrypt(ciphertext[headerSize:], ciphertext[:headerSize])
+		if err != nil {
+			return nil, errMultiRecipientDecryptionFailed
+		}
+		return pt, nil
+	}
+	return nil, errMultiRecipientDecryptionFailed
+}
+
+// deriveMultiRecipientKeys returns the payload key and the key commitment
+// of dek.
+func deriveMultiRecipientKeys(dek []byte) (payloadKey, commitment []byte) {
+	mac := hmac.New(sha256.New, dek)
+	mac.Write([]byte(multiRecipientPayloadKeyLabel))
+	payloadKey = mac.Sum(nil)
+	mac.Reset()
+	mac.Write([]byte(multiRecipientCommitmentLabel))
+	return payloadKey, mac.Sum(nil)
+}
+
+// parseMultiRecipientHeader returns the wrapped DEKs and the key commitment
+// of ciphertext, and the size of the header that contains them.
+func parseMultiRecipientHeader(ciphertext []byte) ([][]byte, []byte, int, error) {
+	if len(ciphertext) < multiRecipientLengthSize {
+		return nil, nil, 0, fmt.Errorf("multi_recipient_hybrid: ciphertext too short")
+	}
+	n := binary.BigEndian.Uint32(ciphertext)
+	rest := ciphertext[multiRecipientLengthSize:]
+	// Every wrapped DEK takes at least its length field, which bounds n
+	// before anything is allocated.
+	if n == 0 || uint64(n) > uint64(len(rest)/multiRecipientLengthSize) {
+		return nil, nil, 0, fmt.Errorf("multi_recipient_hybrid: invalid number of recipients")
+	}
+	wrappedDEKs := make([][]byte, n)
+	for i := range wrappedDEKs {
+		if len(rest) < multiRecipientLengthSize {
+			return nil, nil, 0, fmt.Errorf("multi_recipient_hybrid: ciphertext too short")
+		}
+		length := binary.BigEndian.Uint32(rest)
+		rest = rest[multiRecipientLengthSize:]
+		if uint64(length) > uint64(len(rest)) {
+			return nil, nil, 0, fmt.Errorf("multi_recipient_hybrid: ciphertext too short")
+		}
+		wrappedDEKs[i] = rest[:length]
+		rest = rest[length:]
+	}
+	if len(rest) < multiRecipientCommitmentSize {
+		return nil, nil, 0, fmt.Errorf("multi_recipient_hybrid: ciphertext too short")
+	}
+	commitment := rest[:multiRecipientCommitmentSize]
+	rest = rest[multiRecipientCommitmentSize:]
+	return wrappedDEKs, commitment, len(ciphertext) - len(rest), nil
+}
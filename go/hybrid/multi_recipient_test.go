@@ -0,0 +1,203 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package hybrid_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/tink/go/hybrid"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/tink"
+)
+
+// newRecipient returns the decrypter and the public keyset handle of a fresh
+// recipient.
+func newRecipient(t *testing.T) (tink.HybridDecrypt, *keyset.Handle) {
+	t.Helper()
+	priv, err := keyset.NewHandle(hybrid.ECIESHKDFAES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v", err)
+	}
+	d, err := hybrid.NewMultiRecipientDecrypt(priv)
+	if err != nil {
+		t.Fatalf("hybrid.NewMultiRecipientDecrypt() err = %v", err)
+	}
+	return d, pub
+}
+
+func TestMultiRecipientEncryptDecrypt(t *testing.T) {
+	var decrypters []tink.HybridDecrypt
+	var pubs []*keyset.Handle
+	for i := 0; i < 3; i++ {
+		d, pub := newRecipient(t)
+		decrypters = append(decrypters, d)
+		pubs = append(pubs, pub)
+	}
+	outsider, _ := newRecipient(t)
+	pt := []byte("shared document")
+	contextInfo := []byte("context info")
+
+	e, err := hybrid.NewMultiRecipientEncrypt(pubs)
+	if err != nil {
+		t.Fatalf("hybrid.NewMultiRecipientEncrypt() err = %v", err)
+	}
+	ct, err := e.Encrypt(pt, contextInfo)
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+	for i, d := range decrypters {
+		got, err := d.Decrypt(ct, contextInfo)
+		if err != nil {
+			t.Fatalf("Decrypt() by recipient %d err = %v", i, err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("Decrypt() by recipient %d = %q, want %q", i, got, pt)
+		}
+		if _, err := d.Decrypt(ct, []byte("other context info")); err == nil {
+			t.Errorf("Decrypt() by recipient %d with wrong context info err = nil, want error", i)
+		}
+	}
+	if _, err := outsider.Decrypt(ct, contextInfo); err == nil {
+		t.Errorf("Decrypt() by non-recipient err = nil, want error")
+	}
+}
+
+func TestMultiRecipientOnlyRecipientCanDecrypt(t *testing.T) {
+	var decrypters []tink.HybridDecrypt
+	var pubs []*keyset.Handle
+	for i := 0; i < 3; i++ {
+		d, pub := newRecipient(t)
+		decrypters = append(decrypters, d)
+		pubs = append(pubs, pub)
+	}
+	pt := []byte("for recipient 1 only")
+	for recipient := range pubs {
+		e, err := hybrid.NewMultiRecipientEncrypt(pubs[recipient : recipient+1])
+		if err != nil {
+			t.Fatalf("hybrid.NewMultiRecipientEncrypt() err = %v", err)
+		}
+		ct, err := e.Encrypt(pt, nil)
+		if err != nil {
+			t.Fatalf("Encrypt() err = %v", err)
+		}
+		for i, d := range decrypters {
+			got, err := d.Decrypt(ct, nil)
+			if i != recipient {
+				if err == nil {
+					t.Errorf("Decrypt() by recipient %d of ciphertext for %d err = nil, want error", i, recipient)
+				}
+				continue
+			}
+			if err != nil || !bytes.Equal(got, pt) {
+				t.Errorf("Decrypt() by recipient %d = %q, %v, want %q, nil", i, got, err, pt)
+			}
+		}
+	}
+}
+
+func TestMultiRecipientDecryptModifiedCiphertext(t *testing.T) {
+	d1, pub1 := newRecipient(t)
+	_, pub2 := newRecipient(t)
+	e, err := hybrid.NewMultiRecipientEncrypt([]*keyset.Handle{pub1, pub2})
+	if err != nil {
+		t.Fatalf("hybrid.NewMultiRecipientEncrypt() err = %v", err)
+	}
+	ct, err := e.Encrypt([]byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+
+	// Modifying the slot of another recipient must be detected as well.
+	slot1Size := binary.BigEndian.Uint32(ct[4:])
+	slot2 := 4 + 4 + int(slot1Size)
+	for _, i := range []int{0, 3, 4, 8, slot2 + 4, slot2 + 20, len(ct) - 1} {
+		modified := append([]byte{}, ct...)
+		modified[i] ^= 1
+		if _, err := d1.Decrypt(modified, nil); err == nil {
+			t.Errorf("Decrypt() with byte %d modified err = nil, want error", i)
+		}
+	}
+	for _, n := range []int{0, 3, 4, slot2, len(ct) - 1} {
+		if _, err := d1.Decrypt(ct[:n], nil); err == nil {
+			t.Errorf("Decrypt() of ciphertext truncated to %d bytes err = nil, want error", n)
+		}
+	}
+	huge := append([]byte{}, ct...)
+	binary.BigEndian.PutUint32(huge, 1<<31)
+	if _, err := d1.Decrypt(huge, nil); err == nil {
+		t.Errorf("Decrypt() with huge number of recipients err = nil, want error")
+	}
+}
+
+func TestMultiRecipientDecryptRejectsUncommittedDEK(t *testing.T) {
+	d1, pub1 := newRecipient(t)
+	d2, pub2 := newRecipient(t)
+	e, err := hybrid.NewMultiRecipientEncrypt([]*keyset.Handle{pub1, pub2})
+	if err != nil {
+		t.Fatalf("hybrid.NewMultiRecipientEncrypt() err = %v", err)
+	}
+	pt := []byte("plaintext")
+	ct, err := e.Encrypt(pt, nil)
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+	other, err := e.Encrypt(pt, nil)
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+
+	// Give recipient 2 a validly wrapped DEK that the commitment of ct does
+	// not match, as a sender trying to show recipients different plaintexts
+	// would.
+	slot1Size := binary.BigEndian.Uint32(ct[4:])
+	slot2 := 4 + 4 + int(slot1Size)
+	slot2Size := int(binary.BigEndian.Uint32(ct[slot2:]))
+	if got := int(binary.BigEndian.Uint32(other[slot2:])); got != slot2Size {
+		t.Fatalf("wrapped DEK sizes differ: %d != %d", got, slot2Size)
+	}
+	spliced := append([]byte{}, ct...)
+	copy(spliced[slot2+4:slot2+4+slot2Size], other[slot2+4:])
+	if _, err := d2.Decrypt(spliced, nil); err == nil {
+		t.Errorf("Decrypt() with a DEK that does not match the commitment err = nil, want error")
+	}
+	if _, err := d1.Decrypt(spliced, nil); err == nil {
+		t.Errorf("Decrypt() of ciphertext with a modified header err = nil, want error")
+	}
+}
+
+func TestNewMultiRecipientEncryptFails(t *testing.T) {
+	if _, err := hybrid.NewMultiRecipientEncrypt(nil); err == nil {
+		t.Errorf("hybrid.NewMultiRecipientEncrypt(nil) err = nil, want error")
+	}
+	_, pub := newRecipient(t)
+	if _, err := hybrid.NewMultiRecipientEncrypt([]*keyset.Handle{pub, nil}); err == nil {
+		t.Errorf("hybrid.NewMultiRecipientEncrypt() with nil handle err = nil, want error")
+	}
+	priv, err := keyset.NewHandle(hybrid.ECIESHKDFAES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	if _, err := hybrid.NewMultiRecipientEncrypt([]*keyset.Handle{priv}); err == nil {
+		t.Errorf("hybrid.NewMultiRecipientEncrypt() with private handle err = nil, want error")
+	}
+}
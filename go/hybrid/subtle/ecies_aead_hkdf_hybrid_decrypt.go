@@ -18,6 +18,7 @@ package subtle
 
 import (
 	"errors"
+	"io"
 
 	"github.com/google/tink/go/tink"
 )
@@ -46,6 +47,22 @@ func NewECIESAEADHKDFHybridDecrypt(pvt *ECPrivateKey, hkdfSalt []byte, hkdfHMACA
 
 // Decrypt is used to decrypt using ECIES with a HKDF-KEM and AEAD-DEM mechanisms.
 func (e *ECIESAEADHKDFHybridDecrypt) Decrypt(ciphertext, contextInfo []byte) ([]byte, error) {
+	return e.decrypt(ciphertext, func(rKem *ECIESHKDFRecipientKem, kemBytes []byte) ([]byte, error) {
+		return rKem.decapsulate(kemBytes, e.hkdfHMACAlgo, e.hkdfSalt, contextInfo, e.demHelper.GetSymmetricKeySize(), e.pointFormat)
+	})
+}
+
+// DecryptWithContextReader is like Decrypt, but reads the context info from
+// contextInfo, see ECIESAEADHKDFHybridEncrypt.EncryptWithContextReader.
+func (e *ECIESAEADHKDFHybridDecrypt) DecryptWithContextReader(ciphertext []byte, contextInfo io.ReadSeeker) ([]byte, error) {
+	return e.decrypt(ciphertext, func(rKem *ECIESHKDFRecipientKem, kemBytes []byte) ([]byte, error) {
+		return rKem.decapsulateWithInfoReader(kemBytes, e.hkdfHMACAlgo, e.hkdfSalt, contextInfo, e.demHelper.GetSymmetricKeySize(), e.pointFormat)
+	})
+}
+
+// decrypt splits ciphertext into the KEM and the DEM ciphertext, and decrypts
+// the latter with the symmetric key that decapsulate derives from the KEM.
+func (e *ECIESAEADHKDFHybridDecrypt) decrypt(ciphertext []byte, decapsulate func(*ECIESHKDFRecipientKem, []byte) ([]byte, error)) ([]byte, error) {
 	curve := e.privateKey.PublicKey.Curve
 	headerSize, err := encodingSizeInBytes(curve, e.pointFormat)
 	if err != nil {
@@ -61,7 +78,7 @@ func (e *ECIESAEADHKDFHybridDecrypt) Decrypt(ciphertext, contextInfo []byte) ([]
 	rKem := &ECIESHKDFRecipientKem{
 		recipientPrivateKey: e.privateKey,
 	}
-	symmetricKey, err := rKem.decapsulate(kemBytes, e.hkdfHMACAlgo, e.hkdfSalt, contextInfo, e.demHelper.GetSymmetricKeySize(), e.pointFormat)
+	symmetricKey, err := decapsulate(rKem, kemBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -19,6 +19,7 @@ package subtle
 import (
 	"bytes"
 	"errors"
+	"io"
 
 	"github.com/google/tink/go/tink"
 )
@@ -54,7 +55,6 @@ func NewECIESAEADHKDFHybridEncrypt(pub *ECPublicKey, hkdfSalt []byte, hkdfHMACAl
 
 // Encrypt is used to encrypt using ECIES with a HKDF-KEM and AEAD-DEM mechanisms.
 func (e *ECIESAEADHKDFHybridEncrypt) Encrypt(plaintext, contextInfo []byte) ([]byte, error) {
-	var b bytes.Buffer
 	sKem := &ECIESHKDFSenderKem{
 		recipientPublicKey: e.publicKey,
 	}
@@ -62,6 +62,29 @@ func (e *ECIESAEADHKDFHybridEncrypt) Encrypt(plaintext, contextInfo []byte) ([]b
 	if err != nil {
 		return nil, err
 	}
+	return e.encrypt(plaintext, kemKey)
+}
+
+// EncryptWithContextReader is like Encrypt, but reads the context info from
+// contextInfo, from its current offset to its end, instead of taking it as a
+// byte slice. This avoids holding large context info in memory. contextInfo
+// is read several times if the DEM key is longer than the HKDF digest, so it
+// must be seekable. The ciphertext can be decrypted with Decrypt or
+// DecryptWithContextReader with the same context info.
+func (e *ECIESAEADHKDFHybridEncrypt) EncryptWithContextReader(plaintext []byte, contextInfo io.ReadSeeker) ([]byte, error) {
+	sKem := &ECIESHKDFSenderKem{
+		recipientPublicKey: e.publicKey,
+	}
+	kemKey, err := sKem.encapsulateWithInfoReader(e.hkdfHMACAlgo, e.hkdfSalt, contextInfo, e.demHelper.GetSymmetricKeySize(), e.pointFormat)
+	if err != nil {
+		return nil, err
+	}
+	return e.encrypt(plaintext, kemKey)
+}
+
+// encrypt encrypts plaintext with the DEM key of kemKey and prepends the KEM.
+func (e *ECIESAEADHKDFHybridEncrypt) encrypt(plaintext []byte, kemKey *KEMKey) ([]byte, error) {
+	var b bytes.Buffer
 	prim, err := e.demHelper.GetAEADOrDAEAD(kemKey.SymmetricKey)
 	if err != nil {
 		return nil, err
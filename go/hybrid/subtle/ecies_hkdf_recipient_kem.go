@@ -16,7 +16,11 @@
 
 package subtle
 
-import "github.com/google/tink/go/subtle"
+import (
+	"io"
+
+	"github.com/google/tink/go/subtle"
+)
 
 // ECIESHKDFRecipientKem represents a HKDF-based KEM (key encapsulation mechanism)
 // for ECIES recipient.
@@ -26,6 +30,25 @@ type ECIESHKDFRecipientKem struct {
 
 // decapsulate uses the KEM to generate a new HKDF-based key.
 func (s *ECIESHKDFRecipientKem) decapsulate(kem []byte, hashAlg string, salt []byte, info []byte, keySize uint32, pointFormat string) ([]byte, error) {
+	i, err := s.inputKeyMaterial(kem, pointFormat)
+	if err != nil {
+		return nil, err
+	}
+	return subtle.ComputeHKDF(hashAlg, i, salt, info, keySize)
+}
+
+// decapsulateWithInfoReader is like decapsulate, but streams info from a reader.
+func (s *ECIESHKDFRecipientKem) decapsulateWithInfoReader(kem []byte, hashAlg string, salt []byte, info io.ReadSeeker, keySize uint32, pointFormat string) ([]byte, error) {
+	i, err := s.inputKeyMaterial(kem, pointFormat)
+	if err != nil {
+		return nil, err
+	}
+	return subtle.ComputeHKDFWithInfoReader(hashAlg, i, salt, info, keySize)
+}
+
+// inputKeyMaterial returns the HKDF input key material for kem, i.e. kem
+// followed by the shared secret.
+func (s *ECIESHKDFRecipientKem) inputKeyMaterial(kem []byte, pointFormat string) ([]byte, error) {
 	pubPoint, err := PointDecode(s.recipientPrivateKey.PublicKey.Curve, pointFormat, kem)
 	if err != nil {
 		return nil, err
@@ -34,7 +57,5 @@ func (s *ECIESHKDFRecipientKem) decapsulate(kem []byte, hashAlg string, salt []b
 	if err != nil {
 		return nil, err
 	}
-	i := append(kem, secret...)
-
-	return subtle.ComputeHKDF(hashAlg, i, salt, info, keySize)
+	return append(kem, secret...), nil
 }
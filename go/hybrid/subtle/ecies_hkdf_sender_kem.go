@@ -16,7 +16,11 @@
 
 package subtle
 
-import "github.com/google/tink/go/subtle"
+import (
+	"io"
+
+	"github.com/google/tink/go/subtle"
+)
 
 // KEMKey represents a KEM managed key.
 type KEMKey struct {
@@ -31,31 +35,53 @@ type ECIESHKDFSenderKem struct {
 
 // GenerateKey a HDKF based KEM.
 func (s *ECIESHKDFSenderKem) encapsulate(hashAlg string, salt []byte, info []byte, keySize uint32, pointFormat string) (*KEMKey, error) {
-
-	pvt, err := GenerateECDHKeyPair(s.recipientPublicKey.Curve)
+	sdata, i, err := s.generate(pointFormat)
 	if err != nil {
 		return nil, err
 	}
-	pub := pvt.PublicKey
-	secret, err := ComputeSharedSecret(&s.recipientPublicKey.Point, pvt)
+	sKey, err := subtle.ComputeHKDF(hashAlg, i, salt, info, keySize)
 	if err != nil {
 		return nil, err
 	}
+	return &KEMKey{
+		Kem:          sdata,
+		SymmetricKey: sKey,
+	}, nil
+}
 
-	sdata, err := PointEncode(pub.Curve, pointFormat, pub.Point)
+// encapsulateWithInfoReader is like encapsulate, but streams info from a reader.
+func (s *ECIESHKDFSenderKem) encapsulateWithInfoReader(hashAlg string, salt []byte, info io.ReadSeeker, keySize uint32, pointFormat string) (*KEMKey, error) {
+	sdata, i, err := s.generate(pointFormat)
 	if err != nil {
 		return nil, err
 	}
-	i := append(sdata, secret...)
-
-	sKey, err := subtle.ComputeHKDF(hashAlg, i, salt, info, keySize)
+	sKey, err := subtle.ComputeHKDFWithInfoReader(hashAlg, i, salt, info, keySize)
 	if err != nil {
 		return nil, err
 	}
-
 	return &KEMKey{
 		Kem:          sdata,
 		SymmetricKey: sKey,
 	}, nil
+}
 
+// generate returns the encoded ephemeral public key and the HKDF input key
+// material, i.e. the encoded public key followed by the shared secret.
+func (s *ECIESHKDFSenderKem) generate(pointFormat string) ([]byte, []byte, error) {
+	pvt, err := GenerateECDHKeyPair(s.recipientPublicKey.Curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub := pvt.PublicKey
+	secret, err := ComputeSharedSecret(&s.recipientPublicKey.Point, pvt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sdata, err := PointEncode(pub.Curve, pointFormat, pub.Point)
+	if err != nil {
+		return nil, nil, err
+	}
+	i := append(append([]byte{}, sdata...), secret...)
+	return sdata, i, nil
 }
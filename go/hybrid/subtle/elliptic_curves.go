@@ -218,14 +218,31 @@ func getY(x *big.Int, lsb bool, c elliptic.Curve) *big.Int {
 }
 
 func validatePublicPoint(pub *ECPoint, priv *ECPrivateKey) error {
+	if pub == nil || pub.X == nil || pub.Y == nil {
+		return errors.New("invalid public key")
+	}
+	// IsOnCurve rejects the point at infinity, which has no affine
+	// coordinates, and coordinates outside of [0, p).
 	if priv.PublicKey.Curve.IsOnCurve(pub.X, pub.Y) {
 		return nil
 	}
 	return errors.New("invalid public key")
 }
 
-// ComputeSharedSecret is used to compute a shared secret using given private key and peer public key.
+// ComputeSharedSecret computes the ECDH shared secret of the given private key
+// and peer public point, i.e. the x-coordinate of priv.D * pub, encoded as a
+// fixed-size big-endian integer. It returns an error if pub is not a valid
+// point on the curve of priv or if priv.D is not a valid private scalar.
+//
+// The result is a raw ECDH output and must be passed through a key derivation
+// function, e.g. HKDF, before it is used as a key.
 func ComputeSharedSecret(pub *ECPoint, priv *ECPrivateKey) ([]byte, error) {
+	if priv == nil || priv.PublicKey.Curve == nil || priv.D == nil {
+		return nil, errors.New("invalid private key")
+	}
+	if priv.D.Sign() <= 0 || priv.D.Cmp(priv.getParams().N) >= 0 {
+		return nil, errors.New("invalid private key")
+	}
 	if err := validatePublicPoint(pub, priv); err != nil {
 		return nil, err
 	}
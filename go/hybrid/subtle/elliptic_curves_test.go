@@ -539,6 +539,139 @@ func convertPointPublicKey(t *testing.T, pk []byte, curve elliptic.Curve, flags
 	}, nil
 }
 
+func hexToBigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	i, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("invalid hex string %q", s)
+	}
+	return i
+}
+
+// ECDH test vectors from RFC 5903, section 8.
+var ecdhTestVectors = []struct {
+	name        string
+	curve       elliptic.Curve
+	privI       string
+	pubIX       string
+	pubIY       string
+	privR       string
+	pubRX       string
+	pubRY       string
+	sharedPoint string
+}{
+	{
+		name:        "P-256",
+		curve:       elliptic.P256(),
+		privI:       "C88F01F510D9AC3F70A292DAA2316DE544E9AAB8AFE84049C62A9C57862D1433",
+		pubIX:       "DAD0B65394221CF9B051E1FECA5787D098DFE637FC90B9EF945D0C3772581180",
+		pubIY:       "5271A0461CDB8252D61F1C456FA3E59AB1F45B33ACCF5F58389E0577B8990BB3",
+		privR:       "C6EF9C5D78AE012A011164ACB397CE2088685D8F06BF9BE0B283AB46476BEE53",
+		pubRX:       "D12DFB5289C8D4F81208B70270398C342296970A0BCCB74C736FC7554494BF63",
+		pubRY:       "56FBF3CA366CC23E8157854C13C58D6AAC23F046ADA30F8353E74F33039872AB",
+		sharedPoint: "D6840F6B42F6EDAFD13116E0E12565202FEF8E9ECE7DCE03812464D04B9442DE",
+	},
+}
+
+func TestComputeSharedSecretVectors(t *testing.T) {
+	for _, tc := range ecdhTestVectors {
+		t.Run(tc.name, func(t *testing.T) {
+			privI, err := hex.DecodeString(tc.privI)
+			if err != nil {
+				t.Fatal(err)
+			}
+			privR, err := hex.DecodeString(tc.privR)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keyI := subtle.GetECPrivateKey(tc.curve, privI)
+			keyR := subtle.GetECPrivateKey(tc.curve, privR)
+			pubI := &subtle.ECPoint{X: hexToBigInt(t, tc.pubIX), Y: hexToBigInt(t, tc.pubIY)}
+			pubR := &subtle.ECPoint{X: hexToBigInt(t, tc.pubRX), Y: hexToBigInt(t, tc.pubRY)}
+			want, err := hex.DecodeString(tc.sharedPoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := subtle.ComputeSharedSecret(pubR, keyI)
+			if err != nil {
+				t.Fatalf("subtle.ComputeSharedSecret() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("subtle.ComputeSharedSecret() = %x, want %x", got, want)
+			}
+			got, err = subtle.ComputeSharedSecret(pubI, keyR)
+			if err != nil {
+				t.Fatalf("subtle.ComputeSharedSecret() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("subtle.ComputeSharedSecret() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestComputeSharedSecretRejectsInvalidPublicPoints(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			priv, err := subtle.GenerateECDHKeyPair(curve)
+			if err != nil {
+				t.Fatalf("subtle.GenerateECDHKeyPair() err = %v, want nil", err)
+			}
+			peer, err := subtle.GenerateECDHKeyPair(curve)
+			if err != nil {
+				t.Fatalf("subtle.GenerateECDHKeyPair() err = %v, want nil", err)
+			}
+			x, y := peer.PublicKey.Point.X, peer.PublicKey.Point.Y
+			p := curve.Params().P
+			other := elliptic.P224()
+			if curve == elliptic.P256() {
+				other = elliptic.P384()
+			}
+			otherX, otherY := other.Params().Gx, other.Params().Gy
+			invalid := map[string]*subtle.ECPoint{
+				"nil point":            nil,
+				"nil coordinates":      &subtle.ECPoint{},
+				"identity":             &subtle.ECPoint{X: big.NewInt(0), Y: big.NewInt(0)},
+				"off curve":            &subtle.ECPoint{X: x, Y: new(big.Int).Add(y, big.NewInt(1))},
+				"x not reduced":        &subtle.ECPoint{X: new(big.Int).Add(x, p), Y: y},
+				"y not reduced":        &subtle.ECPoint{X: x, Y: new(big.Int).Add(y, p)},
+				"negative y":           &subtle.ECPoint{X: x, Y: new(big.Int).Neg(y)},
+				"point of other curve": &subtle.ECPoint{X: otherX, Y: otherY},
+			}
+			for name, pub := range invalid {
+				if _, err := subtle.ComputeSharedSecret(pub, priv); err == nil {
+					t.Errorf("subtle.ComputeSharedSecret() with %s err = nil, want error", name)
+				}
+			}
+			if _, err := subtle.ComputeSharedSecret(&peer.PublicKey.Point, priv); err != nil {
+				t.Errorf("subtle.ComputeSharedSecret() with valid point err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestComputeSharedSecretRejectsInvalidPrivateKeys(t *testing.T) {
+	curve := elliptic.P256()
+	peer, err := subtle.GenerateECDHKeyPair(curve)
+	if err != nil {
+		t.Fatalf("subtle.GenerateECDHKeyPair() err = %v, want nil", err)
+	}
+	pub := &peer.PublicKey.Point
+	invalid := map[string]*subtle.ECPrivateKey{
+		"nil key":  nil,
+		"nil D":    &subtle.ECPrivateKey{PublicKey: subtle.ECPublicKey{Curve: curve}},
+		"zero D":   &subtle.ECPrivateKey{PublicKey: subtle.ECPublicKey{Curve: curve}, D: big.NewInt(0)},
+		"D = n":    &subtle.ECPrivateKey{PublicKey: subtle.ECPublicKey{Curve: curve}, D: curve.Params().N},
+		"no curve": &subtle.ECPrivateKey{D: big.NewInt(1)},
+	}
+	for name, priv := range invalid {
+		if _, err := subtle.ComputeSharedSecret(pub, priv); err == nil {
+			t.Errorf("subtle.ComputeSharedSecret() with %s err = nil, want error", name)
+		}
+	}
+}
+
 func TestECWycheproofCases(t *testing.T) {
 	testutil.SkipTestIfTestSrcDirIsNotSet(t)
 
@@ -22,7 +22,13 @@
 package insecurecleartextkeyset
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
 
 	"github.com/google/tink/go/internal"
 	"github.com/google/tink/go/keyset"
@@ -39,8 +45,16 @@ var (
 	errInvalidHandle = errors.New("insecurecleartextkeyset: invalid handle")
 	errInvalidReader = errors.New("insecurecleartextkeyset: invalid reader")
 	errInvalidWriter = errors.New("insecurecleartextkeyset: invalid writer")
+
+	errChecksumMismatch = errors.New("insecurecleartextkeyset: checksum mismatch, the keyset is corrupted")
+
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
 )
 
+// checksumSize is the size of the CRC-32C checksum appended by
+// WriteWithChecksum.
+const checksumSize = 4
+
 // Read creates a keyset.Handle from a cleartext keyset obtained via r.
 func Read(r keyset.Reader) (*keyset.Handle, error) {
 	if r == nil {
@@ -53,6 +67,20 @@ func Read(r keyset.Reader) (*keyset.Handle, error) {
 	return KeysetHandle(ks), nil
 }
 
+// NewHandleFromSerializedKeyset creates a keyset.Handle from b, a cleartext
+// keyset in binary proto format, e.g. taken from a bytes field of a larger
+// message. The keyset must pass keyset.Validate.
+func NewHandleFromSerializedKeyset(b []byte) (*keyset.Handle, error) {
+	ks, err := keyset.NewBinaryReader(bytes.NewReader(b)).Read()
+	if err != nil {
+		return nil, errInvalidKeyset
+	}
+	if err := keyset.Validate(ks); err != nil {
+		return nil, fmt.Errorf("insecurecleartextkeyset: invalid keyset: %s", err)
+	}
+	return KeysetHandle(ks), nil
+}
+
 // Write exports the keyset from h to the given writer w without encrypting it.
 //
 // Storing secret key material in an unencrypted fashion is dangerous. If
@@ -66,3 +94,48 @@ func Write(h *keyset.Handle, w keyset.Writer) error {
 	}
 	return w.Write(KeysetMaterial(h))
 }
+
+// WriteWithChecksum writes the keyset from h to w without encrypting it, in
+// binary format followed by a big-endian CRC-32C checksum of the serialized
+// keyset.
+//
+// The checksum detects accidental corruption, e.g. of test fixtures checked
+// into a repository. It is not keyed and provides neither secrecy nor
+// protection against deliberate modification.
+func WriteWithChecksum(h *keyset.Handle, w io.Writer) error {
+	if h == nil {
+		return errInvalidHandle
+	}
+	if w == nil {
+		return errInvalidWriter
+	}
+	buf := new(bytes.Buffer)
+	if err := keyset.NewBinaryWriter(buf).Write(KeysetMaterial(h)); err != nil {
+		return fmt.Errorf("insecurecleartextkeyset: cannot serialize keyset: %s", err)
+	}
+	var checksum [checksumSize]byte
+	binary.BigEndian.PutUint32(checksum[:], crc32.Checksum(buf.Bytes(), castagnoli))
+	buf.Write(checksum[:])
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadWithChecksum creates a keyset.Handle from a cleartext keyset written by
+// WriteWithChecksum. It fails if the checksum does not match the keyset.
+func ReadWithChecksum(r io.Reader) (*keyset.Handle, error) {
+	if r == nil {
+		return nil, errInvalidReader
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("insecurecleartextkeyset: cannot read keyset: %s", err)
+	}
+	if len(data) < checksumSize {
+		return nil, errChecksumMismatch
+	}
+	serialized, checksum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+	if binary.BigEndian.Uint32(checksum) != crc32.Checksum(serialized, castagnoli) {
+		return nil, errChecksumMismatch
+	}
+	return Read(keyset.NewBinaryReader(bytes.NewReader(serialized)))
+}
@@ -17,11 +17,14 @@
 package insecurecleartextkeyset_test
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/google/tink/go/insecurecleartextkeyset"
 	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
 	"github.com/google/tink/go/testutil"
 
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
@@ -73,3 +76,120 @@ func TestWrite(t *testing.T) {
 		t.Errorf("exported keyset (%s) doesn't match original keyset (%s)", exported.Keyset, ks)
 	}
 }
+
+func TestKeysetHandleMatchesBinaryReader(t *testing.T) {
+	manager := testutil.NewHMACKeysetManager()
+	handle, err := manager.Handle()
+	if handle == nil || err != nil {
+		t.Fatalf("cannot get keyset handle: %v", err)
+	}
+	ks := insecurecleartextkeyset.KeysetMaterial(handle)
+
+	buf := new(bytes.Buffer)
+	if err := insecurecleartextkeyset.Write(handle, keyset.NewBinaryWriter(buf)); err != nil {
+		t.Fatalf("unexpected error writing keyset: %v", err)
+	}
+	readHandle, err := insecurecleartextkeyset.Read(keyset.NewBinaryReader(buf))
+	if err != nil {
+		t.Fatalf("unexpected error reading keyset: %v", err)
+	}
+	directHandle := insecurecleartextkeyset.KeysetHandle(ks)
+
+	if !proto.Equal(insecurecleartextkeyset.KeysetMaterial(directHandle), insecurecleartextkeyset.KeysetMaterial(readHandle)) {
+		t.Errorf("KeysetHandle(ks) keyset doesn't match the keyset read from the buffer")
+	}
+	if directHandle.String() != readHandle.String() {
+		t.Errorf("directHandle.String() = %q, want %q", directHandle.String(), readHandle.String())
+	}
+}
+
+func TestWriteReadWithChecksum(t *testing.T) {
+	handle, err := testutil.NewHMACKeysetManager().Handle()
+	if err != nil {
+		t.Fatalf("cannot get keyset handle: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := insecurecleartextkeyset.WriteWithChecksum(handle, buf); err != nil {
+		t.Fatalf("insecurecleartextkeyset.WriteWithChecksum() err = %v", err)
+	}
+	readHandle, err := insecurecleartextkeyset.ReadWithChecksum(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("insecurecleartextkeyset.ReadWithChecksum() err = %v", err)
+	}
+	if got, want := insecurecleartextkeyset.KeysetMaterial(readHandle), insecurecleartextkeyset.KeysetMaterial(handle); !proto.Equal(got, want) {
+		t.Errorf("read keyset (%s) doesn't match original keyset (%s)", got, want)
+	}
+}
+
+func TestReadWithChecksumDetectsCorruption(t *testing.T) {
+	handle, err := testutil.NewHMACKeysetManager().Handle()
+	if err != nil {
+		t.Fatalf("cannot get keyset handle: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := insecurecleartextkeyset.WriteWithChecksum(handle, buf); err != nil {
+		t.Fatalf("insecurecleartextkeyset.WriteWithChecksum() err = %v", err)
+	}
+	data := buf.Bytes()
+	for i := range data {
+		corrupted := append([]byte{}, data...)
+		corrupted[i] ^= 0x40
+		if _, err := insecurecleartextkeyset.ReadWithChecksum(bytes.NewReader(corrupted)); err == nil || !strings.Contains(err.Error(), "corrupted") {
+			t.Errorf("insecurecleartextkeyset.ReadWithChecksum() with byte %d flipped err = %v, want checksum error", i, err)
+		}
+	}
+	for _, truncated := range [][]byte{nil, data[:3], data[:len(data)-1]} {
+		if _, err := insecurecleartextkeyset.ReadWithChecksum(bytes.NewReader(truncated)); err == nil {
+			t.Errorf("insecurecleartextkeyset.ReadWithChecksum() of %d of %d bytes err = nil, want error", len(truncated), len(data))
+		}
+	}
+	// A keyset without a checksum is rejected as well.
+	plain := new(bytes.Buffer)
+	if err := insecurecleartextkeyset.Write(handle, keyset.NewBinaryWriter(plain)); err != nil {
+		t.Fatalf("insecurecleartextkeyset.Write() err = %v", err)
+	}
+	if _, err := insecurecleartextkeyset.ReadWithChecksum(plain); err == nil {
+		t.Error("insecurecleartextkeyset.ReadWithChecksum() of a keyset without checksum err = nil, want error")
+	}
+}
+
+func TestWithChecksumInvalidInput(t *testing.T) {
+	if _, err := insecurecleartextkeyset.ReadWithChecksum(nil); err == nil {
+		t.Error("insecurecleartextkeyset.ReadWithChecksum should not accept nil as reader")
+	}
+	if err := insecurecleartextkeyset.WriteWithChecksum(nil, new(bytes.Buffer)); err == nil {
+		t.Error("insecurecleartextkeyset.WriteWithChecksum should not accept nil as keyset")
+	}
+	if err := insecurecleartextkeyset.WriteWithChecksum(&keyset.Handle{}, nil); err == nil {
+		t.Error("insecurecleartextkeyset.WriteWithChecksum should not accept nil as writer")
+	}
+}
+
+func TestNewHandleFromSerializedKeyset(t *testing.T) {
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle(): %v", err)
+	}
+	serialized, err := proto.Marshal(insecurecleartextkeyset.KeysetMaterial(h))
+	if err != nil {
+		t.Fatalf("proto.Marshal(): %v", err)
+	}
+	h2, err := insecurecleartextkeyset.NewHandleFromSerializedKeyset(serialized)
+	if err != nil {
+		t.Fatalf("insecurecleartextkeyset.NewHandleFromSerializedKeyset(): %v", err)
+	}
+	if !proto.Equal(insecurecleartextkeyset.KeysetMaterial(h), insecurecleartextkeyset.KeysetMaterial(h2)) {
+		t.Errorf("got %v, want %v", h2, h)
+	}
+
+	ks := insecurecleartextkeyset.KeysetMaterial(h)
+	noPrimary, err := proto.Marshal(&tinkpb.Keyset{PrimaryKeyId: ks.PrimaryKeyId + 1, Key: ks.Key})
+	if err != nil {
+		t.Fatalf("proto.Marshal(): %v", err)
+	}
+	for _, b := range [][]byte{nil, {0xff, 0xff}, noPrimary} {
+		if _, err := insecurecleartextkeyset.NewHandleFromSerializedKeyset(b); err == nil {
+			t.Errorf("insecurecleartextkeyset.NewHandleFromSerializedKeyset(%x) err = nil, want error", b)
+		}
+	}
+}
@@ -18,6 +18,7 @@
 package awskms
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"strings"
@@ -25,8 +26,12 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/google/tink/go/tink"
 )
 
+// Assert that AWSAEAD implements the AEADWithContext interface.
+var _ tink.AEADWithContext = (*AWSAEAD)(nil)
+
 // AWSAEAD represents a AWS KMS service to a particular URI.
 type AWSAEAD struct {
 	keyURI string
@@ -45,6 +50,11 @@ func newAWSAEAD(keyURI string, kms kmsiface.KMSAPI) *AWSAEAD {
 
 // Encrypt AEAD encrypts the plaintext data and uses addtionaldata from authentication.
 func (a *AWSAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	return a.EncryptContext(context.Background(), plaintext, additionalData)
+}
+
+// EncryptContext is like Encrypt, but passes ctx on to the AWS KMS request.
+func (a *AWSAEAD) EncryptContext(ctx context.Context, plaintext, additionalData []byte) ([]byte, error) {
 	ad := hex.EncodeToString(additionalData)
 	req := &kms.EncryptInput{
 		KeyId:             aws.String(a.keyURI),
@@ -57,7 +67,7 @@ func (a *AWSAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
 			Plaintext: plaintext,
 		}
 	}
-	resp, err := a.kms.Encrypt(req)
+	resp, err := a.kms.EncryptWithContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +75,13 @@ func (a *AWSAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
 	return resp.CiphertextBlob, nil
 }
 
-// Decrypt AEAD decrypts the data and verified the additional data.
+// Decrypt AEAD decrypts the data and verified the additional data, see
+// DecryptContext.
+func (a *AWSAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	return a.DecryptContext(context.Background(), ciphertext, additionalData)
+}
+
+// DecryptContext is like Decrypt, but passes ctx on to the AWS KMS request.
 //
 // Returns an error if the KeyId field in the response does not match the KeyURI
 // provided when creating the client. If we don't do this, the possibility exists
@@ -75,7 +91,7 @@ func (a *AWSAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
 // This check is disabled if AWSAEAD.keyURI is not in key ARN format.
 //
 // See https://docs.aws.amazon.com/kms/latest/developerguide/concepts.html#key-id.
-func (a *AWSAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+func (a *AWSAEAD) DecryptContext(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error) {
 	ad := hex.EncodeToString(additionalData)
 	req := &kms.DecryptInput{
 		KeyId:             aws.String(a.keyURI),
@@ -87,7 +103,7 @@ func (a *AWSAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
 			CiphertextBlob: ciphertext,
 		}
 	}
-	resp, err := a.kms.Decrypt(req)
+	resp, err := a.kms.DecryptWithContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -18,14 +18,23 @@ package awskms
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"flag"
 	// context is used to cancel outstanding requests
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/aead/subtle"
 	"github.com/google/tink/go/core/registry"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/subtle/random"
@@ -143,3 +152,178 @@ func TestBasicAeadWithoutAdditionalData(t *testing.T) {
 		}
 	}
 }
+
+// fakeAWSKMS is a kmsiface.KMSAPI that implements EncryptWithContext and
+// DecryptWithContext locally with AES-GCM, binding the encryption context to
+// the ciphertext the way AWS KMS does.
+type fakeAWSKMS struct {
+	kmsiface.KMSAPI
+
+	keyARN string
+	aead   tink.AEAD
+}
+
+func newFakeAWSKMS(t *testing.T, keyARN string) *fakeAWSKMS {
+	t.Helper()
+	a, err := subtle.NewAESGCM(random.GetRandomBytes(32))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM() failed: %v", err)
+	}
+	return &fakeAWSKMS{keyARN: keyARN, aead: a}
+}
+
+// serializeContext returns a canonical encoding of an encryption context.
+func serializeContext(context map[string]*string) []byte {
+	keys := make([]string, 0, len(context))
+	for k := range context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%d:%s%d:%s", len(k), k, len(*context[k]), *context[k])
+	}
+	return b.Bytes()
+}
+
+func (f *fakeAWSKMS) EncryptWithContext(ctx aws.Context, req *kms.EncryptInput, opts ...request.Option) (*kms.EncryptOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if aws.StringValue(req.KeyId) != f.keyARN {
+		return nil, fmt.Errorf("unknown key id %q", aws.StringValue(req.KeyId))
+	}
+	ct, err := f.aead.Encrypt(req.Plaintext, serializeContext(req.EncryptionContext))
+	if err != nil {
+		return nil, err
+	}
+	return &kms.EncryptOutput{
+		CiphertextBlob: ct,
+		KeyId:          aws.String(f.keyARN),
+	}, nil
+}
+
+func (f *fakeAWSKMS) DecryptWithContext(ctx aws.Context, req *kms.DecryptInput, opts ...request.Option) (*kms.DecryptOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if req.KeyId != nil && aws.StringValue(req.KeyId) != f.keyARN {
+		return nil, fmt.Errorf("unknown key id %q", aws.StringValue(req.KeyId))
+	}
+	pt, err := f.aead.Decrypt(req.CiphertextBlob, serializeContext(req.EncryptionContext))
+	if err != nil {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return &kms.DecryptOutput{
+		Plaintext: pt,
+		KeyId:     aws.String(f.keyARN),
+	}, nil
+}
+
+func TestAEADWithFakeKMS(t *testing.T) {
+	client, err := NewClientWithKMS(keyURI, newFakeAWSKMS(t, strings.TrimPrefix(keyURI, awsPrefix)))
+	if err != nil {
+		t.Fatalf("NewClientWithKMS() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	if err := basicAEADTest(t, a); err != nil {
+		t.Errorf("error in basic aead tests: %v", err)
+	}
+	if err := basicAEADTestWithOptions(t, a, 10 /*loopCount*/, false /*withAdditionalData*/); err != nil {
+		t.Errorf("error in basic aead tests without additional data: %v", err)
+	}
+
+	// The additional data is passed as encryption context, so a mismatch must be detected by KMS.
+	ct, err := a.Encrypt([]byte("plaintext"), []byte("additional data"))
+	if err != nil {
+		t.Fatalf("a.Encrypt() failed: %v", err)
+	}
+	if _, err := a.Decrypt(ct, []byte("other additional data")); err == nil {
+		t.Errorf("a.Decrypt() with wrong additional data succeeded, want error")
+	}
+	if _, err := a.Decrypt(ct, nil); err == nil {
+		t.Errorf("a.Decrypt() without additional data succeeded, want error")
+	}
+}
+
+func TestAEADWithFakeKMSPassesContext(t *testing.T) {
+	client, err := NewClientWithKMS(keyURI, newFakeAWSKMS(t, strings.TrimPrefix(keyURI, awsPrefix)))
+	if err != nil {
+		t.Fatalf("NewClientWithKMS() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	ca, ok := a.(tink.AEADWithContext)
+	if !ok {
+		t.Fatalf("client.GetAEAD(keyURI) = %T, want a tink.AEADWithContext", a)
+	}
+	ct, err := ca.EncryptContext(context.Background(), []byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("ca.EncryptContext() failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ca.EncryptContext(ctx, []byte("plaintext"), nil); err != context.Canceled {
+		t.Errorf("ca.EncryptContext() with cancelled context err = %v, want %v", err, context.Canceled)
+	}
+	if _, err := ca.DecryptContext(ctx, ct, nil); err != context.Canceled {
+		t.Errorf("ca.DecryptContext() with cancelled context err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAEADWithFakeKMSRejectsWrongKeyID(t *testing.T) {
+	otherKeyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/00000000-0000-0000-0000-000000000000"
+	fake := newFakeAWSKMS(t, strings.TrimPrefix(keyURI, awsPrefix))
+	client, err := NewClientWithKMS(keyURI, fake)
+	if err != nil {
+		t.Fatalf("NewClientWithKMS() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	ct, err := a.Encrypt([]byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("a.Encrypt() failed: %v", err)
+	}
+	// Decryption responses reporting a different key than the one requested must be rejected.
+	otherClient, err := NewClientWithKMS(otherKeyURI, fake)
+	if err != nil {
+		t.Fatalf("NewClientWithKMS() failed: %v", err)
+	}
+	otherAEAD, err := otherClient.GetAEAD(otherKeyURI)
+	if err != nil {
+		t.Fatalf("otherClient.GetAEAD(otherKeyURI) failed: %v", err)
+	}
+	if _, err := otherAEAD.Decrypt(ct, nil); err == nil {
+		t.Errorf("Decrypt() with a response for another key id succeeded, want error")
+	}
+}
+
+func TestKMSEnvelopeAEADWithFakeKMS(t *testing.T) {
+	client, err := NewClientWithKMS(keyURI, newFakeAWSKMS(t, strings.TrimPrefix(keyURI, awsPrefix)))
+	if err != nil {
+		t.Fatalf("NewClientWithKMS() failed: %v", err)
+	}
+	registry.ClearKMSClients()
+	defer registry.ClearKMSClients()
+	registry.RegisterKMSClient(client)
+
+	dek := aead.AES128CTRHMACSHA256KeyTemplate()
+	kh, err := keyset.NewHandle(aead.KMSEnvelopeAEADKeyTemplate(keyURI, dek))
+	if err != nil {
+		t.Fatalf("error getting a new keyset handle: %v", err)
+	}
+	a, err := aead.New(kh)
+	if err != nil {
+		t.Fatalf("error getting the primitive: %v", err)
+	}
+	if err := basicAEADTest(t, a); err != nil {
+		t.Errorf("error in basic aead tests: %v", err)
+	}
+}
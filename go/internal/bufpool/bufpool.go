@@ -0,0 +1,93 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+// Package bufpool provides byte buffers, recycled through a sync.Pool, for the
+// primitive wrappers.
+package bufpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxPooledCapacity is the largest buffer capacity that is returned to the
+// pool, so that a few large messages do not pin large amounts of memory.
+const maxPooledCapacity = 64 << 10
+
+// Buffer is a byte slice borrowed from the pool.
+type Buffer struct {
+	// B is the borrowed slice.
+	B []byte
+
+	e *entry
+	// gen is the generation of e that this borrow owns.
+	gen uint64
+}
+
+// entry is the pooled state behind a Buffer. Its generation is advanced by
+// every release, so that releasing a borrow again, or late, does not affect
+// a later borrow of the same entry.
+type entry struct {
+	// gen is accessed atomically and is the first field so that it is 64-bit
+	// aligned on 32-bit platforms.
+	gen uint64
+	b   []byte
+}
+
+var pool = sync.Pool{
+	New: func() interface{} { return new(entry) },
+}
+
+// Get returns a Buffer whose B has length 0 and capacity at least n.
+func Get(n int) Buffer {
+	e := pool.Get().(*entry)
+	if cap(e.b) < n {
+		e.b = make([]byte, 0, n)
+	}
+	return Buffer{B: e.b[:0], e: e, gen: atomic.LoadUint64(&e.gen)}
+}
+
+// Release returns the buffer to the pool. Neither B nor any copy of the
+// Buffer may be used afterwards. Only the first release of a borrow has an
+// effect, so a stray second call, on b or on a copy of it, cannot return a
+// slice that was since borrowed by someone else.
+func (b *Buffer) Release() {
+	if b.e == nil {
+		return
+	}
+	release(b.e, b.gen, b.B)
+	b.B, b.e = nil, nil
+}
+
+// ReleaseFunc returns a function that releases b with its current B, for APIs
+// that hand the release to their caller. Like Release, only the first call
+// has an effect. b must not be modified or released afterwards.
+func (b *Buffer) ReleaseFunc() func() {
+	e, gen, data := b.e, b.gen, b.B
+	return func() { release(e, gen, data) }
+}
+
+func release(e *entry, gen uint64, data []byte) {
+	if e == nil || !atomic.CompareAndSwapUint64(&e.gen, gen, gen+1) {
+		return
+	}
+	// data may have grown beyond the pooled slice; keep the larger one.
+	if cap(data) > maxPooledCapacity {
+		data = nil
+	}
+	e.b = data[:0]
+	pool.Put(e)
+}
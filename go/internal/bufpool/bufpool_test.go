@@ -0,0 +1,98 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package bufpool_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/tink/go/internal/bufpool"
+)
+
+func TestGet(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 1 << 20} {
+		b := bufpool.Get(n)
+		if len(b.B) != 0 || cap(b.B) < n {
+			t.Errorf("Get(%d): len = %d, cap = %d", n, len(b.B), cap(b.B))
+		}
+		b.B = append(b.B, make([]byte, n)...)
+		b.Release()
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b := bufpool.Get(32)
+				for k := 0; k < 32; k++ {
+					b.B = append(b.B, byte(i))
+				}
+				for _, c := range b.B {
+					if c != byte(i) {
+						t.Errorf("buffer shared between goroutines")
+						break
+					}
+				}
+				b.Release()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDoubleRelease(t *testing.T) {
+	b := bufpool.Get(32)
+	stale := b
+	b.Release()
+	b.Release()
+	f := bufpool.Get(32)
+	staleFunc := f.ReleaseFunc()
+	staleFunc()
+
+	// Each later borrower must own its slice, even if earlier borrowers
+	// release their buffers again.
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int, stale bufpool.Buffer) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c := bufpool.Get(32)
+				s := stale
+				s.Release()
+				staleFunc()
+				for k := 0; k < 32; k++ {
+					c.B = append(c.B, byte(i))
+				}
+				for _, x := range c.B {
+					if x != byte(i) {
+						t.Errorf("buffer shared between borrowers after a double release")
+						break
+					}
+				}
+				release := c.ReleaseFunc()
+				release()
+				release()
+			}
+		}(i, stale)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+// Package randomsource holds the source of randomness of subtle/random. Tests
+// of this module can replace it with SetReader to make the output of
+// random.GetRandomBytes, and thereby keys and nonces, deterministic.
+package randomsource
+
+import (
+	"crypto/rand"
+	"flag"
+	"io"
+	"sync"
+)
+
+var (
+	mu sync.RWMutex
+	// override is the reader set by SetReader, or nil for crypto/rand.
+	override io.Reader
+)
+
+// SetReader makes Read draw from r instead of crypto/rand until Reset is
+// called. Passing nil is equivalent to calling Reset. The override is
+// process-wide, so tests that use it must not run in parallel with tests
+// that need proper randomness. r does not have to be safe for concurrent
+// use, since Read never calls it concurrently.
+//
+// SetReader panics when it is not called from a test binary, so that the
+// source of randomness of keys and nonces cannot be replaced in production.
+func SetReader(r io.Reader) {
+	if r != nil && flag.Lookup("test.v") == nil {
+		panic("randomsource: SetReader called outside of a test")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	override = r
+}
+
+// Reset restores crypto/rand as the source of randomness.
+func Reset() {
+	SetReader(nil)
+}
+
+// Read fills buf with random bytes from crypto/rand, or from the reader set
+// by SetReader.
+func Read(buf []byte) error {
+	mu.RLock()
+	if override == nil {
+		mu.RUnlock()
+		_, err := io.ReadFull(rand.Reader, buf)
+		return err
+	}
+	mu.RUnlock()
+
+	// Readers used in tests, such as bytes.Reader, are not safe for
+	// concurrent use, so they are read under the exclusive lock.
+	mu.Lock()
+	defer mu.Unlock()
+	r := override
+	if r == nil {
+		r = rand.Reader
+	}
+	_, err := io.ReadFull(r, buf)
+	return err
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package randomsource_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/google/tink/go/internal/randomsource"
+	"github.com/google/tink/go/subtle/random"
+)
+
+func TestSetReader(t *testing.T) {
+	defer randomsource.Reset()
+	want := []byte("0123456789abcdef")
+	randomsource.SetReader(bytes.NewReader(want))
+	if got := random.GetRandomBytes(uint32(len(want))); !bytes.Equal(got, want) {
+		t.Errorf("GetRandomBytes() = %q, want %q", got, want)
+	}
+}
+
+func TestSetReaderPanicsWhenReaderIsExhausted(t *testing.T) {
+	defer randomsource.Reset()
+	randomsource.SetReader(bytes.NewReader([]byte{1, 2, 3}))
+	defer func() {
+		if recover() == nil {
+			t.Error("GetRandomBytes() did not panic on a short read")
+		}
+	}()
+	random.GetRandomBytes(4)
+}
+
+func TestReset(t *testing.T) {
+	randomsource.SetReader(bytes.NewReader(make([]byte, 64)))
+	randomsource.Reset()
+	if got := random.GetRandomBytes(64); bytes.Equal(got, make([]byte, 64)) {
+		t.Error("GetRandomBytes() still reads from the overridden reader after Reset()")
+	}
+}
+
+func TestSetReaderConcurrentUse(t *testing.T) {
+	defer randomsource.Reset()
+	const goroutines, n = 8, 100
+	// bytes.Reader is not safe for concurrent use; the race detector reports
+	// it if it is read concurrently.
+	data := make([]byte, goroutines*n*4)
+	for i := 0; i < goroutines*n; i++ {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(i))
+	}
+	randomsource.SetReader(bytes.NewReader(data))
+	var mu sync.Mutex
+	seen := make(map[[4]byte]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				var b [4]byte
+				copy(b[:], random.GetRandomBytes(4))
+				mu.Lock()
+				seen[b] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// Every read consumed its own 4 bytes of data.
+	if len(seen) != goroutines*n {
+		t.Errorf("got %d distinct outputs, want %d", len(seen), goroutines*n)
+	}
+}
+
+func TestSetReaderConcurrentWithReset(t *testing.T) {
+	defer randomsource.Reset()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			randomsource.SetReader(bytes.NewReader(make([]byte, 16)))
+			randomsource.Reset()
+		}()
+		go func() {
+			defer wg.Done()
+			random.GetRandomBytes(16)
+		}()
+	}
+	wg.Wait()
+}
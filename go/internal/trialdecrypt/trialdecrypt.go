@@ -0,0 +1,79 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+// Package trialdecrypt runs the trial decryptions of the primitive wrappers
+// concurrently.
+package trialdecrypt
+
+import "sync"
+
+// First calls try(0), ..., try(n-1) on up to workers goroutines and returns
+// the result of the successful call with the lowest index, which is what a
+// loop that stops at the first success returns. Once a call succeeds, no call
+// with a higher index is started; calls that are already running are not
+// interrupted. The second return value is false if all calls fail.
+//
+// If workers is 1 or less, the calls are made one after the other on the
+// calling goroutine.
+func First(n, workers int, try func(i int) ([]byte, error)) ([]byte, bool) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if pt, err := try(i); err == nil {
+				return pt, true
+			}
+		}
+		return nil, false
+	}
+
+	var (
+		mu   sync.Mutex
+		next int    // the index of the next call to start
+		best = n    // the lowest index of a successful call so far
+		pt   []byte // the result of call best
+		wg   sync.WaitGroup
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				i := next
+				if i >= best {
+					mu.Unlock()
+					return
+				}
+				next++
+				mu.Unlock()
+
+				res, err := try(i)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				if i < best {
+					best, pt = i, res
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return pt, best < n
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package trialdecrypt_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/internal/trialdecrypt"
+)
+
+var errTrialFailed = errors.New("trial failed")
+
+func TestFirst(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 10, 100} {
+		for _, workers := range []int{-1, 0, 1, 2, 8, 200} {
+			for _, success := range []map[int]bool{
+				{},
+				{0: true},
+				{n - 1: true},
+				{n / 2: true, n - 1: true},
+				{1: true, 3: true, 5: true},
+			} {
+				t.Run(fmt.Sprintf("n=%d,workers=%d,%v", n, workers, success), func(t *testing.T) {
+					want := -1
+					for i := 0; i < n; i++ {
+						if success[i] {
+							want = i
+							break
+						}
+					}
+					pt, ok := trialdecrypt.First(n, workers, func(i int) ([]byte, error) {
+						if success[i] {
+							return []byte{byte(i)}, nil
+						}
+						return nil, errTrialFailed
+					})
+					if want < 0 {
+						if ok {
+							t.Errorf("First() = %v, true, want false", pt)
+						}
+						return
+					}
+					if !ok || len(pt) != 1 || int(pt[0]) != want {
+						t.Errorf("First() = %v, %t, want [%d], true", pt, ok, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestFirstBoundsConcurrency(t *testing.T) {
+	const workers = 4
+	var running, maxRunning int32
+	var mu sync.Mutex
+	trialdecrypt.First(100, workers, func(i int) ([]byte, error) {
+		r := atomic.AddInt32(&running, 1)
+		mu.Lock()
+		if r > maxRunning {
+			maxRunning = r
+		}
+		mu.Unlock()
+		for j := 0; j < 1000; j++ {
+			_ = fmt.Sprint(j)
+		}
+		atomic.AddInt32(&running, -1)
+		return nil, errTrialFailed
+	})
+	if maxRunning > workers {
+		t.Errorf("%d calls ran concurrently, want at most %d", maxRunning, workers)
+	}
+}
+
+func TestFirstStopsAfterSuccess(t *testing.T) {
+	const n, workers = 1000, 4
+	var calls int32
+	trialdecrypt.First(n, workers, func(i int) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		if i == 0 {
+			return []byte{}, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil, errTrialFailed
+	})
+	// Only the calls that were started before call 0 returned can run.
+	if calls > 2*workers {
+		t.Errorf("First() made %d calls after a success at index 0, want at most %d", calls, 2*workers)
+	}
+}
@@ -17,17 +17,26 @@
 package keyset
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 
 	"github.com/golang/protobuf/proto"
 
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
+// ErrKeysetTooLarge is returned by a BinaryReader created with
+// NewBinaryReaderSized if the input exceeds its size limit.
+var ErrKeysetTooLarge = errors.New("binary_io: keyset exceeds size limit")
+
 // BinaryReader deserializes a keyset from binary proto format.
 type BinaryReader struct {
 	r io.Reader
+	// maxBytes is the largest input accepted; 0 means no limit.
+	maxBytes int64
 }
 
 // NewBinaryReader returns new BinaryReader that will read from r.
@@ -35,11 +44,25 @@ func NewBinaryReader(r io.Reader) *BinaryReader {
 	return &BinaryReader{r: r}
 }
 
+// NewBinaryReaderSized returns a new BinaryReader that will read from r and
+// fail, without buffering the remainder of the input, if r yields more than
+// maxBytes bytes. Use it when reading keysets from untrusted sources.
+func NewBinaryReaderSized(r io.Reader, maxBytes int64) (*BinaryReader, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("binary_io: maxBytes must be positive, got %d", maxBytes)
+	}
+	return &BinaryReader{r: r, maxBytes: maxBytes}, nil
+}
+
 // Read parses a (cleartext) keyset from the underlying io.Reader.
+//
+// Input that ends in the middle of a key results in an error. Note that the
+// binary proto format does not record its own length, so input that is cut
+// exactly between two keys cannot be told apart from a smaller keyset.
 func (bkr *BinaryReader) Read() (*tinkpb.Keyset, error) {
 	keyset := &tinkpb.Keyset{}
 
-	if err := read(bkr.r, keyset); err != nil {
+	if err := read(bkr.r, bkr.maxBytes, keyset); err != nil {
 		return nil, err
 	}
 	return keyset, nil
@@ -49,19 +72,34 @@ func (bkr *BinaryReader) Read() (*tinkpb.Keyset, error) {
 func (bkr *BinaryReader) ReadEncrypted() (*tinkpb.EncryptedKeyset, error) {
 	keyset := &tinkpb.EncryptedKeyset{}
 
-	if err := read(bkr.r, keyset); err != nil {
+	if err := read(bkr.r, bkr.maxBytes, keyset); err != nil {
 		return nil, err
 	}
 	return keyset, nil
 }
 
-func read(r io.Reader, msg proto.Message) error {
+func read(r io.Reader, maxBytes int64, msg proto.Message) error {
+	if maxBytes > 0 {
+		// Read one byte more than allowed to detect oversized input. No input
+		// can exceed math.MaxInt64 bytes, and adding one would overflow.
+		limit := maxBytes
+		if limit < math.MaxInt64 {
+			limit++
+		}
+		r = io.LimitReader(r, limit)
+	}
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return err
 	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return ErrKeysetTooLarge
+	}
 
-	return proto.Unmarshal(data, msg)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("binary_io: cannot parse keyset: %s", err)
+	}
+	return nil
 }
 
 // BinaryWriter serializes a keyset into binary proto format.
@@ -18,10 +18,13 @@ package keyset_test
 
 import (
 	"bytes"
+	"math"
 	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/core/cryptofmt"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/testkeyset"
 	"github.com/google/tink/go/testutil"
@@ -75,3 +78,232 @@ func TestBinaryIOEncrypted(t *testing.T) {
 		t.Errorf("written encrypted keyset (%s) doesn't match read encrypted keyset (%s)", kse1, kse2)
 	}
 }
+
+func serializedHMACKeyset(t *testing.T) []byte {
+	t.Helper()
+	h, err := testutil.NewHMACKeysetManager().Handle()
+	if err != nil {
+		t.Fatalf("cannot get keyset handle: %v", err)
+	}
+	data, err := proto.Marshal(testkeyset.KeysetMaterial(h))
+	if err != nil {
+		t.Fatalf("proto.Marshal() err = %v", err)
+	}
+	return data
+}
+
+func TestBinaryReaderSized(t *testing.T) {
+	data := serializedHMACKeyset(t)
+	r, err := keyset.NewBinaryReaderSized(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("keyset.NewBinaryReaderSized() err = %v", err)
+	}
+	ks, err := r.Read()
+	if err != nil {
+		t.Fatalf("r.Read() err = %v", err)
+	}
+	if len(ks.Key) == 0 {
+		t.Errorf("r.Read() returned an empty keyset")
+	}
+}
+
+func TestBinaryReaderSizedRejectsOversizedInput(t *testing.T) {
+	data := serializedHMACKeyset(t)
+	r, err := keyset.NewBinaryReaderSized(bytes.NewReader(data), int64(len(data)-1))
+	if err != nil {
+		t.Fatalf("keyset.NewBinaryReaderSized() err = %v", err)
+	}
+	if _, err := r.Read(); err != keyset.ErrKeysetTooLarge {
+		t.Errorf("r.Read() of oversized keyset err = %v, want %v", err, keyset.ErrKeysetTooLarge)
+	}
+
+	kse := &tinkpb.EncryptedKeyset{EncryptedKeyset: []byte(strings.Repeat("A", 64))}
+	data, err = proto.Marshal(kse)
+	if err != nil {
+		t.Fatalf("proto.Marshal() err = %v", err)
+	}
+	r, err = keyset.NewBinaryReaderSized(bytes.NewReader(data), 32)
+	if err != nil {
+		t.Fatalf("keyset.NewBinaryReaderSized() err = %v", err)
+	}
+	if _, err := r.ReadEncrypted(); err != keyset.ErrKeysetTooLarge {
+		t.Errorf("r.ReadEncrypted() of oversized keyset err = %v, want %v", err, keyset.ErrKeysetTooLarge)
+	}
+}
+
+func TestBinaryReaderSizedWithMaxInt64Limit(t *testing.T) {
+	data := serializedHMACKeyset(t)
+	r, err := keyset.NewBinaryReaderSized(bytes.NewReader(data), math.MaxInt64)
+	if err != nil {
+		t.Fatalf("keyset.NewBinaryReaderSized() err = %v", err)
+	}
+	ks, err := r.Read()
+	if err != nil {
+		t.Fatalf("r.Read() err = %v", err)
+	}
+	if len(ks.Key) == 0 {
+		t.Errorf("r.Read() with limit math.MaxInt64 returned an empty keyset")
+	}
+}
+
+func TestBinaryReaderSizedWithInvalidKeyset(t *testing.T) {
+	r, err := keyset.NewBinaryReaderSized(bytes.NewReader([]byte{0xff, 0xff}), 32)
+	if err != nil {
+		t.Fatalf("keyset.NewBinaryReaderSized() err = %v", err)
+	}
+	_, err = r.Read()
+	if err == nil {
+		t.Fatal("r.Read() of invalid keyset err = nil, want error")
+	}
+	if err == keyset.ErrKeysetTooLarge {
+		t.Errorf("r.Read() of invalid keyset err = %v, want parse error", err)
+	}
+}
+
+func TestBinaryReaderSizedWithInvalidLimit(t *testing.T) {
+	for _, maxBytes := range []int64{0, -1} {
+		if _, err := keyset.NewBinaryReaderSized(new(bytes.Buffer), maxBytes); err == nil {
+			t.Errorf("keyset.NewBinaryReaderSized(_, %d) err = nil, want error", maxBytes)
+		}
+	}
+}
+
+func TestBinaryReaderRejectsTruncatedInput(t *testing.T) {
+	data := serializedHMACKeyset(t)
+	// Every cut inside the last key must fail rather than yield a partial key.
+	for _, cut := range []int{1, 2, 10, 20} {
+		truncated := data[:len(data)-cut]
+		if _, err := keyset.NewBinaryReader(bytes.NewReader(truncated)).Read(); err == nil {
+			t.Errorf("Read() of keyset truncated by %d bytes err = nil, want error", cut)
+		}
+	}
+}
+
+// The key ID of the key in the legacy keyset below. Its high bit is set, so it
+// is negative as an int32.
+const legacyKeyID = 0x80000001
+
+// appendInt32Field appends a varint field with value v encoded the way an
+// int32 field is: negative values are sign-extended to 64 bits.
+func appendInt32Field(b []byte, fieldNum int, v int32) []byte {
+	b = append(b, byte(fieldNum<<3))
+	return append(b, proto.EncodeVarint(uint64(int64(v)))...)
+}
+
+func appendBytesField(b []byte, fieldNum int, v []byte) []byte {
+	b = append(b, byte(fieldNum<<3|2))
+	b = append(b, proto.EncodeVarint(uint64(len(v)))...)
+	return append(b, v...)
+}
+
+// serializeWithInt32KeyIDs serializes ks like an implementation that declares
+// the key ID fields as int32, which writes IDs with the high bit set as
+// 10-byte varints instead of the 5-byte varints of uint32.
+func serializeWithInt32KeyIDs(t *testing.T, ks *tinkpb.Keyset) []byte {
+	t.Helper()
+	out := appendInt32Field(nil, 1, int32(ks.PrimaryKeyId))
+	for _, k := range ks.Key {
+		keyData, err := proto.Marshal(k.KeyData)
+		if err != nil {
+			t.Fatalf("proto.Marshal() err = %v, want nil", err)
+		}
+		var key []byte
+		key = appendBytesField(key, 1, keyData)
+		key = appendInt32Field(key, 2, int32(k.Status))
+		key = appendInt32Field(key, 3, int32(k.KeyId))
+		key = appendInt32Field(key, 4, int32(k.OutputPrefixType))
+		out = appendBytesField(out, 2, key)
+	}
+	return out
+}
+
+func TestBinaryReaderWithInt32EncodedKeyIDs(t *testing.T) {
+	ks := testutil.NewTestAESGCMKeyset(tinkpb.OutputPrefixType_TINK)
+	ks.PrimaryKeyId = legacyKeyID
+	ks.Key = ks.Key[:1]
+	ks.Key[0].KeyId = legacyKeyID
+	h, err := testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v, want nil", err)
+	}
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	pt, ad := []byte("plaintext"), []byte("ad")
+	ct, err := a.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v, want nil", err)
+	}
+	if want := []byte{cryptofmt.TinkStartByte, 0x80, 0x00, 0x00, 0x01}; !bytes.HasPrefix(ct, want) {
+		t.Fatalf("ciphertext prefix = %x, want %x", ct[:cryptofmt.NonRawPrefixSize], want)
+	}
+
+	legacy := serializeWithInt32KeyIDs(t, ks)
+	if standard, err := proto.Marshal(ks); err != nil || bytes.Equal(standard, legacy) {
+		t.Fatalf("the int32 encoding of the keyset must differ from its uint32 encoding")
+	}
+	got, err := keyset.NewBinaryReader(bytes.NewReader(legacy)).Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v, want nil", err)
+	}
+	if !proto.Equal(got, ks) {
+		t.Errorf("Read() = %s, want %s", got, ks)
+	}
+
+	// The key read from the legacy encoding decrypts ciphertexts of the key.
+	legacyHandle, err := testkeyset.NewHandle(got)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v, want nil", err)
+	}
+	ps, err := legacyHandle.Primitives()
+	if err != nil {
+		t.Fatalf("legacyHandle.Primitives() err = %v, want nil", err)
+	}
+	entries, err := ps.EntriesForPrefix(string(ct[:cryptofmt.NonRawPrefixSize]))
+	if err != nil || len(entries) != 1 || entries[0].KeyID != legacyKeyID {
+		t.Errorf("ps.EntriesForPrefix() = %v, %v, want the entry of key %d", entries, err, uint32(legacyKeyID))
+	}
+	legacyAEAD, err := aead.New(legacyHandle)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	if decrypted, err := legacyAEAD.Decrypt(ct, ad); err != nil || !bytes.Equal(decrypted, pt) {
+		t.Errorf("legacyAEAD.Decrypt() = %q, %v, want %q, nil", decrypted, err, pt)
+	}
+
+	// Writing the keyset again uses the standard uint32 encoding, and the
+	// key ID round-trips.
+	buf := new(bytes.Buffer)
+	if err := keyset.NewBinaryWriter(buf).Write(got); err != nil {
+		t.Fatalf("Write() err = %v, want nil", err)
+	}
+	again, err := keyset.NewBinaryReader(buf).Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v, want nil", err)
+	}
+	if again.PrimaryKeyId != legacyKeyID || again.Key[0].KeyId != legacyKeyID {
+		t.Errorf("key IDs after round trip = %d, %d, want %d", again.PrimaryKeyId, again.Key[0].KeyId, uint32(legacyKeyID))
+	}
+}
+
+func TestJSONIOWithHighBitKeyIDs(t *testing.T) {
+	ks := testutil.NewTestAESGCMKeyset(tinkpb.OutputPrefixType_TINK)
+	ks.PrimaryKeyId = legacyKeyID
+	ks.Key = ks.Key[:1]
+	ks.Key[0].KeyId = legacyKeyID
+	buf := new(bytes.Buffer)
+	if err := keyset.NewJSONWriter(buf).Write(ks); err != nil {
+		t.Fatalf("Write() err = %v, want nil", err)
+	}
+	if !strings.Contains(buf.String(), `"keyId":2147483649`) {
+		t.Errorf("written keyset %s does not contain keyId 2147483649", buf)
+	}
+	got, err := keyset.NewJSONReader(buf).Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v, want nil", err)
+	}
+	if !proto.Equal(got, ks) {
+		t.Errorf("Read() = %s, want %s", got, ks)
+	}
+}
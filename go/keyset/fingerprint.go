@@ -0,0 +1,101 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// fingerprintContext is hashed first so that fingerprints cannot collide with
+// other SHA-256 digests computed over key material.
+const fingerprintContext = "tink keyset fingerprint v1"
+
+// Fingerprint returns a SHA-256 fingerprint of the public keys in h, e.g. to
+// pin or log which keysets are trusted. Each key contributes its key ID,
+// status, output prefix type, type URL, key material type and serialized
+// public key. The keys are sorted, so the fingerprint does not depend on their
+// order in the keyset, and since it only depends on the serialized keys it is
+// stable across writing and reading the keyset. The primary key ID is not
+// included.
+//
+// If h contains asymmetric private keys, the fingerprint is computed over the
+// corresponding public keys and thus equals the fingerprint of h.Public().
+// Fingerprint fails if h contains any other keys, e.g. symmetric keys, so that
+// the result never depends on secret key material.
+func Fingerprint(h *Handle) ([]byte, error) {
+	if h == nil || h.ks == nil {
+		return nil, fmt.Errorf("keyset.Fingerprint: nil handle")
+	}
+	if err := Validate(h.ks); err != nil {
+		return nil, fmt.Errorf("keyset.Fingerprint: invalid keyset: %s", err)
+	}
+	encoded := make([][]byte, len(h.ks.Key))
+	for i, key := range h.ks.Key {
+		kd := key.KeyData
+		switch kd.KeyMaterialType {
+		case tinkpb.KeyData_ASYMMETRIC_PUBLIC:
+		case tinkpb.KeyData_ASYMMETRIC_PRIVATE:
+			pub, err := publicKeyData(kd)
+			if err != nil {
+				return nil, fmt.Errorf("keyset.Fingerprint: key %d: %s", key.KeyId, err)
+			}
+			kd = pub
+		default:
+			return nil, fmt.Errorf("keyset.Fingerprint: key %d has secret key material of type %s", key.KeyId, kd.KeyMaterialType)
+		}
+		encoded[i] = encodeFingerprintKey(key, kd)
+	}
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+	d := sha256.New()
+	d.Write([]byte(fingerprintContext))
+	writeUint32(d, uint32(len(encoded)))
+	for _, e := range encoded {
+		writeUint32(d, uint32(len(e)))
+		d.Write(e)
+	}
+	return d.Sum(nil), nil
+}
+
+// encodeFingerprintKey returns an unambiguous encoding of key with the given
+// public key data.
+func encodeFingerprintKey(key *tinkpb.Keyset_Key, kd *tinkpb.KeyData) []byte {
+	buf := new(bytes.Buffer)
+	writeUint32(buf, key.KeyId)
+	writeUint32(buf, uint32(key.Status))
+	writeUint32(buf, uint32(key.OutputPrefixType))
+	writeUint32(buf, uint32(kd.KeyMaterialType))
+	writeUint32(buf, uint32(len(kd.TypeUrl)))
+	buf.WriteString(kd.TypeUrl)
+	writeUint32(buf, uint32(len(kd.Value)))
+	buf.Write(kd.Value)
+	return buf.Bytes()
+}
+
+// writeUint32 writes v to w as a big-endian integer.
+func writeUint32(w interface{ Write([]byte) (int, error) }, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	w.Write(b[:])
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/hybrid"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/signature"
+	"github.com/google/tink/go/testkeyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// newPrivateHandle returns a handle with a key for each of the templates.
+func newPrivateHandle(t *testing.T, templates ...*tinkpb.KeyTemplate) *keyset.Handle {
+	t.Helper()
+	ksm := keyset.NewManager()
+	for _, kt := range templates {
+		if err := ksm.Rotate(kt); err != nil {
+			t.Fatalf("ksm.Rotate() err = %v", err)
+		}
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	return h
+}
+
+func fingerprint(t *testing.T, h *keyset.Handle) []byte {
+	t.Helper()
+	fp, err := keyset.Fingerprint(h)
+	if err != nil {
+		t.Fatalf("keyset.Fingerprint() err = %v", err)
+	}
+	return fp
+}
+
+func TestFingerprintIsStableUnderReordering(t *testing.T) {
+	priv := newPrivateHandle(t, signature.ECDSAP256KeyTemplate(), signature.ED25519KeyTemplate(), signature.ECDSAP384KeyTemplate())
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v", err)
+	}
+	want := fingerprint(t, pub)
+	if len(want) != 32 {
+		t.Errorf("len(keyset.Fingerprint()) = %d, want 32", len(want))
+	}
+
+	ks := testkeyset.KeysetMaterial(pub)
+	n := len(ks.Key)
+	for i := 0; i < n; i++ {
+		rotated := &tinkpb.Keyset{PrimaryKeyId: ks.PrimaryKeyId}
+		for j := 0; j < n; j++ {
+			rotated.Key = append(rotated.Key, ks.Key[(i+j)%n])
+		}
+		reversed := &tinkpb.Keyset{PrimaryKeyId: ks.PrimaryKeyId}
+		for j := n - 1; j >= 0; j-- {
+			reversed.Key = append(reversed.Key, rotated.Key[j])
+		}
+		for _, reordered := range []*tinkpb.Keyset{rotated, reversed} {
+			if got := fingerprint(t, testkeyset.KeysetHandle(reordered)); !bytes.Equal(got, want) {
+				t.Errorf("keyset.Fingerprint() of reordered keyset = %x, want %x", got, want)
+			}
+		}
+	}
+}
+
+func TestFingerprintIsStableAcrossSerialization(t *testing.T) {
+	priv := newPrivateHandle(t, hybrid.ECIESHKDFAES128GCMKeyTemplate(), hybrid.ECIESHKDFAES128CTRHMACSHA256KeyTemplate())
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v", err)
+	}
+	want := fingerprint(t, pub)
+
+	buf := new(bytes.Buffer)
+	if err := pub.WriteWithNoSecrets(keyset.NewBinaryWriter(buf)); err != nil {
+		t.Fatalf("pub.WriteWithNoSecrets() err = %v", err)
+	}
+	fromBinary, err := keyset.ReadWithNoSecrets(keyset.NewBinaryReader(buf))
+	if err != nil {
+		t.Fatalf("keyset.ReadWithNoSecrets() err = %v", err)
+	}
+	if got := fingerprint(t, fromBinary); !bytes.Equal(got, want) {
+		t.Errorf("keyset.Fingerprint() after binary round trip = %x, want %x", got, want)
+	}
+
+	buf.Reset()
+	if err := pub.WriteWithNoSecrets(keyset.NewJSONWriter(buf)); err != nil {
+		t.Fatalf("pub.WriteWithNoSecrets() err = %v", err)
+	}
+	fromJSON, err := keyset.ReadWithNoSecrets(keyset.NewJSONReader(buf))
+	if err != nil {
+		t.Fatalf("keyset.ReadWithNoSecrets() err = %v", err)
+	}
+	if got := fingerprint(t, fromJSON); !bytes.Equal(got, want) {
+		t.Errorf("keyset.Fingerprint() after JSON round trip = %x, want %x", got, want)
+	}
+}
+
+func TestFingerprintOfPrivateHandleMatchesPublicHandle(t *testing.T) {
+	priv := newPrivateHandle(t, signature.ECDSAP256KeyTemplate(), signature.ED25519KeyTemplate())
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v", err)
+	}
+	if got, want := fingerprint(t, priv), fingerprint(t, pub); !bytes.Equal(got, want) {
+		t.Errorf("keyset.Fingerprint(priv) = %x, want keyset.Fingerprint(pub) = %x", got, want)
+	}
+}
+
+func TestFingerprintDependsOnKeys(t *testing.T) {
+	pub1, err := newPrivateHandle(t, signature.ECDSAP256KeyTemplate()).Public()
+	if err != nil {
+		t.Fatalf("Public() err = %v", err)
+	}
+	pub2, err := newPrivateHandle(t, signature.ECDSAP256KeyTemplate()).Public()
+	if err != nil {
+		t.Fatalf("Public() err = %v", err)
+	}
+	fp1 := fingerprint(t, pub1)
+	if fp2 := fingerprint(t, pub2); bytes.Equal(fp1, fp2) {
+		t.Errorf("keyset.Fingerprint() of different keysets are both %x", fp1)
+	}
+
+	// Changing the status of a key changes the fingerprint.
+	ks := testkeyset.KeysetMaterial(pub1)
+	ks.Key = append(ks.Key, testkeyset.KeysetMaterial(pub2).Key...)
+	combined := fingerprint(t, testkeyset.KeysetHandle(ks))
+	ks.Key[1].Status = tinkpb.KeyStatusType_DISABLED
+	if disabled := fingerprint(t, testkeyset.KeysetHandle(ks)); bytes.Equal(combined, disabled) {
+		t.Errorf("keyset.Fingerprint() did not change after disabling a key")
+	}
+}
+
+func TestFingerprintFails(t *testing.T) {
+	if _, err := keyset.Fingerprint(nil); err == nil {
+		t.Errorf("keyset.Fingerprint(nil) err = nil, want error")
+	}
+	symmetric := newPrivateHandle(t, aead.AES128GCMKeyTemplate())
+	if _, err := keyset.Fingerprint(symmetric); err == nil {
+		t.Errorf("keyset.Fingerprint() of symmetric keyset err = nil, want error")
+	}
+	mixed := newPrivateHandle(t, signature.ECDSAP256KeyTemplate(), aead.AES128GCMKeyTemplate())
+	if _, err := keyset.Fingerprint(mixed); err == nil {
+		t.Errorf("keyset.Fingerprint() of keyset with a symmetric key err = nil, want error")
+	}
+}
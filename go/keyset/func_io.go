@@ -0,0 +1,115 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// FuncReader implements Reader by calling a function that returns a keyset
+// serialized in binary proto format, e.g. one that fetches it from a secret
+// manager.
+type FuncReader struct {
+	fetch func() ([]byte, error)
+}
+
+// FuncReader implements Reader.
+var _ Reader = &FuncReader{}
+
+// NewReaderFromFunc returns a FuncReader that reads keysets from fetch. fetch
+// is called once per Read or ReadEncrypted.
+func NewReaderFromFunc(fetch func() ([]byte, error)) *FuncReader {
+	return &FuncReader{fetch: fetch}
+}
+
+// Read parses a (cleartext) keyset from the bytes returned by the function.
+func (fr *FuncReader) Read() (*tinkpb.Keyset, error) {
+	keyset := &tinkpb.Keyset{}
+	if err := fr.read(keyset); err != nil {
+		return nil, err
+	}
+	return keyset, nil
+}
+
+// ReadEncrypted parses an EncryptedKeyset from the bytes returned by the
+// function.
+func (fr *FuncReader) ReadEncrypted() (*tinkpb.EncryptedKeyset, error) {
+	keyset := &tinkpb.EncryptedKeyset{}
+	if err := fr.read(keyset); err != nil {
+		return nil, err
+	}
+	return keyset, nil
+}
+
+func (fr *FuncReader) read(msg proto.Message) error {
+	if fr.fetch == nil {
+		return errors.New("func_io: nil function")
+	}
+	data, err := fr.fetch()
+	if err != nil {
+		return fmt.Errorf("func_io: cannot fetch keyset: %s", err)
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("func_io: cannot parse keyset: %s", err)
+	}
+	return nil
+}
+
+// FuncWriter implements Writer by passing keysets serialized in binary proto
+// format to a function, e.g. one that stores them in a secret manager.
+type FuncWriter struct {
+	store func([]byte) error
+}
+
+// FuncWriter implements Writer.
+var _ Writer = &FuncWriter{}
+
+// NewWriterToFunc returns a FuncWriter that writes keysets to store. store is
+// called once per Write or WriteEncrypted.
+func NewWriterToFunc(store func([]byte) error) *FuncWriter {
+	return &FuncWriter{store: store}
+}
+
+// Write serializes the keyset and passes it to the function.
+func (fw *FuncWriter) Write(keyset *tinkpb.Keyset) error {
+	return fw.write(keyset)
+}
+
+// WriteEncrypted serializes the encrypted keyset and passes it to the
+// function.
+func (fw *FuncWriter) WriteEncrypted(keyset *tinkpb.EncryptedKeyset) error {
+	return fw.write(keyset)
+}
+
+func (fw *FuncWriter) write(msg proto.Message) error {
+	if fw.store == nil {
+		return errors.New("func_io: nil function")
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := fw.store(data); err != nil {
+		return fmt.Errorf("func_io: cannot store keyset: %s", err)
+	}
+	return nil
+}
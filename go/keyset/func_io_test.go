@@ -0,0 +1,124 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/testkeyset"
+)
+
+// secretStore simulates a secret manager that stores opaque blobs by name.
+type secretStore map[string][]byte
+
+func (s secretStore) fetcher(name string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		data, ok := s[name]
+		if !ok {
+			return nil, errors.New("secret not found")
+		}
+		return data, nil
+	}
+}
+
+func (s secretStore) storer(name string) func([]byte) error {
+	return func(data []byte) error {
+		s[name] = data
+		return nil
+	}
+}
+
+func TestFuncIOEncryptedRoundTrip(t *testing.T) {
+	kek, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	masterKey, err := aead.New(kek)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	h, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+
+	store := secretStore{}
+	if err := h.Write(keyset.NewWriterToFunc(store.storer("keyset")), masterKey); err != nil {
+		t.Fatalf("h.Write() err = %v, want nil", err)
+	}
+	if len(store["keyset"]) == 0 {
+		t.Fatalf("nothing was stored")
+	}
+	got, err := keyset.Read(keyset.NewReaderFromFunc(store.fetcher("keyset")), masterKey)
+	if err != nil {
+		t.Fatalf("keyset.Read() err = %v, want nil", err)
+	}
+	if !proto.Equal(testkeyset.KeysetMaterial(got), testkeyset.KeysetMaterial(h)) {
+		t.Errorf("read keyset differs from the written keyset")
+	}
+	if _, err := keyset.Read(keyset.NewReaderFromFunc(store.fetcher("other")), masterKey); err == nil {
+		t.Errorf("keyset.Read() of a missing secret err = nil, want error")
+	}
+}
+
+func TestFuncIOUnencryptedRoundTrip(t *testing.T) {
+	h, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+	store := secretStore{}
+	if err := keyset.NewWriterToFunc(store.storer("keyset")).Write(ks); err != nil {
+		t.Fatalf("Write() err = %v, want nil", err)
+	}
+	got, err := keyset.NewReaderFromFunc(store.fetcher("keyset")).Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v, want nil", err)
+	}
+	if !proto.Equal(got, ks) {
+		t.Errorf("Read() = %v, want %v", got, ks)
+	}
+}
+
+func TestFuncIOErrors(t *testing.T) {
+	h, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+	failingStore := func([]byte) error { return errors.New("unavailable") }
+	if err := keyset.NewWriterToFunc(failingStore).Write(ks); err == nil {
+		t.Errorf("Write() with failing function err = nil, want error")
+	}
+	if err := keyset.NewWriterToFunc(nil).Write(ks); err == nil {
+		t.Errorf("Write() with nil function err = nil, want error")
+	}
+	if _, err := keyset.NewReaderFromFunc(nil).Read(); err == nil {
+		t.Errorf("Read() with nil function err = nil, want error")
+	}
+	garbage := func() ([]byte, error) { return []byte{0xff, 0xff, 0xff}, nil }
+	if _, err := keyset.NewReaderFromFunc(garbage).Read(); err == nil {
+		t.Errorf("Read() of invalid data err = nil, want error")
+	}
+	if _, err := keyset.NewReaderFromFunc(garbage).ReadEncrypted(); err == nil {
+		t.Errorf("ReadEncrypted() of invalid data err = nil, want error")
+	}
+}
@@ -17,6 +17,7 @@
 package keyset
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 
@@ -32,8 +33,21 @@ var errInvalidKeyset = fmt.Errorf("keyset.Handle: invalid keyset")
 
 // Handle provides access to a Keyset protobuf, to limit the exposure of actual protocol
 // buffers that hold sensitive key material.
+//
+// The primitives created by Primitives are cached on the handle and reused by
+// later calls, e.g. of aead.New, as long as its keyset is unchanged. If the
+// keyset is modified, by a Manager or e.g. through
+// insecurecleartextkeyset.KeysetMaterial, the primitives are built again.
 type Handle struct {
 	ks *tinkpb.Keyset
+
+	// policy is consulted by primitives before using a key, see
+	// WithUsagePolicy.
+	policy UsagePolicy
+
+	// cache holds the primitive set of ks. It is nil for handles that are not
+	// created by this package, which then do not cache.
+	cache *primitiveCache
 }
 
 // NewHandle creates a keyset handle that contains a single fresh key generated according
@@ -51,13 +65,25 @@ func NewHandle(kt *tinkpb.KeyTemplate) (*Handle, error) {
 	return handle, nil
 }
 
+// NewHandleByName creates a keyset handle that contains a single fresh key
+// generated from the key template with the given canonical name, such as
+// "AES128_GCM". The templates of a primitive package are only known if the
+// package is linked into the binary; see registry.KeyTemplate.
+func NewHandleByName(name string) (*Handle, error) {
+	kt, err := registry.KeyTemplate(name)
+	if err != nil {
+		return nil, fmt.Errorf("keyset.Handle: %s", err)
+	}
+	return NewHandle(kt)
+}
+
 // NewHandleWithNoSecrets creates a new instance of KeysetHandle using the given keyset which does
 // not contain any secret key material.
 func NewHandleWithNoSecrets(ks *tinkpb.Keyset) (*Handle, error) {
 	if ks == nil {
 		return nil, errors.New("keyset.Handle: nil keyset")
 	}
-	h := &Handle{ks}
+	h := newHandle(ks)
 	if h.hasSecrets() {
 		// If you need to do this, you have to use func insecurecleartextkeyset.Read() instead.
 		return nil, errors.New("importing unencrypted secret key material is forbidden")
@@ -71,11 +97,11 @@ func Read(reader Reader, masterKey tink.AEAD) (*Handle, error) {
 	if err != nil {
 		return nil, err
 	}
-	ks, err := decrypt(encryptedKeyset, masterKey)
+	ks, err := decrypt(encryptedKeyset, masterKey, []byte{})
 	if err != nil {
 		return nil, err
 	}
-	return &Handle{ks}, nil
+	return newHandle(ks), nil
 }
 
 // ReadWithNoSecrets tries to create a keyset.Handle from a keyset obtained via reader.
@@ -87,7 +113,26 @@ func ReadWithNoSecrets(reader Reader) (*Handle, error) {
 	return NewHandleWithNoSecrets(ks)
 }
 
+// NewHandleFromSerializedKeyset creates a Handle from b, an EncryptedKeyset in
+// binary proto format as written by Handle.Write with a BinaryWriter. This is
+// meant for keysets embedded in a bytes field, or the value of an Any field,
+// of a larger message. The keyset is decrypted with masterKey and must pass
+// Validate.
+func NewHandleFromSerializedKeyset(b []byte, masterKey tink.AEAD) (*Handle, error) {
+	h, err := Read(NewBinaryReader(bytes.NewReader(b)), masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(h.ks); err != nil {
+		return nil, fmt.Errorf("keyset.Handle: invalid keyset: %s", err)
+	}
+	return h, nil
+}
+
 // Public returns a Handle of the public keys if the managed keyset contains private keys.
+// The public keyset has the same key IDs, statuses, output prefix types and
+// primary key as the managed keyset. It returns an error if the managed keyset
+// contains any key that is not an asymmetric private key, e.g. a symmetric key.
 func (h *Handle) Public() (*Handle, error) {
 	privKeys := h.ks.Key
 	pubKeys := make([]*tinkpb.Keyset_Key, len(privKeys))
@@ -99,7 +144,7 @@ func (h *Handle) Public() (*Handle, error) {
 		privKeyData := privKeys[i].KeyData
 		pubKeyData, err := publicKeyData(privKeyData)
 		if err != nil {
-			return nil, fmt.Errorf("keyset.Handle: %s", err)
+			return nil, fmt.Errorf("keyset.Handle: key %d: %s", privKeys[i].KeyId, err)
 		}
 		pubKeys[i] = &tinkpb.Keyset_Key{
 			KeyData:          pubKeyData,
@@ -112,7 +157,7 @@ func (h *Handle) Public() (*Handle, error) {
 		PrimaryKeyId: h.ks.PrimaryKeyId,
 		Key:          pubKeys,
 	}
-	return &Handle{ks}, nil
+	return newHandle(ks), nil
 }
 
 // String returns a string representation of the managed keyset.
@@ -129,7 +174,7 @@ func (h *Handle) KeysetInfo() *tinkpb.KeysetInfo {
 
 // Write encrypts and writes the enclosing keyset.
 func (h *Handle) Write(writer Writer, masterKey tink.AEAD) error {
-	encrypted, err := encrypt(h.ks, masterKey)
+	encrypted, err := encrypt(h.ks, masterKey, []byte{})
 	if err != nil {
 		return err
 	}
@@ -166,9 +211,20 @@ func (h *Handle) Primitives() (*primitiveset.PrimitiveSet, error) {
 // (e.g. credentials for accessing keys managed by a KMS), or gathering custom
 // monitoring/profiling information.
 //
+// If km is nil, the primitives are built only once per handle and then reused,
+// see Handle.
+//
 // The returned set is usually later "wrapped" into a class that implements
 // the corresponding Primitive-interface.
 func (h *Handle) PrimitivesWithKeyManager(km registry.KeyManager) (*primitiveset.PrimitiveSet, error) {
+	if km == nil && h.cache != nil {
+		return h.cache.primitives(h)
+	}
+	return h.newPrimitiveSet(km)
+}
+
+// newPrimitiveSet builds the primitive set of h, see PrimitivesWithKeyManager.
+func (h *Handle) newPrimitiveSet(km registry.KeyManager) (*primitiveset.PrimitiveSet, error) {
 	if err := Validate(h.ks); err != nil {
 		return nil, fmt.Errorf("registry.PrimitivesWithKeyManager: invalid keyset: %s", err)
 	}
@@ -198,6 +254,42 @@ func (h *Handle) PrimitivesWithKeyManager(km registry.KeyManager) (*primitiveset
 	return primitiveSet, nil
 }
 
+// ValidatePrimitives creates the primitive of every key in h that is not
+// DESTROYED, including DISABLED keys, and returns an error for the first key
+// whose primitive cannot be created, e.g. because its key material is
+// corrupted or its key manager is not registered. Primitives skips keys that
+// are not ENABLED, so calling this when a keyset is loaded surfaces such keys
+// before they are enabled rather than when they are needed. The primitives are
+// discarded and h is not modified.
+func (h *Handle) ValidatePrimitives() error {
+	if err := Validate(h.ks); err != nil {
+		return fmt.Errorf("keyset.Handle: invalid keyset: %s", err)
+	}
+	for _, key := range h.ks.Key {
+		if key.Status == tinkpb.KeyStatusType_DESTROYED {
+			continue
+		}
+		if _, err := registry.PrimitiveFromKeyData(key.KeyData); err != nil {
+			return fmt.Errorf("keyset.Handle: cannot get primitive from key %d: %s", key.KeyId, err)
+		}
+	}
+	return nil
+}
+
+// HasSecrets returns true if the keyset in h contains secret key material,
+// i.e. a symmetric key, an asymmetric private key or a key of unknown key
+// material type. A keyset without secrets, e.g. the result of Public, can be
+// exported with WriteWithNoSecrets. It returns an error if the keyset contains
+// a key without key data.
+func (h *Handle) HasSecrets() (bool, error) {
+	for _, k := range h.ks.Key {
+		if k == nil || k.KeyData == nil {
+			return false, errInvalidKeyset
+		}
+	}
+	return h.hasSecrets(), nil
+}
+
 // hasSecrets checks if the keyset handle contains any key material considered secret.
 // Both symmetric keys and the private key of an assymmetric crypto system are considered secret keys.
 // Also returns true when encountering any errors.
@@ -219,9 +311,12 @@ func (h *Handle) hasSecrets() bool {
 	return false
 }
 
+// publicKeyData returns the public key data of an asymmetric private key. It
+// fails for any other key, so that no secret key material ends up in the
+// result.
 func publicKeyData(privKeyData *tinkpb.KeyData) (*tinkpb.KeyData, error) {
 	if privKeyData.KeyMaterialType != tinkpb.KeyData_ASYMMETRIC_PRIVATE {
-		return nil, fmt.Errorf("keyset.Handle: keyset contains a non-private key")
+		return nil, fmt.Errorf("%s is not an asymmetric private key (key material type %s)", privKeyData.TypeUrl, privKeyData.KeyMaterialType)
 	}
 	km, err := registry.GetKeyManager(privKeyData.TypeUrl)
 	if err != nil {
@@ -229,16 +324,23 @@ func publicKeyData(privKeyData *tinkpb.KeyData) (*tinkpb.KeyData, error) {
 	}
 	pkm, ok := km.(registry.PrivateKeyManager)
 	if !ok {
-		return nil, fmt.Errorf("keyset.Handle: %s does not belong to a PrivateKeyManager", privKeyData.TypeUrl)
+		return nil, fmt.Errorf("%s does not belong to a PrivateKeyManager", privKeyData.TypeUrl)
+	}
+	pubKeyData, err := pkm.PublicKeyData(privKeyData.Value)
+	if err != nil {
+		return nil, err
+	}
+	if pubKeyData.KeyMaterialType != tinkpb.KeyData_ASYMMETRIC_PUBLIC {
+		return nil, fmt.Errorf("the key manager of %s returned key material of type %s, want %s", privKeyData.TypeUrl, pubKeyData.KeyMaterialType, tinkpb.KeyData_ASYMMETRIC_PUBLIC)
 	}
-	return pkm.PublicKeyData(privKeyData.Value)
+	return pubKeyData, nil
 }
 
-func decrypt(encryptedKeyset *tinkpb.EncryptedKeyset, masterKey tink.AEAD) (*tinkpb.Keyset, error) {
+func decrypt(encryptedKeyset *tinkpb.EncryptedKeyset, masterKey tink.AEAD, associatedData []byte) (*tinkpb.Keyset, error) {
 	if encryptedKeyset == nil || masterKey == nil {
 		return nil, fmt.Errorf("keyset.Handle: invalid encrypted keyset")
 	}
-	decrypted, err := masterKey.Decrypt(encryptedKeyset.EncryptedKeyset, []byte{})
+	decrypted, err := masterKey.Decrypt(encryptedKeyset.EncryptedKeyset, associatedData)
 	if err != nil {
 		return nil, fmt.Errorf("keyset.Handle: decryption failed: %s", err)
 	}
@@ -249,12 +351,12 @@ func decrypt(encryptedKeyset *tinkpb.EncryptedKeyset, masterKey tink.AEAD) (*tin
 	return keyset, nil
 }
 
-func encrypt(keyset *tinkpb.Keyset, masterKey tink.AEAD) (*tinkpb.EncryptedKeyset, error) {
+func encrypt(keyset *tinkpb.Keyset, masterKey tink.AEAD, associatedData []byte) (*tinkpb.EncryptedKeyset, error) {
 	serializedKeyset, err := proto.Marshal(keyset)
 	if err != nil {
 		return nil, errInvalidKeyset
 	}
-	encrypted, err := masterKey.Encrypt(serializedKeyset, []byte{})
+	encrypted, err := masterKey.Encrypt(serializedKeyset, associatedData)
 	if err != nil {
 		return nil, fmt.Errorf("keyset.Handle: encrypted failed: %s", err)
 	}
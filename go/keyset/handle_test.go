@@ -17,16 +17,21 @@
 package keyset_test
 
 import (
+	"bytes"
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/core/registry"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/signature"
 	"github.com/google/tink/go/testkeyset"
 	"github.com/google/tink/go/testutil"
 
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
@@ -52,6 +57,41 @@ func TestNewHandle(t *testing.T) {
 	}
 }
 
+func TestNewHandleByName(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		template *tinkpb.KeyTemplate
+	}{
+		{"HMAC_SHA256_128BITTAG", mac.HMACSHA256Tag128KeyTemplate()},
+		{"ECDSA_P256", signature.ECDSAP256KeyTemplate()},
+		{"ED25519_RAW", signature.ED25519KeyWithoutPrefixTemplate()},
+	} {
+		h, err := keyset.NewHandleByName(tc.name)
+		if err != nil {
+			t.Errorf("keyset.NewHandleByName(%q) err = %v", tc.name, err)
+			continue
+		}
+		info := h.KeysetInfo()
+		if len(info.KeyInfo) != 1 {
+			t.Errorf("keyset.NewHandleByName(%q) has %d keys, want 1", tc.name, len(info.KeyInfo))
+			continue
+		}
+		if got := info.KeyInfo[0]; got.TypeUrl != tc.template.TypeUrl || got.OutputPrefixType != tc.template.OutputPrefixType {
+			t.Errorf("keyset.NewHandleByName(%q) key = %v, want type %s and prefix %s", tc.name, got, tc.template.TypeUrl, tc.template.OutputPrefixType)
+		}
+	}
+}
+
+func TestNewHandleByNameWithUnknownName(t *testing.T) {
+	_, err := keyset.NewHandleByName("HMAC_SHA256")
+	if err == nil {
+		t.Fatal("keyset.NewHandleByName(\"HMAC_SHA256\") err = nil, want error")
+	}
+	if !strings.Contains(err.Error(), "HMAC_SHA256_128BITTAG") {
+		t.Errorf("keyset.NewHandleByName(\"HMAC_SHA256\") err = %q, want it to list the known names", err)
+	}
+}
+
 func TestNewHandleWithInvalidInput(t *testing.T) {
 	// template unregistered TypeUrl
 	template := mac.HMACSHA256Tag128KeyTemplate()
@@ -90,6 +130,49 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestNewHandleFromSerializedKeyset(t *testing.T) {
+	masterKey, err := subtle.NewAESGCM([]byte(strings.Repeat("A", 32)))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM(): %v", err)
+	}
+	keyData := testutil.NewKeyData("some type url", []byte{0}, tinkpb.KeyData_SYMMETRIC)
+	key := testutil.NewKey(keyData, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK)
+	h := testkeyset.KeysetHandle(testutil.NewKeyset(1, []*tinkpb.Keyset_Key{key}))
+	buf := new(bytes.Buffer)
+	if err := h.Write(keyset.NewBinaryWriter(buf), masterKey); err != nil {
+		t.Fatalf("handle.Write(): %v", err)
+	}
+
+	h2, err := keyset.NewHandleFromSerializedKeyset(buf.Bytes(), masterKey)
+	if err != nil {
+		t.Fatalf("keyset.NewHandleFromSerializedKeyset(): %v", err)
+	}
+	if !proto.Equal(testkeyset.KeysetMaterial(h), testkeyset.KeysetMaterial(h2)) {
+		t.Errorf("got %v, want %v", h2, h)
+	}
+
+	otherKey, err := subtle.NewAESGCM([]byte(strings.Repeat("B", 32)))
+	if err != nil {
+		t.Fatalf("subtle.NewAESGCM(): %v", err)
+	}
+	if _, err := keyset.NewHandleFromSerializedKeyset(buf.Bytes(), otherKey); err == nil {
+		t.Errorf("keyset.NewHandleFromSerializedKeyset() with wrong master key err = nil, want error")
+	}
+	if _, err := keyset.NewHandleFromSerializedKeyset([]byte{0xff, 0xff}, masterKey); err == nil {
+		t.Errorf("keyset.NewHandleFromSerializedKeyset() of invalid data err = nil, want error")
+	}
+
+	// A keyset whose primary key does not exist is rejected.
+	invalid := testkeyset.KeysetHandle(testutil.NewKeyset(2, []*tinkpb.Keyset_Key{key}))
+	buf.Reset()
+	if err := invalid.Write(keyset.NewBinaryWriter(buf), masterKey); err != nil {
+		t.Fatalf("handle.Write(): %v", err)
+	}
+	if _, err := keyset.NewHandleFromSerializedKeyset(buf.Bytes(), masterKey); err == nil {
+		t.Errorf("keyset.NewHandleFromSerializedKeyset() of keyset without primary key err = nil, want error")
+	}
+}
+
 func TestReadWithNoSecrets(t *testing.T) {
 	// Create a keyset containing public key material
 	keyData := testutil.NewKeyData("some type url", []byte{0}, tinkpb.KeyData_ASYMMETRIC_PUBLIC)
@@ -158,6 +241,125 @@ func TestWithNoSecretsFunctionsFailWithAsymmetricPrivateKeyMaterial(t *testing.T
 	}
 }
 
+func TestHasSecrets(t *testing.T) {
+	priv, err := keyset.NewHandle(signature.ECDSAP256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v, want nil", err)
+	}
+	symmetric, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	unknownKeyData := testutil.NewKeyData("some type url", []byte{0}, tinkpb.KeyData_UNKNOWN_KEYMATERIAL)
+	unknown, err := testkeyset.NewHandle(testutil.NewKeyset(1, []*tinkpb.Keyset_Key{
+		testutil.NewKey(unknownKeyData, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK),
+	}))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v, want nil", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		h    *keyset.Handle
+		want bool
+	}{
+		{"asymmetric private", priv, true},
+		{"asymmetric public", pub, false},
+		{"symmetric", symmetric, true},
+		{"unknown key material", unknown, true},
+	} {
+		got, err := tc.h.HasSecrets()
+		if err != nil {
+			t.Errorf("%s: HasSecrets() err = %v, want nil", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: HasSecrets() = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+
+	// A keyset without secrets can be exported without encryption.
+	if err := pub.WriteWithNoSecrets(&keyset.MemReaderWriter{}); err != nil {
+		t.Errorf("pub.WriteWithNoSecrets() err = %v, want nil", err)
+	}
+}
+
+func TestHasSecretsWithInvalidKeyset(t *testing.T) {
+	ks := testutil.NewKeyset(1, []*tinkpb.Keyset_Key{
+		testutil.NewKey(nil, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK),
+	})
+	h, err := testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v, want nil", err)
+	}
+	if _, err := h.HasSecrets(); err == nil {
+		t.Error("HasSecrets() err = nil, want error")
+	}
+}
+
+func TestValidatePrimitives(t *testing.T) {
+	good := testutil.NewHMACKeyData(commonpb.HashType_SHA256, 32)
+	corrupt := testutil.NewKeyData(testutil.HMACTypeURL, []byte("corrupt"), tinkpb.KeyData_SYMMETRIC)
+	ks := testutil.NewKeyset(1, []*tinkpb.Keyset_Key{
+		testutil.NewKey(good, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK),
+		testutil.NewKey(corrupt, tinkpb.KeyStatusType_DISABLED, 2, tinkpb.OutputPrefixType_TINK),
+	})
+	h, err := testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v, want nil", err)
+	}
+
+	// Creating the primitive skips the DISABLED key, so the corrupt key goes
+	// unnoticed until it is enabled.
+	if _, err := mac.New(h); err != nil {
+		t.Fatalf("mac.New() err = %v, want nil", err)
+	}
+	if err := h.ValidatePrimitives(); err == nil || !strings.Contains(err.Error(), "key 2") {
+		t.Errorf("ValidatePrimitives() err = %v, want error for key 2", err)
+	}
+	ks.Key[1].Status = tinkpb.KeyStatusType_ENABLED
+	if _, err := mac.New(h); err == nil {
+		t.Errorf("mac.New() with corrupt ENABLED key err = nil, want error")
+	}
+
+	// DESTROYED keys have no key material and are skipped.
+	ks.Key[1].Status = tinkpb.KeyStatusType_DESTROYED
+	if err := h.ValidatePrimitives(); err != nil {
+		t.Errorf("ValidatePrimitives() with corrupt DESTROYED key err = %v, want nil", err)
+	}
+	if !proto.Equal(ks.Key[0].KeyData, good) {
+		t.Errorf("ValidatePrimitives() modified the keyset")
+	}
+}
+
+func TestValidatePrimitivesWithValidKeysets(t *testing.T) {
+	priv, err := keyset.NewHandle(signature.ECDSAP256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v, want nil", err)
+	}
+	ksm := keyset.NewManagerFromHandle(priv)
+	if err := ksm.Rotate(signature.ED25519KeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v, want nil", err)
+	}
+	for _, h := range []*keyset.Handle{priv, pub} {
+		if err := h.ValidatePrimitives(); err != nil {
+			t.Errorf("ValidatePrimitives() err = %v, want nil", err)
+		}
+	}
+	invalid := testkeyset.KeysetHandle(testutil.NewKeyset(1, nil))
+	if err := invalid.ValidatePrimitives(); err == nil {
+		t.Errorf("ValidatePrimitives() of empty keyset err = nil, want error")
+	}
+}
+
 func TestKeysetInfo(t *testing.T) {
 	kt := mac.HMACSHA256Tag128KeyTemplate()
 	kh, err := keyset.NewHandle(kt)
@@ -169,3 +371,115 @@ func TestKeysetInfo(t *testing.T) {
 		t.Errorf("Expected primary key id: %d, but got: %d", info.KeyInfo[0].KeyId, info.PrimaryKeyId)
 	}
 }
+
+func TestPrimitivesAreCached(t *testing.T) {
+	kh, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	ps1, err := kh.Primitives()
+	if err != nil {
+		t.Fatalf("kh.Primitives() err = %v, want nil", err)
+	}
+	ps2, err := kh.Primitives()
+	if err != nil {
+		t.Fatalf("kh.Primitives() err = %v, want nil", err)
+	}
+	if ps1 == ps2 {
+		t.Errorf("kh.Primitives() returned the same set twice, want copies")
+	}
+	if ps1.Primary != ps2.Primary {
+		t.Errorf("kh.Primitives() built the primary again, want cached entry")
+	}
+
+	// Modifying a returned set does not affect the cache.
+	for prefix := range ps1.Entries {
+		delete(ps1.Entries, prefix)
+	}
+	ps3, err := kh.Primitives()
+	if err != nil {
+		t.Fatalf("kh.Primitives() err = %v, want nil", err)
+	}
+	if len(ps3.Entries) != 1 {
+		t.Errorf("len(ps3.Entries) = %d, want 1", len(ps3.Entries))
+	}
+}
+
+func TestManagerInvalidatesPrimitiveCache(t *testing.T) {
+	kh, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	old, err := kh.Primitives()
+	if err != nil {
+		t.Fatalf("kh.Primitives() err = %v, want nil", err)
+	}
+	manager := keyset.NewManagerFromHandle(kh)
+	if err := manager.Rotate(mac.HMACSHA256Tag256KeyTemplate()); err != nil {
+		t.Fatalf("manager.Rotate() err = %v, want nil", err)
+	}
+	for _, h := range []*keyset.Handle{kh, mustHandle(t, manager)} {
+		ps, err := h.Primitives()
+		if err != nil {
+			t.Fatalf("h.Primitives() err = %v, want nil", err)
+		}
+		if ps.Primary == old.Primary {
+			t.Errorf("h.Primitives() returned the stale primary after Rotate()")
+		}
+		if got, want := ps.Primary.KeyID, h.KeysetInfo().PrimaryKeyId; got != want {
+			t.Errorf("ps.Primary.KeyID = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestPrimitiveCacheDetectsModifiedKeyset(t *testing.T) {
+	kh, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	old, err := kh.Primitives()
+	if err != nil {
+		t.Fatalf("kh.Primitives() err = %v, want nil", err)
+	}
+	ks := testkeyset.KeysetMaterial(kh)
+	key := proto.Clone(ks.Key[0]).(*tinkpb.Keyset_Key)
+	key.KeyId++
+	key.KeyData = testutil.NewHMACKeyData(commonpb.HashType_SHA256, 32)
+	ks.Key = append(ks.Key, key)
+	ks.PrimaryKeyId = key.KeyId
+
+	ps, err := kh.Primitives()
+	if err != nil {
+		t.Fatalf("kh.Primitives() err = %v, want nil", err)
+	}
+	if ps.Primary == old.Primary || ps.Primary.KeyID != key.KeyId {
+		t.Errorf("kh.Primitives() returned the stale primary after modifying the keyset")
+	}
+	if got := len(ps.AllEntries()); got != 2 {
+		t.Errorf("len(ps.AllEntries()) = %d, want 2", got)
+	}
+}
+
+func TestCachedPrimitivesRespectPrimitiveFilter(t *testing.T) {
+	kh, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	if _, err := mac.New(kh); err != nil {
+		t.Fatalf("mac.New() err = %v, want nil", err)
+	}
+	registry.SetPrimitiveFilter(func(string) error { return errors.New("rejected") })
+	defer registry.SetPrimitiveFilter(nil)
+	if _, err := mac.New(kh); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("mac.New() err = %v, want rejected by filter", err)
+	}
+}
+
+func mustHandle(t *testing.T, m *keyset.Manager) *keyset.Handle {
+	t.Helper()
+	h, err := m.Handle()
+	if err != nil {
+		t.Fatalf("m.Handle() err = %v, want nil", err)
+	}
+	return h
+}
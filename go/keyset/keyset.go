@@ -27,7 +27,7 @@ import (
 // testkeyset (via package internal) to create a keyset.Handle from cleartext
 // key material.
 func keysetHandle(ks *tinkpb.Keyset) *Handle {
-	return &Handle{ks}
+	return newHandle(ks)
 }
 
 // keysetMaterial is used by package insecurecleartextkeyset and package
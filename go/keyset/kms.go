@@ -0,0 +1,75 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/tink/go/core/registry"
+)
+
+// WriteEncryptedToKMS encrypts the keyset in h with the KMS key identified by
+// keyURI and writes it to writer. The key encryption AEAD is obtained from
+// kmsClient, and associatedData is bound to the encrypted keyset: it must be
+// passed unchanged to ReadEncryptedFromKMS.
+func WriteEncryptedToKMS(h *Handle, kmsClient registry.KMSClient, keyURI string, associatedData []byte, writer Writer) error {
+	if h == nil {
+		return errors.New("keyset.WriteEncryptedToKMS: invalid handle")
+	}
+	if kmsClient == nil {
+		return errors.New("keyset.WriteEncryptedToKMS: invalid KMS client")
+	}
+	if writer == nil {
+		return errors.New("keyset.WriteEncryptedToKMS: invalid writer")
+	}
+	masterKey, err := kmsClient.GetAEAD(keyURI)
+	if err != nil {
+		return fmt.Errorf("keyset.WriteEncryptedToKMS: cannot obtain AEAD for %s: %s", keyURI, err)
+	}
+	encrypted, err := encrypt(h.ks, masterKey, associatedData)
+	if err != nil {
+		return fmt.Errorf("keyset.WriteEncryptedToKMS: %s", err)
+	}
+	return writer.WriteEncrypted(encrypted)
+}
+
+// ReadEncryptedFromKMS reads a keyset written by WriteEncryptedToKMS from
+// reader and decrypts it with the KMS key identified by keyURI, using the KMS
+// client registered for keyURI in the registry.
+func ReadEncryptedFromKMS(reader Reader, keyURI string, associatedData []byte) (*Handle, error) {
+	if reader == nil {
+		return nil, errors.New("keyset.ReadEncryptedFromKMS: invalid reader")
+	}
+	kmsClient, err := registry.GetKMSClient(keyURI)
+	if err != nil {
+		return nil, fmt.Errorf("keyset.ReadEncryptedFromKMS: %s", err)
+	}
+	masterKey, err := kmsClient.GetAEAD(keyURI)
+	if err != nil {
+		return nil, fmt.Errorf("keyset.ReadEncryptedFromKMS: cannot obtain AEAD for %s: %s", keyURI, err)
+	}
+	encryptedKeyset, err := reader.ReadEncrypted()
+	if err != nil {
+		return nil, fmt.Errorf("keyset.ReadEncryptedFromKMS: %s", err)
+	}
+	ks, err := decrypt(encryptedKeyset, masterKey, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("keyset.ReadEncryptedFromKMS: %s", err)
+	}
+	return newHandle(ks), nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/testing/fakekms"
+	"github.com/google/tink/go/testkeyset"
+	"github.com/google/tink/go/tink"
+)
+
+var errUnavailableKMS = errors.New("KMS unavailable")
+
+// unavailableKMSClient is a KMS client whose keys can never be used.
+type unavailableKMSClient struct{}
+
+func (c *unavailableKMSClient) Supported(keyURI string) bool {
+	return strings.HasPrefix(keyURI, "unavailable-kms://")
+}
+
+func (c *unavailableKMSClient) GetAEAD(keyURI string) (tink.AEAD, error) {
+	return nil, errUnavailableKMS
+}
+
+func TestKMSEncryptedKeysetRoundTrip(t *testing.T) {
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() err = %v, want nil", err)
+	}
+	client, err := fakekms.NewClient(keyURI)
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() err = %v, want nil", err)
+	}
+	registry.RegisterKMSClient(client)
+	defer registry.RemoveKMSClient(keyURI)
+
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	ad := []byte("tenant-a")
+	buf := new(bytes.Buffer)
+	if err := keyset.WriteEncryptedToKMS(h, client, keyURI, ad, keyset.NewBinaryWriter(buf)); err != nil {
+		t.Fatalf("keyset.WriteEncryptedToKMS() err = %v, want nil", err)
+	}
+	serialized := buf.Bytes()
+
+	got, err := keyset.ReadEncryptedFromKMS(keyset.NewBinaryReader(bytes.NewReader(serialized)), keyURI, ad)
+	if err != nil {
+		t.Fatalf("keyset.ReadEncryptedFromKMS() err = %v, want nil", err)
+	}
+	if !proto.Equal(testkeyset.KeysetMaterial(got), testkeyset.KeysetMaterial(h)) {
+		t.Errorf("keyset.ReadEncryptedFromKMS() = %s, want %s", got, h)
+	}
+
+	if _, err := keyset.ReadEncryptedFromKMS(keyset.NewBinaryReader(bytes.NewReader(serialized)), keyURI, []byte("tenant-b")); err == nil {
+		t.Errorf("keyset.ReadEncryptedFromKMS() with wrong associated data err = nil, want error")
+	}
+	// The keyset is not encrypted with an empty associated data.
+	masterKey, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	if _, err := keyset.Read(keyset.NewBinaryReader(bytes.NewReader(serialized)), masterKey); err == nil {
+		t.Errorf("keyset.Read() without associated data err = nil, want error")
+	}
+}
+
+func TestReadEncryptedFromKMSWithUnregisteredKeyURI(t *testing.T) {
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() err = %v, want nil", err)
+	}
+	client, err := fakekms.NewClient(keyURI)
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() err = %v, want nil", err)
+	}
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := keyset.WriteEncryptedToKMS(h, client, keyURI, nil, keyset.NewBinaryWriter(buf)); err != nil {
+		t.Fatalf("keyset.WriteEncryptedToKMS() err = %v, want nil", err)
+	}
+	if _, err := keyset.ReadEncryptedFromKMS(keyset.NewBinaryReader(buf), keyURI, nil); err == nil {
+		t.Errorf("keyset.ReadEncryptedFromKMS() with unregistered client err = nil, want error")
+	}
+}
+
+func TestKMSEncryptedKeysetPropagatesKMSErrors(t *testing.T) {
+	keyURI := "unavailable-kms://key"
+	client := &unavailableKMSClient{}
+	registry.RegisterKMSClient(client)
+	defer registry.RemoveKMSClient(keyURI)
+
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	buf := new(bytes.Buffer)
+	err = keyset.WriteEncryptedToKMS(h, client, keyURI, nil, keyset.NewBinaryWriter(buf))
+	if err == nil || !strings.Contains(err.Error(), errUnavailableKMS.Error()) {
+		t.Errorf("keyset.WriteEncryptedToKMS() err = %v, want error containing %q", err, errUnavailableKMS)
+	}
+	_, err = keyset.ReadEncryptedFromKMS(keyset.NewBinaryReader(buf), keyURI, nil)
+	if err == nil || !strings.Contains(err.Error(), errUnavailableKMS.Error()) {
+		t.Errorf("keyset.ReadEncryptedFromKMS() err = %v, want error containing %q", err, errUnavailableKMS)
+	}
+}
+
+func TestWriteEncryptedToKMSWithInvalidArguments(t *testing.T) {
+	keyURI, err := fakekms.NewKeyURI()
+	if err != nil {
+		t.Fatalf("fakekms.NewKeyURI() err = %v, want nil", err)
+	}
+	client, err := fakekms.NewClient(keyURI)
+	if err != nil {
+		t.Fatalf("fakekms.NewClient() err = %v, want nil", err)
+	}
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	w := keyset.NewBinaryWriter(new(bytes.Buffer))
+	if err := keyset.WriteEncryptedToKMS(nil, client, keyURI, nil, w); err == nil {
+		t.Errorf("keyset.WriteEncryptedToKMS() with nil handle err = nil, want error")
+	}
+	if err := keyset.WriteEncryptedToKMS(h, nil, keyURI, nil, w); err == nil {
+		t.Errorf("keyset.WriteEncryptedToKMS() with nil client err = nil, want error")
+	}
+	if err := keyset.WriteEncryptedToKMS(h, client, keyURI, nil, nil); err == nil {
+		t.Errorf("keyset.WriteEncryptedToKMS() with nil writer err = nil, want error")
+	}
+	if _, err := keyset.ReadEncryptedFromKMS(nil, keyURI, nil); err == nil {
+		t.Errorf("keyset.ReadEncryptedFromKMS() with nil reader err = nil, want error")
+	}
+}
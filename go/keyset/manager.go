@@ -17,6 +17,8 @@
 package keyset
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/google/tink/go/core/registry"
@@ -28,12 +30,38 @@ import (
 // Note: It is not thread-safe.
 type Manager struct {
 	ks *tinkpb.Keyset
+
+	// cache is the primitive cache of the handles of ks, which is invalidated
+	// whenever ks is modified.
+	cache *primitiveCache
+
+	// derivedKeyIDs makes new keys get IDs derived from their key material.
+	derivedKeyIDs bool
+}
+
+// ManagerOption configures a Manager.
+type ManagerOption func(*Manager)
+
+// WithKeyIDsFromKeyMaterial makes the Manager derive the IDs of new keys from
+// their key material instead of choosing them at random, see DerivedKeyID.
+// Identical keys thus get identical IDs, which allows reconstructing the same
+// keyset independently. Since the key ID is part of TINK and LEGACY
+// ciphertexts, it reveals a hash of the key material; only use this if that is
+// acceptable.
+func WithKeyIDsFromKeyMaterial() ManagerOption {
+	return func(km *Manager) {
+		km.derivedKeyIDs = true
+	}
 }
 
 // NewManager creates a new instance with an empty Keyset.
-func NewManager() *Manager {
+func NewManager(opts ...ManagerOption) *Manager {
 	ret := new(Manager)
 	ret.ks = new(tinkpb.Keyset)
+	ret.cache = new(primitiveCache)
+	for _, opt := range opts {
+		opt(ret)
+	}
 	return ret
 }
 
@@ -41,23 +69,41 @@ func NewManager() *Manager {
 func NewManagerFromHandle(kh *Handle) *Manager {
 	ret := new(Manager)
 	ret.ks = kh.ks
+	ret.cache = kh.cache
+	if ret.cache == nil {
+		ret.cache = new(primitiveCache)
+	}
 	return ret
 }
 
 // Rotate generates a fresh key using the given key template and
 // sets the new key as the primary key.
 func (km *Manager) Rotate(kt *tinkpb.KeyTemplate) error {
+	_, err := km.RotateKey(kt)
+	return err
+}
+
+// RotateKey generates a fresh key using the given key template, sets it as the
+// primary key and returns its key ID. Existing keys keep their status, so
+// ciphertexts produced under the previous primary key can still be decrypted.
+// If key generation fails, the keyset is left unchanged.
+func (km *Manager) RotateKey(kt *tinkpb.KeyTemplate) (uint32, error) {
 	if kt == nil {
-		return fmt.Errorf("keyset_manager: cannot rotate, need key template")
+		return 0, fmt.Errorf("keyset_manager: cannot rotate, need key template")
 	}
 	if kt.OutputPrefixType == tinkpb.OutputPrefixType_UNKNOWN_PREFIX {
-		return fmt.Errorf("keyset_manager: unknown output prefix type")
+		return 0, fmt.Errorf("keyset_manager: unknown output prefix type")
 	}
 	keyData, err := registry.NewKeyData(kt)
 	if err != nil {
-		return fmt.Errorf("keyset_manager: cannot create KeyData: %s", err)
+		return 0, fmt.Errorf("keyset_manager: cannot create KeyData: %s", err)
+	}
+	var keyID uint32
+	if km.derivedKeyIDs {
+		keyID = derivedKeyID(keyData, km.ks)
+	} else {
+		keyID = km.newKeyID()
 	}
-	keyID := km.newKeyID()
 	key := &tinkpb.Keyset_Key{
 		KeyData:          keyData,
 		Status:           tinkpb.KeyStatusType_ENABLED,
@@ -67,12 +113,64 @@ func (km *Manager) Rotate(kt *tinkpb.KeyTemplate) error {
 	// Set the new key as the primary key
 	km.ks.Key = append(km.ks.Key, key)
 	km.ks.PrimaryKeyId = keyID
+	km.cache.invalidate()
+	return keyID, nil
+}
+
+// Prune removes all keys that are not ENABLED, i.e. DISABLED and DESTROYED
+// keys, from the keyset. It fails without modifying the keyset if the primary
+// key would be removed.
+func (km *Manager) Prune() error {
+	var kept []*tinkpb.Keyset_Key
+	hasPrimary := false
+	for _, key := range km.ks.Key {
+		if key.Status != tinkpb.KeyStatusType_ENABLED {
+			continue
+		}
+		if key.KeyId == km.ks.PrimaryKeyId {
+			hasPrimary = true
+		}
+		kept = append(kept, key)
+	}
+	if !hasPrimary {
+		return fmt.Errorf("keyset_manager: cannot prune, primary key %d is not ENABLED", km.ks.PrimaryKeyId)
+	}
+	km.ks.Key = kept
+	km.cache.invalidate()
 	return nil
 }
 
+// Destroy sets the status of the key with the given ID to DESTROYED and
+// overwrites its key material with zeros before discarding it. The type URL
+// and key material type of the key are kept, so that the keyset remains
+// valid. Destroyed keys are skipped when creating primitives, so ciphertexts
+// and signatures of the key can no longer be decrypted or verified. It fails
+// without modifying the keyset if the key is the primary key or does not
+// exist.
+func (km *Manager) Destroy(keyID uint32) error {
+	if keyID == km.ks.PrimaryKeyId {
+		return fmt.Errorf("keyset_manager: cannot destroy primary key %d", keyID)
+	}
+	for _, key := range km.ks.Key {
+		if key.KeyId != keyID {
+			continue
+		}
+		if key.KeyData != nil {
+			for i := range key.KeyData.Value {
+				key.KeyData.Value[i] = 0
+			}
+			key.KeyData.Value = nil
+		}
+		key.Status = tinkpb.KeyStatusType_DESTROYED
+		km.cache.invalidate()
+		return nil
+	}
+	return fmt.Errorf("keyset_manager: key %d not found", keyID)
+}
+
 // Handle creates a new Handle for the managed keyset.
 func (km *Manager) Handle() (*Handle, error) {
-	return &Handle{km.ks}, nil
+	return &Handle{ks: km.ks, cache: km.cache}, nil
 }
 
 // newKeyID generates a key id that has not been used by any key in the keyset.
@@ -91,3 +189,40 @@ func (km *Manager) newKeyID() uint32 {
 		}
 	}
 }
+
+// DerivedKeyID returns the key ID that a Manager created with
+// WithKeyIDsFromKeyMaterial assigns to a new key with the given key data in an
+// empty keyset: the first 4 bytes, as a big-endian integer, of the SHA-256
+// digest of the type URL and the value of kd.
+func DerivedKeyID(kd *tinkpb.KeyData) uint32 {
+	return derivedKeyID(kd, nil)
+}
+
+// derivedKeyID returns the key ID derived from kd that is not 0 and not used
+// by any key in ks. If the ID derived from a digest is taken, the digest is
+// hashed again.
+func derivedKeyID(kd *tinkpb.KeyData, ks *tinkpb.Keyset) uint32 {
+	h := sha256.New()
+	h.Write([]byte(kd.GetTypeUrl()))
+	h.Write([]byte{0})
+	h.Write(kd.GetValue())
+	digest := h.Sum(nil)
+	for {
+		id := binary.BigEndian.Uint32(digest)
+		if id != 0 && !hasKeyID(ks, id) {
+			return id
+		}
+		next := sha256.Sum256(digest)
+		digest = next[:]
+	}
+}
+
+// hasKeyID returns true if a key in ks has the given ID.
+func hasKeyID(ks *tinkpb.Keyset, id uint32) bool {
+	for _, key := range ks.GetKey() {
+		if key.KeyId == id {
+			return true
+		}
+	}
+	return false
+}
@@ -17,8 +17,10 @@
 package keyset_test
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/google/tink/go/aead"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/testkeyset"
 
@@ -94,3 +96,262 @@ func TestUnknowOutputPrefixTypeFails(t *testing.T) {
 		t.Errorf("ksm1.Rotate(kt) where kt has an unknown prefix succeeded, want error")
 	}
 }
+
+func TestRotateKeyKeepsOldKeysForDecryption(t *testing.T) {
+	ksm := keyset.NewManager()
+	firstID, err := ksm.RotateKey(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	h1, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	a1, err := aead.New(h1)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	pt, ad := []byte("plaintext"), []byte("ad")
+	ct, err := a1.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a1.Encrypt() err = %v", err)
+	}
+
+	secondID, err := ksm.RotateKey(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	if secondID == firstID {
+		t.Errorf("ksm.RotateKey() returned the previous key ID %d", firstID)
+	}
+	h2, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	ks := testkeyset.KeysetMaterial(h2)
+	if ks.PrimaryKeyId != secondID {
+		t.Errorf("PrimaryKeyId = %d, want %d", ks.PrimaryKeyId, secondID)
+	}
+	for _, key := range ks.Key {
+		if key.Status != tinkpb.KeyStatusType_ENABLED {
+			t.Errorf("key %d has status %s, want ENABLED", key.KeyId, key.Status)
+		}
+	}
+	a2, err := aead.New(h2)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	got, err := a2.Decrypt(ct, ad)
+	if err != nil {
+		t.Fatalf("a2.Decrypt() of old ciphertext err = %v", err)
+	}
+	if !bytes.Equal(got, pt) {
+		t.Errorf("a2.Decrypt() = %q, want %q", got, pt)
+	}
+}
+
+func TestRotateKeyFailureLeavesKeysetUnchanged(t *testing.T) {
+	ksm := keyset.NewManager()
+	keyID, err := ksm.RotateKey(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	kt := mac.HMACSHA256Tag128KeyTemplate()
+	kt.TypeUrl = "some unknown type url"
+	if _, err := ksm.RotateKey(kt); err == nil {
+		t.Fatal("ksm.RotateKey() with unknown type url err = nil, want error")
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+	if len(ks.Key) != 1 || ks.PrimaryKeyId != keyID {
+		t.Errorf("keyset was modified by a failed rotation: %s", ks)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	ksm := keyset.NewManager()
+	var cts [][]byte
+	for i := 0; i < 3; i++ {
+		if _, err := ksm.RotateKey(aead.AES128GCMKeyTemplate()); err != nil {
+			t.Fatalf("ksm.RotateKey() err = %v", err)
+		}
+		h, err := ksm.Handle()
+		if err != nil {
+			t.Fatalf("ksm.Handle() err = %v", err)
+		}
+		a, err := aead.New(h)
+		if err != nil {
+			t.Fatalf("aead.New() err = %v", err)
+		}
+		ct, err := a.Encrypt(pt, ad)
+		if err != nil {
+			t.Fatalf("a.Encrypt() err = %v", err)
+		}
+		cts = append(cts, ct)
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+	ks.Key[0].Status = tinkpb.KeyStatusType_DISABLED
+	ks.Key[1].Status = tinkpb.KeyStatusType_DESTROYED
+	ks.Key[1].KeyData = nil
+
+	if err := ksm.Prune(); err != nil {
+		t.Fatalf("ksm.Prune() err = %v", err)
+	}
+	h, err = ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	ks = testkeyset.KeysetMaterial(h)
+	if len(ks.Key) != 1 || ks.Key[0].KeyId != ks.PrimaryKeyId {
+		t.Fatalf("pruned keyset = %s, want only the primary key", ks)
+	}
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	if got, err := a.Decrypt(cts[2], ad); err != nil || !bytes.Equal(got, pt) {
+		t.Errorf("a.Decrypt() under remaining key = %q, %v, want %q, nil", got, err, pt)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Decrypt(cts[i], ad); err == nil {
+			t.Errorf("a.Decrypt() under pruned key %d err = nil, want error", i)
+		}
+	}
+}
+
+func TestPruneFailsWhenPrimaryIsNotEnabled(t *testing.T) {
+	ksm := keyset.NewManager()
+	for i := 0; i < 2; i++ {
+		if err := ksm.Rotate(mac.HMACSHA256Tag128KeyTemplate()); err != nil {
+			t.Fatalf("ksm.Rotate() err = %v", err)
+		}
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+	ks.Key[1].Status = tinkpb.KeyStatusType_DISABLED
+	if err := ksm.Prune(); err == nil {
+		t.Fatal("ksm.Prune() with disabled primary err = nil, want error")
+	}
+	if len(ks.Key) != 2 {
+		t.Errorf("failed ksm.Prune() left %d keys, want 2", len(ks.Key))
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	pt, ad := []byte("plaintext"), []byte("ad")
+	ksm := keyset.NewManager()
+	oldKeyID, err := ksm.RotateKey(aead.AES128GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	ct, err := a.Encrypt(pt, ad)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v", err)
+	}
+	if _, err := ksm.RotateKey(aead.AES128GCMKeyTemplate()); err != nil {
+		t.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	h, err = ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	keyValue := testkeyset.KeysetMaterial(h).Key[0].KeyData.Value
+
+	if err := ksm.Destroy(oldKeyID); err != nil {
+		t.Fatalf("ksm.Destroy() err = %v", err)
+	}
+	h, err = ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	if err := keyset.Validate(testkeyset.KeysetMaterial(h)); err != nil {
+		t.Errorf("keyset.Validate() after ksm.Destroy() err = %v", err)
+	}
+	info := h.KeysetInfo()
+	if len(info.KeyInfo) != 2 || info.KeyInfo[0].KeyId != oldKeyID || info.KeyInfo[0].Status != tinkpb.KeyStatusType_DESTROYED {
+		t.Errorf("h.KeysetInfo() = %v, want key %d DESTROYED", info, oldKeyID)
+	}
+	if kd := testkeyset.KeysetMaterial(h).Key[0].KeyData; len(kd.Value) != 0 {
+		t.Errorf("destroyed key has %d bytes of key material, want 0", len(kd.Value))
+	}
+	if !bytes.Equal(keyValue, make([]byte, len(keyValue))) {
+		t.Error("key material of destroyed key was not zeroed")
+	}
+
+	ps, err := h.Primitives()
+	if err != nil {
+		t.Fatalf("h.Primitives() err = %v", err)
+	}
+	for _, entries := range ps.Entries {
+		for _, e := range entries {
+			if e.KeyID == oldKeyID {
+				t.Errorf("h.Primitives() contains destroyed key %d", oldKeyID)
+			}
+		}
+	}
+	a, err = aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	if _, err := a.Decrypt(ct, ad); err == nil {
+		t.Error("a.Decrypt() under destroyed key err = nil, want error")
+	}
+}
+
+func TestDestroyFails(t *testing.T) {
+	ksm := keyset.NewManager()
+	primaryKeyID, err := ksm.RotateKey(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("ksm.RotateKey() err = %v", err)
+	}
+	if err := ksm.Destroy(primaryKeyID); err == nil {
+		t.Error("ksm.Destroy() of primary key err = nil, want error")
+	}
+	if err := ksm.Destroy(primaryKeyID + 1); err == nil {
+		t.Error("ksm.Destroy() of unknown key err = nil, want error")
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	if ks := testkeyset.KeysetMaterial(h); ks.Key[0].Status != tinkpb.KeyStatusType_ENABLED || len(ks.Key[0].KeyData.Value) == 0 {
+		t.Errorf("failed ksm.Destroy() modified the keyset: %s", ks)
+	}
+}
+
+func TestManagerWithKeyIDsFromKeyMaterial(t *testing.T) {
+	ksm := keyset.NewManager(keyset.WithKeyIDsFromKeyMaterial())
+	for i := 0; i < 3; i++ {
+		if _, err := ksm.RotateKey(aead.AES256GCMKeyTemplate()); err != nil {
+			t.Fatalf("ksm.RotateKey() err = %v", err)
+		}
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	for _, key := range testkeyset.KeysetMaterial(h).Key {
+		if want := keyset.DerivedKeyID(key.KeyData); key.KeyId != want {
+			t.Errorf("key ID = %d, want keyset.DerivedKeyID() = %d", key.KeyId, want)
+		}
+	}
+}
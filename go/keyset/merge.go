@@ -0,0 +1,136 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/subtle/random"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// prf mirrors the prf.PRF interface, which cannot be imported here.
+type prf interface {
+	ComputePRF(input []byte, outputLength uint32) ([]byte, error)
+}
+
+// Merge returns a handle for a keyset that contains the keys of primary
+// followed by the keys of secondary, and whose primary key is the primary key
+// of primary. Keys keep their status. The handles are not modified.
+//
+// If a key of secondary has the same ID as a key of primary, it is handled
+// as follows:
+//   - if both keys have the same key data and output prefix type, the key of
+//     secondary is dropped;
+//   - if the key of secondary has a RAW prefix, it gets a fresh key ID. This
+//     is safe because RAW ciphertexts and signatures do not contain the ID;
+//   - otherwise Merge fails, since changing the ID of a key with a TINK,
+//     LEGACY or CRUNCHY prefix would make its existing ciphertexts and
+//     signatures unusable.
+//
+// Merge fails if the keys of the two keysets are not all of the same
+// primitive, e.g. if one is an AEAD keyset and the other a MAC keyset, or
+// one is a private and the other a public signature keyset.
+func Merge(primary, secondary *Handle) (*Handle, error) {
+	if primary == nil || primary.ks == nil || secondary == nil || secondary.ks == nil {
+		return nil, fmt.Errorf("keyset.Merge: nil handle")
+	}
+	if err := Validate(primary.ks); err != nil {
+		return nil, fmt.Errorf("keyset.Merge: invalid primary keyset: %s", err)
+	}
+	if err := Validate(secondary.ks); err != nil {
+		return nil, fmt.Errorf("keyset.Merge: invalid secondary keyset: %s", err)
+	}
+	if err := checkSamePrimitive(primary.ks, secondary.ks); err != nil {
+		return nil, fmt.Errorf("keyset.Merge: %s", err)
+	}
+
+	byID := make(map[uint32]*tinkpb.Keyset_Key)
+	used := make(map[uint32]bool)
+	for _, key := range primary.ks.Key {
+		byID[key.KeyId] = key
+		used[key.KeyId] = true
+	}
+	for _, key := range secondary.ks.Key {
+		used[key.KeyId] = true
+	}
+	merged := &tinkpb.Keyset{PrimaryKeyId: primary.ks.PrimaryKeyId}
+	for _, key := range primary.ks.Key {
+		merged.Key = append(merged.Key, proto.Clone(key).(*tinkpb.Keyset_Key))
+	}
+	for _, key := range secondary.ks.Key {
+		clone := proto.Clone(key).(*tinkpb.Keyset_Key)
+		if other, ok := byID[key.KeyId]; ok {
+			switch {
+			case key.OutputPrefixType == other.OutputPrefixType && proto.Equal(key.KeyData, other.KeyData):
+				continue
+			case key.OutputPrefixType == tinkpb.OutputPrefixType_RAW:
+				clone.KeyId = unusedKeyID(used)
+				used[clone.KeyId] = true
+			default:
+				return nil, fmt.Errorf("keyset.Merge: key ID %d is used by different keys and the key of the secondary keyset has %s prefix", key.KeyId, key.OutputPrefixType)
+			}
+		}
+		merged.Key = append(merged.Key, clone)
+	}
+	return newHandle(merged), nil
+}
+
+// unusedKeyID returns a random non-zero key ID that is not in used.
+func unusedKeyID(used map[uint32]bool) uint32 {
+	for {
+		id := random.GetRandomUint32()
+		if id != 0 && !used[id] {
+			return id
+		}
+	}
+}
+
+// checkSamePrimitive returns an error if the keys of a and b that have key
+// material are not all of the same primitive.
+func checkSamePrimitive(a, b *tinkpb.Keyset) error {
+	want := ""
+	for _, ks := range []*tinkpb.Keyset{a, b} {
+		for _, key := range ks.Key {
+			if key.Status == tinkpb.KeyStatusType_DESTROYED {
+				continue
+			}
+			p, err := registry.PrimitiveFromKeyData(key.KeyData)
+			if err != nil {
+				return fmt.Errorf("cannot get primitive of key %d: %s", key.KeyId, err)
+			}
+			got := primitiveName(p)
+			if want == "" {
+				want = got
+			} else if got != want {
+				return fmt.Errorf("incompatible primitives %s and %s", want, got)
+			}
+		}
+	}
+	return nil
+}
+
+// primitiveName returns the name of the Tink interface that p implements.
+func primitiveName(p interface{}) string {
+	if k := primitiveKind(p); k != UnknownPrimitive {
+		return k.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/testkeyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+func newHandleFromTemplate(t *testing.T, kt *tinkpb.KeyTemplate) *keyset.Handle {
+	t.Helper()
+	h, err := keyset.NewHandle(kt)
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	return h
+}
+
+func encryptWith(t *testing.T, h *keyset.Handle, pt []byte) []byte {
+	t.Helper()
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	ct, err := a.Encrypt(pt, nil)
+	if err != nil {
+		t.Fatalf("Encrypt() err = %v", err)
+	}
+	return ct
+}
+
+func decryptWith(t *testing.T, h *keyset.Handle, ct, want []byte) {
+	t.Helper()
+	a, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	pt, err := a.Decrypt(ct, nil)
+	if err != nil {
+		t.Fatalf("Decrypt() err = %v", err)
+	}
+	if !bytes.Equal(pt, want) {
+		t.Errorf("Decrypt() = %q, want %q", pt, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	primary := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	ksm := keyset.NewManager()
+	for i := 0; i < 2; i++ {
+		if err := ksm.Rotate(aead.AES256GCMKeyTemplate()); err != nil {
+			t.Fatalf("ksm.Rotate() err = %v", err)
+		}
+	}
+	secondary, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	pt1, pt2 := []byte("primary plaintext"), []byte("secondary plaintext")
+	ct1 := encryptWith(t, primary, pt1)
+	ct2 := encryptWith(t, secondary, pt2)
+	destroyed := secondary.KeysetInfo().KeyInfo[0].KeyId
+	if err := ksm.Destroy(destroyed); err != nil {
+		t.Fatalf("ksm.Destroy() err = %v", err)
+	}
+	primaryInfo := primary.KeysetInfo()
+	secondaryInfo := secondary.KeysetInfo()
+
+	merged, err := keyset.Merge(primary, secondary)
+	if err != nil {
+		t.Fatalf("keyset.Merge() err = %v", err)
+	}
+	decryptWith(t, merged, ct1, pt1)
+	decryptWith(t, merged, ct2, pt2)
+	decryptWith(t, primary, encryptWith(t, merged, pt1), pt1)
+
+	want := &tinkpb.KeysetInfo{
+		PrimaryKeyId: primaryInfo.PrimaryKeyId,
+		KeyInfo:      append(primaryInfo.KeyInfo, secondaryInfo.KeyInfo...),
+	}
+	if got := merged.KeysetInfo(); !proto.Equal(got, want) {
+		t.Errorf("merged.KeysetInfo() = %s, want %s", got, want)
+	}
+	if !proto.Equal(primary.KeysetInfo(), primaryInfo) || !proto.Equal(secondary.KeysetInfo(), secondaryInfo) {
+		t.Error("keyset.Merge() modified its arguments")
+	}
+}
+
+func TestMergeCollidingKeyIDs(t *testing.T) {
+	primary := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	id := primary.KeysetInfo().PrimaryKeyId
+
+	// Merging a keyset into itself drops the duplicate keys.
+	merged, err := keyset.Merge(primary, primary)
+	if err != nil {
+		t.Fatalf("keyset.Merge(primary, primary) err = %v", err)
+	}
+	if got := len(merged.KeysetInfo().KeyInfo); got != 1 {
+		t.Errorf("len(merged.KeysetInfo().KeyInfo) = %d, want 1", got)
+	}
+
+	// A colliding RAW key gets a fresh ID.
+	raw := newHandleFromTemplate(t, aead.AES256GCMNoPrefixKeyTemplate())
+	pt := []byte("raw plaintext")
+	ct := encryptWith(t, raw, pt)
+	ks := testkeyset.KeysetMaterial(raw)
+	ks.Key[0].KeyId = id
+	ks.PrimaryKeyId = id
+	raw, err = testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v", err)
+	}
+	merged, err = keyset.Merge(primary, raw)
+	if err != nil {
+		t.Fatalf("keyset.Merge(primary, raw) err = %v", err)
+	}
+	info := merged.KeysetInfo()
+	if len(info.KeyInfo) != 2 || info.KeyInfo[1].KeyId == id || info.KeyInfo[1].KeyId == 0 {
+		t.Errorf("merged.KeysetInfo() = %s, want a second key with a fresh ID", info)
+	}
+	decryptWith(t, merged, ct, pt)
+
+	// A colliding TINK key cannot be renumbered.
+	other := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	ks = testkeyset.KeysetMaterial(other)
+	ks.Key[0].KeyId = id
+	ks.PrimaryKeyId = id
+	other, err = testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v", err)
+	}
+	if _, err := keyset.Merge(primary, other); err == nil {
+		t.Error("keyset.Merge() with colliding TINK keys err = nil, want error")
+	}
+}
+
+func TestMergeFails(t *testing.T) {
+	aeadHandle := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	macHandle := newHandleFromTemplate(t, mac.HMACSHA256Tag128KeyTemplate())
+	if _, err := keyset.Merge(aeadHandle, macHandle); err == nil {
+		t.Error("keyset.Merge(aeadHandle, macHandle) err = nil, want error")
+	}
+	if _, err := keyset.Merge(aeadHandle, nil); err == nil {
+		t.Error("keyset.Merge(aeadHandle, nil) err = nil, want error")
+	}
+	if _, err := keyset.Merge(nil, aeadHandle); err == nil {
+		t.Error("keyset.Merge(nil, aeadHandle) err = nil, want error")
+	}
+}
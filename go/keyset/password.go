@@ -0,0 +1,176 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/google/tink/go/aead/subtle"
+	"github.com/google/tink/go/subtle/random"
+)
+
+const (
+	passwordFormatVersion = 1
+	passwordSaltSize      = 16
+	passwordKEKSize       = 32
+	// passwordHeaderSize is the size of version || N || r || p || salt.
+	passwordHeaderSize = 1 + 3*4 + passwordSaltSize
+
+	// The limits of the scrypt parameters. maxScryptMemory bounds the memory
+	// that reading an encrypted keyset with attacker-chosen parameters takes.
+	minScryptN      = 1 << 14
+	maxScryptN      = 1 << 20
+	maxScryptR      = 32
+	maxScryptP      = 16
+	maxScryptMemory = 256 << 20
+)
+
+// ScryptParams are the parameters of the scrypt key derivation of
+// WriteWithPassword, see https://tools.ietf.org/html/rfc7914. N is the
+// CPU/memory cost and must be a power of two between 2^14 and 2^20, R is the
+// block size and P the parallelization. Deriving a key takes about 128*N*R
+// bytes of memory, which must not exceed 256 MiB.
+type ScryptParams struct {
+	N, R, P int
+}
+
+// DefaultScryptParams returns the scrypt parameters N = 2^15, R = 8, P = 1,
+// which take 32 MiB of memory and are suitable for interactive use.
+func DefaultScryptParams() ScryptParams {
+	return ScryptParams{N: 1 << 15, R: 8, P: 1}
+}
+
+func (p ScryptParams) validate() error {
+	if p.N < minScryptN || p.N > maxScryptN || p.N&(p.N-1) != 0 {
+		return fmt.Errorf("scrypt N must be a power of two between %d and %d, got %d", minScryptN, maxScryptN, p.N)
+	}
+	if p.R < 1 || p.R > maxScryptR {
+		return fmt.Errorf("scrypt r must be between 1 and %d, got %d", maxScryptR, p.R)
+	}
+	if p.P < 1 || p.P > maxScryptP {
+		return fmt.Errorf("scrypt p must be between 1 and %d, got %d", maxScryptP, p.P)
+	}
+	if 128*p.N*p.R > maxScryptMemory {
+		return fmt.Errorf("scrypt parameters N = %d and r = %d take more than %d bytes of memory", p.N, p.R, maxScryptMemory)
+	}
+	return nil
+}
+
+// WriteWithPassword encrypts the keyset in h with a key derived from password
+// and writes it to writer. The key encryption key is an AES-256-GCM key that
+// scrypt derives with params from password and a random salt. The encrypted
+// keyset starts with a version byte, N, r and p as 4-byte big-endian
+// integers and the salt, followed by the AES-GCM ciphertext, whose associated
+// data is that header. The security of the keyset is bounded by the strength
+// of the password; prefer a KMS, see WriteEncryptedToKMS, where possible.
+func WriteWithPassword(h *Handle, writer Writer, password []byte, params ScryptParams) error {
+	if h == nil {
+		return errors.New("keyset.WriteWithPassword: invalid handle")
+	}
+	if writer == nil {
+		return errors.New("keyset.WriteWithPassword: invalid writer")
+	}
+	if len(password) == 0 {
+		return errors.New("keyset.WriteWithPassword: empty password")
+	}
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("keyset.WriteWithPassword: %s", err)
+	}
+	encrypted, err := encrypt(h.ks, &passwordAEAD{password: password, params: params}, []byte{})
+	if err != nil {
+		return fmt.Errorf("keyset.WriteWithPassword: %s", err)
+	}
+	return writer.WriteEncrypted(encrypted)
+}
+
+// ReadWithPassword reads a keyset written by WriteWithPassword from reader and
+// decrypts it with password. It fails if the password is wrong.
+func ReadWithPassword(reader Reader, password []byte) (*Handle, error) {
+	if reader == nil {
+		return nil, errors.New("keyset.ReadWithPassword: invalid reader")
+	}
+	encryptedKeyset, err := reader.ReadEncrypted()
+	if err != nil {
+		return nil, fmt.Errorf("keyset.ReadWithPassword: %s", err)
+	}
+	ks, err := decrypt(encryptedKeyset, &passwordAEAD{password: password}, []byte{})
+	if err != nil {
+		return nil, fmt.Errorf("keyset.ReadWithPassword: %s", err)
+	}
+	return newHandle(ks), nil
+}
+
+// passwordAEAD is the AEAD that encrypts and decrypts keysets with a
+// password. Encrypt uses params, Decrypt the parameters in the ciphertext.
+type passwordAEAD struct {
+	password []byte
+	params   ScryptParams
+}
+
+func (a *passwordAEAD) Encrypt(pt, ad []byte) ([]byte, error) {
+	header := make([]byte, passwordHeaderSize)
+	header[0] = passwordFormatVersion
+	binary.BigEndian.PutUint32(header[1:], uint32(a.params.N))
+	binary.BigEndian.PutUint32(header[5:], uint32(a.params.R))
+	binary.BigEndian.PutUint32(header[9:], uint32(a.params.P))
+	copy(header[13:], random.GetRandomBytes(passwordSaltSize))
+	kek, err := a.kek(a.params, header[13:])
+	if err != nil {
+		return nil, err
+	}
+	ct, err := kek.Encrypt(pt, append(header[:passwordHeaderSize:passwordHeaderSize], ad...))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ct...), nil
+}
+
+func (a *passwordAEAD) Decrypt(ct, ad []byte) ([]byte, error) {
+	if len(ct) < passwordHeaderSize {
+		return nil, errors.New("ciphertext too short")
+	}
+	header := ct[:passwordHeaderSize]
+	if header[0] != passwordFormatVersion {
+		return nil, fmt.Errorf("unsupported format version %d", header[0])
+	}
+	params := ScryptParams{
+		N: int(binary.BigEndian.Uint32(header[1:])),
+		R: int(binary.BigEndian.Uint32(header[5:])),
+		P: int(binary.BigEndian.Uint32(header[9:])),
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	kek, err := a.kek(params, header[13:])
+	if err != nil {
+		return nil, err
+	}
+	return kek.Decrypt(ct[passwordHeaderSize:], append(header[:passwordHeaderSize:passwordHeaderSize], ad...))
+}
+
+// kek returns the AES-GCM key encryption key derived from the password.
+func (a *passwordAEAD) kek(params ScryptParams, salt []byte) (*subtle.AESGCM, error) {
+	key, err := scrypt.Key(a.password, salt, params.N, params.R, params.P, passwordKEKSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive key: %s", err)
+	}
+	return subtle.NewAESGCM(key)
+}
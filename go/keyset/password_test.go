@@ -0,0 +1,130 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/testkeyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// testScryptParams are the cheapest parameters WriteWithPassword accepts.
+var testScryptParams = keyset.ScryptParams{N: 1 << 14, R: 8, P: 1}
+
+func TestWriteWithPasswordRoundTrip(t *testing.T) {
+	h := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	password := []byte("correct horse battery staple")
+	buf := new(bytes.Buffer)
+	if err := keyset.WriteWithPassword(h, keyset.NewBinaryWriter(buf), password, testScryptParams); err != nil {
+		t.Fatalf("keyset.WriteWithPassword() err = %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), testkeyset.KeysetMaterial(h).Key[0].KeyData.Value) {
+		t.Errorf("keyset.WriteWithPassword() wrote the key material in the clear")
+	}
+	got, err := keyset.ReadWithPassword(keyset.NewBinaryReader(bytes.NewReader(buf.Bytes())), password)
+	if err != nil {
+		t.Fatalf("keyset.ReadWithPassword() err = %v", err)
+	}
+	if !testkeyset.Equal(got, h) {
+		t.Errorf("keyset.ReadWithPassword() = %v, want %v", got, h)
+	}
+	pt := []byte("plaintext")
+	decryptWith(t, got, encryptWith(t, h, pt), pt)
+}
+
+func TestReadWithPasswordFails(t *testing.T) {
+	h := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	password := []byte("password")
+	mem := &keyset.MemReaderWriter{}
+	if err := keyset.WriteWithPassword(h, mem, password, testScryptParams); err != nil {
+		t.Fatalf("keyset.WriteWithPassword() err = %v", err)
+	}
+	encrypted := mem.EncryptedKeyset.EncryptedKeyset
+
+	for _, wrong := range [][]byte{[]byte("passwore"), []byte("password "), nil} {
+		if _, err := keyset.ReadWithPassword(mem, wrong); err == nil {
+			t.Errorf("keyset.ReadWithPassword() with password %q err = nil, want error", wrong)
+		}
+	}
+
+	modify := func(f func(ct []byte)) *keyset.MemReaderWriter {
+		ct := append([]byte{}, encrypted...)
+		f(ct)
+		return &keyset.MemReaderWriter{EncryptedKeyset: &tinkpb.EncryptedKeyset{EncryptedKeyset: ct}}
+	}
+	for _, tc := range []struct {
+		name   string
+		reader *keyset.MemReaderWriter
+	}{
+		{"version", modify(func(ct []byte) { ct[0] = 2 })},
+		// The parameters are authenticated, so even valid ones must fail.
+		{"N", modify(func(ct []byte) { binary.BigEndian.PutUint32(ct[1:], 1<<15) })},
+		{"huge N", modify(func(ct []byte) { binary.BigEndian.PutUint32(ct[1:], 1<<30) })},
+		{"huge r", modify(func(ct []byte) { binary.BigEndian.PutUint32(ct[5:], 1<<20) })},
+		{"p", modify(func(ct []byte) { binary.BigEndian.PutUint32(ct[9:], 2) })},
+		{"salt", modify(func(ct []byte) { ct[13] ^= 1 })},
+		{"ciphertext", modify(func(ct []byte) { ct[len(ct)-1] ^= 1 })},
+		{"truncated", &keyset.MemReaderWriter{EncryptedKeyset: &tinkpb.EncryptedKeyset{EncryptedKeyset: encrypted[:20]}}},
+	} {
+		if _, err := keyset.ReadWithPassword(tc.reader, password); err == nil {
+			t.Errorf("keyset.ReadWithPassword() with modified %s err = nil, want error", tc.name)
+		}
+	}
+}
+
+func TestWriteWithPasswordFails(t *testing.T) {
+	h := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	password := []byte("password")
+	if err := keyset.WriteWithPassword(nil, &keyset.MemReaderWriter{}, password, testScryptParams); err == nil {
+		t.Errorf("keyset.WriteWithPassword(nil) err = nil, want error")
+	}
+	if err := keyset.WriteWithPassword(h, nil, password, testScryptParams); err == nil {
+		t.Errorf("keyset.WriteWithPassword() with nil writer err = nil, want error")
+	}
+	if err := keyset.WriteWithPassword(h, &keyset.MemReaderWriter{}, nil, testScryptParams); err == nil {
+		t.Errorf("keyset.WriteWithPassword() with empty password err = nil, want error")
+	}
+	for _, params := range []keyset.ScryptParams{
+		{},
+		{N: 1 << 13, R: 8, P: 1},
+		{N: 3 << 14, R: 8, P: 1},
+		{N: 1 << 21, R: 8, P: 1},
+		{N: 1 << 14, R: 0, P: 1},
+		{N: 1 << 14, R: 8, P: 0},
+		{N: 1 << 20, R: 8, P: 1},
+	} {
+		if err := keyset.WriteWithPassword(h, &keyset.MemReaderWriter{}, password, params); err == nil {
+			t.Errorf("keyset.WriteWithPassword() with params %+v err = nil, want error", params)
+		}
+	}
+}
+
+func TestDefaultScryptParams(t *testing.T) {
+	h := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	mem := &keyset.MemReaderWriter{}
+	if err := keyset.WriteWithPassword(h, mem, []byte("password"), keyset.DefaultScryptParams()); err != nil {
+		t.Fatalf("keyset.WriteWithPassword() with default params err = %v", err)
+	}
+	if _, err := keyset.ReadWithPassword(mem, []byte("password")); err != nil {
+		t.Errorf("keyset.ReadWithPassword() err = %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/google/tink/go/core/primitiveset"
+	"github.com/google/tink/go/core/registry"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// primitiveCache holds the primitive set built for a keyset by
+// Handle.Primitives, so that creating primitives from the same handle
+// repeatedly, e.g. with aead.New, does not construct every key's primitive
+// again. It is shared by all handles and the Manager that refer to the same
+// keyset. The Manager invalidates it whenever it modifies the keyset, and a
+// keyset that was modified in any other way, e.g. through
+// insecurecleartextkeyset.KeysetMaterial, is detected by comparing it with a
+// snapshot taken when the primitive set was built.
+type primitiveCache struct {
+	mu sync.Mutex
+	ps *primitiveset.PrimitiveSet
+	// snapshot records the keyset that ps was built from.
+	snapshot *keysetSnapshot
+	typeURLs []string
+}
+
+// keysetSnapshot records the fields of a keyset that its primitive set
+// depends on. It is much cheaper to compare than the keyset proto. It holds a
+// digest of the key material rather than a copy, so that the material is not
+// retained beyond the keyset, e.g. after Manager.Destroy.
+type keysetSnapshot struct {
+	primaryKeyID uint32
+	keys         []keySnapshot
+}
+
+type keySnapshot struct {
+	keyID           uint32
+	status          tinkpb.KeyStatusType
+	prefixType      tinkpb.OutputPrefixType
+	typeURL         string
+	keyMaterialType tinkpb.KeyData_KeyMaterialType
+	hasKeyData      bool
+	valueDigest     [sha256.Size]byte
+}
+
+func newKeysetSnapshot(ks *tinkpb.Keyset) *keysetSnapshot {
+	s := &keysetSnapshot{
+		primaryKeyID: ks.PrimaryKeyId,
+		keys:         make([]keySnapshot, len(ks.Key)),
+	}
+	for i, key := range ks.Key {
+		s.keys[i] = keySnapshot{
+			keyID:      key.KeyId,
+			status:     key.Status,
+			prefixType: key.OutputPrefixType,
+		}
+		if kd := key.KeyData; kd != nil {
+			s.keys[i].typeURL = kd.TypeUrl
+			s.keys[i].keyMaterialType = kd.KeyMaterialType
+			s.keys[i].hasKeyData = true
+			s.keys[i].valueDigest = sha256.Sum256(kd.Value)
+		}
+	}
+	return s
+}
+
+// matches returns whether ks is equal to the keyset s was taken of, as far as
+// its primitive set is concerned.
+func (s *keysetSnapshot) matches(ks *tinkpb.Keyset) bool {
+	if ks.PrimaryKeyId != s.primaryKeyID || len(ks.Key) != len(s.keys) {
+		return false
+	}
+	for i, key := range ks.Key {
+		k := &s.keys[i]
+		if key == nil || key.KeyId != k.keyID || key.Status != k.status || key.OutputPrefixType != k.prefixType {
+			return false
+		}
+		kd := key.KeyData
+		if (kd != nil) != k.hasKeyData {
+			return false
+		}
+		if kd == nil {
+			continue
+		}
+		if kd.TypeUrl != k.typeURL || kd.KeyMaterialType != k.keyMaterialType || sha256.Sum256(kd.Value) != k.valueDigest {
+			return false
+		}
+	}
+	return true
+}
+
+// newHandle returns a Handle for ks with an empty primitive cache.
+func newHandle(ks *tinkpb.Keyset) *Handle {
+	return &Handle{ks: ks, cache: new(primitiveCache)}
+}
+
+// primitives returns a copy of the cached primitive set of h, building it
+// first if needed or if the keyset of h has changed. Cached primitives are
+// checked against the registry's primitive filter again, since it may have
+// changed since they were built.
+func (c *primitiveCache) primitives(h *Handle) (*primitiveset.PrimitiveSet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ps == nil || !c.snapshot.matches(h.ks) {
+		c.ps, c.snapshot, c.typeURLs = nil, nil, nil
+		ps, err := h.newPrimitiveSet(nil)
+		if err != nil {
+			return nil, err
+		}
+		var typeURLs []string
+		for _, key := range h.ks.Key {
+			if key.Status == tinkpb.KeyStatusType_ENABLED {
+				typeURLs = append(typeURLs, key.KeyData.TypeUrl)
+			}
+		}
+		c.ps, c.snapshot, c.typeURLs = ps, newKeysetSnapshot(h.ks), typeURLs
+	} else {
+		for _, typeURL := range c.typeURLs {
+			if err := registry.CheckPrimitiveFilter(typeURL); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Callers may reorder the entries, e.g. aead.NewWithHotKeys.
+	return c.ps.Copy(), nil
+}
+
+// invalidate discards the cached primitive set. It is a no-op for a nil cache.
+func (c *primitiveCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ps, c.snapshot, c.typeURLs = nil, nil, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"fmt"
+
+	"github.com/google/tink/go/tink"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// PrimitiveKind identifies the Tink primitive that a keyset is for.
+type PrimitiveKind int
+
+// The primitive kinds returned by PrimitiveType.
+const (
+	UnknownPrimitive PrimitiveKind = iota
+	AEADPrimitive
+	DeterministicAEADPrimitive
+	StreamingAEADPrimitive
+	MACPrimitive
+	SignerPrimitive
+	VerifierPrimitive
+	HybridEncryptPrimitive
+	HybridDecryptPrimitive
+	PRFPrimitive
+)
+
+var primitiveKindNames = map[PrimitiveKind]string{
+	UnknownPrimitive:           "Unknown",
+	AEADPrimitive:              "AEAD",
+	DeterministicAEADPrimitive: "DeterministicAEAD",
+	StreamingAEADPrimitive:     "StreamingAEAD",
+	MACPrimitive:               "MAC",
+	SignerPrimitive:            "Signer",
+	VerifierPrimitive:          "Verifier",
+	HybridEncryptPrimitive:     "HybridEncrypt",
+	HybridDecryptPrimitive:     "HybridDecrypt",
+	PRFPrimitive:               "PRF",
+}
+
+// String returns the name of the Tink interface of the primitive kind.
+func (k PrimitiveKind) String() string {
+	if name, ok := primitiveKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("PrimitiveKind(%d)", int(k))
+}
+
+// primitiveKindsByTypeURL maps the type URLs of the key managers in Tink to
+// the kind of their primitives.
+var primitiveKindsByTypeURL = map[string]PrimitiveKind{
+	"type.googleapis.com/google.crypto.tink.AesCtrHmacAeadKey":       AEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.AesGcmKey":               AEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.AesGcmSivKey":            AEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.ChaCha20Poly1305Key":     AEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.KmsEnvelopeAeadKey":      AEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.XChaCha20Poly1305Key":    AEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.AesSivKey":               DeterministicAEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.AesCtrHmacStreamingKey":  StreamingAEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.AesGcmHkdfStreamingKey":  StreamingAEADPrimitive,
+	"type.googleapis.com/google.crypto.tink.AesCmacKey":              MACPrimitive,
+	"type.googleapis.com/google.crypto.tink.HmacKey":                 MACPrimitive,
+	"type.googleapis.com/google.crypto.tink.EcdsaPrivateKey":         SignerPrimitive,
+	"type.googleapis.com/google.crypto.tink.Ed25519PrivateKey":       SignerPrimitive,
+	"type.googleapis.com/google.crypto.tink.EcdsaPublicKey":          VerifierPrimitive,
+	"type.googleapis.com/google.crypto.tink.Ed25519PublicKey":        VerifierPrimitive,
+	"type.googleapis.com/google.crypto.tink.EciesAeadHkdfPublicKey":  HybridEncryptPrimitive,
+	"type.googleapis.com/google.crypto.tink.EciesAeadHkdfPrivateKey": HybridDecryptPrimitive,
+	"type.googleapis.com/google.crypto.tink.AesCmacPrfKey":           PRFPrimitive,
+	"type.googleapis.com/google.crypto.tink.HkdfPrfKey":              PRFPrimitive,
+	"type.googleapis.com/google.crypto.tink.HmacPrfKey":              PRFPrimitive,
+}
+
+// PrimitiveType returns the kind of primitive that the keys in h are for,
+// e.g. AEADPrimitive if h should be passed to aead.New, or SignerPrimitive for
+// signature.NewSigner. The kind is looked up by the type URL of the primary
+// key, so neither key managers nor KMS clients need to be registered. It
+// fails if the type URL is not one of the key types in Tink, or if a key that
+// is not DESTROYED has a type URL of a different kind.
+func PrimitiveType(h *Handle) (PrimitiveKind, error) {
+	if h == nil || h.ks == nil {
+		return UnknownPrimitive, fmt.Errorf("keyset.PrimitiveType: nil handle")
+	}
+	var primary *tinkpb.Keyset_Key
+	for _, key := range h.ks.Key {
+		if key.KeyId == h.ks.PrimaryKeyId && key.Status == tinkpb.KeyStatusType_ENABLED {
+			primary = key
+			break
+		}
+	}
+	if primary == nil {
+		return UnknownPrimitive, fmt.Errorf("keyset.PrimitiveType: keyset has no enabled primary key")
+	}
+	kind, err := keyKind(primary)
+	if err != nil {
+		return UnknownPrimitive, fmt.Errorf("keyset.PrimitiveType: %s", err)
+	}
+	for _, key := range h.ks.Key {
+		if key.Status == tinkpb.KeyStatusType_DESTROYED {
+			continue
+		}
+		k, err := keyKind(key)
+		if err != nil {
+			return UnknownPrimitive, fmt.Errorf("keyset.PrimitiveType: %s", err)
+		}
+		if k != kind {
+			return UnknownPrimitive, fmt.Errorf("keyset.PrimitiveType: incompatible primitives %s and %s", kind, k)
+		}
+	}
+	return kind, nil
+}
+
+// keyKind returns the kind of primitive of key, by its type URL.
+func keyKind(key *tinkpb.Keyset_Key) (PrimitiveKind, error) {
+	typeURL := key.GetKeyData().GetTypeUrl()
+	kind, ok := primitiveKindsByTypeURL[typeURL]
+	if !ok {
+		return UnknownPrimitive, fmt.Errorf("key %d has unknown type URL %q", key.KeyId, typeURL)
+	}
+	return kind, nil
+}
+
+// primitiveKind returns the kind of the Tink interface that p implements.
+// AEAD is tested before the hybrid interfaces, which every AEAD implements.
+func primitiveKind(p interface{}) PrimitiveKind {
+	switch p.(type) {
+	case tink.DeterministicAEAD:
+		return DeterministicAEADPrimitive
+	case tink.StreamingAEAD:
+		return StreamingAEADPrimitive
+	case tink.MAC:
+		return MACPrimitive
+	case tink.Signer:
+		return SignerPrimitive
+	case tink.Verifier:
+		return VerifierPrimitive
+	case tink.AEAD:
+		return AEADPrimitive
+	case tink.HybridEncrypt:
+		return HybridEncryptPrimitive
+	case tink.HybridDecrypt:
+		return HybridDecryptPrimitive
+	case prf:
+		return PRFPrimitive
+	default:
+		return UnknownPrimitive
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/daead"
+	"github.com/google/tink/go/hybrid"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/prf"
+	"github.com/google/tink/go/signature"
+	"github.com/google/tink/go/streamingaead"
+	"github.com/google/tink/go/testkeyset"
+	"github.com/google/tink/go/testutil"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+func publicHandle(t *testing.T, kt *tinkpb.KeyTemplate) *keyset.Handle {
+	t.Helper()
+	pub, err := newPrivateHandle(t, kt).Public()
+	if err != nil {
+		t.Fatalf("h.Public() err = %v", err)
+	}
+	return pub
+}
+
+func TestPrimitiveType(t *testing.T) {
+	tests := []struct {
+		name string
+		h    *keyset.Handle
+		want keyset.PrimitiveKind
+	}{
+		{"AEAD", newPrivateHandle(t, aead.AES128GCMKeyTemplate(), aead.XChaCha20Poly1305KeyTemplate()), keyset.AEADPrimitive},
+		{"DeterministicAEAD", newPrivateHandle(t, daead.AESSIVKeyTemplate()), keyset.DeterministicAEADPrimitive},
+		{"StreamingAEAD", newPrivateHandle(t, streamingaead.AES128GCMHKDF4KBKeyTemplate()), keyset.StreamingAEADPrimitive},
+		{"MAC", newPrivateHandle(t, mac.HMACSHA256Tag128KeyTemplate()), keyset.MACPrimitive},
+		{"Signer", newPrivateHandle(t, signature.ECDSAP256KeyTemplate(), signature.ED25519KeyTemplate()), keyset.SignerPrimitive},
+		{"Verifier", publicHandle(t, signature.ED25519KeyTemplate()), keyset.VerifierPrimitive},
+		{"HybridDecrypt", newPrivateHandle(t, hybrid.ECIESHKDFAES128GCMKeyTemplate()), keyset.HybridDecryptPrimitive},
+		{"HybridEncrypt", publicHandle(t, hybrid.ECIESHKDFAES128GCMKeyTemplate()), keyset.HybridEncryptPrimitive},
+		{"PRF", newPrivateHandle(t, prf.HMACSHA256PRFKeyTemplate()), keyset.PRFPrimitive},
+		// No KMS client is registered for the KEK, and the DEK is never created.
+		{"AEAD", newPrivateHandle(t, aead.KMSEnvelopeAEADKeyTemplate("fake-kms://unregistered", aead.AES128GCMKeyTemplate())), keyset.AEADPrimitive},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := keyset.PrimitiveType(tc.h)
+			if err != nil {
+				t.Fatalf("keyset.PrimitiveType() err = %v, want nil", err)
+			}
+			if got != tc.want {
+				t.Errorf("keyset.PrimitiveType() = %v, want %v", got, tc.want)
+			}
+			if got.String() != tc.name {
+				t.Errorf("keyset.PrimitiveType().String() = %q, want %q", got.String(), tc.name)
+			}
+		})
+	}
+}
+
+func TestPrimitiveTypeFailsForMixedKeysets(t *testing.T) {
+	mixed := newPrivateHandle(t, aead.AES128GCMKeyTemplate(), mac.HMACSHA256Tag128KeyTemplate())
+	if _, err := keyset.PrimitiveType(mixed); err == nil {
+		t.Errorf("keyset.PrimitiveType() of AEAD and MAC keys err = nil, want error")
+	}
+	if _, err := keyset.PrimitiveType(nil); err == nil {
+		t.Errorf("keyset.PrimitiveType(nil) err = nil, want error")
+	}
+}
+
+func TestPrimitiveTypeChecksTypeURLs(t *testing.T) {
+	known := testutil.NewKeyData("type.googleapis.com/google.crypto.tink.HmacKey", []byte{0}, tinkpb.KeyData_SYMMETRIC)
+	unknown := testutil.NewKeyData("some type url", []byte{0}, tinkpb.KeyData_SYMMETRIC)
+	tests := []struct {
+		name    string
+		keys    []*tinkpb.Keyset_Key
+		want    keyset.PrimitiveKind
+		wantErr bool
+	}{
+		{
+			name: "unknown primary",
+			keys: []*tinkpb.Keyset_Key{
+				testutil.NewKey(unknown, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown secondary",
+			keys: []*tinkpb.Keyset_Key{
+				testutil.NewKey(known, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK),
+				testutil.NewKey(unknown, tinkpb.KeyStatusType_DISABLED, 2, tinkpb.OutputPrefixType_TINK),
+			},
+			wantErr: true,
+		},
+		{
+			name: "destroyed unknown secondary",
+			keys: []*tinkpb.Keyset_Key{
+				testutil.NewKey(known, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK),
+				testutil.NewKey(unknown, tinkpb.KeyStatusType_DESTROYED, 2, tinkpb.OutputPrefixType_TINK),
+			},
+			want: keyset.MACPrimitive,
+		},
+		{
+			name: "disabled primary",
+			keys: []*tinkpb.Keyset_Key{
+				testutil.NewKey(known, tinkpb.KeyStatusType_DISABLED, 1, tinkpb.OutputPrefixType_TINK),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := testkeyset.KeysetHandle(testutil.NewKeyset(1, tc.keys))
+			got, err := keyset.PrimitiveType(h)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("keyset.PrimitiveType() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("keyset.PrimitiveType() = %v, %v, want %v, nil", got, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,488 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// The text format is a small, stable subset of the protobuf text format meant
+// for keysets that are reviewed by humans, e.g. test fixtures checked into a
+// repository. Every field is on its own line, fields are always written in
+// the same order, and bytes fields are base64-encoded. For example:
+//
+//   # Tink keyset in text format.
+//   primary_key_id: 42
+//   key {
+//     key_data {
+//       type_url: "type.googleapis.com/google.crypto.tink.HmacKey"
+//       key_material_type: SYMMETRIC
+//       value: "EgQIAxAQGiA..."  # SECRET key material, base64-encoded
+//     }
+//     status: ENABLED
+//     key_id: 42
+//     output_prefix_type: TINK
+//   }
+//
+// Comments start with '#' and run until the end of the line.
+
+const (
+	textKeysetHeader          = "# Tink keyset in text format."
+	textEncryptedKeysetHeader = "# Tink encrypted keyset in text format."
+	textSecretComment         = "# SECRET key material, base64-encoded"
+	textIndent                = "  "
+
+	// maxTextLineSize bounds the length of a line, which must hold the
+	// base64-encoded value of a key.
+	maxTextLineSize = 1 << 20
+)
+
+// TextReader deserializes a keyset from the text format written by
+// TextWriter.
+type TextReader struct {
+	r io.Reader
+}
+
+// NewTextReader returns new TextReader that will read from r.
+func NewTextReader(r io.Reader) *TextReader {
+	return &TextReader{r: r}
+}
+
+// Read parses a (cleartext) keyset from the underlying io.Reader.
+func (tr *TextReader) Read() (*tinkpb.Keyset, error) {
+	root, err := parseText(tr.r)
+	if err != nil {
+		return nil, err
+	}
+	return textToKeyset(root)
+}
+
+// ReadEncrypted parses an EncryptedKeyset from the underlying io.Reader.
+func (tr *TextReader) ReadEncrypted() (*tinkpb.EncryptedKeyset, error) {
+	root, err := parseText(tr.r)
+	if err != nil {
+		return nil, err
+	}
+	return textToEncryptedKeyset(root)
+}
+
+// TextWriter serializes a keyset into a human-readable text format.
+//
+// The output of Write contains the secret key material of the keyset, which is
+// only base64-encoded. It is meant for test fixtures and must not be used to
+// store production keys.
+type TextWriter struct {
+	w io.Writer
+}
+
+// NewTextWriter returns a new TextWriter that will write to w.
+func NewTextWriter(w io.Writer) *TextWriter {
+	return &TextWriter{w: w}
+}
+
+// Write writes the keyset to the underlying io.Writer.
+func (tw *TextWriter) Write(keyset *tinkpb.Keyset) error {
+	if keyset == nil {
+		return fmt.Errorf("keyset.TextWriter: invalid keyset")
+	}
+	p := &textPrinter{}
+	p.line(textKeysetHeader)
+	p.field("primary_key_id", strconv.FormatUint(uint64(keyset.PrimaryKeyId), 10))
+	for _, k := range keyset.Key {
+		p.open("key")
+		if kd := k.KeyData; kd != nil {
+			p.open("key_data")
+			p.field("type_url", strconv.Quote(kd.TypeUrl))
+			p.field("key_material_type", kd.KeyMaterialType.String())
+			value := strconv.Quote(base64.StdEncoding.EncodeToString(kd.Value))
+			if isSecretKeyMaterial(kd.KeyMaterialType) {
+				value += "  " + textSecretComment
+			}
+			p.field("value", value)
+			p.close()
+		}
+		p.field("status", k.Status.String())
+		p.field("key_id", strconv.FormatUint(uint64(k.KeyId), 10))
+		p.field("output_prefix_type", k.OutputPrefixType.String())
+		p.close()
+	}
+	_, err := io.WriteString(tw.w, p.b.String())
+	return err
+}
+
+// WriteEncrypted writes the encrypted keyset to the underlying io.Writer.
+func (tw *TextWriter) WriteEncrypted(keyset *tinkpb.EncryptedKeyset) error {
+	if keyset == nil {
+		return fmt.Errorf("keyset.TextWriter: invalid encrypted keyset")
+	}
+	p := &textPrinter{}
+	p.line(textEncryptedKeysetHeader)
+	p.field("encrypted_keyset", strconv.Quote(base64.StdEncoding.EncodeToString(keyset.EncryptedKeyset)))
+	if info := keyset.KeysetInfo; info != nil {
+		p.open("keyset_info")
+		p.field("primary_key_id", strconv.FormatUint(uint64(info.PrimaryKeyId), 10))
+		for _, ki := range info.KeyInfo {
+			p.open("key_info")
+			p.field("type_url", strconv.Quote(ki.TypeUrl))
+			p.field("status", ki.Status.String())
+			p.field("key_id", strconv.FormatUint(uint64(ki.KeyId), 10))
+			p.field("output_prefix_type", ki.OutputPrefixType.String())
+			p.close()
+		}
+		p.close()
+	}
+	_, err := io.WriteString(tw.w, p.b.String())
+	return err
+}
+
+// isSecretKeyMaterial returns true if key data of type t must be kept secret.
+func isSecretKeyMaterial(t tinkpb.KeyData_KeyMaterialType) bool {
+	return t != tinkpb.KeyData_ASYMMETRIC_PUBLIC && t != tinkpb.KeyData_REMOTE
+}
+
+// textPrinter builds the text format line by line.
+type textPrinter struct {
+	b     strings.Builder
+	depth int
+}
+
+func (p *textPrinter) line(s string) {
+	p.b.WriteString(strings.Repeat(textIndent, p.depth))
+	p.b.WriteString(s)
+	p.b.WriteByte('\n')
+}
+
+func (p *textPrinter) field(name, value string) {
+	p.line(name + ": " + value)
+}
+
+func (p *textPrinter) open(name string) {
+	p.line(name + " {")
+	p.depth++
+}
+
+func (p *textPrinter) close() {
+	p.depth--
+	p.line("}")
+}
+
+// textNode is a field of a parsed text message. Scalar fields have a value,
+// message fields have children.
+type textNode struct {
+	name     string
+	value    string
+	children []*textNode
+	isMsg    bool
+	line     int
+}
+
+// parseText parses the text format into a tree of fields. The returned root
+// node is the top-level message.
+func parseText(r io.Reader) (*textNode, error) {
+	root := &textNode{isMsg: true}
+	stack := []*textNode{root}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxTextLineSize)
+	for n := 1; s.Scan(); n++ {
+		line, err := stripTextComment(s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("keyset.TextReader: line %d: %s", n, err)
+		}
+		line = strings.TrimSpace(line)
+		parent := stack[len(stack)-1]
+		switch {
+		case line == "":
+		case line == "}":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("keyset.TextReader: line %d: unexpected '}'", n)
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasSuffix(line, "{"):
+			name := strings.TrimSpace(strings.TrimSuffix(line, "{"))
+			if !isTextFieldName(name) {
+				return nil, fmt.Errorf("keyset.TextReader: line %d: invalid field name %q", n, name)
+			}
+			child := &textNode{name: name, isMsg: true, line: n}
+			parent.children = append(parent.children, child)
+			stack = append(stack, child)
+		default:
+			i := strings.Index(line, ":")
+			if i < 0 {
+				return nil, fmt.Errorf("keyset.TextReader: line %d: expected 'name: value'", n)
+			}
+			name := strings.TrimSpace(line[:i])
+			if !isTextFieldName(name) {
+				return nil, fmt.Errorf("keyset.TextReader: line %d: invalid field name %q", n, name)
+			}
+			value := strings.TrimSpace(line[i+1:])
+			if strings.HasPrefix(value, `"`) {
+				value, err = strconv.Unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("keyset.TextReader: line %d: invalid string for %s", n, name)
+				}
+			} else if value == "" || strings.ContainsAny(value, " \t\"") {
+				return nil, fmt.Errorf("keyset.TextReader: line %d: invalid value for %s", n, name)
+			}
+			parent.children = append(parent.children, &textNode{name: name, value: value, line: n})
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("keyset.TextReader: %s", err)
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("keyset.TextReader: unterminated message %s", stack[len(stack)-1].name)
+	}
+	return root, nil
+}
+
+// stripTextComment removes a trailing comment from line, ignoring '#' inside
+// quoted strings.
+func stripTextComment(line string) (string, error) {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case !inString && c == '#':
+			return line[:i], nil
+		}
+	}
+	if inString {
+		return "", fmt.Errorf("unterminated string")
+	}
+	return line, nil
+}
+
+func isTextFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// textFields checks that msg only contains the given fields, each of which
+// is either a scalar or a message as indicated, and that only repeated fields
+// occur more than once.
+func textFields(msg *textNode, isMsg map[string]bool, repeated ...string) error {
+	seen := make(map[string]bool)
+	for _, f := range msg.children {
+		wantMsg, ok := isMsg[f.name]
+		if !ok {
+			return fmt.Errorf("keyset.TextReader: line %d: unknown field %s", f.line, f.name)
+		}
+		if wantMsg != f.isMsg {
+			return fmt.Errorf("keyset.TextReader: line %d: invalid field %s", f.line, f.name)
+		}
+		if seen[f.name] && !containsString(repeated, f.name) {
+			return fmt.Errorf("keyset.TextReader: line %d: duplicate field %s", f.line, f.name)
+		}
+		seen[f.name] = true
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func textUint32(f *textNode) (uint32, error) {
+	v, err := strconv.ParseUint(f.value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("keyset.TextReader: line %d: invalid %s %q", f.line, f.name, f.value)
+	}
+	return uint32(v), nil
+}
+
+func textBytes(f *textNode) ([]byte, error) {
+	v, err := base64.StdEncoding.DecodeString(f.value)
+	if err != nil {
+		return nil, fmt.Errorf("keyset.TextReader: line %d: invalid base64 in %s", f.line, f.name)
+	}
+	return v, nil
+}
+
+func textEnum(f *textNode, values map[string]int32) (int32, error) {
+	v, ok := values[f.value]
+	if !ok {
+		return 0, fmt.Errorf("keyset.TextReader: line %d: invalid %s %q", f.line, f.name, f.value)
+	}
+	return v, nil
+}
+
+func textToKeyset(root *textNode) (*tinkpb.Keyset, error) {
+	if err := textFields(root, map[string]bool{"primary_key_id": false, "key": true}, "key"); err != nil {
+		return nil, err
+	}
+	ks := &tinkpb.Keyset{}
+	for _, f := range root.children {
+		var err error
+		switch f.name {
+		case "primary_key_id":
+			ks.PrimaryKeyId, err = textUint32(f)
+		case "key":
+			var k *tinkpb.Keyset_Key
+			k, err = textToKey(f)
+			ks.Key = append(ks.Key, k)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+func textToKey(msg *textNode) (*tinkpb.Keyset_Key, error) {
+	fields := map[string]bool{"key_data": true, "status": false, "key_id": false, "output_prefix_type": false}
+	if err := textFields(msg, fields); err != nil {
+		return nil, err
+	}
+	k := &tinkpb.Keyset_Key{}
+	for _, f := range msg.children {
+		var err error
+		var v int32
+		switch f.name {
+		case "key_data":
+			k.KeyData, err = textToKeyData(f)
+		case "status":
+			v, err = textEnum(f, tinkpb.KeyStatusType_value)
+			k.Status = tinkpb.KeyStatusType(v)
+		case "key_id":
+			k.KeyId, err = textUint32(f)
+		case "output_prefix_type":
+			v, err = textEnum(f, tinkpb.OutputPrefixType_value)
+			k.OutputPrefixType = tinkpb.OutputPrefixType(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func textToKeyData(msg *textNode) (*tinkpb.KeyData, error) {
+	fields := map[string]bool{"type_url": false, "key_material_type": false, "value": false}
+	if err := textFields(msg, fields); err != nil {
+		return nil, err
+	}
+	kd := &tinkpb.KeyData{}
+	for _, f := range msg.children {
+		var err error
+		var v int32
+		switch f.name {
+		case "type_url":
+			kd.TypeUrl = f.value
+		case "key_material_type":
+			v, err = textEnum(f, tinkpb.KeyData_KeyMaterialType_value)
+			kd.KeyMaterialType = tinkpb.KeyData_KeyMaterialType(v)
+		case "value":
+			kd.Value, err = textBytes(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return kd, nil
+}
+
+func textToEncryptedKeyset(root *textNode) (*tinkpb.EncryptedKeyset, error) {
+	if err := textFields(root, map[string]bool{"encrypted_keyset": false, "keyset_info": true}); err != nil {
+		return nil, err
+	}
+	eks := &tinkpb.EncryptedKeyset{}
+	for _, f := range root.children {
+		var err error
+		switch f.name {
+		case "encrypted_keyset":
+			eks.EncryptedKeyset, err = textBytes(f)
+		case "keyset_info":
+			eks.KeysetInfo, err = textToKeysetInfo(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return eks, nil
+}
+
+func textToKeysetInfo(msg *textNode) (*tinkpb.KeysetInfo, error) {
+	if err := textFields(msg, map[string]bool{"primary_key_id": false, "key_info": true}, "key_info"); err != nil {
+		return nil, err
+	}
+	info := &tinkpb.KeysetInfo{}
+	for _, f := range msg.children {
+		var err error
+		switch f.name {
+		case "primary_key_id":
+			info.PrimaryKeyId, err = textUint32(f)
+		case "key_info":
+			var ki *tinkpb.KeysetInfo_KeyInfo
+			ki, err = textToKeyInfo(f)
+			info.KeyInfo = append(info.KeyInfo, ki)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+func textToKeyInfo(msg *textNode) (*tinkpb.KeysetInfo_KeyInfo, error) {
+	fields := map[string]bool{"type_url": false, "status": false, "key_id": false, "output_prefix_type": false}
+	if err := textFields(msg, fields); err != nil {
+		return nil, err
+	}
+	ki := &tinkpb.KeysetInfo_KeyInfo{}
+	for _, f := range msg.children {
+		var err error
+		var v int32
+		switch f.name {
+		case "type_url":
+			ki.TypeUrl = f.value
+		case "status":
+			v, err = textEnum(f, tinkpb.KeyStatusType_value)
+			ki.Status = tinkpb.KeyStatusType(v)
+		case "key_id":
+			ki.KeyId, err = textUint32(f)
+		case "output_prefix_type":
+			v, err = textEnum(f, tinkpb.OutputPrefixType_value)
+			ki.OutputPrefixType = tinkpb.OutputPrefixType(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ki, nil
+}
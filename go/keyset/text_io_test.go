@@ -0,0 +1,258 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/signature"
+	"github.com/google/tink/go/testkeyset"
+	"github.com/google/tink/go/testutil"
+
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	hmacpb "github.com/google/tink/go/proto/hmac_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// goldenHMACKeyset returns the keyset stored in testdata/hmac_keyset.txt.
+func goldenHMACKeyset(t *testing.T) *tinkpb.Keyset {
+	t.Helper()
+	newKeyData := func(keyValue []byte) *tinkpb.KeyData {
+		key := &hmacpb.HmacKey{
+			Version: testutil.HMACKeyVersion,
+			Params: &hmacpb.HmacParams{
+				Hash:    commonpb.HashType_SHA256,
+				TagSize: 16,
+			},
+			KeyValue: keyValue,
+		}
+		serializedKey, err := proto.Marshal(key)
+		if err != nil {
+			t.Fatalf("proto.Marshal() err = %v, want nil", err)
+		}
+		return testutil.NewKeyData(testutil.HMACTypeURL, serializedKey, tinkpb.KeyData_SYMMETRIC)
+	}
+	return &tinkpb.Keyset{
+		PrimaryKeyId: 4294967275,
+		Key: []*tinkpb.Keyset_Key{
+			testutil.NewKey(newKeyData(bytes.Repeat([]byte{0x01}, 32)), tinkpb.KeyStatusType_ENABLED, 4294967275, tinkpb.OutputPrefixType_TINK),
+			testutil.NewKey(newKeyData(bytes.Repeat([]byte{0x02}, 32)), tinkpb.KeyStatusType_DISABLED, 42, tinkpb.OutputPrefixType_RAW),
+		},
+	}
+}
+
+func TestTextWriterGolden(t *testing.T) {
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "hmac_keyset.txt"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() err = %v, want nil", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := keyset.NewTextWriter(buf).Write(goldenHMACKeyset(t)); err != nil {
+		t.Fatalf("Write() err = %v, want nil", err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("Write() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTextReaderGolden(t *testing.T) {
+	serialized, err := ioutil.ReadFile(filepath.Join("testdata", "hmac_keyset.txt"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() err = %v, want nil", err)
+	}
+	h, err := insecurecleartextkeyset.Read(keyset.NewTextReader(bytes.NewReader(serialized)))
+	if err != nil {
+		t.Fatalf("insecurecleartextkeyset.Read() err = %v, want nil", err)
+	}
+	if got, want := insecurecleartextkeyset.KeysetMaterial(h), goldenHMACKeyset(t); !proto.Equal(got, want) {
+		t.Errorf("read keyset = %s, want %s", got, want)
+	}
+	p, err := mac.New(h)
+	if err != nil {
+		t.Fatalf("mac.New() err = %v, want nil", err)
+	}
+	data := []byte("data")
+	tag, err := p.ComputeMAC(data)
+	if err != nil {
+		t.Fatalf("ComputeMAC() err = %v, want nil", err)
+	}
+	if err := p.VerifyMAC(tag, data); err != nil {
+		t.Errorf("VerifyMAC() err = %v, want nil", err)
+	}
+}
+
+func TestTextIOUnencrypted(t *testing.T) {
+	templates := map[string]*tinkpb.KeyTemplate{
+		"HMAC":        mac.HMACSHA256Tag128KeyTemplate(),
+		"ECDSAP256":   signature.ECDSAP256KeyTemplate(),
+		"ED25519":     signature.ED25519KeyTemplate(),
+		"ED25519 RAW": signature.ED25519KeyWithoutPrefixTemplate(),
+	}
+	for name, template := range templates {
+		t.Run(name, func(t *testing.T) {
+			manager := keyset.NewManager()
+			for i := 0; i < 3; i++ {
+				if err := manager.Rotate(template); err != nil {
+					t.Fatalf("manager.Rotate() err = %v, want nil", err)
+				}
+			}
+			h, err := manager.Handle()
+			if err != nil {
+				t.Fatalf("manager.Handle() err = %v, want nil", err)
+			}
+			buf := new(bytes.Buffer)
+			if err := insecurecleartextkeyset.Write(h, keyset.NewTextWriter(buf)); err != nil {
+				t.Fatalf("insecurecleartextkeyset.Write() err = %v, want nil", err)
+			}
+			got, err := insecurecleartextkeyset.Read(keyset.NewTextReader(buf))
+			if err != nil {
+				t.Fatalf("insecurecleartextkeyset.Read() err = %v, want nil", err)
+			}
+			if !proto.Equal(testkeyset.KeysetMaterial(got), testkeyset.KeysetMaterial(h)) {
+				t.Errorf("read keyset = %s, want %s", testkeyset.KeysetMaterial(got), testkeyset.KeysetMaterial(h))
+			}
+		})
+	}
+}
+
+func TestTextWriterLabelsSecretKeyMaterial(t *testing.T) {
+	priv, err := keyset.NewHandle(signature.ED25519KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v, want nil", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := keyset.NewTextWriter(buf).Write(testkeyset.KeysetMaterial(priv)); err != nil {
+		t.Fatalf("Write() err = %v, want nil", err)
+	}
+	if !strings.Contains(buf.String(), "SECRET") {
+		t.Errorf("private keyset is not labeled as secret:\n%s", buf)
+	}
+
+	buf.Reset()
+	if err := keyset.NewTextWriter(buf).Write(testkeyset.KeysetMaterial(pub)); err != nil {
+		t.Fatalf("Write() err = %v, want nil", err)
+	}
+	if strings.Contains(buf.String(), "SECRET") {
+		t.Errorf("public keyset is labeled as secret:\n%s", buf)
+	}
+}
+
+func TestTextIOEncrypted(t *testing.T) {
+	eks := &tinkpb.EncryptedKeyset{
+		EncryptedKeyset: []byte("not really encrypted"),
+		KeysetInfo: &tinkpb.KeysetInfo{
+			PrimaryKeyId: 42,
+			KeyInfo: []*tinkpb.KeysetInfo_KeyInfo{
+				{
+					TypeUrl:          testutil.AESGCMTypeURL,
+					Status:           tinkpb.KeyStatusType_ENABLED,
+					KeyId:            42,
+					OutputPrefixType: tinkpb.OutputPrefixType_TINK,
+				},
+				{
+					TypeUrl:          testutil.AESGCMTypeURL,
+					Status:           tinkpb.KeyStatusType_DESTROYED,
+					KeyId:            711,
+					OutputPrefixType: tinkpb.OutputPrefixType_RAW,
+				},
+			},
+		},
+	}
+	buf := new(bytes.Buffer)
+	if err := keyset.NewTextWriter(buf).WriteEncrypted(eks); err != nil {
+		t.Fatalf("WriteEncrypted() err = %v, want nil", err)
+	}
+	got, err := keyset.NewTextReader(buf).ReadEncrypted()
+	if err != nil {
+		t.Fatalf("ReadEncrypted() err = %v, want nil", err)
+	}
+	if !proto.Equal(got, eks) {
+		t.Errorf("ReadEncrypted() = %s, want %s", got, eks)
+	}
+}
+
+func TestTextReaderIgnoresCommentsAndBlankLines(t *testing.T) {
+	text := `
+# A keyset with a single key.
+primary_key_id: 42  # the primary
+key {
+  key_data {
+    type_url: "type.googleapis.com/#not-a-comment"
+    key_material_type: SYMMETRIC
+
+    value: "AQID"
+  }
+  status: ENABLED
+  key_id: 42
+  output_prefix_type: TINK
+}
+`
+	got, err := keyset.NewTextReader(strings.NewReader(text)).Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v, want nil", err)
+	}
+	want := &tinkpb.Keyset{
+		PrimaryKeyId: 42,
+		Key: []*tinkpb.Keyset_Key{
+			testutil.NewKey(
+				testutil.NewKeyData("type.googleapis.com/#not-a-comment", []byte{1, 2, 3}, tinkpb.KeyData_SYMMETRIC),
+				tinkpb.KeyStatusType_ENABLED, 42, tinkpb.OutputPrefixType_TINK),
+		},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("Read() = %s, want %s", got, want)
+	}
+}
+
+func TestTextReaderInvalidInput(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":       "primary_key_id: 1\nfoo: 2\n",
+		"duplicate field":     "primary_key_id: 1\nprimary_key_id: 2\n",
+		"negative key ID":     "primary_key_id: -1\n",
+		"key ID overflow":     "primary_key_id: 4294967296\n",
+		"unterminated":        "key {\n  key_id: 1\n",
+		"unbalanced":          "}\n",
+		"scalar as message":   "primary_key_id {\n}\n",
+		"message as scalar":   "key: 1\n",
+		"invalid enum":        "key {\n  status: ON\n}\n",
+		"invalid base64":      "key {\n  key_data {\n    value: \"!!\"\n  }\n}\n",
+		"unterminated string": "key {\n  key_data {\n    type_url: \"abc\n  }\n}\n",
+		"missing colon":       "primary_key_id 1\n",
+		"missing value":       "primary_key_id:\n",
+		"trailing garbage":    "key {\n  key_data {\n    type_url: \"a\" \"b\"\n  }\n}\n",
+	}
+	for name, text := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := keyset.NewTextReader(strings.NewReader(text)).Read(); err == nil {
+				t.Errorf("Read() err = nil, want error")
+			}
+		})
+	}
+}
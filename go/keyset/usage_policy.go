@@ -0,0 +1,80 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset
+
+import "fmt"
+
+// Operations passed to UsagePolicy.Allow by the primitive wrappers.
+const (
+	OpEncrypt    = "encrypt"
+	OpDecrypt    = "decrypt"
+	OpComputeMAC = "compute_mac"
+	OpVerifyMAC  = "verify_mac"
+	OpSign       = "sign"
+	OpVerify     = "verify"
+)
+
+// UsagePolicy decides whether a key of a keyset may be used, e.g. to limit
+// the number of operations per second. Allow is called with the ID of the key
+// and one of the Op constants before the key is used, and must be safe for
+// concurrent use. If it returns an error, the key is not used.
+type UsagePolicy interface {
+	Allow(keyID uint32, op string) error
+}
+
+// UsageDeniedError is returned by an operation of a primitive if the
+// UsagePolicy of its keyset handle denied the use of a key.
+type UsageDeniedError struct {
+	KeyID uint32
+	Op    string
+	// Err is the error returned by UsagePolicy.Allow.
+	Err error
+}
+
+func (e *UsageDeniedError) Error() string {
+	return fmt.Sprintf("keyset: %s with key %d denied by usage policy: %s", e.Op, e.KeyID, e.Err)
+}
+
+// WithUsagePolicy returns a handle for the same keyset as h whose primitives
+// consult p before each use of a key. The AEAD, MAC, Signer and Verifier
+// primitives created from the returned handle do so; for operations that use
+// the primary key, it is checked before the operation, and for decryption
+// and verification, every key that is tried is checked. Such an operation
+// fails with a *UsageDeniedError if a key was denied and no allowed key
+// succeeded. A nil p allows everything. h itself is not modified, and handles
+// derived from the returned handle, e.g. by Public, have no policy.
+func (h *Handle) WithUsagePolicy(p UsagePolicy) *Handle {
+	return &Handle{ks: h.ks, policy: p, cache: h.cache}
+}
+
+// UsagePolicy returns the usage policy of h, or nil if it has none.
+func (h *Handle) UsagePolicy() UsagePolicy {
+	return h.policy
+}
+
+// CheckUsage returns nil if p is nil or allows using the key with the given ID
+// for op, and a *UsageDeniedError otherwise. It is meant for primitive
+// wrappers.
+func CheckUsage(p UsagePolicy, keyID uint32, op string) error {
+	if p == nil {
+		return nil
+	}
+	if err := p.Allow(keyID, op); err != nil {
+		return &UsageDeniedError{KeyID: keyID, Op: op, Err: err}
+	}
+	return nil
+}
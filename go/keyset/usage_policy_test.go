@@ -0,0 +1,188 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package keyset_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/signature"
+)
+
+// quotaPolicy allows each operation at most a fixed number of times.
+type quotaPolicy struct {
+	mu    sync.Mutex
+	quota map[string]int
+}
+
+var errQuotaExceeded = errors.New("quota exceeded")
+
+func (p *quotaPolicy) Allow(keyID uint32, op string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.quota[op] <= 0 {
+		return errQuotaExceeded
+	}
+	p.quota[op]--
+	return nil
+}
+
+// denyAllPolicy denies every use of a key.
+type denyAllPolicy struct{}
+
+func (denyAllPolicy) Allow(keyID uint32, op string) error {
+	return errQuotaExceeded
+}
+
+// checkDenied fails the test unless err is a *keyset.UsageDeniedError for the
+// given key and operation.
+func checkDenied(t *testing.T, err error, keyID uint32, op string) {
+	t.Helper()
+	denied, ok := err.(*keyset.UsageDeniedError)
+	if !ok {
+		t.Fatalf("err = %v, want *keyset.UsageDeniedError", err)
+	}
+	if denied.KeyID != keyID || denied.Op != op || denied.Err != errQuotaExceeded {
+		t.Errorf("err = %+v, want key ID %d, op %q and Err %v", denied, keyID, op, errQuotaExceeded)
+	}
+}
+
+func TestUsagePolicyBlocksAfterNEncryptions(t *testing.T) {
+	const n = 3
+	h := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	primaryID := h.KeysetInfo().PrimaryKeyId
+	policy := &quotaPolicy{quota: map[string]int{keyset.OpEncrypt: n, keyset.OpDecrypt: 10}}
+	limited := h.WithUsagePolicy(policy)
+	if limited.UsagePolicy() != policy {
+		t.Errorf("limited.UsagePolicy() = %v, want %v", limited.UsagePolicy(), policy)
+	}
+	if h.UsagePolicy() != nil {
+		t.Errorf("h.UsagePolicy() = %v, want nil", h.UsagePolicy())
+	}
+
+	a, err := aead.New(limited)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	pt := []byte("plaintext")
+	var cts [][]byte
+	for i := 0; i < n; i++ {
+		ct, err := a.Encrypt(pt, nil)
+		if err != nil {
+			t.Fatalf("Encrypt() #%d err = %v, want nil", i, err)
+		}
+		cts = append(cts, ct)
+	}
+	_, err = a.Encrypt(pt, nil)
+	checkDenied(t, err, primaryID, keyset.OpEncrypt)
+
+	// Decryption has its own quota.
+	for _, ct := range cts {
+		if _, err := a.Decrypt(ct, nil); err != nil {
+			t.Errorf("Decrypt() err = %v, want nil", err)
+		}
+	}
+
+	// The original handle has no policy.
+	unlimited, err := aead.New(h)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v", err)
+	}
+	for i := 0; i <= n; i++ {
+		if _, err := unlimited.Encrypt(pt, nil); err != nil {
+			t.Fatalf("Encrypt() without policy err = %v, want nil", err)
+		}
+	}
+}
+
+func TestUsagePolicyDeniesDecryption(t *testing.T) {
+	h := newHandleFromTemplate(t, aead.AES128GCMKeyTemplate())
+	primaryID := h.KeysetInfo().PrimaryKeyId
+	ct := encryptWith(t, h, []byte("plaintext"))
+
+	for _, opts := range [][]aead.Option{nil, {aead.WithConcurrentTrialDecryption(4)}} {
+		a, err := aead.New(h.WithUsagePolicy(denyAllPolicy{}), opts...)
+		if err != nil {
+			t.Fatalf("aead.New() err = %v", err)
+		}
+		_, err = a.Decrypt(ct, nil)
+		checkDenied(t, err, primaryID, keyset.OpDecrypt)
+		// Ciphertexts that no key could decrypt are not affected.
+		if _, err := a.Decrypt([]byte("garbage"), nil); err != aead.ErrNoMatchingKey {
+			t.Errorf("Decrypt() of garbage err = %v, want %v", err, aead.ErrNoMatchingKey)
+		}
+	}
+}
+
+func TestUsagePolicyMAC(t *testing.T) {
+	h := newHandleFromTemplate(t, mac.HMACSHA256Tag256KeyTemplate())
+	primaryID := h.KeysetInfo().PrimaryKeyId
+	data := []byte("data")
+	m, err := mac.New(h)
+	if err != nil {
+		t.Fatalf("mac.New() err = %v", err)
+	}
+	tag, err := m.ComputeMAC(data)
+	if err != nil {
+		t.Fatalf("ComputeMAC() err = %v", err)
+	}
+
+	denied, err := mac.New(h.WithUsagePolicy(denyAllPolicy{}))
+	if err != nil {
+		t.Fatalf("mac.New() err = %v", err)
+	}
+	_, err = denied.ComputeMAC(data)
+	checkDenied(t, err, primaryID, keyset.OpComputeMAC)
+	err = denied.VerifyMAC(tag, data)
+	checkDenied(t, err, primaryID, keyset.OpVerifyMAC)
+}
+
+func TestUsagePolicySignature(t *testing.T) {
+	priv := newHandleFromTemplate(t, signature.ED25519KeyTemplate())
+	primaryID := priv.KeysetInfo().PrimaryKeyId
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v", err)
+	}
+	data := []byte("data")
+	signer, err := signature.NewSigner(priv)
+	if err != nil {
+		t.Fatalf("signature.NewSigner() err = %v", err)
+	}
+	sig, err := signer.Sign(data)
+	if err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+
+	deniedSigner, err := signature.NewSigner(priv.WithUsagePolicy(denyAllPolicy{}))
+	if err != nil {
+		t.Fatalf("signature.NewSigner() err = %v", err)
+	}
+	_, err = deniedSigner.Sign(data)
+	checkDenied(t, err, primaryID, keyset.OpSign)
+
+	deniedVerifier, err := signature.NewVerifier(pub.WithUsagePolicy(denyAllPolicy{}))
+	if err != nil {
+		t.Fatalf("signature.NewVerifier() err = %v", err)
+	}
+	err = deniedVerifier.Verify(sig, data)
+	checkDenied(t, err, primaryID, keyset.OpVerify)
+}
@@ -18,6 +18,7 @@ package keyset
 
 import (
 	"fmt"
+	"strings"
 
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
@@ -73,6 +74,66 @@ func Validate(keyset *tinkpb.Keyset) error {
 	return nil
 }
 
+// ValidateHandle runs structural checks over the keyset held by h that go
+// beyond what is needed to use it, and is meant to be called before a keyset
+// is promoted to production. It reports, all at once:
+//   - keys that fail the per-key checks done by Validate,
+//   - key IDs that are used by more than one key,
+//   - a primary key ID that does not refer to exactly one ENABLED key,
+//   - ENABLED RAW keys in a keyset that also contains TINK keys, since their
+//     ciphertexts cannot be attributed to a key.
+// It works for handles with and without secret key material.
+func ValidateHandle(h *Handle) error {
+	if h == nil || h.ks == nil {
+		return fmt.Errorf("ValidateHandle() called with nil")
+	}
+	ks := h.ks
+	if len(ks.Key) == 0 {
+		return fmt.Errorf("empty keyset")
+	}
+	var errs []string
+	seen := make(map[uint32]int)
+	numPrimary := 0
+	hasTink := false
+	var enabledRaw []uint32
+	for _, key := range ks.Key {
+		if err := validateKey(key); err != nil {
+			errs = append(errs, err.Error())
+			if key == nil {
+				continue
+			}
+		}
+		seen[key.KeyId]++
+		if seen[key.KeyId] == 2 {
+			errs = append(errs, fmt.Sprintf("key id %d is used by more than one key", key.KeyId))
+		}
+		if key.OutputPrefixType == tinkpb.OutputPrefixType_TINK {
+			hasTink = true
+		}
+		if key.Status != tinkpb.KeyStatusType_ENABLED {
+			continue
+		}
+		if key.KeyId == ks.PrimaryKeyId {
+			numPrimary++
+		}
+		if key.OutputPrefixType == tinkpb.OutputPrefixType_RAW {
+			enabledRaw = append(enabledRaw, key.KeyId)
+		}
+	}
+	if numPrimary == 0 {
+		errs = append(errs, fmt.Sprintf("primary key id %d does not refer to an ENABLED key", ks.PrimaryKeyId))
+	}
+	if hasTink {
+		for _, id := range enabledRaw {
+			errs = append(errs, fmt.Sprintf("key %d has RAW prefix in a keyset with TINK keys", id))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid keyset: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 /*
 validateKey validates the given key.
 Returns nil if it is valid; an error otherwise.
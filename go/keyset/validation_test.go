@@ -17,10 +17,14 @@
 package keyset_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/signature"
 	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/testkeyset"
 	"github.com/google/tink/go/testutil"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
@@ -111,3 +115,113 @@ func generateInvalidKeys() []*tinkpb.Keyset_Key {
 		testutil.NewKey(new(tinkpb.KeyData), tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_UNKNOWN_PREFIX),
 	}
 }
+
+func TestValidateHandle(t *testing.T) {
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	if err := keyset.ValidateHandle(h); err != nil {
+		t.Errorf("keyset.ValidateHandle() err = %v, want nil", err)
+	}
+	if err := keyset.ValidateHandle(nil); err == nil {
+		t.Errorf("keyset.ValidateHandle(nil) err = nil, want error")
+	}
+}
+
+func TestValidateHandleWithPublicHandle(t *testing.T) {
+	h, err := keyset.NewHandle(signature.ECDSAP256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	pub, err := h.Public()
+	if err != nil {
+		t.Fatalf("h.Public() err = %v", err)
+	}
+	if err := keyset.ValidateHandle(pub); err != nil {
+		t.Errorf("keyset.ValidateHandle() err = %v, want nil", err)
+	}
+}
+
+func TestValidateHandleRejectsDuplicateKeyIDs(t *testing.T) {
+	keys := []*tinkpb.Keyset_Key{
+		testutil.NewDummyKey(1, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_TINK),
+		testutil.NewDummyKey(2, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_TINK),
+		testutil.NewDummyKey(2, tinkpb.KeyStatusType_DISABLED, tinkpb.OutputPrefixType_TINK),
+	}
+	h, err := testkeyset.NewHandle(testutil.NewKeyset(1, keys))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v", err)
+	}
+	err = keyset.ValidateHandle(h)
+	if err == nil || !strings.Contains(err.Error(), "key id 2") {
+		t.Errorf("keyset.ValidateHandle() err = %v, want duplicate key id error", err)
+	}
+}
+
+func TestValidateHandleRejectsMissingPrimary(t *testing.T) {
+	keyData := testutil.NewKeyData(testutil.EciesAeadHkdfPublicKeyTypeURL, random.GetRandomBytes(10), tinkpb.KeyData_ASYMMETRIC_PUBLIC)
+	keys := []*tinkpb.Keyset_Key{
+		testutil.NewKey(keyData, tinkpb.KeyStatusType_ENABLED, 1, tinkpb.OutputPrefixType_TINK),
+	}
+	// Validate accepts public keysets without a primary key, ValidateHandle does not.
+	h, err := keyset.NewHandleWithNoSecrets(testutil.NewKeyset(2, keys))
+	if err != nil {
+		t.Fatalf("keyset.NewHandleWithNoSecrets() err = %v", err)
+	}
+	if err := keyset.ValidateHandle(h); err == nil {
+		t.Errorf("keyset.ValidateHandle() err = nil, want error")
+	}
+
+	keys = []*tinkpb.Keyset_Key{
+		testutil.NewDummyKey(1, tinkpb.KeyStatusType_DISABLED, tinkpb.OutputPrefixType_TINK),
+		testutil.NewDummyKey(2, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_TINK),
+	}
+	h, err = testkeyset.NewHandle(testutil.NewKeyset(1, keys))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v", err)
+	}
+	if err := keyset.ValidateHandle(h); err == nil {
+		t.Errorf("keyset.ValidateHandle() with a disabled primary err = nil, want error")
+	}
+}
+
+func TestValidateHandleRejectsRawKeysNextToTinkKeys(t *testing.T) {
+	keys := []*tinkpb.Keyset_Key{
+		testutil.NewDummyKey(1, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_TINK),
+		testutil.NewDummyKey(2, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_RAW),
+	}
+	h, err := testkeyset.NewHandle(testutil.NewKeyset(1, keys))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v", err)
+	}
+	if err := keyset.ValidateHandle(h); err == nil {
+		t.Errorf("keyset.ValidateHandle() err = nil, want error")
+	}
+
+	// A keyset made only of RAW keys is fine.
+	keys[0].OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	if err := keyset.ValidateHandle(h); err != nil {
+		t.Errorf("keyset.ValidateHandle() err = %v, want nil", err)
+	}
+}
+
+func TestValidateHandleReportsAllProblems(t *testing.T) {
+	keys := []*tinkpb.Keyset_Key{
+		testutil.NewDummyKey(1, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_TINK),
+		testutil.NewDummyKey(1, tinkpb.KeyStatusType_ENABLED, tinkpb.OutputPrefixType_RAW),
+	}
+	h, err := testkeyset.NewHandle(testutil.NewKeyset(3, keys))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() err = %v", err)
+	}
+	err = keyset.ValidateHandle(h)
+	if err == nil {
+		t.Fatal("keyset.ValidateHandle() err = nil, want error")
+	}
+	for _, want := range []string{"more than one key", "primary key id 3", "RAW prefix"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("keyset.ValidateHandle() err = %q, want it to mention %q", err, want)
+		}
+	}
+}
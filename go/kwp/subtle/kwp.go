@@ -62,13 +62,13 @@ type KWP struct {
 
 // NewKWP returns a KWP instance.
 //
-// The key argument should be the AES wrapping key, either 16 or 32 bytes
-// to select AES-128 or AES-256.
+// The key argument should be the AES wrapping key, either 16, 24 or 32 bytes
+// to select AES-128, AES-192 or AES-256.
 func NewKWP(wrappingKey []byte) (*KWP, error) {
 	switch len(wrappingKey) {
 	default:
-		return nil, fmt.Errorf("kwp: invalid AES key size; want 16 or 32, got %d", len(wrappingKey))
-	case 16, 32:
+		return nil, fmt.Errorf("kwp: invalid AES key size; want 16, 24 or 32, got %d", len(wrappingKey))
+	case 16, 24, 32:
 		block, err := aes.NewCipher(wrappingKey)
 		if err != nil {
 			return nil, fmt.Errorf("kwp: error building AES cipher: %v", err)
@@ -60,7 +60,7 @@ func TestWrapUnwrap(t *testing.T) {
 
 func TestKeySizes(t *testing.T) {
 	for i := 0; i < 255; i++ {
-		expectSuccess := i == 16 || i == 32
+		expectSuccess := i == 16 || i == 24 || i == 32
 		t.Run(fmt.Sprintf("KeySize%d", i), func(t *testing.T) {
 			_, err := subtle.NewKWP(make([]byte, i))
 
@@ -92,6 +92,58 @@ func TestInvalidWrappingSizes(t *testing.T) {
 	}
 }
 
+// TestRFC5649Vectors checks the example with a key of at least MinWrapSize
+// bytes from Section 6 of RFC 5649, which uses a 192-bit wrapping key.
+func TestRFC5649Vectors(t *testing.T) {
+	testCases := []struct {
+		kek     string
+		key     string
+		wrapped string
+	}{
+		{
+			kek:     "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			key:     "c37b7e6492584340bed12207808941155068f738",
+			wrapped: "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+	}
+	for _, tc := range testCases {
+		kek, err := hex.DecodeString(tc.kek)
+		if err != nil {
+			t.Fatalf("hex.DecodeString(tc.kek) => %v", err)
+		}
+		key, err := hex.DecodeString(tc.key)
+		if err != nil {
+			t.Fatalf("hex.DecodeString(tc.key) => %v", err)
+		}
+		wrapped, err := hex.DecodeString(tc.wrapped)
+		if err != nil {
+			t.Fatalf("hex.DecodeString(tc.wrapped) => %v", err)
+		}
+		cipher, err := subtle.NewKWP(kek)
+		if err != nil {
+			t.Fatalf("cannot create kwp, error: %v", err)
+		}
+		got, err := cipher.Wrap(key)
+		if err != nil {
+			t.Fatalf("cannot wrap, error: %v", err)
+		}
+		if !bytes.Equal(got, wrapped) {
+			t.Errorf("cipher.Wrap() = %x, want %x", got, wrapped)
+		}
+		got, err = cipher.Unwrap(wrapped)
+		if err != nil {
+			t.Fatalf("cannot unwrap, error: %v", err)
+		}
+		if !bytes.Equal(got, key) {
+			t.Errorf("cipher.Unwrap() = %x, want %x", got, key)
+		}
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := cipher.Unwrap(wrapped); err == nil {
+			t.Error("unwrapped a modified wrapped key")
+		}
+	}
+}
+
 type KwpCase struct {
 	testutil.WycheproofCase
 	Key        string `json:"key"`
@@ -126,10 +178,6 @@ func TestWycheproofCases(t *testing.T) {
 	}
 
 	for _, group := range suite.Groups {
-		if group.KeySize == 192 {
-			continue
-		}
-
 		for _, test := range group.Tests {
 			caseName := fmt.Sprintf("%s-%s(%d):Case-%d",
 				suite.Algorithm, group.Type, group.KeySize, test.CaseID)
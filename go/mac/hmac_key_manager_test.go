@@ -241,6 +241,8 @@ func genInvalidHMACKeyFormats() []proto.Message {
 		testutil.NewHMACKeyFormat(commonpb.HashType_SHA1, 21),
 		testutil.NewHMACKeyFormat(commonpb.HashType_SHA256, 33),
 		testutil.NewHMACKeyFormat(commonpb.HashType_SHA512, 65),
+		testutil.NewHMACKeyFormat(commonpb.HashType_SHA224, 29),
+		testutil.NewHMACKeyFormat(commonpb.HashType_SHA384, 49),
 		// tag size too small
 		testutil.NewHMACKeyFormat(commonpb.HashType_SHA256, 1),
 		// key too short
@@ -253,7 +255,9 @@ func genInvalidHMACKeyFormats() []proto.Message {
 func genValidHMACKeyFormats() []*hmacpb.HmacKeyFormat {
 	return []*hmacpb.HmacKeyFormat{
 		testutil.NewHMACKeyFormat(commonpb.HashType_SHA1, 20),
+		testutil.NewHMACKeyFormat(commonpb.HashType_SHA224, 28),
 		testutil.NewHMACKeyFormat(commonpb.HashType_SHA256, 32),
+		testutil.NewHMACKeyFormat(commonpb.HashType_SHA384, 48),
 		testutil.NewHMACKeyFormat(commonpb.HashType_SHA512, 64),
 	}
 }
@@ -261,7 +265,9 @@ func genValidHMACKeyFormats() []*hmacpb.HmacKeyFormat {
 func genValidHMACKeys() []*hmacpb.HmacKey {
 	return []*hmacpb.HmacKey{
 		testutil.NewHMACKey(commonpb.HashType_SHA1, 20),
+		testutil.NewHMACKey(commonpb.HashType_SHA224, 28),
 		testutil.NewHMACKey(commonpb.HashType_SHA256, 32),
+		testutil.NewHMACKey(commonpb.HashType_SHA384, 48),
 		testutil.NewHMACKey(commonpb.HashType_SHA512, 64),
 	}
 }
@@ -34,4 +34,7 @@ func init() {
 	if err := registry.RegisterKeyManager(newAESCMACKeyManager()); err != nil {
 		panic(fmt.Sprintf("mac.init() failed: %v", err))
 	}
+	if err := registry.RegisterKeyTemplates(SupportedTemplates()); err != nil {
+		panic(fmt.Sprintf("mac.init() failed: %v", err))
+	}
 }
@@ -17,12 +17,15 @@
 package mac
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/google/tink/go/core/cryptofmt"
 	"github.com/google/tink/go/core/primitiveset"
 	"github.com/google/tink/go/core/registry"
+	"github.com/google/tink/go/internal/bufpool"
 	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac/subtle"
 	"github.com/google/tink/go/tink"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
@@ -31,6 +34,38 @@ const (
 	maxInt = int(^uint(0) >> 1)
 )
 
+// PooledMAC is implemented by the MAC primitives returned by New.
+//
+// ComputeMACPooled is like ComputeMAC, but returns the tag in a buffer borrowed
+// from a pool, which saves an allocation per call. The caller must call
+// release exactly once and must neither use nor retain tag afterwards; use
+// ComputeMAC if the tag has to outlive the call.
+type PooledMAC interface {
+	tink.MAC
+	ComputeMACPooled(data []byte) (tag []byte, release func(), err error)
+}
+
+// Assert that wrappedMAC implements the PooledMAC interface.
+var _ PooledMAC = (*wrappedMAC)(nil)
+
+// ContextMAC is implemented by the MAC primitives returned by New.
+//
+// ComputeMACWithContext and VerifyMACWithContext authenticate data together
+// with a context label, e.g. the purpose of the tag. Each key of the keyset
+// authenticates data with a key derived with HKDF from its own key material
+// and context, see subtle.HMAC.WithContext and subtle.AESCMAC.WithContext, so
+// tags of one context can neither be computed with another context nor with
+// ComputeMAC. The output prefix and LEGACY handling of ComputeMAC and
+// VerifyMAC apply unchanged.
+type ContextMAC interface {
+	tink.MAC
+	ComputeMACWithContext(data, context []byte) ([]byte, error)
+	VerifyMACWithContext(mac, data, context []byte) error
+}
+
+// Assert that wrappedMAC implements the ContextMAC interface.
+var _ ContextMAC = (*wrappedMAC)(nil)
+
 // New creates a MAC primitive from the given keyset handle.
 func New(h *keyset.Handle) (tink.MAC, error) {
 	return NewWithKeyManager(h, nil /*keyManager*/)
@@ -44,30 +79,33 @@ func NewWithKeyManager(h *keyset.Handle, km registry.KeyManager) (tink.MAC, erro
 		return nil, fmt.Errorf("mac_factory: cannot obtain primitive set: %s", err)
 	}
 
-	return newWrappedMAC(ps)
+	return newWrappedMAC(ps, h.UsagePolicy())
 }
 
 // wrappedMAC is a MAC implementation that uses the underlying primitive set to compute and
 // verify MACs.
 type wrappedMAC struct {
 	ps *primitiveset.PrimitiveSet
+
+	// policy is consulted before each use of a key, see
+	// keyset.Handle.WithUsagePolicy.
+	policy keyset.UsagePolicy
 }
 
-func newWrappedMAC(ps *primitiveset.PrimitiveSet) (*wrappedMAC, error) {
+func newWrappedMAC(ps *primitiveset.PrimitiveSet, policy keyset.UsagePolicy) (*wrappedMAC, error) {
 	if _, ok := (ps.Primary.Primitive).(tink.MAC); !ok {
 		return nil, fmt.Errorf("mac_factory: not a MAC primitive")
 	}
 
-	for _, primitives := range ps.Entries {
-		for _, p := range primitives {
-			if _, ok := (p.Primitive).(tink.MAC); !ok {
-				return nil, fmt.Errorf("mac_factory: not an MAC primitive")
-			}
+	for _, p := range ps.AllEntries() {
+		if _, ok := (p.Primitive).(tink.MAC); !ok {
+			return nil, fmt.Errorf("mac_factory: not an MAC primitive")
 		}
 	}
 
 	ret := new(wrappedMAC)
 	ret.ps = ps
+	ret.policy = policy
 
 	return ret, nil
 }
@@ -75,43 +113,101 @@ func newWrappedMAC(ps *primitiveset.PrimitiveSet) (*wrappedMAC, error) {
 // ComputeMAC calculates a MAC over the given data using the primary primitive
 // and returns the concatenation of the primary's identifier and the calculated mac.
 func (m *wrappedMAC) ComputeMAC(data []byte) ([]byte, error) {
+	mac, err := m.computePrimaryMAC(data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(m.ps.Primary.Prefix), mac...), nil
+}
+
+// ComputeMACPooled is like ComputeMAC, but stores the result in a pooled
+// buffer, see PooledMAC.
+func (m *wrappedMAC) ComputeMACPooled(data []byte) ([]byte, func(), error) {
+	mac, err := m.computePrimaryMAC(data, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	prefix := m.ps.Primary.Prefix
+	buf := bufpool.Get(len(prefix) + len(mac))
+	buf.B = append(buf.B, prefix...)
+	buf.B = append(buf.B, mac...)
+	return buf.B, buf.ReleaseFunc(), nil
+}
+
+// computePrimaryMAC computes the MAC of data, without output prefix, with the
+// primary primitive. If derive is not nil, the MAC is computed with the
+// primitive that derive returns for the primary primitive instead.
+func (m *wrappedMAC) computePrimaryMAC(data []byte, derive deriveFunc) ([]byte, error) {
 	primary := m.ps.Primary
 	primitive, ok := (primary.Primitive).(tink.MAC)
 	if !ok {
 		return nil, fmt.Errorf("mac_factory: not a MAC primitive")
 	}
-	if m.ps.Primary.PrefixType == tinkpb.OutputPrefixType_LEGACY {
-		d := data
-		if len(d) == maxInt {
-			return nil, fmt.Errorf("mac_factory: data too long")
+	if err := keyset.CheckUsage(m.policy, primary.KeyID, keyset.OpComputeMAC); err != nil {
+		return nil, err
+	}
+	if derive != nil {
+		var err error
+		if primitive, err = derive(primitive); err != nil {
+			return nil, err
 		}
-		data = make([]byte, 0, len(d)+1)
-		data = append(data, d...)
-		data = append(data, byte(0))
 	}
-	mac, err := primitive.ComputeMAC(data)
-	if err != nil {
-		return nil, err
+	if primary.PrefixType != tinkpb.OutputPrefixType_LEGACY {
+		return primitive.ComputeMAC(data)
+	}
+	if len(data) == maxInt {
+		return nil, fmt.Errorf("mac_factory: data too long")
 	}
-	return append([]byte(primary.Prefix), mac...), nil
+	buf := legacyData(data)
+	defer buf.Release()
+	return primitive.ComputeMAC(buf.B)
 }
 
-var errInvalidMAC = fmt.Errorf("mac_factory: invalid mac")
+// legacyData returns data || 0x00 in a pooled buffer, which is what LEGACY
+// keys authenticate.
+func legacyData(data []byte) bufpool.Buffer {
+	buf := bufpool.Get(len(data) + 1)
+	buf.B = append(buf.B, data...)
+	buf.B = append(buf.B, byte(0))
+	return buf
+}
+
+var (
+	// ErrNoMatchingKey is returned by the VerifyMAC method of the MAC
+	// primitives returned by New if no key of the keyset was tried, i.e. the
+	// MAC prefix matches no key and there are no RAW keys to try.
+	ErrNoMatchingKey = errors.New("mac_factory: invalid mac: no matching key")
+	// ErrInvalidMAC is returned by the VerifyMAC method of the MAC primitives
+	// returned by New if the MAC is too short, or if at least one key was
+	// tried, but none of them verified the MAC.
+	ErrInvalidMAC = errors.New("mac_factory: invalid mac")
+)
 
 // VerifyMAC verifies whether the given mac is a correct authentication code
 // for the given data.
 func (m *wrappedMAC) VerifyMAC(mac, data []byte) error {
+	return m.verifyMAC(mac, data, nil)
+}
+
+// verifyMAC verifies mac for data with the keys that match its prefix and the
+// RAW keys. If derive is not nil, each key verifies with the primitive that
+// derive returns for its primitive instead.
+func (m *wrappedMAC) verifyMAC(mac, data []byte, derive deriveFunc) error {
 	// This also rejects raw MAC with size of 4 bytes or fewer. Those MACs are
 	// clearly insecure, thus should be discouraged.
 	prefixSize := cryptofmt.NonRawPrefixSize
 	if len(mac) <= prefixSize {
-		return errInvalidMAC
+		return ErrInvalidMAC
 	}
 
 	// try non raw keys
 	prefix := mac[:prefixSize]
 	macNoPrefix := mac[prefixSize:]
 	entries, err := m.ps.EntriesForPrefix(string(prefix))
+	// matched is true if a key was tried.
+	matched := err == nil && len(entries) > 0
+	// denied is the first error of the usage policy.
+	var denied error
 	if err == nil {
 		for i := 0; i < len(entries); i++ {
 			entry := entries[i]
@@ -119,16 +215,28 @@ func (m *wrappedMAC) VerifyMAC(mac, data []byte) error {
 			if !ok {
 				return fmt.Errorf("mac_factory: not an MAC primitive")
 			}
+			if err := keyset.CheckUsage(m.policy, entry.KeyID, keyset.OpVerifyMAC); err != nil {
+				if denied == nil {
+					denied = err
+				}
+				continue
+			}
+			if derive != nil {
+				if p, err = derive(p); err != nil {
+					return err
+				}
+			}
 			if entry.PrefixType == tinkpb.OutputPrefixType_LEGACY {
-				d := data
-				if len(d) == maxInt {
+				if len(data) == maxInt {
 					return fmt.Errorf("mac_factory: data too long")
 				}
-				data = make([]byte, 0, len(d)+1)
-				data = append(data, d...)
-				data = append(data, byte(0))
+				buf := legacyData(data)
+				err = p.VerifyMAC(macNoPrefix, buf.B)
+				buf.Release()
+			} else {
+				err = p.VerifyMAC(macNoPrefix, data)
 			}
-			if err = p.VerifyMAC(macNoPrefix, data); err == nil {
+			if err == nil {
 				return nil
 			}
 		}
@@ -137,11 +245,23 @@ func (m *wrappedMAC) VerifyMAC(mac, data []byte) error {
 	// try raw keys
 	entries, err = m.ps.RawEntries()
 	if err == nil {
+		matched = matched || len(entries) > 0
 		for i := 0; i < len(entries); i++ {
 			p, ok := (entries[i].Primitive).(tink.MAC)
 			if !ok {
 				return fmt.Errorf("mac_factory: not an MAC primitive")
 			}
+			if err := keyset.CheckUsage(m.policy, entries[i].KeyID, keyset.OpVerifyMAC); err != nil {
+				if denied == nil {
+					denied = err
+				}
+				continue
+			}
+			if derive != nil {
+				if p, err = derive(p); err != nil {
+					return err
+				}
+			}
 
 			if err = p.VerifyMAC(mac, data); err == nil {
 				return nil
@@ -150,5 +270,52 @@ func (m *wrappedMAC) VerifyMAC(mac, data []byte) error {
 	}
 
 	// nothing worked
-	return errInvalidMAC
+	if denied != nil {
+		return denied
+	}
+	if !matched {
+		return ErrNoMatchingKey
+	}
+	return ErrInvalidMAC
+}
+
+// deriveFunc returns the MAC primitive that a key uses instead of p, e.g. the
+// one for a context.
+type deriveFunc func(p tink.MAC) (tink.MAC, error)
+
+// withContext returns the deriveFunc of the primitives for context.
+func withContext(context []byte) deriveFunc {
+	return func(p tink.MAC) (tink.MAC, error) {
+		switch p := p.(type) {
+		case *subtle.HMAC:
+			derived, err := p.WithContext(context)
+			if err != nil {
+				return nil, fmt.Errorf("mac_factory: %s", err)
+			}
+			return derived, nil
+		case *subtle.AESCMAC:
+			derived, err := p.WithContext(context)
+			if err != nil {
+				return nil, fmt.Errorf("mac_factory: %s", err)
+			}
+			return derived, nil
+		default:
+			return nil, fmt.Errorf("mac_factory: %T does not support contexts", p)
+		}
+	}
+}
+
+// ComputeMACWithContext computes the MAC of data for context, see ContextMAC.
+func (m *wrappedMAC) ComputeMACWithContext(data, context []byte) ([]byte, error) {
+	mac, err := m.computePrimaryMAC(data, withContext(context))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(m.ps.Primary.Prefix), mac...), nil
+}
+
+// VerifyMACWithContext verifies whether mac is a correct authentication code
+// for data and context, see ContextMAC.
+func (m *wrappedMAC) VerifyMACWithContext(mac, data, context []byte) error {
+	return m.verifyMAC(mac, data, withContext(context))
 }
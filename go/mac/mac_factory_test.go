@@ -17,8 +17,10 @@
 package mac_test
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
@@ -242,3 +244,255 @@ func TestFactoryWithValidPrimitiveSetType(t *testing.T) {
 		t.Fatalf("calling New() with good *keyset.Handle failed: %s", err)
 	}
 }
+
+func TestComputeMACPooled(t *testing.T) {
+	for _, prefixType := range []tinkpb.OutputPrefixType{
+		tinkpb.OutputPrefixType_TINK,
+		tinkpb.OutputPrefixType_LEGACY,
+		tinkpb.OutputPrefixType_RAW,
+	} {
+		kh, err := testkeyset.NewHandle(testutil.NewTestHMACKeyset(16, prefixType))
+		if err != nil {
+			t.Fatalf("testkeyset.NewHandle failed: %s", err)
+		}
+		p, err := mac.New(kh)
+		if err != nil {
+			t.Fatalf("mac.New failed: %s", err)
+		}
+		pooled, ok := p.(mac.PooledMAC)
+		if !ok {
+			t.Fatal("mac.New did not return a mac.PooledMAC")
+		}
+		data := []byte("some data")
+		want, err := p.ComputeMAC(data)
+		if err != nil {
+			t.Fatalf("ComputeMAC failed: %s", err)
+		}
+		tag, release, err := pooled.ComputeMACPooled(data)
+		if err != nil {
+			t.Fatalf("ComputeMACPooled failed: %s", err)
+		}
+		if !bytes.Equal(tag, want) {
+			t.Errorf("%s: ComputeMACPooled = %x, want %x", prefixType, tag, want)
+		}
+		if err := p.VerifyMAC(tag, data); err != nil {
+			t.Errorf("%s: VerifyMAC of pooled tag failed: %s", prefixType, err)
+		}
+		release()
+	}
+}
+
+func TestComputeMACPooledConcurrently(t *testing.T) {
+	kh, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle failed: %s", err)
+	}
+	p, err := mac.New(kh)
+	if err != nil {
+		t.Fatalf("mac.New failed: %s", err)
+	}
+	pooled := p.(mac.PooledMAC)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf("data %d", i))
+			want, err := p.ComputeMAC(data)
+			if err != nil {
+				t.Errorf("ComputeMAC failed: %s", err)
+				return
+			}
+			for j := 0; j < 100; j++ {
+				tag, release, err := pooled.ComputeMACPooled(data)
+				if err != nil {
+					t.Errorf("ComputeMACPooled failed: %s", err)
+					return
+				}
+				if !bytes.Equal(tag, want) {
+					t.Errorf("ComputeMACPooled = %x, want %x", tag, want)
+				}
+				release()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkComputeMAC(b *testing.B) {
+	kh, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
+	if err != nil {
+		b.Fatalf("keyset.NewHandle failed: %s", err)
+	}
+	p, err := mac.New(kh)
+	if err != nil {
+		b.Fatalf("mac.New failed: %s", err)
+	}
+	data := make([]byte, 256)
+	b.Run("ComputeMAC", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := p.ComputeMAC(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ComputeMACPooled", func(b *testing.B) {
+		b.ReportAllocs()
+		pooled := p.(mac.PooledMAC)
+		for i := 0; i < b.N; i++ {
+			_, release, err := pooled.ComputeMACPooled(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			release()
+		}
+	})
+}
+
+func TestVerifyMACErrors(t *testing.T) {
+	newMAC := func(template *tinkpb.KeyTemplate) tink.MAC {
+		t.Helper()
+		h, err := keyset.NewHandle(template)
+		if err != nil {
+			t.Fatalf("keyset.NewHandle() err = %v", err)
+		}
+		m, err := mac.New(h)
+		if err != nil {
+			t.Fatalf("mac.New() err = %v", err)
+		}
+		return m
+	}
+	rawTemplate := mac.HMACSHA256Tag256KeyTemplate()
+	rawTemplate.OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	m := newMAC(mac.HMACSHA256Tag256KeyTemplate())
+	other := newMAC(mac.HMACSHA256Tag256KeyTemplate())
+	raw := newMAC(rawTemplate)
+	data := []byte("data")
+	tag, err := m.ComputeMAC(data)
+	if err != nil {
+		t.Fatalf("m.ComputeMAC() err = %v", err)
+	}
+	tampered := append([]byte{}, tag...)
+	tampered[len(tampered)-1] ^= 1
+
+	var testCases = []struct {
+		name string
+		m    tink.MAC
+		tag  []byte
+		want error
+	}{
+		{name: "tampered MAC", m: m, tag: tampered, want: mac.ErrInvalidMAC},
+		{name: "short MAC", m: m, tag: tag[:cryptofmt.NonRawPrefixSize], want: mac.ErrInvalidMAC},
+		{name: "unknown key", m: other, tag: tag, want: mac.ErrNoMatchingKey},
+		{name: "unknown key with RAW keys", m: raw, tag: tag, want: mac.ErrInvalidMAC},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.m.VerifyMAC(tc.tag, data); err != tc.want {
+				t.Errorf("VerifyMAC() err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestComputeMACWithContext(t *testing.T) {
+	for _, prefixType := range []tinkpb.OutputPrefixType{
+		tinkpb.OutputPrefixType_TINK,
+		tinkpb.OutputPrefixType_LEGACY,
+		tinkpb.OutputPrefixType_RAW,
+	} {
+		kh, err := testkeyset.NewHandle(testutil.NewTestHMACKeyset(16, prefixType))
+		if err != nil {
+			t.Fatalf("testkeyset.NewHandle failed: %s", err)
+		}
+		p, err := mac.New(kh)
+		if err != nil {
+			t.Fatalf("mac.New failed: %s", err)
+		}
+		cm, ok := p.(mac.ContextMAC)
+		if !ok {
+			t.Fatal("mac.New did not return a mac.ContextMAC")
+		}
+		tag, err := cm.ComputeMACWithContext([]byte("c"), []byte("ab"))
+		if err != nil {
+			t.Fatalf("ComputeMACWithContext failed: %s", err)
+		}
+		if err := cm.VerifyMACWithContext(tag, []byte("c"), []byte("ab")); err != nil {
+			t.Errorf("%s: VerifyMACWithContext failed: %s", prefixType, err)
+		}
+		// Tags with a context are not MACs of any input of plain VerifyMAC, in
+		// particular not of the encoding of an earlier version.
+		for _, input := range [][]byte{[]byte("c"), []byte("abc"), []byte{0, 0, 0, 2, 'a', 'b', 'c'}} {
+			if err := p.VerifyMAC(tag, input); err == nil {
+				t.Errorf("%s: VerifyMAC(%q) of a tag with context succeeded", prefixType, input)
+			}
+		}
+		plain, err := p.ComputeMAC([]byte("c"))
+		if err != nil {
+			t.Fatalf("ComputeMAC failed: %s", err)
+		}
+		if err := cm.VerifyMACWithContext(plain, []byte("c"), []byte("ab")); err == nil {
+			t.Errorf("%s: VerifyMACWithContext of a plain tag succeeded", prefixType)
+		}
+
+		// Moving bytes between context and data changes the tag.
+		shifted, err := cm.ComputeMACWithContext([]byte("bc"), []byte("a"))
+		if err != nil {
+			t.Fatalf("ComputeMACWithContext failed: %s", err)
+		}
+		if bytes.Equal(tag, shifted) {
+			t.Errorf("%s: tags of (ab, c) and (a, bc) are equal", prefixType)
+		}
+		if err := cm.VerifyMACWithContext(tag, []byte("bc"), []byte("a")); err == nil {
+			t.Errorf("%s: VerifyMACWithContext with shifted context succeeded", prefixType)
+		}
+		if err := p.VerifyMAC(tag, []byte("abc")); err == nil {
+			t.Errorf("%s: VerifyMAC of concatenation succeeded", prefixType)
+		}
+
+		// Distinct contexts give distinct tags.
+		other, err := cm.ComputeMACWithContext([]byte("c"), []byte("other"))
+		if err != nil {
+			t.Fatalf("ComputeMACWithContext failed: %s", err)
+		}
+		if bytes.Equal(tag, other) {
+			t.Errorf("%s: tags with different contexts are equal", prefixType)
+		}
+		if err := cm.VerifyMACWithContext(tag, []byte("c"), []byte("other")); err == nil {
+			t.Errorf("%s: VerifyMACWithContext with other context succeeded", prefixType)
+		}
+		empty, err := cm.ComputeMACWithContext([]byte("c"), nil)
+		if err != nil {
+			t.Fatalf("ComputeMACWithContext failed: %s", err)
+		}
+		if err := cm.VerifyMACWithContext(empty, []byte("c"), nil); err != nil {
+			t.Errorf("%s: VerifyMACWithContext with empty context failed: %s", prefixType, err)
+		}
+	}
+}
+
+func TestComputeMACWithContextAESCMAC(t *testing.T) {
+	kh, err := keyset.NewHandle(mac.AESCMACTag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle failed: %s", err)
+	}
+	p, err := mac.New(kh)
+	if err != nil {
+		t.Fatalf("mac.New failed: %s", err)
+	}
+	cm := p.(mac.ContextMAC)
+	tag, err := cm.ComputeMACWithContext([]byte("data"), []byte("context"))
+	if err != nil {
+		t.Fatalf("ComputeMACWithContext failed: %s", err)
+	}
+	if err := cm.VerifyMACWithContext(tag, []byte("data"), []byte("context")); err != nil {
+		t.Errorf("VerifyMACWithContext failed: %s", err)
+	}
+	if err := cm.VerifyMACWithContext(tag, []byte("data"), []byte("other")); err == nil {
+		t.Error("VerifyMACWithContext with other context succeeded")
+	}
+	if err := p.VerifyMAC(tag, []byte("data")); err == nil {
+		t.Error("VerifyMAC of a tag with context succeeded")
+	}
+}
@@ -26,6 +26,29 @@ import (
 
 // This file contains pre-generated KeyTemplate for MAC.
 
+// SupportedTemplates returns the MAC key templates of this package by their
+// canonical names, such as "HMAC_SHA256_128BITTAG". These are the names used by
+// Tinkey. Each call returns a new map.
+func SupportedTemplates() map[string]func() *tinkpb.KeyTemplate {
+	return map[string]func() *tinkpb.KeyTemplate{
+		"HMAC_SHA224_112BITTAG": HMACSHA224Tag112KeyTemplate,
+		"HMAC_SHA256_128BITTAG": HMACSHA256Tag128KeyTemplate,
+		"HMAC_SHA256_256BITTAG": HMACSHA256Tag256KeyTemplate,
+		"HMAC_SHA384_192BITTAG": HMACSHA384Tag192KeyTemplate,
+		"HMAC_SHA512_256BITTAG": HMACSHA512Tag256KeyTemplate,
+		"HMAC_SHA512_512BITTAG": HMACSHA512Tag512KeyTemplate,
+		"AES_CMAC":              AESCMACTag128KeyTemplate,
+	}
+}
+
+// HMACSHA224Tag112KeyTemplate is a KeyTemplate that generates a HMAC key with the following parameters:
+//   - Key size: 28 bytes
+//   - Tag size: 14 bytes
+//   - Hash function: SHA224
+func HMACSHA224Tag112KeyTemplate() *tinkpb.KeyTemplate {
+	return createHMACKeyTemplate(28, 14, commonpb.HashType_SHA224)
+}
+
 // HMACSHA256Tag128KeyTemplate is a KeyTemplate that generates a HMAC key with the following parameters:
 //   - Key size: 32 bytes
 //   - Tag size: 16 bytes
@@ -42,6 +65,14 @@ func HMACSHA256Tag256KeyTemplate() *tinkpb.KeyTemplate {
 	return createHMACKeyTemplate(32, 32, commonpb.HashType_SHA256)
 }
 
+// HMACSHA384Tag192KeyTemplate is a KeyTemplate that generates a HMAC key with the following parameters:
+//   - Key size: 48 bytes
+//   - Tag size: 24 bytes
+//   - Hash function: SHA384
+func HMACSHA384Tag192KeyTemplate() *tinkpb.KeyTemplate {
+	return createHMACKeyTemplate(48, 24, commonpb.HashType_SHA384)
+}
+
 // HMACSHA512Tag256KeyTemplate is a KeyTemplate that generates a HMAC key with the following parameters:
 //   - Key size: 64 bytes
 //   - Tag size: 32 bytes
@@ -20,9 +20,12 @@ import (
 	"testing"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/core/cryptofmt"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/mac"
 	"github.com/google/tink/go/testutil"
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	hmacpb "github.com/google/tink/go/proto/hmac_go_proto"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
@@ -95,3 +98,68 @@ func TestKeyTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportedTemplates(t *testing.T) {
+	templates := mac.SupportedTemplates()
+	if got, want := templates["HMAC_SHA256_128BITTAG"](), mac.HMACSHA256Tag128KeyTemplate(); !proto.Equal(got, want) {
+		t.Errorf("SupportedTemplates()[%q]() = %s, want %s", "HMAC_SHA256_128BITTAG", got, want)
+	}
+	for name, newTemplate := range templates {
+		template := newTemplate()
+		if _, err := keyset.NewHandle(template); err != nil {
+			t.Errorf("keyset.NewHandle(SupportedTemplates()[%q]()) failed: %s", name, err)
+		}
+	}
+}
+
+func TestHMACSHA224AndSHA384KeyTemplates(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		template *tinkpb.KeyTemplate
+		hash     commonpb.HashType
+		tagSize  uint32
+	}{
+		{name: "HMAC_SHA224_112BITTAG",
+			template: mac.HMACSHA224Tag112KeyTemplate(),
+			hash:     commonpb.HashType_SHA224,
+			tagSize:  14},
+		{name: "HMAC_SHA384_192BITTAG",
+			template: mac.HMACSHA384Tag192KeyTemplate(),
+			hash:     commonpb.HashType_SHA384,
+			tagSize:  24},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			format := new(hmacpb.HmacKeyFormat)
+			if err := proto.Unmarshal(tc.template.Value, format); err != nil {
+				t.Fatalf("proto.Unmarshal() failed: %v", err)
+			}
+			if format.Params.Hash != tc.hash || format.Params.TagSize != tc.tagSize {
+				t.Errorf("format.Params = %s, want hash %s and tag size %d", format.Params, tc.hash, tc.tagSize)
+			}
+
+			handle, err := keyset.NewHandle(tc.template)
+			if err != nil {
+				t.Fatalf("keyset.NewHandle(tc.template) failed: %v", err)
+			}
+			primitive, err := mac.New(handle)
+			if err != nil {
+				t.Fatalf("mac.New(handle) failed: %v", err)
+			}
+			data := []byte("this data needs to be authenticated")
+			tag, err := primitive.ComputeMAC(data)
+			if err != nil {
+				t.Fatalf("primitive.ComputeMAC(data) failed: %v", err)
+			}
+			if got, want := len(tag), cryptofmt.NonRawPrefixSize+int(tc.tagSize); got != want {
+				t.Errorf("len(tag) = %d, want %d", got, want)
+			}
+			if err := primitive.VerifyMAC(tag, data); err != nil {
+				t.Errorf("primitive.VerifyMAC(tag, data) failed: %v", err)
+			}
+			if err := primitive.VerifyMAC(tag, []byte("other data")); err == nil {
+				t.Error("primitive.VerifyMAC(tag, other data) succeeded, want error")
+			}
+		})
+	}
+}
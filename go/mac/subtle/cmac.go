@@ -17,12 +17,11 @@
 package subtle
 
 import (
-	"crypto/subtle"
+	"crypto/sha256"
 	"fmt"
 
 	subtleprf "github.com/google/tink/go/prf/subtle"
-
-	// Placeholder for internal crypto/subtle allowlist, please ignore.
+	"github.com/google/tink/go/subtle"
 )
 
 const (
@@ -35,6 +34,7 @@ const (
 // AESCMAC represents an AES-CMAC struct that implements the MAC interface.
 type AESCMAC struct {
 	prf       *subtleprf.AESCMACPRF
+	key       []byte
 	tagLength uint32
 }
 
@@ -55,10 +55,24 @@ func NewAESCMAC(key []byte, tagLength uint32) (*AESCMAC, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Could not create AES-CMAC prf: %v", err)
 	}
+	ac.key = key
 	ac.tagLength = tagLength
 	return ac, nil
 }
 
+// WithContext returns an AESCMAC with the same key size and tag length whose
+// key is derived with HKDF-SHA256 from the key of a and context. The derived
+// keys of distinct contexts are independent of each other and of the key of
+// a, so tags of the returned AESCMAC cannot be computed with a or with the
+// AESCMAC of another context.
+func (a AESCMAC) WithContext(context []byte) (*AESCMAC, error) {
+	key, err := deriveContextKey(sha256.New, a.key, context)
+	if err != nil {
+		return nil, err
+	}
+	return NewAESCMAC(key, a.tagLength)
+}
+
 // ComputeMAC computes message authentication code (MAC) for code data.
 func (a AESCMAC) ComputeMAC(data []byte) ([]byte, error) {
 	return a.prf.ComputePRF(data, a.tagLength)
@@ -71,7 +85,7 @@ func (a AESCMAC) VerifyMAC(mac, data []byte) error {
 	if err != nil {
 		return fmt.Errorf("Could not compute MAC: %v", err)
 	}
-	if subtle.ConstantTimeCompare(mac, computed) != 1 {
+	if !subtle.ConstantTimeCompare(mac, computed) {
 		return fmt.Errorf("CMAC: Invalid MAC")
 	}
 	return nil
@@ -153,6 +153,39 @@ func TestCMACBasic(t *testing.T) {
 	}
 }
 
+func TestCMACWithContext(t *testing.T) {
+	key := random.GetRandomBytes(32)
+	a, err := subtle.NewAESCMAC(key, 16)
+	if err != nil {
+		t.Fatalf("subtle.NewAESCMAC() err = %v", err)
+	}
+	c, err := a.WithContext([]byte("context"))
+	if err != nil {
+		t.Fatalf("a.WithContext() err = %v", err)
+	}
+	want, err := subtle.NewAESCMAC(contextKey(t, key, []byte("context")), 16)
+	if err != nil {
+		t.Fatalf("subtle.NewAESCMAC() err = %v", err)
+	}
+	tag, err := c.ComputeMAC(dataRFC4493)
+	if err != nil {
+		t.Fatalf("c.ComputeMAC() err = %v", err)
+	}
+	if err := want.VerifyMAC(tag, dataRFC4493); err != nil {
+		t.Errorf("tag of c is not the AES-CMAC with the HKDF-derived key: %v", err)
+	}
+	if err := a.VerifyMAC(tag, dataRFC4493); err == nil {
+		t.Error("a.VerifyMAC() of a tag with context succeeded, want error")
+	}
+	other, err := a.WithContext([]byte("other"))
+	if err != nil {
+		t.Fatalf("a.WithContext() err = %v", err)
+	}
+	if err := other.VerifyMAC(tag, dataRFC4493); err == nil {
+		t.Error("VerifyMAC() with another context succeeded, want error")
+	}
+}
+
 func TestNewCMACWithInvalidInput(t *testing.T) {
 	// key too short
 	_, err := subtle.NewAESCMAC(random.GetRandomBytes(1), 16)
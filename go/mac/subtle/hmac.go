@@ -22,6 +22,9 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
 
 	"github.com/google/tink/go/subtle"
 )
@@ -32,6 +35,10 @@ const (
 
 	// Minimum tag size in bytes. This provides minimum 80-bit security strength.
 	minTagSizeInBytes = uint32(10)
+
+	// contextKeyInfo is the prefix of the HKDF info of the keys derived by
+	// HMAC.WithContext and AESCMAC.WithContext. The context follows it.
+	contextKeyInfo = "tink mac context key"
 )
 
 var errHMACInvalidInput = errors.New("HMAC: invalid input")
@@ -97,8 +104,34 @@ func (h *HMAC) VerifyMAC(mac []byte, data []byte) error {
 	if err != nil {
 		return err
 	}
-	if hmac.Equal(expectedMAC, mac) {
+	if subtle.ConstantTimeCompare(expectedMAC, mac) {
 		return nil
 	}
 	return errors.New("HMAC: invalid MAC")
 }
+
+// WithContext returns an HMAC with the same hash function and tag size whose
+// key is derived with HKDF from h.Key and context. The derived keys of
+// distinct contexts are independent of each other and of h.Key, so tags of
+// the returned HMAC cannot be computed with h or with the HMAC of another
+// context.
+func (h *HMAC) WithContext(context []byte) (*HMAC, error) {
+	key, err := deriveContextKey(h.HashFunc, h.Key, context)
+	if err != nil {
+		return nil, err
+	}
+	return &HMAC{HashFunc: h.HashFunc, Key: key, TagSize: h.TagSize}, nil
+}
+
+// deriveContextKey derives a key of the size of key from key and context with
+// HKDF, using hashFunc and no salt.
+func deriveContextKey(hashFunc func() hash.Hash, key, context []byte) ([]byte, error) {
+	info := make([]byte, 0, len(contextKeyInfo)+len(context))
+	info = append(info, contextKeyInfo...)
+	info = append(info, context...)
+	derived := make([]byte, len(key))
+	if _, err := io.ReadFull(hkdf.New(hashFunc, key, nil, info), derived); err != nil {
+		return nil, fmt.Errorf("cannot derive context key: %s", err)
+	}
+	return derived, nil
+}
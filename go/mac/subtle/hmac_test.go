@@ -17,12 +17,19 @@
 package subtle_test
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
 
+	"golang.org/x/crypto/hkdf"
+
 	"github.com/google/tink/go/mac/subtle"
 	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/testutil"
 )
 
 var key, _ = hex.DecodeString("000102030405060708090a0b0c0d0e0f")
@@ -49,6 +56,21 @@ var hmacTests = []struct {
 		expectedMac: "481e10d823ba64c15b94537a3de3f253c16642451ac45124dd4dde120bf1e5c15" +
 			"e55487d55ba72b43039f235226e7954cd5854b30abc4b5b53171a4177047c9b",
 	},
+	{
+		hashAlg:     "SHA224",
+		tagSize:     28,
+		data:        data,
+		key:         key,
+		expectedMac: "39f2ac028dec678b103dc25bcde4d1adba1f0d4bd43db98e44ebf356",
+	},
+	{
+		hashAlg: "SHA384",
+		tagSize: 48,
+		data:    data,
+		key:     key,
+		expectedMac: "404593418e3ed5ce081381b562a276b03d70fb13568484634c986f67" +
+			"f1fd7637715b73c16709328ae6be50ef5f0be487",
+	},
 	// empty data
 	{
 		hashAlg:     "SHA256",
@@ -108,6 +130,14 @@ func TestNewHMACWithInvalidInput(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "tag size too big") {
 		t.Errorf("expect an error when tag size is too big")
 	}
+	_, err = subtle.NewHMAC("SHA224", random.GetRandomBytes(16), 29)
+	if err == nil || !strings.Contains(err.Error(), "tag size too big") {
+		t.Errorf("expect an error when tag size is too big")
+	}
+	_, err = subtle.NewHMAC("SHA384", random.GetRandomBytes(16), 49)
+	if err == nil || !strings.Contains(err.Error(), "tag size too big") {
+		t.Errorf("expect an error when tag size is too big")
+	}
 }
 
 func TestHMAComputeVerifyWithNilInput(t *testing.T) {
@@ -177,3 +207,117 @@ func TestHMACTruncation(t *testing.T) {
 		}
 	}
 }
+
+type hmacSuite struct {
+	testutil.WycheproofSuite
+	TestGroups []*hmacGroup `json:"testGroups"`
+}
+
+type hmacGroup struct {
+	testutil.WycheproofGroup
+	KeySize uint32      `json:"keySize"`
+	TagSize uint32      `json:"tagSize"`
+	Tests   []*hmacCase `json:"tests"`
+}
+
+type hmacCase struct {
+	testutil.WycheproofCase
+	Key     testutil.HexBytes `json:"key"`
+	Message testutil.HexBytes `json:"msg"`
+	Tag     testutil.HexBytes `json:"tag"`
+}
+
+// contextKey returns the key that WithContext derives from key and context.
+func contextKey(t *testing.T, key, context []byte) []byte {
+	t.Helper()
+	derived := make([]byte, len(key))
+	info := append([]byte("tink mac context key"), context...)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, info), derived); err != nil {
+		t.Fatalf("hkdf failed: %v", err)
+	}
+	return derived
+}
+
+func TestHMACWithContext(t *testing.T) {
+	key := random.GetRandomBytes(32)
+	h, err := subtle.NewHMAC("SHA256", key, 16)
+	if err != nil {
+		t.Fatalf("subtle.NewHMAC() err = %v", err)
+	}
+	c, err := h.WithContext([]byte("context"))
+	if err != nil {
+		t.Fatalf("h.WithContext() err = %v", err)
+	}
+	want, err := subtle.NewHMAC("SHA256", contextKey(t, key, []byte("context")), 16)
+	if err != nil {
+		t.Fatalf("subtle.NewHMAC() err = %v", err)
+	}
+	tag, err := c.ComputeMAC(data)
+	if err != nil {
+		t.Fatalf("c.ComputeMAC() err = %v", err)
+	}
+	if err := want.VerifyMAC(tag, data); err != nil {
+		t.Errorf("tag of c is not the HMAC with the HKDF-derived key: %v", err)
+	}
+	if err := h.VerifyMAC(tag, data); err == nil {
+		t.Error("h.VerifyMAC() of a tag with context succeeded, want error")
+	}
+	other, err := h.WithContext([]byte("other"))
+	if err != nil {
+		t.Fatalf("h.WithContext() err = %v", err)
+	}
+	if err := other.VerifyMAC(tag, data); err == nil {
+		t.Error("VerifyMAC() with another context succeeded, want error")
+	}
+}
+
+func TestHMACWycheproofCases(t *testing.T) {
+	testutil.SkipTestIfTestSrcDirIsNotSet(t)
+	for _, hash := range []string{"SHA224", "SHA256", "SHA384", "SHA512"} {
+		filename := fmt.Sprintf("hmac_%s_test.json", strings.ToLower(hash))
+		suite := new(hmacSuite)
+		if err := testutil.PopulateSuite(suite, filename); err != nil {
+			t.Fatalf("Failed populating suite: %s", err)
+		}
+		for _, group := range suite.TestGroups {
+			groupName := fmt.Sprintf("%s-%s(%d,%d)", suite.Algorithm, hash, group.KeySize, group.TagSize)
+			if group.TagSize%8 != 0 {
+				t.Errorf("For %s, requested tag size is not a multiple of 8, but %d", groupName, group.TagSize)
+				continue
+			}
+			for _, test := range group.Tests {
+				caseName := fmt.Sprintf("%s:Case-%d", groupName, test.CaseID)
+				t.Run(caseName, func(t *testing.T) {
+					h, err := subtle.NewHMAC(hash, test.Key, group.TagSize/8)
+					if err != nil {
+						// Keys and tags that are too short for Tink are rejected
+						// regardless of the expected result.
+						if subtle.ValidateHMACParams(hash, uint32(len(test.Key)), group.TagSize/8) == nil {
+							t.Fatalf("NewHMAC() failed: %v", err)
+						}
+						return
+					}
+					switch test.Result {
+					case "valid":
+						res, err := h.ComputeMAC(test.Message)
+						if err != nil {
+							t.Fatalf("ComputeMAC() failed: %v", err)
+						}
+						if !bytes.Equal(res, test.Tag) {
+							t.Errorf("ComputeMAC() = %q, want %q", hex.EncodeToString(res), hex.EncodeToString(test.Tag))
+						}
+						if err := h.VerifyMAC(test.Tag, test.Message); err != nil {
+							t.Errorf("VerifyMAC() failed: %v", err)
+						}
+					case "invalid":
+						if err := h.VerifyMAC(test.Tag, test.Message); err == nil {
+							t.Errorf("VerifyMAC() of an invalid tag succeeded")
+						}
+					default:
+						t.Fatalf("Unsupported test result: %q", test.Result)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/google/tink/go/subtle"
+)
+
+const (
+	kmac128Rate = 168
+	kmac256Rate = 136
+
+	// maxKMACTagSizeInBytes bounds the tag size; longer tags add no security.
+	maxKMACTagSizeInBytes = uint32(64)
+)
+
+// KMAC is an implementation of KMAC128 and KMAC256 as specified in NIST SP
+// 800-185 that implements the tink.MAC interface. The requested tag length is
+// an input to KMAC, so tags of different lengths are unrelated rather than
+// truncations of each other.
+type KMAC struct {
+	newCShake     func(n, s []byte) sha3.ShakeHash
+	customization []byte
+	// encodedKey is bytepad(encode_string(key), rate).
+	encodedKey []byte
+	tagSize    uint32
+}
+
+// NewKMAC128 creates a KMAC128 instance with the given key, tag size and
+// customization string. The customization string separates the domains of
+// different uses of the same key and may be empty. The key must be at least
+// 16 and the tag between 10 and 64 bytes long.
+func NewKMAC128(key []byte, tagSize uint32, customization []byte) (*KMAC, error) {
+	return newKMAC("kmac128", sha3.NewCShake128, kmac128Rate, minKeySizeInBytes, key, tagSize, customization)
+}
+
+// NewKMAC256 creates a KMAC256 instance with the given key, tag size and
+// customization string. The customization string separates the domains of
+// different uses of the same key and may be empty. The key must be at least
+// 32 and the tag between 10 and 64 bytes long.
+func NewKMAC256(key []byte, tagSize uint32, customization []byte) (*KMAC, error) {
+	return newKMAC("kmac256", sha3.NewCShake256, kmac256Rate, 2*minKeySizeInBytes, key, tagSize, customization)
+}
+
+func newKMAC(name string, newCShake func(n, s []byte) sha3.ShakeHash, rate int, minKeySize uint32, key []byte, tagSize uint32, customization []byte) (*KMAC, error) {
+	if uint32(len(key)) < minKeySize {
+		return nil, fmt.Errorf("%s: key too short, need at least %d bytes", name, minKeySize)
+	}
+	if tagSize < minTagSizeInBytes {
+		return nil, fmt.Errorf("%s: tag size too small", name)
+	}
+	if tagSize > maxKMACTagSizeInBytes {
+		return nil, fmt.Errorf("%s: tag size too big", name)
+	}
+	return &KMAC{
+		newCShake:     newCShake,
+		customization: append([]byte{}, customization...),
+		encodedKey:    bytepad(encodeString(key), rate),
+		tagSize:       tagSize,
+	}, nil
+}
+
+// ComputeMAC computes the KMAC of data.
+func (k *KMAC) ComputeMAC(data []byte) ([]byte, error) {
+	h := k.newCShake([]byte("KMAC"), k.customization)
+	h.Write(k.encodedKey)
+	h.Write(data)
+	h.Write(rightEncode(uint64(k.tagSize) * 8))
+	tag := make([]byte, k.tagSize)
+	h.Read(tag)
+	return tag, nil
+}
+
+// VerifyMAC verifies whether mac is the KMAC of data.
+func (k *KMAC) VerifyMAC(mac, data []byte) error {
+	expectedMAC, err := k.ComputeMAC(data)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(expectedMAC, mac) {
+		return nil
+	}
+	return errors.New("KMAC: invalid MAC")
+}
+
+// leftEncode and rightEncode encode x as in NIST SP 800-185, section 2.3.1.
+func leftEncode(x uint64) []byte {
+	b := encodeUint(x)
+	return append([]byte{byte(len(b))}, b...)
+}
+
+func rightEncode(x uint64) []byte {
+	b := encodeUint(x)
+	return append(b, byte(len(b)))
+}
+
+// encodeUint returns the big-endian encoding of x without leading zeros, and
+// at least one byte.
+func encodeUint(x uint64) []byte {
+	var b []byte
+	for x > 0 {
+		b = append([]byte{byte(x)}, b...)
+		x >>= 8
+	}
+	if len(b) == 0 {
+		b = []byte{0}
+	}
+	return b
+}
+
+// encodeString returns encode_string(s) of NIST SP 800-185, section 2.3.2.
+func encodeString(s []byte) []byte {
+	return append(leftEncode(uint64(len(s))*8), s...)
+}
+
+// bytepad returns bytepad(x, w) of NIST SP 800-185, section 2.3.3.
+func bytepad(x []byte, w int) []byte {
+	b := append(leftEncode(uint64(w)), x...)
+	if r := len(b) % w; r != 0 {
+		b = append(b, make([]byte, w-r)...)
+	}
+	return b
+}
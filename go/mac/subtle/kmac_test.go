@@ -0,0 +1,161 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/tink/go/mac/subtle"
+	"github.com/google/tink/go/subtle/random"
+)
+
+func kmacSampleBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestKMACNISTVectors(t *testing.T) {
+	// Samples from NIST's "KMAC_samples.pdf" for SP 800-185.
+	key, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f")
+	tagged := []byte("My Tagged Application")
+	tests := []struct {
+		name          string
+		newKMAC       func([]byte, uint32, []byte) (*subtle.KMAC, error)
+		data          []byte
+		customization []byte
+		tag           string
+	}{
+		{
+			name:    "KMAC128 sample 1",
+			newKMAC: subtle.NewKMAC128,
+			data:    kmacSampleBytes(4),
+			tag:     "e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e",
+		},
+		{
+			name:          "KMAC128 sample 2",
+			newKMAC:       subtle.NewKMAC128,
+			data:          kmacSampleBytes(4),
+			customization: tagged,
+			tag:           "3b1fba963cd8b0b59e8c1a6d71888b7143651af8ba0a7070c0979e2811324aa5",
+		},
+		{
+			name:          "KMAC128 sample 3",
+			newKMAC:       subtle.NewKMAC128,
+			data:          kmacSampleBytes(200),
+			customization: tagged,
+			tag:           "1f5b4e6cca02209e0dcb5ca635b89a15e271ecc760071dfd805faa38f9729230",
+		},
+		{
+			name:          "KMAC256 sample 4",
+			newKMAC:       subtle.NewKMAC256,
+			data:          kmacSampleBytes(4),
+			customization: tagged,
+			tag:           "20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd",
+		},
+		{
+			name:    "KMAC256 sample 5",
+			newKMAC: subtle.NewKMAC256,
+			data:    kmacSampleBytes(200),
+			tag:     "75358cf39e41494e949707927cee0af20a3ff553904c86b08f21cc414bcfd691589d27cf5e15369cbbff8b9a4c2eb17800855d0235ff635da82533ec6b759b69",
+		},
+		{
+			name:          "KMAC256 sample 6",
+			newKMAC:       subtle.NewKMAC256,
+			data:          kmacSampleBytes(200),
+			customization: tagged,
+			tag:           "b58618f71f92e1d56c1b8c55ddd7cd188b97b4ca4d99831eb2699a837da2e4d970fbacfde50033aea585f1a2708510c32d07880801bd182898fe476876fc8965",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			want, _ := hex.DecodeString(tc.tag)
+			k, err := tc.newKMAC(key, uint32(len(want)), tc.customization)
+			if err != nil {
+				t.Fatalf("newKMAC() err = %v, want nil", err)
+			}
+			got, err := k.ComputeMAC(tc.data)
+			if err != nil {
+				t.Fatalf("k.ComputeMAC() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("k.ComputeMAC() = %x, want %x", got, want)
+			}
+			if err := k.VerifyMAC(want, tc.data); err != nil {
+				t.Errorf("k.VerifyMAC() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestKMACVerifyMACRejectsModifiedInput(t *testing.T) {
+	key := random.GetRandomBytes(32)
+	k, err := subtle.NewKMAC256(key, 32, []byte("context"))
+	if err != nil {
+		t.Fatalf("subtle.NewKMAC256() err = %v, want nil", err)
+	}
+	data := []byte("some data")
+	tag, err := k.ComputeMAC(data)
+	if err != nil {
+		t.Fatalf("k.ComputeMAC() err = %v, want nil", err)
+	}
+	if err := k.VerifyMAC(tag, []byte("other data")); err == nil {
+		t.Errorf("k.VerifyMAC() with modified data err = nil, want error")
+	}
+	modified := append([]byte{}, tag...)
+	modified[0] ^= 1
+	if err := k.VerifyMAC(modified, data); err == nil {
+		t.Errorf("k.VerifyMAC() with modified tag err = nil, want error")
+	}
+	if err := k.VerifyMAC(tag[:16], data); err == nil {
+		t.Errorf("k.VerifyMAC() with truncated tag err = nil, want error")
+	}
+	other, err := subtle.NewKMAC256(key, 32, []byte("other context"))
+	if err != nil {
+		t.Fatalf("subtle.NewKMAC256() err = %v, want nil", err)
+	}
+	if err := other.VerifyMAC(tag, data); err == nil {
+		t.Errorf("VerifyMAC() with other customization err = nil, want error")
+	}
+}
+
+func TestKMACInvalidParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		newKMAC func([]byte, uint32, []byte) (*subtle.KMAC, error)
+		keySize uint32
+		tagSize uint32
+	}{
+		{"KMAC128 short key", subtle.NewKMAC128, 15, 16},
+		{"KMAC256 short key", subtle.NewKMAC256, 31, 32},
+		{"KMAC128 short tag", subtle.NewKMAC128, 16, 9},
+		{"KMAC256 short tag", subtle.NewKMAC256, 32, 9},
+		{"KMAC128 long tag", subtle.NewKMAC128, 16, 65},
+		{"KMAC256 long tag", subtle.NewKMAC256, 32, 65},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.newKMAC(random.GetRandomBytes(tc.keySize), tc.tagSize, nil); err == nil {
+				t.Errorf("newKMAC() err = nil, want error")
+			}
+		})
+	}
+}
@@ -27,6 +27,18 @@ import (
 
 // This file contains pre-generated KeyTemplate for PRF.
 
+// SupportedTemplates returns the PRF key templates of this package by their
+// canonical names, such as "HMAC_PRF_SHA256". These are the names used by
+// Tinkey. Each call returns a new map.
+func SupportedTemplates() map[string]func() *tinkpb.KeyTemplate {
+	return map[string]func() *tinkpb.KeyTemplate{
+		"HMAC_PRF_SHA256": HMACSHA256PRFKeyTemplate,
+		"HMAC_PRF_SHA512": HMACSHA512PRFKeyTemplate,
+		"HKDF_PRF_SHA256": HKDFSHA256PRFKeyTemplate,
+		"AES_CMAC_PRF":    AESCMACPRFKeyTemplate,
+	}
+}
+
 // HMACSHA256PRFKeyTemplate is a KeyTemplate that generates an HMAC key with the following parameters:
 //   - Key size: 32 bytes
 //   - Hash function: SHA256
@@ -101,3 +101,16 @@ func TestKeyTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportedTemplates(t *testing.T) {
+	templates := prf.SupportedTemplates()
+	if got, want := templates["HMAC_PRF_SHA256"](), prf.HMACSHA256PRFKeyTemplate(); !proto.Equal(got, want) {
+		t.Errorf("SupportedTemplates()[%q]() = %s, want %s", "HMAC_PRF_SHA256", got, want)
+	}
+	for name, newTemplate := range templates {
+		template := newTemplate()
+		if _, err := keyset.NewHandle(template); err != nil {
+			t.Errorf("keyset.NewHandle(SupportedTemplates()[%q]()) failed: %s", name, err)
+		}
+	}
+}
@@ -85,4 +85,7 @@ func init() {
 	if err := registry.RegisterKeyManager(newAESCMACPRFKeyManager()); err != nil {
 		panic(fmt.Sprintf("prf.init() failed: %v", err))
 	}
+	if err := registry.RegisterKeyTemplates(SupportedTemplates()); err != nil {
+		panic(fmt.Sprintf("prf.init() failed: %v", err))
+	}
 }
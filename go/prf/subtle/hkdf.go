@@ -18,10 +18,7 @@ package subtle
 
 import (
 	"fmt"
-	"hash"
-	"io"
 
-	"golang.org/x/crypto/hkdf"
 	"github.com/google/tink/go/subtle"
 )
 
@@ -35,22 +32,18 @@ const (
 
 // HKDFPRF is a type that can be used to compute several HKDFs with the same key material.
 type HKDFPRF struct {
-	h    func() hash.Hash
-	key  []byte
-	salt []byte
+	hashAlg string
+	// prk is the pseudorandom key output by HKDF-Extract.
+	prk []byte
 }
 
 // NewHKDFPRF creates a new HKDFPRF object and initializes it with the correct key material.
 func NewHKDFPRF(hashAlg string, key []byte, salt []byte) (*HKDFPRF, error) {
-	h := &HKDFPRF{}
-	hashFunc := subtle.GetHashFunc(hashAlg)
-	if hashFunc == nil {
-		return nil, fmt.Errorf("hkdf: invalid hash algorithm")
+	prk, err := subtle.HKDFExtract(hashAlg, key, salt)
+	if err != nil {
+		return nil, err
 	}
-	h.h = hashFunc
-	h.key = key
-	h.salt = salt
-	return h, nil
+	return &HKDFPRF{hashAlg: hashAlg, prk: prk}, nil
 }
 
 // ValidateHKDFPRFParams validates parameters of HKDF constructor.
@@ -70,11 +63,12 @@ func ValidateHKDFPRFParams(hash string, keySize uint32, salt []byte) error {
 
 // ComputePRF computes the HKDF for the given key and data, returning outputLength bytes.
 func (h HKDFPRF) ComputePRF(data []byte, outputLength uint32) ([]byte, error) {
-	kdf := hkdf.New(h.h, h.key, h.salt, data)
-	output := make([]byte, outputLength)
-	_, err := io.ReadAtLeast(kdf, output, int(outputLength))
+	if outputLength == 0 {
+		return []byte{}, nil
+	}
+	output, err := subtle.HKDFExpand(h.hashAlg, h.prk, data, outputLength)
 	if err != nil {
 		return nil, fmt.Errorf("Error computing HKDF: %v", err)
 	}
-	return output[:outputLength], nil
+	return output, nil
 }
@@ -0,0 +1,160 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: third_party/tink/proto/aes_gcm_siv.proto
+
+package aes_gcm_siv_go_proto
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// The only allowed IV size is 12 bytes and tag size is 16 bytes.
+// Thus, accept no params.
+type AesGcmSivKeyFormat struct {
+	KeySize              uint32   `protobuf:"varint,2,opt,name=key_size,json=keySize,proto3" json:"key_size,omitempty"`
+	Version              uint32   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AesGcmSivKeyFormat) Reset()         { *m = AesGcmSivKeyFormat{} }
+func (m *AesGcmSivKeyFormat) String() string { return proto.CompactTextString(m) }
+func (*AesGcmSivKeyFormat) ProtoMessage()    {}
+func (*AesGcmSivKeyFormat) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64a9f9e03bd72ddc, []int{0}
+}
+
+func (m *AesGcmSivKeyFormat) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AesGcmSivKeyFormat.Unmarshal(m, b)
+}
+func (m *AesGcmSivKeyFormat) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AesGcmSivKeyFormat.Marshal(b, m, deterministic)
+}
+func (m *AesGcmSivKeyFormat) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AesGcmSivKeyFormat.Merge(m, src)
+}
+func (m *AesGcmSivKeyFormat) XXX_Size() int {
+	return xxx_messageInfo_AesGcmSivKeyFormat.Size(m)
+}
+func (m *AesGcmSivKeyFormat) XXX_DiscardUnknown() {
+	xxx_messageInfo_AesGcmSivKeyFormat.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AesGcmSivKeyFormat proto.InternalMessageInfo
+
+func (m *AesGcmSivKeyFormat) GetKeySize() uint32 {
+	if m != nil {
+		return m.KeySize
+	}
+	return 0
+}
+
+func (m *AesGcmSivKeyFormat) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+// key_type: type.googleapis.com/google.crypto.tink.AesGcmSivKey
+type AesGcmSivKey struct {
+	Version              uint32   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	KeyValue             []byte   `protobuf:"bytes,3,opt,name=key_value,json=keyValue,proto3" json:"key_value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AesGcmSivKey) Reset()         { *m = AesGcmSivKey{} }
+func (m *AesGcmSivKey) String() string { return proto.CompactTextString(m) }
+func (*AesGcmSivKey) ProtoMessage()    {}
+func (*AesGcmSivKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_64a9f9e03bd72ddc, []int{1}
+}
+
+func (m *AesGcmSivKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AesGcmSivKey.Unmarshal(m, b)
+}
+func (m *AesGcmSivKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AesGcmSivKey.Marshal(b, m, deterministic)
+}
+func (m *AesGcmSivKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AesGcmSivKey.Merge(m, src)
+}
+func (m *AesGcmSivKey) XXX_Size() int {
+	return xxx_messageInfo_AesGcmSivKey.Size(m)
+}
+func (m *AesGcmSivKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_AesGcmSivKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AesGcmSivKey proto.InternalMessageInfo
+
+func (m *AesGcmSivKey) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *AesGcmSivKey) GetKeyValue() []byte {
+	if m != nil {
+		return m.KeyValue
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*AesGcmSivKeyFormat)(nil), "google.crypto.tink.AesGcmSivKeyFormat")
+	proto.RegisterType((*AesGcmSivKey)(nil), "google.crypto.tink.AesGcmSivKey")
+}
+
+func init() {
+	proto.RegisterFile("proto/aes_gcm_siv.proto", fileDescriptor_64a9f9e03bd72ddc)
+}
+
+var fileDescriptor_64a9f9e03bd72ddc = []byte{
+	// 206 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x2f, 0x28, 0xca, 0x2f,
+	0xc9, 0xd7, 0x4f, 0x4c, 0x2d, 0x8e, 0x4f, 0x4f, 0xce, 0x8d, 0x2f, 0xce, 0x2c, 0xd3, 0x03, 0x8b,
+	0x08, 0x09, 0xa5, 0xe7, 0xe7, 0xa7, 0xe7, 0xa4, 0xea, 0x25, 0x17, 0x55, 0x16, 0x94, 0xe4, 0xeb,
+	0x95, 0x64, 0xe6, 0x65, 0x2b, 0x79, 0x72, 0x09, 0x39, 0xa6, 0x16, 0xbb, 0x27, 0xe7, 0x06, 0x67,
+	0x96, 0x79, 0xa7, 0x56, 0xba, 0xe5, 0x17, 0xe5, 0x26, 0x96, 0x08, 0x49, 0x72, 0x71, 0x64, 0xa7,
+	0x56, 0xc6, 0x17, 0x67, 0x56, 0xa5, 0x4a, 0x30, 0x29, 0x30, 0x6a, 0xf0, 0x06, 0xb1, 0x67, 0xa7,
+	0x56, 0x06, 0x67, 0x56, 0xa5, 0x0a, 0x49, 0x70, 0xb1, 0x97, 0xa5, 0x16, 0x15, 0x67, 0xe6, 0xe7,
+	0x49, 0x30, 0x42, 0x64, 0xa0, 0x5c, 0x25, 0x57, 0x2e, 0x1e, 0x64, 0xa3, 0x70, 0xab, 0x14, 0x92,
+	0xe6, 0xe2, 0x04, 0x19, 0x5f, 0x96, 0x98, 0x53, 0x9a, 0x2a, 0xc1, 0xac, 0xc0, 0xa8, 0xc1, 0x13,
+	0x04, 0xb2, 0x2f, 0x0c, 0xc4, 0x77, 0x0a, 0xe6, 0x92, 0x49, 0xce, 0xcf, 0xd5, 0xc3, 0x74, 0x2b,
+	0xc4, 0x17, 0x01, 0x8c, 0x51, 0x86, 0xe9, 0x99, 0x25, 0x19, 0xa5, 0x49, 0x7a, 0xc9, 0xf9, 0xb9,
+	0xfa, 0x10, 0x65, 0xfa, 0x20, 0x79, 0x7d, 0x0c, 0x7f, 0xc7, 0xa7, 0xe7, 0xc7, 0x83, 0x05, 0x93,
+	0xd8, 0xc0, 0x94, 0x31, 0x60, 0x00, 0x11, 0xb6, 0x36, 0x3c, 0x1c, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package signature
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/subtle/random"
+	ed25519pb "github.com/google/tink/go/proto/ed25519_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+const (
+	openSSHPrivateKeyType = "OPENSSH PRIVATE KEY"
+	openSSHKeyMagic       = "openssh-key-v1\x00"
+	// openSSHBlockSize is the block size of the "none" cipher, to which the
+	// private section of an unencrypted OpenSSH key is padded.
+	openSSHBlockSize = 8
+)
+
+// openSSHKey is the outer structure of an OpenSSH private key, following the
+// magic string. See PROTOCOL.key in the OpenSSH sources.
+type openSSHKey struct {
+	CipherName   string
+	KdfName      string
+	KdfOpts      string
+	NumKeys      uint32
+	PubKey       []byte
+	PrivKeyBlock []byte
+}
+
+// openSSHED25519PrivateKey is the private section of an unencrypted OpenSSH
+// private key that holds one Ed25519 key, without the padding.
+type openSSHED25519PrivateKey struct {
+	Check1  uint32
+	Check2  uint32
+	KeyType string
+	Pub     []byte
+	Priv    []byte
+	Comment string
+}
+
+// ED25519ToOpenSSH returns the Ed25519 private key of h as an unencrypted,
+// PEM-encoded private key in OpenSSH format, which is what ssh-keygen writes
+// and other SSH tooling reads. h must contain exactly one key, an ENABLED
+// Ed25519 private key.
+//
+// The OpenSSH format has no key ID or output prefix type, so only the key
+// material is exported. OpenSSH tools create raw Ed25519 signatures, which
+// match the signatures of the key only if it has a RAW prefix.
+//
+// The key is written without any encryption, so the result must be protected
+// like the cleartext keyset.
+func ED25519ToOpenSSH(h *keyset.Handle) ([]byte, error) {
+	if h == nil {
+		return nil, fmt.Errorf("signature: nil handle")
+	}
+	info := h.KeysetInfo()
+	if len(info.GetKeyInfo()) != 1 {
+		return nil, fmt.Errorf("signature: keyset has %d keys, want 1", len(info.GetKeyInfo()))
+	}
+	if typeURL := info.GetKeyInfo()[0].GetTypeUrl(); typeURL != ed25519SignerTypeURL {
+		return nil, fmt.Errorf("signature: key has type %s, want %s", typeURL, ed25519SignerTypeURL)
+	}
+	// The key manager is given the serialized key, so the key material is
+	// not read from the keyset.
+	ps, err := h.PrimitivesWithKeyManager(&ed25519ExportKeyManager{newED25519SignerKeyManager()})
+	if err != nil {
+		return nil, fmt.Errorf("signature: %s", err)
+	}
+	if ps.Primary == nil {
+		return nil, fmt.Errorf("signature: key is not ENABLED")
+	}
+	key := ps.Primary.Primitive.(*ed25519pb.Ed25519PrivateKey)
+	priv := ed25519.NewKeyFromSeed(key.KeyValue)
+	pub := priv.Public().(ed25519.PublicKey)
+	if !bytes.Equal(pub, key.GetPublicKey().GetKeyValue()) {
+		return nil, fmt.Errorf("signature: public key does not match private key")
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %s", err)
+	}
+
+	check := random.GetRandomUint32()
+	block := ssh.Marshal(openSSHED25519PrivateKey{
+		Check1:  check,
+		Check2:  check,
+		KeyType: ssh.KeyAlgoED25519,
+		Pub:     pub,
+		Priv:    priv,
+	})
+	for i := 1; len(block)%openSSHBlockSize != 0; i++ {
+		block = append(block, byte(i))
+	}
+	encoded := append([]byte(openSSHKeyMagic), ssh.Marshal(openSSHKey{
+		CipherName:   "none",
+		KdfName:      "none",
+		NumKeys:      1,
+		PubKey:       sshPub.Marshal(),
+		PrivKeyBlock: block,
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: openSSHPrivateKeyType, Bytes: encoded}), nil
+}
+
+// ed25519ExportKeyManager is an Ed25519 signer key manager whose primitives
+// are the validated private key protos, for ED25519ToOpenSSH.
+type ed25519ExportKeyManager struct {
+	*ed25519SignerKeyManager
+}
+
+func (km *ed25519ExportKeyManager) Primitive(serializedKey []byte) (interface{}, error) {
+	key := new(ed25519pb.Ed25519PrivateKey)
+	if err := proto.Unmarshal(serializedKey, key); err != nil {
+		return nil, errInvalidED25519SignKey
+	}
+	if err := km.validateKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ED25519FromOpenSSH returns a handle for a keyset that consists of a single
+// ENABLED Ed25519 private key with a RAW prefix and a random key ID, whose key
+// material is read from the given unencrypted, PEM-encoded OpenSSH private
+// key. Its signatures are those of OpenSSH tools for the same key. It fails
+// for keys of other types and for encrypted keys.
+func ED25519FromOpenSSH(pemBytes []byte) (*keyset.Handle, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil || block.Type != openSSHPrivateKeyType {
+		return nil, fmt.Errorf("signature: not a PEM-encoded OpenSSH private key")
+	}
+	raw, err := ssh.ParseRawPrivateKey(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("signature: cannot parse OpenSSH private key: %s", err)
+	}
+	var priv ed25519.PrivateKey
+	switch k := raw.(type) {
+	case *ed25519.PrivateKey:
+		priv = *k
+	case ed25519.PrivateKey:
+		priv = k
+	default:
+		return nil, fmt.Errorf("signature: OpenSSH private key has type %T, want Ed25519", raw)
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errInvalidED25519SignKey
+	}
+	serializedKey, err := proto.Marshal(&ed25519pb.Ed25519PrivateKey{
+		Version: ed25519SignerKeyVersion,
+		PublicKey: &ed25519pb.Ed25519PublicKey{
+			Version:  ed25519SignerKeyVersion,
+			KeyValue: priv.Public().(ed25519.PublicKey),
+		},
+		KeyValue: priv.Seed(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("signature: cannot serialize key: %s", err)
+	}
+	var keyID uint32
+	for keyID == 0 {
+		keyID = random.GetRandomUint32()
+	}
+	ks := &tinkpb.Keyset{
+		PrimaryKeyId: keyID,
+		Key: []*tinkpb.Keyset_Key{{
+			KeyData: &tinkpb.KeyData{
+				TypeUrl:         ed25519SignerTypeURL,
+				Value:           serializedKey,
+				KeyMaterialType: tinkpb.KeyData_ASYMMETRIC_PRIVATE,
+			},
+			Status:           tinkpb.KeyStatusType_ENABLED,
+			KeyId:            keyID,
+			OutputPrefixType: tinkpb.OutputPrefixType_RAW,
+		}},
+	}
+	if err := keyset.Validate(ks); err != nil {
+		return nil, fmt.Errorf("signature: invalid keyset: %s", err)
+	}
+	return insecurecleartextkeyset.KeysetHandle(ks), nil
+}
@@ -42,4 +42,7 @@ func init() {
 	if err := registry.RegisterKeyManager(newED25519VerifierKeyManager()); err != nil {
 		panic(fmt.Sprintf("signature.init() failed: %v", err))
 	}
+	if err := registry.RegisterKeyTemplates(SupportedTemplates()); err != nil {
+		panic(fmt.Sprintf("signature.init() failed: %v", err))
+	}
 }
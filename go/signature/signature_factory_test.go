@@ -22,12 +22,12 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/mac"
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 	"github.com/google/tink/go/signature"
 	"github.com/google/tink/go/subtle/random"
 	"github.com/google/tink/go/testkeyset"
 	"github.com/google/tink/go/testutil"
-	commonpb "github.com/google/tink/go/proto/common_go_proto"
-	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
 func TestSignerVerifyFactory(t *testing.T) {
@@ -146,3 +146,187 @@ func TestFactoryWithValidPrimitiveSetType(t *testing.T) {
 		t.Errorf("calling NewVerifier() with good *keyset.Handle failed: %s", err)
 	}
 }
+
+func TestPublicHandleForSignatureTemplates(t *testing.T) {
+	templates := map[string]*tinkpb.KeyTemplate{
+		"ECDSAP256":              signature.ECDSAP256KeyTemplate(),
+		"ECDSAP256WithoutPrefix": signature.ECDSAP256KeyWithoutPrefixTemplate(),
+		"ECDSAP384":              signature.ECDSAP384KeyTemplate(),
+		"ECDSAP384WithoutPrefix": signature.ECDSAP384KeyWithoutPrefixTemplate(),
+		"ECDSAP521":              signature.ECDSAP521KeyTemplate(),
+		"ECDSAP521WithoutPrefix": signature.ECDSAP521KeyWithoutPrefixTemplate(),
+		"ED25519":                signature.ED25519KeyTemplate(),
+		"ED25519WithoutPrefix":   signature.ED25519KeyWithoutPrefixTemplate(),
+	}
+	for name, template := range templates {
+		t.Run(name, func(t *testing.T) {
+			privHandle, err := keyset.NewHandle(template)
+			if err != nil {
+				t.Fatalf("keyset.NewHandle() failed: %s", err)
+			}
+			pubHandle, err := privHandle.Public()
+			if err != nil {
+				t.Fatalf("privHandle.Public() failed: %s", err)
+			}
+
+			privInfo := privHandle.KeysetInfo()
+			pubInfo := pubHandle.KeysetInfo()
+			if pubInfo.PrimaryKeyId != privInfo.PrimaryKeyId {
+				t.Errorf("public primary key ID = %d, want %d", pubInfo.PrimaryKeyId, privInfo.PrimaryKeyId)
+			}
+			if len(pubInfo.KeyInfo) != len(privInfo.KeyInfo) {
+				t.Fatalf("public keyset has %d keys, want %d", len(pubInfo.KeyInfo), len(privInfo.KeyInfo))
+			}
+			for i, ki := range pubInfo.KeyInfo {
+				if ki.KeyId != privInfo.KeyInfo[i].KeyId || ki.OutputPrefixType != privInfo.KeyInfo[i].OutputPrefixType {
+					t.Errorf("public key %d does not match the private key", i)
+				}
+			}
+			// NewHandleWithNoSecrets only accepts keysets without secret key material.
+			ks := testkeyset.KeysetMaterial(pubHandle)
+			if _, err := keyset.NewHandleWithNoSecrets(ks); err != nil {
+				t.Errorf("the public keyset contains secret key material: %s", err)
+			}
+			for _, k := range ks.Key {
+				if k.KeyData.KeyMaterialType != tinkpb.KeyData_ASYMMETRIC_PUBLIC {
+					t.Errorf("key %d has key material type %s, want ASYMMETRIC_PUBLIC", k.KeyId, k.KeyData.KeyMaterialType)
+				}
+			}
+
+			signer, err := signature.NewSigner(privHandle)
+			if err != nil {
+				t.Fatalf("signature.NewSigner() failed: %s", err)
+			}
+			verifier, err := signature.NewVerifier(pubHandle)
+			if err != nil {
+				t.Fatalf("signature.NewVerifier() failed: %s", err)
+			}
+			data := random.GetRandomBytes(20)
+			sig, err := signer.Sign(data)
+			if err != nil {
+				t.Fatalf("signer.Sign() failed: %s", err)
+			}
+			if err := verifier.Verify(sig, data); err != nil {
+				t.Errorf("verifier.Verify() failed: %s", err)
+			}
+
+			if _, err := signature.NewSigner(pubHandle); err == nil {
+				t.Errorf("signature.NewSigner() with the public handle succeeded, want error")
+			}
+			if _, err := pubHandle.Public(); err == nil {
+				t.Errorf("pubHandle.Public() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestPublicHandleFailsForSymmetricKeysets(t *testing.T) {
+	kh, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() failed: %s", err)
+	}
+	if _, err := kh.Public(); err == nil {
+		t.Errorf("kh.Public() of a MAC keyset succeeded, want error")
+	}
+}
+
+// disablePrimary returns a handle for a copy of the keyset of h whose primary
+// key is DISABLED.
+func disablePrimary(h *keyset.Handle) *keyset.Handle {
+	ks := proto.Clone(testkeyset.KeysetMaterial(h)).(*tinkpb.Keyset)
+	for _, key := range ks.Key {
+		if key.KeyId == ks.PrimaryKeyId {
+			key.Status = tinkpb.KeyStatusType_DISABLED
+		}
+	}
+	return testkeyset.KeysetHandle(ks)
+}
+
+func TestNewVerifierLenientWithDisabledPrimary(t *testing.T) {
+	ksm := keyset.NewManager()
+	if err := ksm.Rotate(signature.ED25519KeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	secondary, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	secondarySigner, err := signature.NewSigner(secondary)
+	if err != nil {
+		t.Fatalf("signature.NewSigner() err = %v", err)
+	}
+	data := []byte("data")
+	secondarySig, err := secondarySigner.Sign(data)
+	if err != nil {
+		t.Fatalf("secondarySigner.Sign() err = %v", err)
+	}
+	if err := ksm.Rotate(signature.ECDSAP256KeyTemplate()); err != nil {
+		t.Fatalf("ksm.Rotate() err = %v", err)
+	}
+	priv, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() err = %v", err)
+	}
+	primarySigner, err := signature.NewSigner(priv)
+	if err != nil {
+		t.Fatalf("signature.NewSigner() err = %v", err)
+	}
+	primarySig, err := primarySigner.Sign(data)
+	if err != nil {
+		t.Fatalf("primarySigner.Sign() err = %v", err)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v", err)
+	}
+
+	disabledPub := disablePrimary(pub)
+	if _, err := signature.NewVerifier(disabledPub); err == nil {
+		t.Errorf("signature.NewVerifier() with DISABLED primary err = nil, want error")
+	}
+	v, err := signature.NewVerifierLenient(disabledPub)
+	if err != nil {
+		t.Fatalf("signature.NewVerifierLenient() err = %v", err)
+	}
+	if err := v.Verify(secondarySig, data); err != nil {
+		t.Errorf("v.Verify() of signature of ENABLED key err = %v", err)
+	}
+	if err := v.Verify(primarySig, data); err == nil {
+		t.Errorf("v.Verify() of signature of DISABLED key err = nil, want error")
+	}
+
+	// Signing still requires an ENABLED primary key.
+	if _, err := signature.NewSigner(disablePrimary(priv)); err == nil {
+		t.Errorf("signature.NewSigner() with DISABLED primary err = nil, want error")
+	}
+	if _, err := signature.NewVerifierLenient(disablePrimary(priv)); err == nil {
+		t.Errorf("signature.NewVerifierLenient() of private keyset with DISABLED primary err = nil, want error")
+	}
+}
+
+func TestNewVerifierLenientWithEnabledPrimary(t *testing.T) {
+	priv, err := keyset.NewHandle(signature.ED25519KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("priv.Public() err = %v", err)
+	}
+	signer, err := signature.NewSigner(priv)
+	if err != nil {
+		t.Fatalf("signature.NewSigner() err = %v", err)
+	}
+	data := []byte("data")
+	sig, err := signer.Sign(data)
+	if err != nil {
+		t.Fatalf("signer.Sign() err = %v", err)
+	}
+	v, err := signature.NewVerifierLenient(pub)
+	if err != nil {
+		t.Fatalf("signature.NewVerifierLenient() err = %v", err)
+	}
+	if err := v.Verify(sig, data); err != nil {
+		t.Errorf("v.Verify() err = %v", err)
+	}
+}
@@ -26,6 +26,26 @@ import (
 // This file contains pre-generated KeyTemplates for Signer and Verifier.
 // One can use these templates to generate new Keysets.
 
+// SupportedTemplates returns the signature key templates of this package by
+// their canonical names, such as "ECDSA_P256". These are the names used by
+// Tinkey. Each call returns a new map.
+func SupportedTemplates() map[string]func() *tinkpb.KeyTemplate {
+	return map[string]func() *tinkpb.KeyTemplate{
+		"ECDSA_P256":                ECDSAP256KeyTemplate,
+		"ECDSA_P256_IEEE_P1363":     ECDSAP256KeyTemplateP1363,
+		"ECDSA_P256_IEEE_P1363_RAW": ECDSAP256KeyWithoutPrefixTemplateP1363,
+		"ECDSA_P256_RAW":            ECDSAP256KeyWithoutPrefixTemplate,
+		"ECDSA_P384":                ECDSAP384KeyTemplate,
+		"ECDSA_P384_IEEE_P1363":     ECDSAP384KeyTemplateP1363,
+		"ECDSA_P384_RAW":            ECDSAP384KeyWithoutPrefixTemplate,
+		"ECDSA_P521":                ECDSAP521KeyTemplate,
+		"ECDSA_P521_IEEE_P1363":     ECDSAP521KeyTemplateP1363,
+		"ECDSA_P521_RAW":            ECDSAP521KeyWithoutPrefixTemplate,
+		"ED25519":                   ED25519KeyTemplate,
+		"ED25519_RAW":               ED25519KeyWithoutPrefixTemplate,
+	}
+}
+
 // ECDSAP256KeyTemplate is a KeyTemplate that generates a new ECDSA private key with the following parameters:
 //   - Hash function: SHA256
 //   - Curve: NIST P-256
@@ -101,6 +121,58 @@ func ECDSAP521KeyWithoutPrefixTemplate() *tinkpb.KeyTemplate {
 		tinkpb.OutputPrefixType_RAW)
 }
 
+// ECDSAP256KeyTemplateP1363 is a KeyTemplate that generates a new ECDSA private key with the following parameters:
+//   - Hash function: SHA256
+//   - Curve: NIST P-256
+//   - Signature encoding: IEEE_P1363
+//   - Output prefix type: TINK
+//
+// IEEE_P1363 signatures are the fixed-size concatenation r || s, as used by
+// WebCrypto and JWS, instead of a DER-encoded ASN.1 structure.
+func ECDSAP256KeyTemplateP1363() *tinkpb.KeyTemplate {
+	return createECDSAKeyTemplate(commonpb.HashType_SHA256,
+		commonpb.EllipticCurveType_NIST_P256,
+		ecdsapb.EcdsaSignatureEncoding_IEEE_P1363,
+		tinkpb.OutputPrefixType_TINK)
+}
+
+// ECDSAP256KeyWithoutPrefixTemplateP1363 is a KeyTemplate that generates a new ECDSA private key with the following
+// parameters:
+//   - Hash function: SHA256
+//   - Curve: NIST P-256
+//   - Signature encoding: IEEE_P1363
+//   - Output prefix type: RAW
+func ECDSAP256KeyWithoutPrefixTemplateP1363() *tinkpb.KeyTemplate {
+	return createECDSAKeyTemplate(commonpb.HashType_SHA256,
+		commonpb.EllipticCurveType_NIST_P256,
+		ecdsapb.EcdsaSignatureEncoding_IEEE_P1363,
+		tinkpb.OutputPrefixType_RAW)
+}
+
+// ECDSAP384KeyTemplateP1363 is a KeyTemplate that generates a new ECDSA private key with the following parameters:
+//   - Hash function: SHA512
+//   - Curve: NIST P-384
+//   - Signature encoding: IEEE_P1363
+//   - Output prefix type: TINK
+func ECDSAP384KeyTemplateP1363() *tinkpb.KeyTemplate {
+	return createECDSAKeyTemplate(commonpb.HashType_SHA512,
+		commonpb.EllipticCurveType_NIST_P384,
+		ecdsapb.EcdsaSignatureEncoding_IEEE_P1363,
+		tinkpb.OutputPrefixType_TINK)
+}
+
+// ECDSAP521KeyTemplateP1363 is a KeyTemplate that generates a new ECDSA private key with the following parameters:
+//   - Hash function: SHA512
+//   - Curve: NIST P-521
+//   - Signature encoding: IEEE_P1363
+//   - Output prefix type: TINK
+func ECDSAP521KeyTemplateP1363() *tinkpb.KeyTemplate {
+	return createECDSAKeyTemplate(commonpb.HashType_SHA512,
+		commonpb.EllipticCurveType_NIST_P521,
+		ecdsapb.EcdsaSignatureEncoding_IEEE_P1363,
+		tinkpb.OutputPrefixType_TINK)
+}
+
 // createECDSAKeyTemplate creates a KeyTemplate containing a EcdasKeyFormat
 // with the given parameters.
 func createECDSAKeyTemplate(hashType commonpb.HashType, curve commonpb.EllipticCurveType,
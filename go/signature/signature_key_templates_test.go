@@ -18,12 +18,17 @@ package signature_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/google/tink/go/keyset"
 	"github.com/google/tink/go/signature"
+	"github.com/google/tink/go/signature/subtle"
+	"github.com/google/tink/go/testkeyset"
 	"github.com/google/tink/go/testutil"
+	"github.com/google/tink/go/tink"
+	ecdsapb "github.com/google/tink/go/proto/ecdsa_go_proto"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
 )
 
@@ -39,6 +44,12 @@ func TestKeyTemplates(t *testing.T) {
 			template: signature.ECDSAP384KeyTemplate()},
 		{name: "ECDSA_P521",
 			template: signature.ECDSAP521KeyTemplate()},
+		{name: "ECDSA_P256_IEEE_P1363",
+			template: signature.ECDSAP256KeyTemplateP1363()},
+		{name: "ECDSA_P384_IEEE_P1363",
+			template: signature.ECDSAP384KeyTemplateP1363()},
+		{name: "ECDSA_P521_IEEE_P1363",
+			template: signature.ECDSAP521KeyTemplateP1363()},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -68,6 +79,8 @@ func TestKeyWithoutPrefixTemplates(t *testing.T) {
 			template: signature.ECDSAP384KeyWithoutPrefixTemplate()},
 		{name: "ECDSA_P521",
 			template: signature.ECDSAP521KeyWithoutPrefixTemplate()},
+		{name: "ECDSA_P256_IEEE_P1363",
+			template: signature.ECDSAP256KeyWithoutPrefixTemplateP1363()},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -137,3 +150,128 @@ func testSignVerify(template *tinkpb.KeyTemplate) error {
 	}
 	return nil
 }
+
+func TestSupportedTemplates(t *testing.T) {
+	templates := signature.SupportedTemplates()
+	if got, want := templates["ED25519"](), signature.ED25519KeyTemplate(); !proto.Equal(got, want) {
+		t.Errorf("SupportedTemplates()[%q]() = %s, want %s", "ED25519", got, want)
+	}
+	for name, newTemplate := range templates {
+		template := newTemplate()
+		if _, err := keyset.NewHandle(template); err != nil {
+			t.Errorf("keyset.NewHandle(SupportedTemplates()[%q]()) failed: %s", name, err)
+		}
+		if wantRAW := strings.HasSuffix(name, "_RAW"); wantRAW != (template.OutputPrefixType == tinkpb.OutputPrefixType_RAW) {
+			t.Errorf("SupportedTemplates()[%q] has output prefix type %s", name, template.OutputPrefixType)
+		}
+	}
+}
+
+// withECDSAEncoding returns a copy of the ECDSA private keyset of h in which
+// the signature encoding of every key is set to encoding.
+func withECDSAEncoding(t *testing.T, h *keyset.Handle, encoding ecdsapb.EcdsaSignatureEncoding) *keyset.Handle {
+	t.Helper()
+	ks := proto.Clone(testkeyset.KeysetMaterial(h)).(*tinkpb.Keyset)
+	for _, k := range ks.Key {
+		key := new(ecdsapb.EcdsaPrivateKey)
+		if err := proto.Unmarshal(k.KeyData.Value, key); err != nil {
+			t.Fatalf("proto.Unmarshal() failed: %s", err)
+		}
+		key.PublicKey.Params.Encoding = encoding
+		value, err := proto.Marshal(key)
+		if err != nil {
+			t.Fatalf("proto.Marshal() failed: %s", err)
+		}
+		k.KeyData.Value = value
+	}
+	ret, err := testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() failed: %s", err)
+	}
+	return ret
+}
+
+func TestECDSAP1363AndDERInterop(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		curve   string
+		sigSize int
+		newKey  func() *tinkpb.KeyTemplate
+	}{
+		{name: "P256", curve: "P-256", sigSize: 64, newKey: signature.ECDSAP256KeyWithoutPrefixTemplateP1363},
+		{name: "P384", curve: "P-384", sigSize: 96, newKey: signature.ECDSAP384KeyWithoutPrefixTemplate},
+		{name: "P521", curve: "P-521", sigSize: 132, newKey: signature.ECDSAP521KeyWithoutPrefixTemplate},
+	}
+	data := []byte("this data needs to be signed")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := keyset.NewHandle(tc.newKey())
+			if err != nil {
+				t.Fatalf("keyset.NewHandle() failed: %s", err)
+			}
+			p1363 := withECDSAEncoding(t, h, ecdsapb.EcdsaSignatureEncoding_IEEE_P1363)
+			der := withECDSAEncoding(t, h, ecdsapb.EcdsaSignatureEncoding_DER)
+			p1363Signer, p1363Verifier := newSignerVerifier(t, p1363)
+			derSigner, derVerifier := newSignerVerifier(t, der)
+
+			p1363Sig, err := p1363Signer.Sign(data)
+			if err != nil {
+				t.Fatalf("p1363Signer.Sign() failed: %s", err)
+			}
+			if len(p1363Sig) != tc.sigSize {
+				t.Errorf("len(p1363Signer.Sign()) = %d, want %d", len(p1363Sig), tc.sigSize)
+			}
+			derSig, err := derSigner.Sign(data)
+			if err != nil {
+				t.Fatalf("derSigner.Sign() failed: %s", err)
+			}
+			if err := derVerifier.Verify(p1363Sig, data); err == nil {
+				t.Error("derVerifier.Verify(p1363Sig) succeeded, want error")
+			}
+			if err := p1363Verifier.Verify(derSig, data); err == nil {
+				t.Error("p1363Verifier.Verify(derSig) succeeded, want error")
+			}
+
+			sig, err := subtle.DecodeECDSASignature(p1363Sig, "IEEE_P1363")
+			if err != nil {
+				t.Fatalf("subtle.DecodeECDSASignature(p1363Sig) failed: %s", err)
+			}
+			converted, err := sig.EncodeECDSASignature("DER", tc.curve)
+			if err != nil {
+				t.Fatalf("EncodeECDSASignature(DER) failed: %s", err)
+			}
+			if err := derVerifier.Verify(converted, data); err != nil {
+				t.Errorf("derVerifier.Verify(P1363 signature converted to DER) failed: %s", err)
+			}
+
+			sig, err = subtle.DecodeECDSASignature(derSig, "DER")
+			if err != nil {
+				t.Fatalf("subtle.DecodeECDSASignature(derSig) failed: %s", err)
+			}
+			converted, err = sig.EncodeECDSASignature("IEEE_P1363", tc.curve)
+			if err != nil {
+				t.Fatalf("EncodeECDSASignature(IEEE_P1363) failed: %s", err)
+			}
+			if err := p1363Verifier.Verify(converted, data); err != nil {
+				t.Errorf("p1363Verifier.Verify(DER signature converted to P1363) failed: %s", err)
+			}
+		})
+	}
+}
+
+func newSignerVerifier(t *testing.T, h *keyset.Handle) (tink.Signer, tink.Verifier) {
+	t.Helper()
+	signer, err := signature.NewSigner(h)
+	if err != nil {
+		t.Fatalf("signature.NewSigner() failed: %s", err)
+	}
+	pub, err := h.Public()
+	if err != nil {
+		t.Fatalf("h.Public() failed: %s", err)
+	}
+	verifier, err := signature.NewVerifier(pub)
+	if err != nil {
+		t.Fatalf("signature.NewVerifier() failed: %s", err)
+	}
+	return signer, verifier
+}
@@ -0,0 +1,254 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/google/tink/go/core/cryptofmt"
+	"github.com/google/tink/go/core/primitiveset"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/tink"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// digestSigner is implemented by signers that sign a digest of the data,
+// such as subtle.ECDSASigner. Such signers can process data incrementally.
+type digestSigner interface {
+	NewHash() hash.Hash
+	SignDigest(digest []byte) ([]byte, error)
+}
+
+// digestVerifier is implemented by verifiers that verify a signature over a
+// digest of the data, such as subtle.ECDSAVerifier.
+type digestVerifier interface {
+	NewHash() hash.Hash
+	VerifyDigest(signature, digest []byte) error
+}
+
+var errStreamFinished = errors.New("signature_stream: stream already finished")
+
+// SignerStream computes a signature over data that is written to it in
+// chunks. The signature is identical in format to the one computed by the
+// Signer returned by NewSigner, and can be verified by its Verifier.
+type SignerStream struct {
+	entry    *primitiveset.Entry
+	policy   keyset.UsagePolicy
+	w        io.Writer
+	h        hash.Hash
+	buf      *bytes.Buffer
+	finished bool
+}
+
+// NewSignerStream returns a SignerStream that signs data with the primary key
+// of the given keyset handle.
+//
+// ECDSA keys hash the data as it is written. Ed25519 signs the full message
+// rather than a digest of it, so for Ed25519 keys the data is buffered in
+// memory until Finish is called.
+//
+// The usage policy of h, see keyset.Handle.WithUsagePolicy, is consulted for
+// the primary key when Finish is called.
+func NewSignerStream(h *keyset.Handle) (*SignerStream, error) {
+	ps, err := h.Primitives()
+	if err != nil {
+		return nil, fmt.Errorf("signature_stream: cannot obtain primitive set: %s", err)
+	}
+	if _, err := newWrappedSigner(ps, h.UsagePolicy()); err != nil {
+		return nil, err
+	}
+	s := &SignerStream{entry: ps.Primary, policy: h.UsagePolicy()}
+	if ds, ok := (ps.Primary.Primitive).(digestSigner); ok {
+		s.h = ds.NewHash()
+		s.w = s.h
+	} else {
+		s.buf = new(bytes.Buffer)
+		s.w = s.buf
+	}
+	return s, nil
+}
+
+// Write adds more data to the message being signed.
+func (s *SignerStream) Write(p []byte) (int, error) {
+	if s.finished {
+		return 0, errStreamFinished
+	}
+	return s.w.Write(p)
+}
+
+// Finish returns the signature of all data written to the stream, prefixed
+// with the identifier of the primary key. The stream cannot be used afterwards.
+func (s *SignerStream) Finish() ([]byte, error) {
+	if s.finished {
+		return nil, errStreamFinished
+	}
+	s.finished = true
+	if err := keyset.CheckUsage(s.policy, s.entry.KeyID, keyset.OpSign); err != nil {
+		return nil, err
+	}
+	if s.entry.PrefixType == tinkpb.OutputPrefixType_LEGACY {
+		if _, err := s.w.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+	}
+	var signature []byte
+	var err error
+	if s.h != nil {
+		signature, err = (s.entry.Primitive).(digestSigner).SignDigest(s.h.Sum(nil))
+	} else {
+		signature, err = (s.entry.Primitive).(tink.Signer).Sign(s.buf.Bytes())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(s.entry.Prefix), signature...), nil
+}
+
+// verifierStreamEntry holds the state of a single key of a VerifierStream.
+type verifierStreamEntry struct {
+	entry *primitiveset.Entry
+	// h is the running hash for keys implementing digestVerifier; it is nil
+	// for keys that need the full message.
+	h hash.Hash
+}
+
+// VerifierStream verifies a signature over data that is written to it in
+// chunks. It accepts the same signatures as the Verifier returned by
+// NewVerifier.
+type VerifierStream struct {
+	entries  map[string][]*verifierStreamEntry
+	policy   keyset.UsagePolicy
+	writers  []io.Writer
+	buf      *bytes.Buffer
+	finished bool
+}
+
+// NewVerifierStream returns a VerifierStream that verifies data with the keys
+// of the given keyset handle.
+//
+// Since the signature, and therefore the key it was made with, is only known
+// when Finish is called, the data is hashed for every ECDSA key in the keyset,
+// and buffered in memory if the keyset contains Ed25519 keys.
+//
+// The usage policy of h is consulted for every key that Finish tries.
+func NewVerifierStream(h *keyset.Handle) (*VerifierStream, error) {
+	ps, err := h.Primitives()
+	if err != nil {
+		return nil, fmt.Errorf("signature_stream: cannot obtain primitive set: %s", err)
+	}
+	if _, err := newWrappedVerifier(ps, h.UsagePolicy()); err != nil {
+		return nil, err
+	}
+	v := &VerifierStream{
+		entries: make(map[string][]*verifierStreamEntry),
+		policy:  h.UsagePolicy(),
+	}
+	for prefix, entries := range ps.Entries {
+		for _, e := range entries {
+			se := &verifierStreamEntry{entry: e}
+			if dv, ok := (e.Primitive).(digestVerifier); ok {
+				se.h = dv.NewHash()
+				v.writers = append(v.writers, se.h)
+			} else if v.buf == nil {
+				v.buf = new(bytes.Buffer)
+				v.writers = append(v.writers, v.buf)
+			}
+			v.entries[prefix] = append(v.entries[prefix], se)
+		}
+	}
+	return v, nil
+}
+
+// Write adds more data to the message being verified.
+func (v *VerifierStream) Write(p []byte) (int, error) {
+	if v.finished {
+		return 0, errStreamFinished
+	}
+	for _, w := range v.writers {
+		if _, err := w.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Finish checks whether the given signature is a valid signature of all data
+// written to the stream. The stream cannot be used afterwards.
+func (v *VerifierStream) Finish(signature []byte) error {
+	if v.finished {
+		return errStreamFinished
+	}
+	v.finished = true
+	prefixSize := cryptofmt.NonRawPrefixSize
+	if len(signature) < prefixSize {
+		return errInvalidSignature
+	}
+
+	// try non-raw keys
+	prefix := signature[:prefixSize]
+	signatureNoPrefix := signature[prefixSize:]
+	// denied is the first error of the usage policy.
+	var denied error
+	for _, e := range v.entries[string(prefix)] {
+		if err := keyset.CheckUsage(v.policy, e.entry.KeyID, keyset.OpVerify); err != nil {
+			if denied == nil {
+				denied = err
+			}
+			continue
+		}
+		if err := v.verify(e, signatureNoPrefix, e.entry.PrefixType == tinkpb.OutputPrefixType_LEGACY); err == nil {
+			return nil
+		}
+	}
+
+	// try raw keys
+	for _, e := range v.entries[cryptofmt.RawPrefix] {
+		if err := keyset.CheckUsage(v.policy, e.entry.KeyID, keyset.OpVerify); err != nil {
+			if denied == nil {
+				denied = err
+			}
+			continue
+		}
+		if err := v.verify(e, signature, false); err == nil {
+			return nil
+		}
+	}
+
+	if denied != nil {
+		return denied
+	}
+	return errInvalidSignature
+}
+
+func (v *VerifierStream) verify(e *verifierStreamEntry, signature []byte, legacy bool) error {
+	if e.h != nil {
+		// Every entry is verified at most once, so its hash can be modified.
+		if legacy {
+			e.h.Write([]byte{0})
+		}
+		return (e.entry.Primitive).(digestVerifier).VerifyDigest(signature, e.h.Sum(nil))
+	}
+	data := v.buf.Bytes()
+	if legacy {
+		data = append(data[:len(data):len(data)], byte(0))
+	}
+	return (e.entry.Primitive).(tink.Verifier).Verify(signature, data)
+}
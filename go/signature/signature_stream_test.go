@@ -0,0 +1,260 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package signature_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/signature"
+	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/testkeyset"
+	"github.com/google/tink/go/testutil"
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+func newED25519KeysetKeypair(outputPrefixType tinkpb.OutputPrefixType, keyID uint32) (*tinkpb.Keyset_Key, *tinkpb.Keyset_Key) {
+	key := testutil.NewED25519PrivateKey()
+	serializedKey, _ := proto.Marshal(key)
+	keyData := testutil.NewKeyData(testutil.ED25519SignerTypeURL,
+		serializedKey,
+		tinkpb.KeyData_ASYMMETRIC_PRIVATE)
+	privKey := testutil.NewKey(keyData, tinkpb.KeyStatusType_ENABLED, keyID, outputPrefixType)
+
+	serializedKey, _ = proto.Marshal(key.PublicKey)
+	keyData = testutil.NewKeyData(testutil.ED25519VerifierTypeURL,
+		serializedKey,
+		tinkpb.KeyData_ASYMMETRIC_PUBLIC)
+	pubKey := testutil.NewKey(keyData, tinkpb.KeyStatusType_ENABLED, keyID, outputPrefixType)
+	return privKey, pubKey
+}
+
+func writeInChunks(t *testing.T, w interface{ Write([]byte) (int, error) }, data []byte) {
+	t.Helper()
+	for len(data) > 0 {
+		n := 100
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatalf("Write() failed: %s", err)
+		}
+		data = data[n:]
+	}
+}
+
+func TestSignerVerifierStream(t *testing.T) {
+	tinkPriv, tinkPub := newECDSAKeysetKeypair(commonpb.HashType_SHA512,
+		commonpb.EllipticCurveType_NIST_P521,
+		tinkpb.OutputPrefixType_TINK,
+		1)
+	legacyPriv, legacyPub := newECDSAKeysetKeypair(commonpb.HashType_SHA256,
+		commonpb.EllipticCurveType_NIST_P256,
+		tinkpb.OutputPrefixType_LEGACY,
+		2)
+	rawPriv, rawPub := newECDSAKeysetKeypair(commonpb.HashType_SHA512,
+		commonpb.EllipticCurveType_NIST_P384,
+		tinkpb.OutputPrefixType_RAW,
+		3)
+	edPriv, edPub := newED25519KeysetKeypair(tinkpb.OutputPrefixType_TINK, 4)
+	edLegacyPriv, edLegacyPub := newED25519KeysetKeypair(tinkpb.OutputPrefixType_LEGACY, 5)
+	privKeys := []*tinkpb.Keyset_Key{tinkPriv, legacyPriv, rawPriv, edPriv, edLegacyPriv}
+	pubKeys := []*tinkpb.Keyset_Key{tinkPub, legacyPub, rawPub, edPub, edLegacyPub}
+	pubKeysetHandle, err := testkeyset.NewHandle(testutil.NewKeyset(pubKeys[0].KeyId, pubKeys))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() failed: %s", err)
+	}
+	verifier, err := signature.NewVerifier(pubKeysetHandle)
+	if err != nil {
+		t.Fatalf("signature.NewVerifier() failed: %s", err)
+	}
+	data := random.GetRandomBytes(1211)
+
+	for _, primary := range privKeys {
+		privKeysetHandle, err := testkeyset.NewHandle(testutil.NewKeyset(primary.KeyId, privKeys))
+		if err != nil {
+			t.Fatalf("testkeyset.NewHandle() failed: %s", err)
+		}
+
+		// A streamed signature must be accepted by the regular verifier.
+		s, err := signature.NewSignerStream(privKeysetHandle)
+		if err != nil {
+			t.Fatalf("signature.NewSignerStream() failed: %s", err)
+		}
+		writeInChunks(t, s, data)
+		sig, err := s.Finish()
+		if err != nil {
+			t.Fatalf("s.Finish() failed: %s", err)
+		}
+		if err := verifier.Verify(sig, data); err != nil {
+			t.Errorf("key %d: verifier.Verify() of a streamed signature failed: %s", primary.KeyId, err)
+		}
+		if _, err := s.Finish(); err == nil {
+			t.Errorf("key %d: second s.Finish() succeeded, want error", primary.KeyId)
+		}
+
+		// A regular signature must be accepted by the streaming verifier.
+		signer, err := signature.NewSigner(privKeysetHandle)
+		if err != nil {
+			t.Fatalf("signature.NewSigner() failed: %s", err)
+		}
+		sig, err = signer.Sign(data)
+		if err != nil {
+			t.Fatalf("signer.Sign() failed: %s", err)
+		}
+		v, err := signature.NewVerifierStream(pubKeysetHandle)
+		if err != nil {
+			t.Fatalf("signature.NewVerifierStream() failed: %s", err)
+		}
+		writeInChunks(t, v, data)
+		if err := v.Finish(sig); err != nil {
+			t.Errorf("key %d: v.Finish() failed: %s", primary.KeyId, err)
+		}
+
+		// Modified data must be rejected.
+		v, err = signature.NewVerifierStream(pubKeysetHandle)
+		if err != nil {
+			t.Fatalf("signature.NewVerifierStream() failed: %s", err)
+		}
+		writeInChunks(t, v, data[1:])
+		if err := v.Finish(sig); err == nil {
+			t.Errorf("key %d: v.Finish() with modified data succeeded, want error", primary.KeyId)
+		}
+	}
+}
+
+func TestVerifierStreamWithInvalidSignature(t *testing.T) {
+	kh, err := keyset.NewHandle(signature.ECDSAP256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() failed: %s", err)
+	}
+	pub, err := kh.Public()
+	if err != nil {
+		t.Fatalf("kh.Public() failed: %s", err)
+	}
+	for _, sig := range [][]byte{nil, {1, 2, 3}, random.GetRandomBytes(72)} {
+		v, err := signature.NewVerifierStream(pub)
+		if err != nil {
+			t.Fatalf("signature.NewVerifierStream() failed: %s", err)
+		}
+		if _, err := v.Write([]byte("data")); err != nil {
+			t.Fatalf("v.Write() failed: %s", err)
+		}
+		if err := v.Finish(sig); err == nil {
+			t.Errorf("v.Finish(%x) succeeded, want error", sig)
+		}
+		if _, err := v.Write([]byte("data")); err == nil {
+			t.Errorf("v.Write() after Finish() succeeded, want error")
+		}
+	}
+}
+
+func TestStreamFactoriesWithWrongKeyset(t *testing.T) {
+	kh, err := keyset.NewHandle(signature.ECDSAP256KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() failed: %s", err)
+	}
+	pub, err := kh.Public()
+	if err != nil {
+		t.Fatalf("kh.Public() failed: %s", err)
+	}
+	if _, err := signature.NewSignerStream(pub); err == nil {
+		t.Errorf("signature.NewSignerStream() with a public keyset succeeded, want error")
+	}
+	if _, err := signature.NewVerifierStream(kh); err == nil {
+		t.Errorf("signature.NewVerifierStream() with a private keyset succeeded, want error")
+	}
+}
+
+// allowOnlyPolicy allows a single operation for every key.
+type allowOnlyPolicy string
+
+func (p allowOnlyPolicy) Allow(keyID uint32, op string) error {
+	if op != string(p) {
+		return errors.New("operation not allowed")
+	}
+	return nil
+}
+
+// isUsageDenied returns whether err is a *keyset.UsageDeniedError for op.
+func isUsageDenied(err error, op string) bool {
+	denied, ok := err.(*keyset.UsageDeniedError)
+	return ok && denied.Op == op
+}
+
+func TestStreamsWithUsagePolicy(t *testing.T) {
+	for _, template := range []*tinkpb.KeyTemplate{signature.ECDSAP256KeyTemplate(), signature.ED25519KeyTemplate()} {
+		kh, err := keyset.NewHandle(template)
+		if err != nil {
+			t.Fatalf("keyset.NewHandle() failed: %s", err)
+		}
+		pub, err := kh.Public()
+		if err != nil {
+			t.Fatalf("kh.Public() failed: %s", err)
+		}
+		data := random.GetRandomBytes(300)
+		signer, err := signature.NewSigner(kh)
+		if err != nil {
+			t.Fatalf("signature.NewSigner() failed: %s", err)
+		}
+		sig, err := signer.Sign(data)
+		if err != nil {
+			t.Fatalf("signer.Sign() failed: %s", err)
+		}
+
+		// A verify-only handle cannot sign.
+		s, err := signature.NewSignerStream(kh.WithUsagePolicy(allowOnlyPolicy(keyset.OpVerify)))
+		if err != nil {
+			t.Fatalf("signature.NewSignerStream() failed: %s", err)
+		}
+		writeInChunks(t, s, data)
+		if _, err := s.Finish(); !isUsageDenied(err, keyset.OpSign) {
+			t.Errorf("s.Finish() with verify-only policy err = %v, want *keyset.UsageDeniedError for %s", err, keyset.OpSign)
+		}
+
+		// A sign-only handle cannot verify.
+		v, err := signature.NewVerifierStream(pub.WithUsagePolicy(allowOnlyPolicy(keyset.OpSign)))
+		if err != nil {
+			t.Fatalf("signature.NewVerifierStream() failed: %s", err)
+		}
+		writeInChunks(t, v, data)
+		if err := v.Finish(sig); !isUsageDenied(err, keyset.OpVerify) {
+			t.Errorf("v.Finish() with sign-only policy err = %v, want *keyset.UsageDeniedError for %s", err, keyset.OpVerify)
+		}
+
+		// The allowed operations succeed.
+		v, err = signature.NewVerifierStream(pub.WithUsagePolicy(allowOnlyPolicy(keyset.OpVerify)))
+		if err != nil {
+			t.Fatalf("signature.NewVerifierStream() failed: %s", err)
+		}
+		writeInChunks(t, v, data)
+		if err := v.Finish(sig); err != nil {
+			t.Errorf("v.Finish() with verify-only policy err = %v, want nil", err)
+		}
+		s, err = signature.NewSignerStream(kh.WithUsagePolicy(allowOnlyPolicy(keyset.OpSign)))
+		if err != nil {
+			t.Fatalf("signature.NewSignerStream() failed: %s", err)
+		}
+		writeInChunks(t, s, data)
+		if _, err := s.Finish(); err != nil {
+			t.Errorf("s.Finish() with sign-only policy err = %v, want nil", err)
+		}
+	}
+}
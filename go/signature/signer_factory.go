@@ -39,18 +39,22 @@ func NewSignerWithKeyManager(h *keyset.Handle, km registry.KeyManager) (tink.Sig
 		return nil, fmt.Errorf("public_key_sign_factory: cannot obtain primitive set: %s", err)
 	}
 
-	return newWrappedSigner(ps)
+	return newWrappedSigner(ps, h.UsagePolicy())
 }
 
 // wrappedSigner is an Signer implementation that uses the underlying primitive set for signing.
 type wrappedSigner struct {
 	ps *primitiveset.PrimitiveSet
+
+	// policy is consulted before each use of a key, see
+	// keyset.Handle.WithUsagePolicy.
+	policy keyset.UsagePolicy
 }
 
 // Asserts that wrappedSigner implements the Signer interface.
 var _ tink.Signer = (*wrappedSigner)(nil)
 
-func newWrappedSigner(ps *primitiveset.PrimitiveSet) (*wrappedSigner, error) {
+func newWrappedSigner(ps *primitiveset.PrimitiveSet, policy keyset.UsagePolicy) (*wrappedSigner, error) {
 	if _, ok := (ps.Primary.Primitive).(tink.Signer); !ok {
 		return nil, fmt.Errorf("public_key_sign_factory: not a Signer primitive")
 	}
@@ -65,6 +69,7 @@ func newWrappedSigner(ps *primitiveset.PrimitiveSet) (*wrappedSigner, error) {
 
 	ret := new(wrappedSigner)
 	ret.ps = ps
+	ret.policy = policy
 
 	return ret, nil
 }
@@ -77,6 +82,9 @@ func (s *wrappedSigner) Sign(data []byte) ([]byte, error) {
 	if !ok {
 		return nil, fmt.Errorf("public_key_sign_factory: not a Signer primitive")
 	}
+	if err := keyset.CheckUsage(s.policy, primary.KeyID, keyset.OpSign); err != nil {
+		return nil, err
+	}
 
 	var signedData []byte
 	if primary.PrefixType == tinkpb.OutputPrefixType_LEGACY {
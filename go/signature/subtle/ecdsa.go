@@ -17,6 +17,7 @@
 package subtle
 
 import (
+	"crypto/elliptic"
 	"errors"
 	"fmt"
 	"math/big"
@@ -72,6 +73,41 @@ func DecodeECDSASignature(encodedBytes []byte, encoding string) (*ECDSASignature
 	return sig, nil
 }
 
+// ECDSAOption configures an ECDSASigner or ECDSAVerifier.
+type ECDSAOption func(*ecdsaOptions)
+
+type ecdsaOptions struct {
+	lowS bool
+}
+
+// WithLowS enforces canonical, low-S signatures, i.e. signatures whose s
+// value is at most half the order of the curve. For each valid signature
+// (r, s), (r, n - s) is valid as well; accepting only one of them makes
+// signatures non-malleable.
+//
+// An ECDSASigner created with this option only produces low-S signatures. An
+// ECDSAVerifier created with this option rejects high-S signatures, including
+// otherwise valid signatures from signers that do not normalize s.
+func WithLowS() ECDSAOption {
+	return func(o *ecdsaOptions) {
+		o.lowS = true
+	}
+}
+
+func newECDSAOptions(opts []ECDSAOption) ecdsaOptions {
+	var o ecdsaOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// isLowS returns true if s is at most half the order of the curve.
+func isLowS(s *big.Int, c elliptic.Curve) bool {
+	halfOrder := new(big.Int).Rsh(c.Params().N, 1)
+	return s.Cmp(halfOrder) <= 0
+}
+
 // ValidateECDSAParams validates ECDSA parameters.
 // The hash's strength must not be weaker than the curve's strength.
 // DER and IEEE_P1363 encodings are supported.
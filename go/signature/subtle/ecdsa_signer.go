@@ -33,25 +33,30 @@ type ECDSASigner struct {
 	privateKey *ecdsa.PrivateKey
 	hashFunc   func() hash.Hash
 	encoding   string
+	lowS       bool
 }
 
 // NewECDSASigner creates a new instance of ECDSASigner.
+// WithLowS can be passed to only produce signatures in low-S form.
 func NewECDSASigner(hashAlg string,
 	curve string,
 	encoding string,
-	keyValue []byte) (*ECDSASigner, error) {
+	keyValue []byte,
+	opts ...ECDSAOption) (*ECDSASigner, error) {
 	privKey := new(ecdsa.PrivateKey)
 	c := subtle.GetCurve(curve)
 	privKey.PublicKey.Curve = c
 	privKey.D = new(big.Int).SetBytes(keyValue)
 	privKey.PublicKey.X, privKey.PublicKey.Y = c.ScalarBaseMult(keyValue)
-	return NewECDSASignerFromPrivateKey(hashAlg, encoding, privKey)
+	return NewECDSASignerFromPrivateKey(hashAlg, encoding, privKey, opts...)
 }
 
-// NewECDSASignerFromPrivateKey creates a new instance of ECDSASigner
+// NewECDSASignerFromPrivateKey creates a new instance of ECDSASigner.
+// WithLowS can be passed to only produce signatures in low-S form.
 func NewECDSASignerFromPrivateKey(hashAlg string,
 	encoding string,
-	privateKey *ecdsa.PrivateKey) (*ECDSASigner, error) {
+	privateKey *ecdsa.PrivateKey,
+	opts ...ECDSAOption) (*ECDSASigner, error) {
 	if privateKey.Curve == nil {
 		return nil, errors.New("ecdsa_signer: privateKey.Curve can't be nil")
 	}
@@ -64,6 +69,7 @@ func NewECDSASignerFromPrivateKey(hashAlg string,
 		privateKey: privateKey,
 		hashFunc:   hashFunc,
 		encoding:   encoding,
+		lowS:       newECDSAOptions(opts).lowS,
 	}, nil
 }
 
@@ -73,10 +79,24 @@ func (e *ECDSASigner) Sign(data []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return e.SignDigest(hashed)
+}
+
+// NewHash returns a new instance of the hash function used by the signer.
+func (e *ECDSASigner) NewHash() hash.Hash {
+	return e.hashFunc()
+}
+
+// SignDigest computes a signature for the given digest, which must be the
+// output of the hash function returned by NewHash.
+func (e *ECDSASigner) SignDigest(hashed []byte) ([]byte, error) {
 	r, s, err := ecdsa.Sign(rand.Reader, e.privateKey, hashed)
 	if err != nil {
 		return nil, fmt.Errorf("ecdsa_signer: signing failed: %s", err)
 	}
+	if e.lowS && !isLowS(s, e.privateKey.Curve) {
+		s.Sub(e.privateKey.Curve.Params().N, s)
+	}
 	// format the signature
 	sig := NewECDSASignature(r, s)
 	ret, err := sig.EncodeECDSASignature(e.encoding, e.privateKey.PublicKey.Curve.Params().Name)
@@ -20,6 +20,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"fmt"
+	"math/big"
 	"testing"
 
 	subtleSignature "github.com/google/tink/go/signature/subtle"
@@ -71,9 +72,55 @@ func TestSignVerify(t *testing.T) {
 	}
 }
 
+func TestSignVerifyDigest(t *testing.T) {
+	data := random.GetRandomBytes(20)
+	priv, _ := ecdsa.GenerateKey(subtle.GetCurve("NIST_P256"), rand.Reader)
+	signer, err := subtleSignature.NewECDSASignerFromPrivateKey("SHA256", "DER", priv)
+	if err != nil {
+		t.Fatalf("unexpected error when creating ECDSASigner: %s", err)
+	}
+	verifier, err := subtleSignature.NewECDSAVerifierFromPublicKey("SHA256", "DER", &priv.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error when creating ECDSAVerifier: %s", err)
+	}
+	h := signer.NewHash()
+	h.Write(data)
+	signature, err := signer.SignDigest(h.Sum(nil))
+	if err != nil {
+		t.Fatalf("unexpected error when signing: %s", err)
+	}
+	if err := verifier.Verify(signature, data); err != nil {
+		t.Errorf("Verify() of a digest signature failed: %s", err)
+	}
+	signature, err = signer.Sign(data)
+	if err != nil {
+		t.Fatalf("unexpected error when signing: %s", err)
+	}
+	h = verifier.NewHash()
+	h.Write(data)
+	if err := verifier.VerifyDigest(signature, h.Sum(nil)); err != nil {
+		t.Errorf("VerifyDigest() failed: %s", err)
+	}
+	if err := verifier.VerifyDigest(signature, data); err == nil {
+		t.Errorf("VerifyDigest() with a wrong digest succeeded, want error")
+	}
+}
+
 func TestECDSAWycheproofCases(t *testing.T) {
 	testutil.SkipTestIfTestSrcDirIsNotSet(t)
+	runECDSAWycheproofCases(t, false)
+}
 
+func TestECDSAWycheproofCasesWithLowS(t *testing.T) {
+	testutil.SkipTestIfTestSrcDirIsNotSet(t)
+	runECDSAWycheproofCases(t, true)
+}
+
+// runECDSAWycheproofCases runs the Wycheproof ECDSA test vectors against
+// ECDSAVerifier. If lowS is set, the verifier is created with WithLowS and
+// valid test cases with a high-S signature are expected to fail.
+func runECDSAWycheproofCases(t *testing.T, lowS bool) {
+	t.Helper()
 	vectors := []struct {
 		Filename string
 		Encoding string
@@ -83,6 +130,10 @@ func TestECDSAWycheproofCases(t *testing.T) {
 		{"ecdsa_secp384r1_sha512_p1363_test.json", "IEEE_P1363"},
 		{"ecdsa_secp521r1_sha512_p1363_test.json", "IEEE_P1363"},
 	}
+	var opts []subtleSignature.ECDSAOption
+	if lowS {
+		opts = append(opts, subtleSignature.WithLowS())
+	}
 
 	for _, v := range vectors {
 		suite := new(ecdsaSuite)
@@ -105,15 +156,26 @@ func TestECDSAWycheproofCases(t *testing.T) {
 				t.Errorf("cannot decode wy: %s", err)
 				continue
 			}
-			verifier, err := subtleSignature.NewECDSAVerifier(hash, curve, v.Encoding, x.Bytes(), y.Bytes())
+			verifier, err := subtleSignature.NewECDSAVerifier(hash, curve, v.Encoding, x.Bytes(), y.Bytes(), opts...)
 			if err != nil {
 				continue
 			}
+			halfOrder := new(big.Int).Rsh(subtle.GetCurve(curve).Params().N, 1)
 			for _, test := range group.Tests {
 				caseName := fmt.Sprintf("%s-%s:Case-%d", group.Type, group.SHA, test.CaseID)
 				t.Run(caseName, func(t *testing.T) {
 					err := verifier.Verify(test.Signature, test.Message)
-					switch test.Result {
+					result := test.Result
+					if lowS && result == "valid" {
+						sig, decodeErr := subtleSignature.DecodeECDSASignature(test.Signature, v.Encoding)
+						if decodeErr != nil {
+							t.Fatalf("cannot decode a valid signature: %s", decodeErr)
+						}
+						if sig.S.Cmp(halfOrder) > 0 {
+							result = "invalid"
+						}
+					}
+					switch result {
 					case "valid":
 						if err != nil {
 							t.Fatalf("ECDSAVerifier.Verify() failed in a valid test case: %s", err)
@@ -132,3 +194,58 @@ func TestECDSAWycheproofCases(t *testing.T) {
 		}
 	}
 }
+
+func TestSignVerifyWithLowS(t *testing.T) {
+	for _, curve := range []string{"NIST_P256", "NIST_P384", "NIST_P521"} {
+		hash := map[string]string{"NIST_P256": "SHA256", "NIST_P384": "SHA384", "NIST_P521": "SHA512"}[curve]
+		c := subtle.GetCurve(curve)
+		halfOrder := new(big.Int).Rsh(c.Params().N, 1)
+		priv, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatalf("ecdsa.GenerateKey() failed: %s", err)
+		}
+		signer, err := subtleSignature.NewECDSASignerFromPrivateKey(hash, "IEEE_P1363", priv, subtleSignature.WithLowS())
+		if err != nil {
+			t.Fatalf("unexpected error when creating ECDSASigner: %s", err)
+		}
+		verifier, err := subtleSignature.NewECDSAVerifierFromPublicKey(hash, "IEEE_P1363", &priv.PublicKey, subtleSignature.WithLowS())
+		if err != nil {
+			t.Fatalf("unexpected error when creating ECDSAVerifier: %s", err)
+		}
+		lenient, err := subtleSignature.NewECDSAVerifierFromPublicKey(hash, "IEEE_P1363", &priv.PublicKey)
+		if err != nil {
+			t.Fatalf("unexpected error when creating ECDSAVerifier: %s", err)
+		}
+		data := random.GetRandomBytes(20)
+		// Unnormalized signatures have a high S with probability 1/2.
+		for i := 0; i < 20; i++ {
+			signature, err := signer.Sign(data)
+			if err != nil {
+				t.Fatalf("unexpected error when signing: %s", err)
+			}
+			sig, err := subtleSignature.DecodeECDSASignature(signature, "IEEE_P1363")
+			if err != nil {
+				t.Fatalf("cannot decode signature: %s", err)
+			}
+			if sig.S.Cmp(halfOrder) > 0 {
+				t.Fatalf("%s: signer created with WithLowS produced a high-S signature", curve)
+			}
+			if err := verifier.Verify(signature, data); err != nil {
+				t.Errorf("%s: verifier.Verify() of a low-S signature failed: %s", curve, err)
+			}
+
+			// (r, n - s) is a valid but malleated signature of the same data.
+			highS := subtleSignature.NewECDSASignature(sig.R, new(big.Int).Sub(c.Params().N, sig.S))
+			malleated, err := highS.EncodeECDSASignature("IEEE_P1363", c.Params().Name)
+			if err != nil {
+				t.Fatalf("cannot encode signature: %s", err)
+			}
+			if err := lenient.Verify(malleated, data); err != nil {
+				t.Errorf("%s: verifier.Verify() without WithLowS of a high-S signature failed: %s", curve, err)
+			}
+			if err := verifier.Verify(malleated, data); err == nil {
+				t.Errorf("%s: verifier.Verify() with WithLowS of a high-S signature succeeded, want error", curve)
+			}
+		}
+	}
+}
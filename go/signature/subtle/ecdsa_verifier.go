@@ -26,7 +26,10 @@ import (
 	"github.com/google/tink/go/subtle"
 )
 
-var errInvalidECDSASignature = errors.New("ecdsa_verifier: invalid signature")
+var (
+	errInvalidECDSASignature = errors.New("ecdsa_verifier: invalid signature")
+	errHighSECDSASignature   = errors.New("ecdsa_verifier: signature is not in low-S form")
+)
 
 // ECDSAVerifier is an implementation of Verifier for ECDSA.
 // At the moment, the implementation only accepts signatures with strict DER encoding.
@@ -34,20 +37,23 @@ type ECDSAVerifier struct {
 	publicKey *ecdsa.PublicKey
 	hashFunc  func() hash.Hash
 	encoding  string
+	lowS      bool
 }
 
 // NewECDSAVerifier creates a new instance of ECDSAVerifier.
-func NewECDSAVerifier(hashAlg string, curve string, encoding string, x []byte, y []byte) (*ECDSAVerifier, error) {
+// WithLowS can be passed to reject signatures that are not in low-S form.
+func NewECDSAVerifier(hashAlg string, curve string, encoding string, x []byte, y []byte, opts ...ECDSAOption) (*ECDSAVerifier, error) {
 	publicKey := &ecdsa.PublicKey{
 		Curve: subtle.GetCurve(curve),
 		X:     new(big.Int).SetBytes(x),
 		Y:     new(big.Int).SetBytes(y),
 	}
-	return NewECDSAVerifierFromPublicKey(hashAlg, encoding, publicKey)
+	return NewECDSAVerifierFromPublicKey(hashAlg, encoding, publicKey, opts...)
 }
 
 // NewECDSAVerifierFromPublicKey creates a new instance of ECDSAVerifier.
-func NewECDSAVerifierFromPublicKey(hashAlg string, encoding string, publicKey *ecdsa.PublicKey) (*ECDSAVerifier, error) {
+// WithLowS can be passed to reject signatures that are not in low-S form.
+func NewECDSAVerifierFromPublicKey(hashAlg string, encoding string, publicKey *ecdsa.PublicKey, opts ...ECDSAOption) (*ECDSAVerifier, error) {
 	if publicKey.Curve == nil {
 		return nil, errors.New("ecdsa_verifier: invalid curve")
 	}
@@ -60,6 +66,7 @@ func NewECDSAVerifierFromPublicKey(hashAlg string, encoding string, publicKey *e
 		publicKey: publicKey,
 		hashFunc:  hashFunc,
 		encoding:  encoding,
+		lowS:      newECDSAOptions(opts).lowS,
 	}, nil
 }
 
@@ -74,6 +81,29 @@ func (e *ECDSAVerifier) Verify(signatureBytes, data []byte) error {
 	if err != nil {
 		return err
 	}
+	return e.verifyDigest(signature, hashed)
+}
+
+// NewHash returns a new instance of the hash function used by the verifier.
+func (e *ECDSAVerifier) NewHash() hash.Hash {
+	return e.hashFunc()
+}
+
+// VerifyDigest verifies whether the given signature is valid for the given
+// digest, which must be the output of the hash function returned by NewHash.
+// It returns an error if the signature is not valid; nil otherwise.
+func (e *ECDSAVerifier) VerifyDigest(signatureBytes, hashed []byte) error {
+	signature, err := DecodeECDSASignature(signatureBytes, e.encoding)
+	if err != nil {
+		return fmt.Errorf("ecdsa_verifier: %s", err)
+	}
+	return e.verifyDigest(signature, hashed)
+}
+
+func (e *ECDSAVerifier) verifyDigest(signature *ECDSASignature, hashed []byte) error {
+	if e.lowS && !isLowS(signature.S, e.publicKey.Curve) {
+		return errHighSECDSASignature
+	}
 	valid := ecdsa.Verify(e.publicKey, hashed, signature.R, signature.S)
 	if !valid {
 		return errInvalidECDSASignature
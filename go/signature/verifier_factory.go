@@ -40,23 +40,55 @@ func NewVerifierWithKeyManager(h *keyset.Handle, km registry.KeyManager) (tink.V
 	if err != nil {
 		return nil, fmt.Errorf("verifier_factory: cannot obtain primitive set: %s", err)
 	}
-	return newWrappedVerifier(ps)
+	return newWrappedVerifier(ps, h.UsagePolicy())
+}
+
+// NewVerifierLenient is like NewVerifier, but also accepts a keyset whose
+// primary key is not ENABLED, e.g. because it was DISABLED during an
+// emergency rotation, as long as the keyset has at least one ENABLED key.
+// Verification tries all ENABLED keys and does not need a primary key, so
+// signatures of the remaining ENABLED keys still verify.
+//
+// This only applies to verification. keyset.Validate accepts a keyset
+// without ENABLED primary key only if it contains just public keys, and
+// signing always requires an ENABLED primary key.
+func NewVerifierLenient(h *keyset.Handle) (tink.Verifier, error) {
+	ps, err := h.Primitives()
+	if err != nil {
+		return nil, fmt.Errorf("verifier_factory: cannot obtain primitive set: %s", err)
+	}
+	if len(ps.AllEntries()) == 0 {
+		return nil, fmt.Errorf("verifier_factory: keyset has no ENABLED key")
+	}
+	return newWrappedVerifierWithoutPrimary(ps, h.UsagePolicy())
 }
 
 // verifierSet is a Verifier implementation that uses the
 // underlying primitive set for verifying.
 type wrappedVerifier struct {
 	ps *primitiveset.PrimitiveSet
+
+	// policy is consulted before each use of a key, see
+	// keyset.Handle.WithUsagePolicy.
+	policy keyset.UsagePolicy
 }
 
 // Asserts that verifierSet implements the Verifier interface.
 var _ tink.Verifier = (*wrappedVerifier)(nil)
 
-func newWrappedVerifier(ps *primitiveset.PrimitiveSet) (*wrappedVerifier, error) {
+func newWrappedVerifier(ps *primitiveset.PrimitiveSet, policy keyset.UsagePolicy) (*wrappedVerifier, error) {
+	if ps.Primary == nil {
+		return nil, fmt.Errorf("verifier_factory: keyset has no ENABLED primary key")
+	}
 	if _, ok := (ps.Primary.Primitive).(tink.Verifier); !ok {
 		return nil, fmt.Errorf("verifier_factory: not a Verifier primitive")
 	}
+	return newWrappedVerifierWithoutPrimary(ps, policy)
+}
 
+// newWrappedVerifierWithoutPrimary is like newWrappedVerifier, but does not
+// require ps to have a primary entry.
+func newWrappedVerifierWithoutPrimary(ps *primitiveset.PrimitiveSet, policy keyset.UsagePolicy) (*wrappedVerifier, error) {
 	for _, primitives := range ps.Entries {
 		for _, p := range primitives {
 			if _, ok := (p.Primitive).(tink.Verifier); !ok {
@@ -67,6 +99,7 @@ func newWrappedVerifier(ps *primitiveset.PrimitiveSet) (*wrappedVerifier, error)
 
 	ret := new(wrappedVerifier)
 	ret.ps = ps
+	ret.policy = policy
 
 	return ret, nil
 }
@@ -84,11 +117,21 @@ func (v *wrappedVerifier) Verify(signature, data []byte) error {
 	prefix := signature[:prefixSize]
 	signatureNoPrefix := signature[prefixSize:]
 	entries, err := v.ps.EntriesForPrefix(string(prefix))
+	// denied is the first error of the usage policy.
+	var denied error
 	if err == nil {
 		for i := 0; i < len(entries); i++ {
+			if err := keyset.CheckUsage(v.policy, entries[i].KeyID, keyset.OpVerify); err != nil {
+				if denied == nil {
+					denied = err
+				}
+				continue
+			}
 			var signedData []byte
 			if entries[i].PrefixType == tinkpb.OutputPrefixType_LEGACY {
-				signedData = append(data, byte(0))
+				// Cap data so that append never writes into the caller's
+				// memory, which would race with concurrent calls.
+				signedData = append(data[:len(data):len(data)], byte(0))
 			} else {
 				signedData = data
 			}
@@ -112,6 +155,12 @@ func (v *wrappedVerifier) Verify(signature, data []byte) error {
 			if !ok {
 				return fmt.Errorf("verifier_factory: not an Verifier primitive")
 			}
+			if err := keyset.CheckUsage(v.policy, entries[i].KeyID, keyset.OpVerify); err != nil {
+				if denied == nil {
+					denied = err
+				}
+				continue
+			}
 
 			if err = verifier.Verify(signature, data); err == nil {
 				return nil
@@ -119,5 +168,8 @@ func (v *wrappedVerifier) Verify(signature, data []byte) error {
 		}
 	}
 
+	if denied != nil {
+		return denied
+	}
 	return errInvalidSignature
 }
@@ -0,0 +1,69 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package signature
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/tink/go/keyset"
+)
+
+// BatchItem is a signature together with the data it signs.
+type BatchItem struct {
+	Sig  []byte
+	Data []byte
+}
+
+// VerifyBatch verifies the signatures of items with the Verifier primitive of
+// h and returns one result per item, in the order of items: nil if the
+// signature is valid, an error otherwise. Each item is verified like
+// Verifier.Verify does, trying all keys of h that may have created the
+// signature. The items are distributed over up to runtime.GOMAXPROCS(0)
+// goroutines. If no Verifier can be created from h, every result is that error.
+func VerifyBatch(h *keyset.Handle, items []BatchItem) []error {
+	errs := make([]error, len(items))
+	v, err := NewVerifier(h)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(items) {
+		workers = len(items)
+	}
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(items) {
+					return
+				}
+				errs[i] = v.Verify(items[i].Sig, items[i].Data)
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
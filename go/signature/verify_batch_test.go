@@ -0,0 +1,143 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package signature_test
+
+import (
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/signature"
+	"github.com/google/tink/go/subtle/random"
+	"github.com/google/tink/go/tink"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+// newMixedSigners returns a public keyset handle with ECDSA and Ed25519 keys
+// of all output prefix types, and a signer for each of its keys.
+func newMixedSigners(t testing.TB) (*keyset.Handle, []tink.Signer) {
+	t.Helper()
+	legacy := signature.ECDSAP256KeyTemplate()
+	legacy.OutputPrefixType = tinkpb.OutputPrefixType_LEGACY
+	templates := []*tinkpb.KeyTemplate{
+		signature.ECDSAP256KeyTemplate(),
+		signature.ED25519KeyTemplate(),
+		legacy,
+		signature.ECDSAP384KeyWithoutPrefixTemplate(),
+		signature.ED25519KeyWithoutPrefixTemplate(),
+	}
+	ksm := keyset.NewManager()
+	var signers []tink.Signer
+	var h *keyset.Handle
+	for _, kt := range templates {
+		if err := ksm.Rotate(kt); err != nil {
+			t.Fatalf("ksm.Rotate() err = %v", err)
+		}
+		var err error
+		h, err = ksm.Handle()
+		if err != nil {
+			t.Fatalf("ksm.Handle() err = %v", err)
+		}
+		s, err := signature.NewSigner(h)
+		if err != nil {
+			t.Fatalf("signature.NewSigner() err = %v", err)
+		}
+		signers = append(signers, s)
+	}
+	pub, err := h.Public()
+	if err != nil {
+		t.Fatalf("h.Public() err = %v", err)
+	}
+	return pub, signers
+}
+
+// newBatch returns n items signed by signers in turn. The data of the items
+// are adjacent parts of one buffer. Every third signature is invalid.
+func newBatch(t testing.TB, signers []tink.Signer, n int) ([]signature.BatchItem, []bool) {
+	t.Helper()
+	const dataSize = 32
+	buf := random.GetRandomBytes(uint32(n * dataSize))
+	items := make([]signature.BatchItem, n)
+	valid := make([]bool, n)
+	for i := range items {
+		data := buf[i*dataSize : (i+1)*dataSize]
+		sig, err := signers[i%len(signers)].Sign(data)
+		if err != nil {
+			t.Fatalf("Sign() err = %v", err)
+		}
+		valid[i] = i%3 != 0
+		if !valid[i] {
+			sig[len(sig)-1] ^= 1
+		}
+		items[i] = signature.BatchItem{Sig: sig, Data: data}
+	}
+	return items, valid
+}
+
+func TestVerifyBatch(t *testing.T) {
+	pub, signers := newMixedSigners(t)
+	items, valid := newBatch(t, signers, 300)
+	errs := signature.VerifyBatch(pub, items)
+	if len(errs) != len(items) {
+		t.Fatalf("len(signature.VerifyBatch()) = %d, want %d", len(errs), len(items))
+	}
+	for i, err := range errs {
+		if valid[i] && err != nil {
+			t.Errorf("signature.VerifyBatch()[%d] = %v, want nil", i, err)
+		}
+		if !valid[i] && err == nil {
+			t.Errorf("signature.VerifyBatch()[%d] = nil, want error", i)
+		}
+	}
+	if errs := signature.VerifyBatch(pub, nil); len(errs) != 0 {
+		t.Errorf("signature.VerifyBatch(pub, nil) = %v, want empty", errs)
+	}
+}
+
+func TestVerifyBatchWithoutVerifier(t *testing.T) {
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v", err)
+	}
+	errs := signature.VerifyBatch(h, make([]signature.BatchItem, 3))
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("signature.VerifyBatch()[%d] with a MAC keyset = nil, want error", i)
+		}
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	pub, signers := newMixedSigners(b)
+	items, _ := newBatch(b, signers, 1000)
+	verifier, err := signature.NewVerifier(pub)
+	if err != nil {
+		b.Fatalf("signature.NewVerifier() err = %v", err)
+	}
+	b.Run("serial", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, item := range items {
+				verifier.Verify(item.Sig, item.Data)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			signature.VerifyBatch(pub, items)
+		}
+	})
+}
@@ -34,4 +34,7 @@ func init() {
 	if err := registry.RegisterKeyManager(&aesCTRHMACKeyManager{}); err != nil {
 		panic(fmt.Sprintf("streamingaead.init() failed: %v", err))
 	}
+	if err := registry.RegisterKeyTemplates(SupportedTemplates()); err != nil {
+		panic(fmt.Sprintf("streamingaead.init() failed: %v", err))
+	}
 }
@@ -57,6 +57,21 @@ func NewWithKeyManager(h *keyset.Handle, km registry.KeyManager) (tink.Streaming
 	return tink.StreamingAEAD(ret), nil
 }
 
+// SeekableStreamingAEAD is a tink.StreamingAEAD which also supports random
+// access decryption of ciphertexts stored in an io.ReaderAt.
+//
+// The primitives returned by New implement this interface.
+type SeekableStreamingAEAD interface {
+	tink.StreamingAEAD
+
+	// NewSeekableDecryptingReader returns an io.ReadSeeker that decrypts the
+	// ciphertext of the given size in r, using aad as associated authenticated
+	// data. Only the ciphertext segments covering the data being read are
+	// fetched and decrypted. Seeking to a negative offset or beyond the end of
+	// the plaintext fails.
+	NewSeekableDecryptingReader(r io.ReaderAt, size int64, aad []byte) (io.ReadSeeker, error)
+}
+
 // wrappedStreamingAEAD is an StreamingAEAD implementation that uses the underlying primitive set
 // for deterministic encryption and decryption.
 type wrappedStreamingAEAD struct {
@@ -64,7 +79,7 @@ type wrappedStreamingAEAD struct {
 }
 
 // Asserts that primitiveSet implements the StreamingAEAD interface.
-var _ tink.StreamingAEAD = (*wrappedStreamingAEAD)(nil)
+var _ SeekableStreamingAEAD = (*wrappedStreamingAEAD)(nil)
 
 // NewEncryptingWriter returns a wrapper around underlying io.Writer, such that any write-operation
 // via the wrapper results in AEAD-encryption of the written data, using aad
@@ -90,3 +105,42 @@ func (s *wrappedStreamingAEAD) NewDecryptingReader(r io.Reader, aad []byte) (io.
 		aad:     aad,
 	}, nil
 }
+
+// NewSeekableDecryptingReader returns an io.ReadSeeker for random access
+// decryption of the ciphertext of the given size in r, using aad as associated
+// authenticated data.
+//
+// The key is selected by decrypting the first segment of the ciphertext with
+// each key in turn, so the keys must support random access decryption.
+func (s *wrappedStreamingAEAD) NewSeekableDecryptingReader(r io.ReaderAt, size int64, aad []byte) (io.ReadSeeker, error) {
+	entries, err := s.ps.RawEntries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		sa, ok := e.Primitive.(seekableDecrypter)
+		if !ok {
+			continue
+		}
+		sr, err := sa.NewSeekableDecryptingReader(r, size, aad)
+		if err != nil {
+			continue
+		}
+		// Reading a single byte decrypts the first segment, or the
+		// only segment of an empty plaintext.
+		if _, err := sr.Read(make([]byte, 1)); err != nil && err != io.EOF {
+			continue
+		}
+		if _, err := sr.Seek(0, io.SeekStart); err != nil {
+			continue
+		}
+		return sr, nil
+	}
+	return nil, errKeyNotFound
+}
+
+// seekableDecrypter is implemented by the streaming AEAD primitives which
+// support random access decryption.
+type seekableDecrypter interface {
+	NewSeekableDecryptingReader(r io.ReaderAt, size int64, aad []byte) (io.ReadSeeker, error)
+}
@@ -150,3 +150,67 @@ func TestFactoryWithValidPrimitiveSetType(t *testing.T) {
 		t.Fatalf("New() failed with good *keyset.Handle: %s", err)
 	}
 }
+
+func TestFactorySeekableDecryptingReader(t *testing.T) {
+	ks := testutil.NewTestAESGCMHKDFKeyset()
+	h, err := testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() failed: %v", err)
+	}
+	a, err := streamingaead.New(h)
+	if err != nil {
+		t.Fatalf("streamingaead.New() failed: %v", err)
+	}
+	sa, ok := a.(streamingaead.SeekableStreamingAEAD)
+	if !ok {
+		t.Fatalf("streamingaead.New() does not return a SeekableStreamingAEAD")
+	}
+
+	// Encrypt with a non-primary key, so that the key has to be found.
+	rawKey := ks.Key[1]
+	h2, err := testkeyset.NewHandle(testutil.NewKeyset(rawKey.KeyId, []*tinkpb.Keyset_Key{rawKey}))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() failed: %v", err)
+	}
+	a2, err := streamingaead.New(h2)
+	if err != nil {
+		t.Fatalf("streamingaead.New() failed: %v", err)
+	}
+
+	pt := random.GetRandomBytes(20000)
+	aad := []byte("aad")
+	buf := &bytes.Buffer{}
+	w, err := a2.NewEncryptingWriter(buf, aad)
+	if err != nil {
+		t.Fatalf("NewEncryptingWriter() failed: %v", err)
+	}
+	if _, err := w.Write(pt); err != nil {
+		t.Fatalf("w.Write() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("w.Close() failed: %v", err)
+	}
+	ct := buf.Bytes()
+
+	r, err := sa.NewSeekableDecryptingReader(bytes.NewReader(ct), int64(len(ct)), aad)
+	if err != nil {
+		t.Fatalf("NewSeekableDecryptingReader() failed: %v", err)
+	}
+	if _, err := r.Seek(12345, io.SeekStart); err != nil {
+		t.Fatalf("r.Seek() failed: %v", err)
+	}
+	got := make([]byte, 5000)
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatalf("io.ReadFull() failed: %v", err)
+	}
+	if !bytes.Equal(got, pt[12345:17345]) {
+		t.Errorf("decrypted range does not match the plaintext")
+	}
+	if _, err := r.Seek(int64(len(pt))+1, io.SeekStart); err == nil {
+		t.Errorf("r.Seek() beyond the end succeeded, want error")
+	}
+
+	if _, err := sa.NewSeekableDecryptingReader(bytes.NewReader(ct), int64(len(ct)), []byte("wrong aad")); err == nil {
+		t.Errorf("NewSeekableDecryptingReader() with wrong aad succeeded, want error")
+	}
+}
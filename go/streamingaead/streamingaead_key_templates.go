@@ -30,6 +30,22 @@ import (
 // This file contains pre-generated KeyTemplates for streaming AEAD keys. One can use these templates
 // to generate new Keysets.
 
+// SupportedTemplates returns the streaming AEAD key templates of this package
+// by their canonical names, such as "AES128_GCM_HKDF_4KB". These are the names
+// used by Tinkey. Each call returns a new map.
+func SupportedTemplates() map[string]func() *tinkpb.KeyTemplate {
+	return map[string]func() *tinkpb.KeyTemplate{
+		"AES128_GCM_HKDF_4KB":        AES128GCMHKDF4KBKeyTemplate,
+		"AES128_GCM_HKDF_1MB":        AES128GCMHKDF1MBKeyTemplate,
+		"AES256_GCM_HKDF_4KB":        AES256GCMHKDF4KBKeyTemplate,
+		"AES256_GCM_HKDF_1MB":        AES256GCMHKDF1MBKeyTemplate,
+		"AES128_CTR_HMAC_SHA256_4KB": AES128CTRHMACSHA256Segment4KBKeyTemplate,
+		"AES128_CTR_HMAC_SHA256_1MB": AES128CTRHMACSHA256Segment1MBKeyTemplate,
+		"AES256_CTR_HMAC_SHA256_4KB": AES256CTRHMACSHA256Segment4KBKeyTemplate,
+		"AES256_CTR_HMAC_SHA256_1MB": AES256CTRHMACSHA256Segment1MBKeyTemplate,
+	}
+}
+
 // AES128GCMHKDF4KBKeyTemplate is a KeyTemplate that generates an AES-GCM key with the following parameters:
 //   - Main key size: 16 bytes
 //   - HKDF algo: HMAC-SHA256
@@ -111,3 +111,16 @@ func TestKeyTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportedTemplates(t *testing.T) {
+	templates := streamingaead.SupportedTemplates()
+	if got, want := templates["AES128_GCM_HKDF_4KB"](), streamingaead.AES128GCMHKDF4KBKeyTemplate(); !proto.Equal(got, want) {
+		t.Errorf("SupportedTemplates()[%q]() = %s, want %s", "AES128_GCM_HKDF_4KB", got, want)
+	}
+	for name, newTemplate := range templates {
+		template := newTemplate()
+		if _, err := keyset.NewHandle(template); err != nil {
+			t.Errorf("keyset.NewHandle(SupportedTemplates()[%q]()) failed: %s", name, err)
+		}
+	}
+}
@@ -256,54 +256,65 @@ type aesCTRHMACReader struct {
 	*noncebased.Reader
 }
 
-// NewDecryptingReader returns a wrapper around underlying io.Reader, such that
-// any read-operation via the wrapper results in AEAD-decryption of the
-// underlying ciphertext, using aad as associated authenticated data.
-func (a *AESCTRHMAC) NewDecryptingReader(r io.Reader, aad []byte) (io.Reader, error) {
+// readHeader reads the header of a ciphertext from r and returns the segment
+// decrypter and the nonce prefix for that ciphertext.
+func (a *AESCTRHMAC) readHeader(r io.Reader, aad []byte) (noncebased.SegmentDecrypter, []byte, error) {
 	hlen := make([]byte, 1)
 	if _, err := io.ReadFull(r, hlen); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if hlen[0] != byte(a.HeaderLength()) {
-		return nil, errors.New("invalid header length")
+		return nil, nil, errors.New("invalid header length")
 	}
 
 	salt := make([]byte, a.keySizeInBytes)
 	if _, err := io.ReadFull(r, salt); err != nil {
-		return nil, fmt.Errorf("cannot read salt: %v", err)
+		return nil, nil, fmt.Errorf("cannot read salt: %v", err)
 	}
 
 	noncePrefix := make([]byte, AESCTRHMACNoncePrefixSizeInBytes)
 	if _, err := io.ReadFull(r, noncePrefix); err != nil {
-		return nil, fmt.Errorf("cannot read noncePrefix: %v", err)
+		return nil, nil, fmt.Errorf("cannot read noncePrefix: %v", err)
 	}
 
 	km, err := a.deriveKeyMaterial(salt, aad)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	aesKey := make([]byte, a.keySizeInBytes)
 	copy(aesKey, km)
 	blockCipher, err := aes.NewCipher(aesKey)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	hmacKey := make([]byte, AESCTRHMACKeySizeInBytes)
 	copy(hmacKey, km[a.keySizeInBytes:])
 	hmac, err := subtlemac.NewHMAC(a.tagAlg, hmacKey, uint32(a.tagSizeInBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aesCTRHMACSegmentDecrypter{
+		blockCipher:    blockCipher,
+		hmac:           hmac,
+		tagSizeInBytes: a.tagSizeInBytes,
+	}, noncePrefix, nil
+}
+
+// NewDecryptingReader returns a wrapper around underlying io.Reader, such that
+// any read-operation via the wrapper results in AEAD-decryption of the
+// underlying ciphertext, using aad as associated authenticated data.
+func (a *AESCTRHMAC) NewDecryptingReader(r io.Reader, aad []byte) (io.Reader, error) {
+	decrypter, noncePrefix, err := a.readHeader(r, aad)
 	if err != nil {
 		return nil, err
 	}
 
 	nr, err := noncebased.NewReader(noncebased.ReaderParams{
-		R: r,
-		SegmentDecrypter: aesCTRHMACSegmentDecrypter{
-			blockCipher:    blockCipher,
-			hmac:           hmac,
-			tagSizeInBytes: a.tagSizeInBytes,
-		},
+		R:                            r,
+		SegmentDecrypter:             decrypter,
 		NonceSize:                    AESCTRHMACNonceSizeInBytes,
 		NoncePrefix:                  noncePrefix,
 		CiphertextSegmentSize:        a.ciphertextSegmentSize,
@@ -315,3 +326,30 @@ func (a *AESCTRHMAC) NewDecryptingReader(r io.Reader, aad []byte) (io.Reader, er
 
 	return &aesCTRHMACReader{Reader: nr}, nil
 }
+
+// NewSeekableDecryptingReader returns a reader for random access decryption of
+// the ciphertext of the given size in r, using aad as associated
+// authenticated data. Only the segments covering the data being read are
+// decrypted.
+func (a *AESCTRHMAC) NewSeekableDecryptingReader(r io.ReaderAt, size int64, aad []byte) (io.ReadSeeker, error) {
+	decrypter, noncePrefix, err := a.readHeader(io.NewSectionReader(r, 0, size), aad)
+	if err != nil {
+		return nil, err
+	}
+
+	sr, err := noncebased.NewSeekableReader(noncebased.SeekableReaderParams{
+		R:                            r,
+		Size:                         size,
+		CiphertextOffset:             int64(a.HeaderLength()),
+		SegmentDecrypter:             decrypter,
+		NonceSize:                    AESCTRHMACNonceSizeInBytes,
+		NoncePrefix:                  noncePrefix,
+		CiphertextSegmentSize:        a.ciphertextSegmentSize,
+		FirstCiphertextSegmentOffset: a.firstCiphertextSegmentOffset,
+		SegmentOverhead:              a.tagSizeInBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
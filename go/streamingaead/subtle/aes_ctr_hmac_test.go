@@ -228,6 +228,9 @@ func TestAESCTRHMACEncryptDecrypt(t *testing.T) {
 			if err := decrypt(cipher, aad, pt, ct, tc.chunkSize); err != nil {
 				t.Errorf("failure during decryption: %v", err)
 			}
+			if err := decryptSeekable(cipher, aad, pt, ct); err != nil {
+				t.Errorf("failure during random access decryption: %v", err)
+			}
 		})
 	}
 }
@@ -268,6 +271,13 @@ func TestAESCTRHMACModifiedCiphertext(t *testing.T) {
 			}
 		}
 	})
+	t.Run("truncate ciphertext with random access", func(t *testing.T) {
+		for i := 0; i < len(ct); i += 8 {
+			if err := decryptSeekable(cipher, aad, pt, ct[:i]); err == nil {
+				t.Error("expected error")
+			}
+		}
+	})
 	t.Run("append to ciphertext", func(t *testing.T) {
 		sizes := []int{1, segmentSize - len(ct)%segmentSize, segmentSize}
 		for _, size := range sizes {
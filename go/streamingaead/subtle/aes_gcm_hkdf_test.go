@@ -208,6 +208,10 @@ func TestAESGCMHKDFEncryptDecrypt(t *testing.T) {
 			if err := decrypt(cipher, aad, pt, ct, tc.chunkSize); err != nil {
 				t.Error(err)
 			}
+
+			if err := decryptSeekable(cipher, aad, pt, ct); err != nil {
+				t.Error(err)
+			}
 		})
 	}
 }
@@ -247,6 +251,13 @@ func TestAESGCMHKDFModifiedCiphertext(t *testing.T) {
 			}
 		}
 	})
+	t.Run("truncate ciphertext with random access", func(t *testing.T) {
+		for i := 0; i < len(ct); i += 8 {
+			if err := decryptSeekable(cipher, aad, pt, ct[:i]); err == nil {
+				t.Errorf("expected error")
+			}
+		}
+	})
 	t.Run("append to ciphertext", func(t *testing.T) {
 		sizes := []int{1, segmentSize - len(ct)%segmentSize, segmentSize}
 		for _, size := range sizes {
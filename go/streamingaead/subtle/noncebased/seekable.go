@@ -0,0 +1,253 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package noncebased
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrInvalidSeek indicates a seek to a negative offset or to an offset
+	// beyond the end of the plaintext.
+	ErrInvalidSeek = errors.New("seek out of range")
+
+	// ErrInvalidCiphertextSize indicates that the size of the ciphertext does
+	// not correspond to a valid segmentation.
+	ErrInvalidCiphertextSize = errors.New("invalid ciphertext size")
+)
+
+// SeekableReader facilitates random access decryption of ciphertexts created
+// using a Writer.
+//
+// Unlike Reader, it does not consume the ciphertext sequentially. Instead, it
+// maps each plaintext offset to the ciphertext segment that contains it and
+// reads and decrypts only that segment. The position of the last segment is
+// derived from the size of the ciphertext, so a truncated or extended
+// ciphertext fails to decrypt at its last segment.
+type SeekableReader struct {
+	r                            io.ReaderAt
+	segmentDecrypter             SegmentDecrypter
+	nonceSize                    int
+	noncePrefix                  []byte
+	ciphertextSegmentSize        int64
+	firstCiphertextSegmentOffset int64
+	ciphertextOffset             int64
+	segmentOverhead              int64
+	ciphertextSize               int64
+	segmentCnt                   int64
+	plaintextSize                int64
+
+	pos          int64
+	ciphertext   []byte
+	plaintext    []byte
+	plaintextSeg int64 // index of the segment held in plaintext, -1 if none
+	lastVerified bool
+}
+
+// SeekableReaderParams contains the options for instantiating a
+// SeekableReader via NewSeekableReader().
+type SeekableReaderParams struct {
+	// R is the underlying ciphertext.
+	R io.ReaderAt
+
+	// Size is the size of the ciphertext in R, including CiphertextOffset.
+	Size int64
+
+	// CiphertextOffset is the position of the first ciphertext segment in R,
+	// e.g. the size of a header preceding the segments.
+	CiphertextOffset int64
+
+	// SegmentDecrypter provides a method for decrypting segments.
+	SegmentDecrypter SegmentDecrypter
+
+	// NonceSize is the length of generated nonces. It must match the NonceSize
+	// of the Writer used to create the ciphertext.
+	NonceSize int
+
+	// NoncePrefix is a constant that all nonces throughout the ciphertext start
+	// with. It's extracted from the header of the ciphertext.
+	NoncePrefix []byte
+
+	// The size of the ciphertext segments.
+	CiphertextSegmentSize int
+
+	// FirstCiphertexSegmentOffset indicates by how much the first ciphertext
+	// segment is shorter than CiphertextSegmentSize. It must match the value
+	// used by the Writer.
+	FirstCiphertextSegmentOffset int
+
+	// SegmentOverhead is the difference between the size of a ciphertext
+	// segment and the size of the plaintext it decrypts to, e.g. a tag size.
+	SegmentOverhead int
+}
+
+// NewSeekableReader creates a new SeekableReader instance.
+func NewSeekableReader(params SeekableReaderParams) (*SeekableReader, error) {
+	if params.NonceSize-len(params.NoncePrefix) < 5 {
+		return nil, ErrNonceSizeTooShort
+	}
+	segSize := int64(params.CiphertextSegmentSize)
+	firstOffset := int64(params.FirstCiphertextSegmentOffset)
+	overhead := int64(params.SegmentOverhead)
+	if overhead < 0 || firstOffset < 0 || segSize-firstOffset <= overhead {
+		return nil, errors.New("invalid segment parameters")
+	}
+	if params.CiphertextOffset < 0 || params.Size < params.CiphertextOffset+overhead {
+		return nil, ErrInvalidCiphertextSize
+	}
+
+	ctSize := params.Size - params.CiphertextOffset
+	segCnt := int64(1)
+	if firstLen := segSize - firstOffset; ctSize > firstLen {
+		segCnt += (ctSize - firstLen + segSize - 1) / segSize
+	}
+	r := &SeekableReader{
+		r:                            params.R,
+		segmentDecrypter:             params.SegmentDecrypter,
+		nonceSize:                    params.NonceSize,
+		noncePrefix:                  params.NoncePrefix,
+		ciphertextSegmentSize:        segSize,
+		firstCiphertextSegmentOffset: firstOffset,
+		ciphertextOffset:             params.CiphertextOffset,
+		segmentOverhead:              overhead,
+		ciphertextSize:               ctSize,
+		segmentCnt:                   segCnt,
+		ciphertext:                   make([]byte, segSize),
+		plaintextSeg:                 -1,
+	}
+	start, end := r.segmentBounds(segCnt - 1)
+	if end-start < overhead {
+		return nil, ErrInvalidCiphertextSize
+	}
+	r.plaintextSize = ctSize - segCnt*overhead
+	return r, nil
+}
+
+// Size returns the size of the plaintext. It is derived from the size of the
+// ciphertext and is only authenticated once the last segment is decrypted.
+func (r *SeekableReader) Size() int64 {
+	return r.plaintextSize
+}
+
+// segmentBounds returns the start and end of the i-th segment, relative to
+// the first segment.
+func (r *SeekableReader) segmentBounds(i int64) (int64, int64) {
+	var start int64
+	if i > 0 {
+		start = i*r.ciphertextSegmentSize - r.firstCiphertextSegmentOffset
+	}
+	end := (i+1)*r.ciphertextSegmentSize - r.firstCiphertextSegmentOffset
+	if end > r.ciphertextSize {
+		end = r.ciphertextSize
+	}
+	return start, end
+}
+
+// plaintextSegment returns the index of the segment which contains the
+// plaintext byte at offset off, and the position of that byte in the segment.
+func (r *SeekableReader) plaintextSegment(off int64) (int64, int64) {
+	firstLen := r.ciphertextSegmentSize - r.firstCiphertextSegmentOffset - r.segmentOverhead
+	if off < firstLen {
+		return 0, off
+	}
+	ptSegSize := r.ciphertextSegmentSize - r.segmentOverhead
+	off -= firstLen
+	return 1 + off/ptSegSize, off % ptSegSize
+}
+
+// decryptSegment reads and decrypts the i-th segment, unless it's the one
+// decrypted last.
+func (r *SeekableReader) decryptSegment(i int64) error {
+	if r.plaintextSeg == i {
+		return nil
+	}
+	r.plaintextSeg = -1
+	start, end := r.segmentBounds(i)
+	ct := r.ciphertext[:end-start]
+	if n, err := r.r.ReadAt(ct, r.ciphertextOffset+start); n != len(ct) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	last := i == r.segmentCnt-1
+	nonce, err := generateSegmentNonce(r.nonceSize, r.noncePrefix, uint64(i), last)
+	if err != nil {
+		return err
+	}
+	pt, err := r.segmentDecrypter.DecryptSegment(ct, nonce)
+	if err != nil {
+		return err
+	}
+	if int64(len(pt)) != end-start-r.segmentOverhead {
+		return fmt.Errorf("segment %d decrypted to an unexpected size", i)
+	}
+	r.plaintext = pt
+	r.plaintextSeg = i
+	if last {
+		r.lastVerified = true
+	}
+	return nil
+}
+
+// Read decrypts data at the current offset and passes it to p.
+//
+// Reaching the end of the plaintext authenticates the last segment, so io.EOF
+// is only returned for a ciphertext that has not been truncated.
+func (r *SeekableReader) Read(p []byte) (int, error) {
+	if r.pos >= r.plaintextSize {
+		if !r.lastVerified {
+			if err := r.decryptSegment(r.segmentCnt - 1); err != nil {
+				return 0, err
+			}
+		}
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	seg, off := r.plaintextSegment(r.pos)
+	if err := r.decryptSegment(seg); err != nil {
+		return 0, err
+	}
+	n := copy(p, r.plaintext[off:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// Seek implements io.Seeker. Seeking to a negative offset or beyond the end
+// of the plaintext returns ErrInvalidSeek.
+func (r *SeekableReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.plaintextSize + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 || pos > r.plaintextSize {
+		return 0, ErrInvalidSeek
+	}
+	r.pos = pos
+	return pos, nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package noncebased_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/tink/go/streamingaead/subtle/noncebased"
+)
+
+const (
+	seekableNonceSize            = 10
+	seekableNoncePrefixSize      = 5
+	seekablePlaintextSegmentSize = 20
+	seekableFirstSegmentOffset   = 10
+)
+
+// seekableEncrypt encrypts a random plaintext of the given size with a
+// noncebased.Writer and returns the plaintext together with a SeekableReader
+// params for its ciphertext.
+func seekableEncrypt(t *testing.T, plaintextSize int) ([]byte, []byte, noncebased.SeekableReaderParams) {
+	t.Helper()
+	wp := noncebased.WriterParams{
+		NonceSize:                    seekableNonceSize,
+		PlaintextSegmentSize:         seekablePlaintextSegmentSize,
+		FirstCiphertextSegmentOffset: seekableFirstSegmentOffset,
+	}
+	pt, ct, noncePrefix, err := testEncrypt(plaintextSize, seekableNoncePrefixSize, wp)
+	if err != nil {
+		t.Fatalf("encrypting failed: %v", err)
+	}
+	return pt, ct, noncebased.SeekableReaderParams{
+		R:                            bytes.NewReader(ct),
+		Size:                         int64(len(ct)),
+		SegmentDecrypter:             testDecrypter{},
+		NonceSize:                    seekableNonceSize,
+		NoncePrefix:                  noncePrefix,
+		CiphertextSegmentSize:        seekablePlaintextSegmentSize + seekableNonceSize,
+		FirstCiphertextSegmentOffset: seekableFirstSegmentOffset,
+		SegmentOverhead:              seekableNonceSize,
+	}
+}
+
+func TestSeekableReader(t *testing.T) {
+	for _, ptSize := range []int{0, 1, 9, 10, 11, 29, 30, 31, 100, 110} {
+		pt, _, params := seekableEncrypt(t, ptSize)
+		r, err := noncebased.NewSeekableReader(params)
+		if err != nil {
+			t.Fatalf("ptSize %d: NewSeekableReader() failed: %v", ptSize, err)
+		}
+		if r.Size() != int64(ptSize) {
+			t.Errorf("ptSize %d: r.Size() = %d, want %d", ptSize, r.Size(), ptSize)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ptSize %d: ioutil.ReadAll() failed: %v", ptSize, err)
+		}
+		if !bytes.Equal(got, pt) {
+			t.Errorf("ptSize %d: decrypted plaintext does not match", ptSize)
+		}
+		for start := 0; start <= ptSize; start++ {
+			for _, n := range []int{1, 7, 20, 45} {
+				if _, err := r.Seek(int64(start), io.SeekStart); err != nil {
+					t.Fatalf("ptSize %d: r.Seek(%d) failed: %v", ptSize, start, err)
+				}
+				end := start + n
+				if end > ptSize {
+					end = ptSize
+				}
+				buf := make([]byte, end-start)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					t.Fatalf("ptSize %d: reading [%d, %d) failed: %v", ptSize, start, end, err)
+				}
+				if !bytes.Equal(buf, pt[start:end]) {
+					t.Errorf("ptSize %d: plaintext [%d, %d) does not match", ptSize, start, end)
+				}
+			}
+		}
+	}
+}
+
+func TestSeekableReaderSeek(t *testing.T) {
+	pt, _, params := seekableEncrypt(t, 100)
+	r, err := noncebased.NewSeekableReader(params)
+	if err != nil {
+		t.Fatalf("NewSeekableReader() failed: %v", err)
+	}
+	testcases := []struct {
+		offset int64
+		whence int
+		want   int64
+	}{
+		{offset: 42, whence: io.SeekStart, want: 42},
+		{offset: 8, whence: io.SeekCurrent, want: 50},
+		{offset: -10, whence: io.SeekCurrent, want: 40},
+		{offset: -1, whence: io.SeekEnd, want: 99},
+		{offset: 0, whence: io.SeekEnd, want: 100},
+	}
+	for _, tc := range testcases {
+		got, err := r.Seek(tc.offset, tc.whence)
+		if err != nil {
+			t.Fatalf("r.Seek(%d, %d) failed: %v", tc.offset, tc.whence, err)
+		}
+		if got != tc.want {
+			t.Errorf("r.Seek(%d, %d) = %d, want %d", tc.offset, tc.whence, got, tc.want)
+		}
+	}
+	if _, err := r.Seek(-1, io.SeekEnd); err != nil {
+		t.Fatalf("r.Seek(-1, io.SeekEnd) failed: %v", err)
+	}
+	b := make([]byte, 2)
+	n, err := r.Read(b)
+	if err != nil || n != 1 || b[0] != pt[99] {
+		t.Errorf("r.Read() = %d, %v, want the last plaintext byte", n, err)
+	}
+	if _, err := r.Read(b); err != io.EOF {
+		t.Errorf("r.Read() at the end = %v, want io.EOF", err)
+	}
+
+	for _, tc := range []struct {
+		offset int64
+		whence int
+	}{
+		{offset: -1, whence: io.SeekStart},
+		{offset: 101, whence: io.SeekStart},
+		{offset: 1, whence: io.SeekEnd},
+		{offset: -101, whence: io.SeekEnd},
+		{offset: 0, whence: 42},
+	} {
+		if _, err := r.Seek(tc.offset, tc.whence); err == nil {
+			t.Errorf("r.Seek(%d, %d) succeeded, want error", tc.offset, tc.whence)
+		}
+	}
+}
+
+func TestSeekableReaderModifiedCiphertext(t *testing.T) {
+	_, ct, params := seekableEncrypt(t, 100)
+	segSize := int64(seekablePlaintextSegmentSize + seekableNonceSize)
+
+	t.Run("truncated", func(t *testing.T) {
+		// Truncating at a segment boundary leaves a valid segmentation, but the
+		// new last segment was not encrypted as the last one.
+		p := params
+		p.Size = 2*segSize - seekableFirstSegmentOffset
+		r, err := noncebased.NewSeekableReader(p)
+		if err != nil {
+			t.Fatalf("NewSeekableReader() failed: %v", err)
+		}
+		if _, err := r.Seek(0, io.SeekEnd); err != nil {
+			t.Fatalf("r.Seek(0, io.SeekEnd) failed: %v", err)
+		}
+		if _, err := r.Read(make([]byte, 1)); err == nil || err == io.EOF {
+			t.Errorf("r.Read() at the end = %v, want decryption error", err)
+		}
+	})
+
+	t.Run("extended", func(t *testing.T) {
+		ext := append(append([]byte{}, ct...), make([]byte, segSize)...)
+		p := params
+		p.R = bytes.NewReader(ext)
+		p.Size = int64(len(ext))
+		r, err := noncebased.NewSeekableReader(p)
+		if err != nil {
+			t.Fatalf("NewSeekableReader() failed: %v", err)
+		}
+		if _, err := ioutil.ReadAll(r); err == nil {
+			t.Errorf("ioutil.ReadAll() succeeded, want error")
+		}
+	})
+
+	t.Run("shorter than reported", func(t *testing.T) {
+		p := params
+		p.R = bytes.NewReader(ct[:len(ct)-1])
+		r, err := noncebased.NewSeekableReader(p)
+		if err != nil {
+			t.Fatalf("NewSeekableReader() failed: %v", err)
+		}
+		if _, err := ioutil.ReadAll(r); err == nil {
+			t.Errorf("ioutil.ReadAll() succeeded, want error")
+		}
+	})
+
+	t.Run("modified segment", func(t *testing.T) {
+		mod := append([]byte{}, ct...)
+		// Flip a bit in the tag of the second segment.
+		mod[2*segSize-seekableFirstSegmentOffset-1] ^= 1
+		p := params
+		p.R = bytes.NewReader(mod)
+		r, err := noncebased.NewSeekableReader(p)
+		if err != nil {
+			t.Fatalf("NewSeekableReader() failed: %v", err)
+		}
+		// The first segment is unaffected.
+		if _, err := r.Read(make([]byte, 1)); err != nil {
+			t.Errorf("r.Read() of the first segment failed: %v", err)
+		}
+		if _, err := r.Seek(seekablePlaintextSegmentSize-seekableFirstSegmentOffset, io.SeekStart); err != nil {
+			t.Fatalf("r.Seek() failed: %v", err)
+		}
+		if _, err := r.Read(make([]byte, 1)); err == nil {
+			t.Errorf("r.Read() of the modified segment succeeded, want error")
+		}
+	})
+
+	t.Run("last segment too short", func(t *testing.T) {
+		p := params
+		p.Size = 2*segSize - seekableFirstSegmentOffset + seekableNonceSize - 1
+		if _, err := noncebased.NewSeekableReader(p); err != noncebased.ErrInvalidCiphertextSize {
+			t.Errorf("NewSeekableReader() err = %v, want %v", err, noncebased.ErrInvalidCiphertextSize)
+		}
+	})
+}
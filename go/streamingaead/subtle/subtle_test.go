@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/google/tink/go/tink"
 )
@@ -93,6 +94,49 @@ func decrypt(cipher tink.StreamingAEAD, aad, pt, ct []byte, chunkSize int) error
 	return nil
 }
 
+// seekableDecrypter is implemented by the streaming AEADs which support random
+// access decryption.
+type seekableDecrypter interface {
+	NewSeekableDecryptingReader(r io.ReaderAt, size int64, aad []byte) (io.ReadSeeker, error)
+}
+
+// decryptSeekable decrypts ciphertext ct using random access decryption and
+// validates that the whole plaintext as well as ranges starting at various
+// offsets match the original plaintext pt.
+func decryptSeekable(cipher seekableDecrypter, aad, pt, ct []byte) error {
+	r, err := cipher.NewSeekableDecryptingReader(bytes.NewReader(ct), int64(len(ct)), aad)
+	if err != nil {
+		return fmt.Errorf("cannot create a seekable decrypt reader: %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("error reading: %v", err)
+	}
+	if !bytes.Equal(got, pt) {
+		return fmt.Errorf("decrypted data doesn't match")
+	}
+	for _, start := range []int{0, 1, len(pt) / 3, len(pt) / 2, len(pt) - 1} {
+		if start < 0 || start > len(pt) {
+			continue
+		}
+		end := start + 300
+		if end > len(pt) {
+			end = len(pt)
+		}
+		if _, err := r.Seek(int64(start), io.SeekStart); err != nil {
+			return fmt.Errorf("cannot seek to %d: %v", start, err)
+		}
+		got := make([]byte, end-start)
+		if _, err := io.ReadFull(r, got); err != nil {
+			return fmt.Errorf("error reading [%d, %d): %v", start, end, err)
+		}
+		if !bytes.Equal(got, pt[start:end]) {
+			return fmt.Errorf("decrypted data [%d, %d) doesn't match", start, end)
+		}
+	}
+	return nil
+}
+
 func segmentPos(segmentSize, firstSegmentOffset, headerLen, segmentNr int) (int, int) {
 	start := segmentSize * segmentNr
 	end := start + segmentSize
@@ -17,6 +17,7 @@
 package subtle
 
 import (
+	"crypto/hmac"
 	"errors"
 	"fmt"
 	"io"
@@ -53,6 +54,18 @@ func ComputeHKDF(hashAlg string, key []byte, salt []byte, info []byte, tagSize u
 	if err := validateHKDFParams(hashAlg, keySize, tagSize); err != nil {
 		return nil, fmt.Errorf("hkdf: %s", err)
 	}
+	prk, err := HKDFExtract(hashAlg, key, salt)
+	if err != nil {
+		return nil, err
+	}
+	return HKDFExpand(hashAlg, prk, info, tagSize)
+}
+
+// HKDFExtract implements the HKDF-Extract step of RFC 5869. It returns a
+// pseudorandom key of the hash's digest size, computed from the input keying
+// material ikm and salt. An empty salt is replaced by a string of zeros of the
+// digest size, as the RFC specifies.
+func HKDFExtract(hashAlg string, ikm, salt []byte) ([]byte, error) {
 	hashFunc := GetHashFunc(hashAlg)
 	if hashFunc == nil {
 		return nil, fmt.Errorf("hkdf: invalid hash algorithm")
@@ -60,12 +73,87 @@ func ComputeHKDF(hashAlg string, key []byte, salt []byte, info []byte, tagSize u
 	if len(salt) == 0 {
 		salt = make([]byte, hashFunc().Size())
 	}
+	return hkdf.Extract(hashFunc, ikm, salt), nil
+}
 
-	result := make([]byte, tagSize)
-	kdf := hkdf.New(hashFunc, key, salt, info)
-	n, err := io.ReadFull(kdf, result)
+// HKDFExpand implements the HKDF-Expand step of RFC 5869. It returns length
+// bytes of output keying material derived from the pseudorandom key prk and
+// info. prk must be at least as long as the hash's digest, and length must be
+// in the range [1..255*digest size].
+func HKDFExpand(hashAlg string, prk, info []byte, length uint32) ([]byte, error) {
+	hashFunc := GetHashFunc(hashAlg)
+	if hashFunc == nil {
+		return nil, fmt.Errorf("hkdf: invalid hash algorithm")
+	}
+	digestSize := uint32(hashFunc().Size())
+	if uint32(len(prk)) < digestSize {
+		return nil, fmt.Errorf("hkdf: pseudorandom key too short")
+	}
+	if length == 0 || length > 255*digestSize {
+		return nil, fmt.Errorf("hkdf: invalid output length %d", length)
+	}
+	result := make([]byte, length)
+	n, err := io.ReadFull(hkdf.Expand(hashFunc, prk, info), result)
 	if n != len(result) || err != nil {
 		return nil, fmt.Errorf("compute of hkdf failed")
 	}
 	return result, nil
 }
+
+// ComputeHKDFWithInfoReader is like ComputeHKDF, but reads info from a
+// seekable stream instead of a byte slice, see HKDFExpandWithInfoReader.
+func ComputeHKDFWithInfoReader(hashAlg string, key []byte, salt []byte, info io.ReadSeeker, tagSize uint32) ([]byte, error) {
+	keySize := uint32(len(key))
+	if err := validateHKDFParams(hashAlg, keySize, tagSize); err != nil {
+		return nil, fmt.Errorf("hkdf: %s", err)
+	}
+	prk, err := HKDFExtract(hashAlg, key, salt)
+	if err != nil {
+		return nil, err
+	}
+	return HKDFExpandWithInfoReader(hashAlg, prk, info, tagSize)
+}
+
+// HKDFExpandWithInfoReader is like HKDFExpand, but streams info, which is
+// everything from the current offset of the reader to its end, into the HMAC
+// instead of holding it in memory. The output is the same as that of
+// HKDFExpand for the same info. Since every block of output keying material
+// depends on info, info is read once per digest size bytes of output,
+// seeking back to the initial offset in between.
+func HKDFExpandWithInfoReader(hashAlg string, prk []byte, info io.ReadSeeker, length uint32) ([]byte, error) {
+	hashFunc := GetHashFunc(hashAlg)
+	if hashFunc == nil {
+		return nil, fmt.Errorf("hkdf: invalid hash algorithm")
+	}
+	digestSize := uint32(hashFunc().Size())
+	if uint32(len(prk)) < digestSize {
+		return nil, fmt.Errorf("hkdf: pseudorandom key too short")
+	}
+	if length == 0 || length > 255*digestSize {
+		return nil, fmt.Errorf("hkdf: invalid output length %d", length)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("hkdf: invalid info reader")
+	}
+	start, err := info.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("hkdf: cannot seek info: %s", err)
+	}
+	mac := hmac.New(hashFunc, prk)
+	result := make([]byte, 0, length)
+	var block []byte
+	for counter := byte(1); uint32(len(result)) < length; counter++ {
+		if _, err := info.Seek(start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("hkdf: cannot seek info: %s", err)
+		}
+		mac.Reset()
+		mac.Write(block)
+		if _, err := io.Copy(mac, info); err != nil {
+			return nil, fmt.Errorf("hkdf: cannot read info: %s", err)
+		}
+		mac.Write([]byte{counter})
+		block = mac.Sum(block[:0])
+		result = append(result, block...)
+	}
+	return result[:length], nil
+}
@@ -17,8 +17,10 @@
 package subtle
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 
@@ -32,6 +34,7 @@ var hkdfTests = []struct {
 	salt        string
 	info        string
 	tagSize     uint32
+	expectedPRK string
 	expectedKDF string
 }{
 	{
@@ -40,6 +43,7 @@ var hkdfTests = []struct {
 		salt:        "000102030405060708090a0b0c",
 		info:        "f0f1f2f3f4f5f6f7f8f9",
 		tagSize:     42,
+		expectedPRK: "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
 		expectedKDF: "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
 	},
 	{
@@ -53,17 +57,19 @@ var hkdfTests = []struct {
 		info: "b0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecf" +
 			"d0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeef" +
 			"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
-		tagSize: 82,
+		tagSize:     82,
+		expectedPRK: "06a6b88c5853361a06104c9ceb35b45cef760014904671014a193f40c15fc244",
 		expectedKDF: "b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c" +
 			"59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71" +
 			"cc30c58179ec3e87c14c01d5c1f3434f1d87",
 	},
 	{
-		hashAlg: "SHA256",
-		key:     "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
-		salt:    "",
-		info:    "",
-		tagSize: 42,
+		hashAlg:     "SHA256",
+		key:         "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		salt:        "",
+		info:        "",
+		tagSize:     42,
+		expectedPRK: "19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
 		expectedKDF: "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d" +
 			"9d201395faa4b61a96c8",
 	},
@@ -73,6 +79,7 @@ var hkdfTests = []struct {
 		salt:        "000102030405060708090a0b0c",
 		info:        "f0f1f2f3f4f5f6f7f8f9",
 		tagSize:     42,
+		expectedPRK: "9b6c18c432a7bf8f0e71c8eb88f4b30baa2ba243",
 		expectedKDF: "085a01ea1b10f36933068b56efa5ad81a4f14b822f5b091568a9cdd4f155fda2c22e422478d305f3f896",
 	},
 	{
@@ -86,26 +93,29 @@ var hkdfTests = []struct {
 		info: "b0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecf" +
 			"d0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeef" +
 			"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
-		tagSize: 82,
+		tagSize:     82,
+		expectedPRK: "8adae09a2a307059478d309b26c4115a224cfaf6",
 		expectedKDF: "0bd770a74d1160f7c9f12cd5912a06ebff6adcae899d92191fe4305673ba2ffe" +
 			"8fa3f1a4e5ad79f3f334b3b202b2173c486ea37ce3d397ed034c7f9dfeb15c5e" +
 			"927336d0441f4c4300e2cff0d0900b52d3b4",
 	},
 	{
-		hashAlg: "SHA1",
-		key:     "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
-		salt:    "",
-		info:    "",
-		tagSize: 42,
+		hashAlg:     "SHA1",
+		key:         "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		salt:        "",
+		info:        "",
+		tagSize:     42,
+		expectedPRK: "da8c8a73c7fa77288ec6f5e7c297786aa0d32d01",
 		expectedKDF: "0ac1af7002b3d761d1e55298da9d0506b9ae52057220a306e07b6b87e8df21d0" +
 			"ea00033de03984d34918",
 	},
 	{
-		hashAlg: "SHA1",
-		key:     "0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c",
-		salt:    "",
-		info:    "",
-		tagSize: 42,
+		hashAlg:     "SHA1",
+		key:         "0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c",
+		salt:        "",
+		info:        "",
+		tagSize:     42,
+		expectedPRK: "2adccada18779e7c2077ad2eb19d3f3e731385dd",
 		expectedKDF: "2c91117204d745f3500d636a62f64f0ab3bae548aa53d423b0d1f27ebba6f5e5" +
 			"673a081d70cce7acfc48",
 	},
@@ -133,6 +143,100 @@ func TestHKDFBasic(t *testing.T) {
 	}
 }
 
+func TestHKDFExtractExpand(t *testing.T) {
+	for ti, test := range hkdfTests {
+		k, _ := hex.DecodeString(test.key)
+		s, _ := hex.DecodeString(test.salt)
+		i, _ := hex.DecodeString(test.info)
+
+		prk, err := HKDFExtract(test.hashAlg, k, s)
+		if err != nil {
+			t.Errorf("HKDFExtract() failed in test case %d: %s", ti, err)
+			continue
+		}
+		if got := hex.EncodeToString(prk); got != test.expectedPRK {
+			t.Errorf("incorrect PRK in test case %d: expect %s, got %s", ti, test.expectedPRK, got)
+		}
+		okm, err := HKDFExpand(test.hashAlg, prk, i, test.tagSize)
+		if err != nil {
+			t.Errorf("HKDFExpand() failed in test case %d: %s", ti, err)
+			continue
+		}
+		if got := hex.EncodeToString(okm); got != test.expectedKDF {
+			t.Errorf("incorrect OKM in test case %d: expect %s, got %s", ti, test.expectedKDF, got)
+		}
+	}
+}
+
+func TestHKDFExpandWithInfoReader(t *testing.T) {
+	for ti, test := range hkdfTests {
+		k, _ := hex.DecodeString(test.key)
+		s, _ := hex.DecodeString(test.salt)
+		i, _ := hex.DecodeString(test.info)
+
+		okm, err := ComputeHKDFWithInfoReader(test.hashAlg, k, s, bytes.NewReader(i), test.tagSize)
+		if err != nil {
+			t.Errorf("ComputeHKDFWithInfoReader() failed in test case %d: %s", ti, err)
+			continue
+		}
+		if got := hex.EncodeToString(okm); got != test.expectedKDF {
+			t.Errorf("incorrect OKM in test case %d: expect %s, got %s", ti, test.expectedKDF, got)
+		}
+	}
+
+	// info is read from the current offset of the reader, for every block.
+	prk := random.GetRandomBytes(32)
+	info := random.GetRandomBytes(3 << 20)
+	for _, length := range []uint32{1, 32, 33, 100} {
+		want, err := HKDFExpand("SHA256", prk, info, length)
+		if err != nil {
+			t.Fatalf("HKDFExpand() failed: %s", err)
+		}
+		r := bytes.NewReader(append([]byte("header"), info...))
+		if _, err := r.Seek(int64(len("header")), io.SeekStart); err != nil {
+			t.Fatalf("r.Seek() failed: %s", err)
+		}
+		got, err := HKDFExpandWithInfoReader("SHA256", prk, r, length)
+		if err != nil {
+			t.Fatalf("HKDFExpandWithInfoReader() failed: %s", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("HKDFExpandWithInfoReader() with length %d differs from HKDFExpand()", length)
+		}
+	}
+	if _, err := HKDFExpandWithInfoReader("SHA256", prk, nil, 32); err == nil {
+		t.Error("HKDFExpandWithInfoReader() with nil reader: expect an error")
+	}
+}
+
+func TestHKDFExtractExpandWithInvalidInput(t *testing.T) {
+	if _, err := HKDFExtract("SHA-256", []byte("ikm"), nil); err == nil {
+		t.Error("HKDFExtract() with unknown hash: expect an error")
+	}
+	prk, err := HKDFExtract("SHA256", []byte("ikm"), nil)
+	if err != nil {
+		t.Fatalf("HKDFExtract() failed: %s", err)
+	}
+	if _, err := HKDFExpand("MD5", prk, nil, 32); err == nil {
+		t.Error("HKDFExpand() with unknown hash: expect an error")
+	}
+	if _, err := HKDFExpand("SHA256", prk[:31], nil, 32); err == nil {
+		t.Error("HKDFExpand() with short prk: expect an error")
+	}
+	if _, err := HKDFExpand("SHA256", prk, nil, 0); err == nil {
+		t.Error("HKDFExpand() with zero length: expect an error")
+	}
+	if _, err := HKDFExpand("SHA256", prk, nil, 255*32); err != nil {
+		t.Errorf("HKDFExpand() with maximal length failed: %s", err)
+	}
+	if _, err := HKDFExpand("SHA256", prk, nil, 255*32+1); err == nil {
+		t.Error("HKDFExpand() with length above 255*HashLen: expect an error")
+	}
+	if _, err := ComputeHKDF("SHA256", []byte("ikm"), nil, nil, 255*32+1); err == nil {
+		t.Error("ComputeHKDF() with length above 255*HashLen: expect an error")
+	}
+}
+
 func TestNewHMACWithInvalidInput(t *testing.T) {
 	// invalid hash algorithm
 	_, err := ComputeHKDF("SHA0", random.GetRandomBytes(16), nil, nil, 32)
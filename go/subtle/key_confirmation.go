@@ -0,0 +1,83 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+)
+
+const (
+	// minKeyConfirmationSecretSize is the minimum size in bytes of a shared
+	// secret accepted by KeyConfirmation.
+	minKeyConfirmationSecretSize = 16
+
+	// keyConfirmationInfo is the HKDF info used to derive the confirmation key,
+	// which separates it from other keys derived from the same shared secret.
+	keyConfirmationInfo = "tink key confirmation"
+)
+
+// ErrInvalidKeyConfirmation is returned by VerifyKeyConfirmation for a tag that
+// does not match.
+var ErrInvalidKeyConfirmation = errors.New("key_confirmation: invalid key confirmation tag")
+
+// KeyConfirmation returns a key confirmation tag over transcript, which the
+// parties of a key agreement exchange to prove that they derived the same
+// sharedSecret. The tag is HMAC(k, transcript), where the confirmation key k is
+// derived from sharedSecret with HKDF, using an empty salt and the info
+// "tink key confirmation". Both k and the tag are as long as the digest of
+// hash, which is one of "SHA256", "SHA384" and "SHA512".
+//
+// The transcript should unambiguously encode all messages of the handshake,
+// and the roles of the parties, so that a tag cannot be replayed in another
+// handshake or reflected back to its sender.
+func KeyConfirmation(sharedSecret, transcript []byte, hash string) ([]byte, error) {
+	switch hash {
+	case "SHA256", "SHA384", "SHA512":
+	default:
+		return nil, fmt.Errorf("key_confirmation: unsupported hash %q", hash)
+	}
+	if len(sharedSecret) < minKeyConfirmationSecretSize {
+		return nil, fmt.Errorf("key_confirmation: shared secret too short: %d bytes, want at least %d", len(sharedSecret), minKeyConfirmationSecretSize)
+	}
+	digestSize, err := GetHashDigestSize(hash)
+	if err != nil {
+		return nil, fmt.Errorf("key_confirmation: %s", err)
+	}
+	key, err := ComputeHKDF(hash, sharedSecret, nil, []byte(keyConfirmationInfo), digestSize)
+	if err != nil {
+		return nil, fmt.Errorf("key_confirmation: %s", err)
+	}
+	mac := hmac.New(GetHashFunc(hash), key)
+	mac.Write(transcript)
+	return mac.Sum(nil), nil
+}
+
+// VerifyKeyConfirmation returns nil if tag is the key confirmation tag of
+// sharedSecret and transcript, see KeyConfirmation. The tags are compared in
+// constant time, and ErrInvalidKeyConfirmation is returned if they differ.
+func VerifyKeyConfirmation(tag, sharedSecret, transcript []byte, hash string) error {
+	want, err := KeyConfirmation(sharedSecret, transcript, hash)
+	if err != nil {
+		return err
+	}
+	if !ConstantTimeCompare(tag, want) {
+		return ErrInvalidKeyConfirmation
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package subtle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// The expected tags were computed with an independent implementation of HKDF
+// and HMAC.
+var keyConfirmationTests = []struct {
+	hash         string
+	sharedSecret string
+	transcript   string
+	tag          string
+}{
+	{
+		hash:         "SHA256",
+		sharedSecret: "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		transcript:   "636c69656e742068656c6c6f7c7365727665722068656c6c6f",
+		tag:          "ebc9635aa5a24f6c1c57b27ce76e90a9764bef130eed5b253da5bbc8580da34a",
+	},
+	{
+		hash:         "SHA384",
+		sharedSecret: "000102030405060708090a0b0c0d0e0f",
+		transcript:   "",
+		tag: "15077b1e9c072895d4bcd091a7ee56023869cb01d7e0f8af91603a433f4c5fc0" +
+			"91e0474fe5bd999d89e77dad07bb2248",
+	},
+	{
+		hash: "SHA512",
+		sharedSecret: "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f" +
+			"202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f",
+		transcript: "7472616e736372697074",
+		tag: "39072e330f33d2d254e6dd3873525a869b37cd656026302a1f8c41e22a5346e2" +
+			"a36cb9894ba4bd3f71805cb82b1e781117c5680aeac68b59563910e0fd7b5097",
+	},
+}
+
+func TestKeyConfirmation(t *testing.T) {
+	for _, test := range keyConfirmationTests {
+		t.Run(test.hash, func(t *testing.T) {
+			sharedSecret, _ := hex.DecodeString(test.sharedSecret)
+			transcript, _ := hex.DecodeString(test.transcript)
+			want, _ := hex.DecodeString(test.tag)
+			tag, err := KeyConfirmation(sharedSecret, transcript, test.hash)
+			if err != nil {
+				t.Fatalf("KeyConfirmation() err = %v, want nil", err)
+			}
+			if !bytes.Equal(tag, want) {
+				t.Errorf("KeyConfirmation() = %x, want %x", tag, want)
+			}
+			if err := VerifyKeyConfirmation(tag, sharedSecret, transcript, test.hash); err != nil {
+				t.Errorf("VerifyKeyConfirmation() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestVerifyKeyConfirmationRejectsTampering(t *testing.T) {
+	sharedSecret := []byte("0123456789abcdef0123456789abcdef")
+	transcript := []byte("client hello|server hello")
+	tag, err := KeyConfirmation(sharedSecret, transcript, "SHA256")
+	if err != nil {
+		t.Fatalf("KeyConfirmation() err = %v, want nil", err)
+	}
+	for i := range transcript {
+		tampered := append([]byte{}, transcript...)
+		tampered[i] ^= 1
+		if err := VerifyKeyConfirmation(tag, sharedSecret, tampered, "SHA256"); err != ErrInvalidKeyConfirmation {
+			t.Errorf("VerifyKeyConfirmation() with byte %d of the transcript flipped err = %v, want %v", i, err, ErrInvalidKeyConfirmation)
+		}
+	}
+	for name, args := range map[string]struct{ tag, sharedSecret, transcript []byte }{
+		"truncated transcript": {tag, sharedSecret, transcript[:len(transcript)-1]},
+		"extended transcript":  {tag, sharedSecret, append(append([]byte{}, transcript...), 0)},
+		"other shared secret":  {tag, []byte("fedcba9876543210fedcba9876543210"), transcript},
+		"truncated tag":        {tag[:len(tag)-1], sharedSecret, transcript},
+		"empty tag":            {nil, sharedSecret, transcript},
+	} {
+		if err := VerifyKeyConfirmation(args.tag, args.sharedSecret, args.transcript, "SHA256"); err != ErrInvalidKeyConfirmation {
+			t.Errorf("VerifyKeyConfirmation() with %s err = %v, want %v", name, err, ErrInvalidKeyConfirmation)
+		}
+	}
+	if err := VerifyKeyConfirmation(tag, sharedSecret, transcript, "SHA512"); err != ErrInvalidKeyConfirmation {
+		t.Errorf("VerifyKeyConfirmation() with another hash err = %v, want %v", err, ErrInvalidKeyConfirmation)
+	}
+}
+
+func TestKeyConfirmationWithInvalidInput(t *testing.T) {
+	sharedSecret := make([]byte, 32)
+	for _, hash := range []string{"", "SHA1", "SHA224", "MD5"} {
+		if _, err := KeyConfirmation(sharedSecret, nil, hash); err == nil {
+			t.Errorf("KeyConfirmation() with hash %q err = nil, want error", hash)
+		}
+	}
+	if _, err := KeyConfirmation(make([]byte, minKeyConfirmationSecretSize-1), nil, "SHA256"); err == nil {
+		t.Errorf("KeyConfirmation() with short shared secret err = nil, want error")
+	}
+}
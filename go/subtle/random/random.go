@@ -18,15 +18,15 @@
 package random
 
 import (
-	"crypto/rand"
 	"encoding/binary"
+
+	"github.com/google/tink/go/internal/randomsource"
 )
 
 // GetRandomBytes randomly generates n bytes.
 func GetRandomBytes(n uint32) []byte {
 	buf := make([]byte, n)
-	_, err := rand.Read(buf)
-	if err != nil {
+	if err := randomsource.Read(buf); err != nil {
 		panic(err) // out of randomness, should never happen
 	}
 	return buf
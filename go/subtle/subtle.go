@@ -22,6 +22,7 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"hash"
@@ -133,6 +134,13 @@ func ComputeHash(hashFunc func() hash.Hash, data []byte) ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
+// ConstantTimeCompare returns true iff a and b are equal. The time taken is a
+// function of the length of the slices and is independent of their contents;
+// slices of different lengths are never equal.
+func ConstantTimeCompare(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
 // NewBigIntFromHex returns a big integer from a hex string.
 func NewBigIntFromHex(s string) (*big.Int, error) {
 	if len(s)%2 == 1 {
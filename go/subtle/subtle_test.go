@@ -87,3 +87,23 @@ func TestGetCurve(t *testing.T) {
 		t.Errorf("expect nil when curve is unknown")
 	}
 }
+
+func TestConstantTimeCompare(t *testing.T) {
+	var tests = []struct {
+		a, b []byte
+		want bool
+	}{
+		{nil, nil, true},
+		{[]byte{}, nil, true},
+		{[]byte{1, 2, 3}, []byte{1, 2, 3}, true},
+		{[]byte{1, 2, 3}, []byte{1, 2, 4}, false},
+		{[]byte{1, 2, 3}, []byte{1, 2}, false},
+		{[]byte{1, 2}, []byte{1, 2, 3}, false},
+		{[]byte{}, []byte{0}, false},
+	}
+	for _, tt := range tests {
+		if got := subtle.ConstantTimeCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("ConstantTimeCompare(%x, %x) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
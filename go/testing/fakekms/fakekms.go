@@ -27,9 +27,14 @@ package fakekms
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"math/rand"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/tink/go/aead"
 	"github.com/google/tink/go/core/registry"
@@ -40,20 +45,69 @@ import (
 
 const fakePrefix = "fake-kms://"
 
+// ErrInjectedFailure is returned by the AEADs of a client created with
+// WithFailureRate or WithFailureSequence when a failure is injected.
+var ErrInjectedFailure = errors.New("fakekms: injected failure")
+
 var _ registry.KMSClient = (*fakeClient)(nil)
 
 type fakeClient struct {
 	uriPrefix string
+	faults    *faultInjector
+}
+
+// ClientOption configures the behaviour of the AEADs returned by a fake KMS client.
+type ClientOption func(*faultInjector)
+
+// WithLatency makes every Encrypt and Decrypt call sleep for d before doing any work.
+// EncryptContext and DecryptContext stop sleeping and fail with the context's
+// error if their context is done first.
+func WithLatency(d time.Duration) ClientOption {
+	return func(f *faultInjector) {
+		f.latency = d
+	}
+}
+
+// WithFailureRate makes Encrypt and Decrypt calls fail with ErrInjectedFailure with
+// probability p. The failures are drawn from a pseudo-random source initialized
+// with seed, so that the same seed always produces the same sequence of failures.
+func WithFailureRate(p float64, seed int64) ClientOption {
+	return func(f *faultInjector) {
+		f.failureRate = p
+		f.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithFailureSequence scripts the outcome of the Encrypt and Decrypt calls: the
+// i-th call fails with ErrInjectedFailure iff failures[i] is true. Calls beyond
+// the end of the sequence succeed. It takes precedence over WithFailureRate.
+func WithFailureSequence(failures []bool) ClientOption {
+	return func(f *faultInjector) {
+		f.script = append([]bool{}, failures...)
+		f.scripted = true
+	}
 }
 
 // NewClient returns a fake KMS client which will handle keys with uriPrefix prefix.
 // keyURI must have the following format: 'fake-kms://<base64 encoded aead keyset>'.
-func NewClient(uriPrefix string) (registry.KMSClient, error) {
+//
+// The AEADs returned by the client implement tink.AEADWithContext. By default
+// they succeed instantly; opts can be used to inject latency and failures.
+// The fault state is shared among all AEADs returned by the client.
+func NewClient(uriPrefix string, opts ...ClientOption) (registry.KMSClient, error) {
 	if !strings.HasPrefix(strings.ToLower(uriPrefix), fakePrefix) {
 		return nil, fmt.Errorf("uriPrefix must start with %s, but got %s", fakePrefix, uriPrefix)
 	}
+	f := &faultInjector{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.failureRate < 0 || f.failureRate > 1 {
+		return nil, fmt.Errorf("failure rate must be in [0, 1], but got %f", f.failureRate)
+	}
 	return &fakeClient{
 		uriPrefix: uriPrefix,
+		faults:    f,
 	}, nil
 }
 
@@ -77,7 +131,94 @@ func (c *fakeClient) GetAEAD(keyURI string) (tink.AEAD, error) {
 	if err != nil {
 		return nil, err
 	}
-	return aead.New(handle)
+	a, err := aead.New(handle)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyAEAD{a: a, faults: c.faults}, nil
+}
+
+// faultInjector decides, for each operation, how long to wait and whether to fail.
+type faultInjector struct {
+	latency     time.Duration
+	failureRate float64
+	scripted    bool
+
+	mu     sync.Mutex
+	rand   *rand.Rand
+	script []bool
+}
+
+func (f *faultInjector) noop() bool {
+	return f.latency == 0 && f.failureRate == 0 && !f.scripted
+}
+
+// next waits for the configured latency and returns the outcome of the next
+// operation. It returns ctx.Err() if ctx is done before the wait is over.
+func (f *faultInjector) next(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f.noop() {
+		return nil
+	}
+	if f.latency > 0 {
+		t := time.NewTimer(f.latency)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.scripted {
+		if len(f.script) == 0 {
+			return nil
+		}
+		fail := f.script[0]
+		f.script = f.script[1:]
+		if fail {
+			return ErrInjectedFailure
+		}
+		return nil
+	}
+	if f.failureRate > 0 && f.rand.Float64() < f.failureRate {
+		return ErrInjectedFailure
+	}
+	return nil
+}
+
+// faultyAEAD is a tink.AEADWithContext that injects latency and failures
+// before delegating to the underlying AEAD.
+type faultyAEAD struct {
+	a      tink.AEAD
+	faults *faultInjector
+}
+
+var _ tink.AEADWithContext = (*faultyAEAD)(nil)
+
+func (a *faultyAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	return a.EncryptContext(context.Background(), plaintext, additionalData)
+}
+
+func (a *faultyAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	return a.DecryptContext(context.Background(), ciphertext, additionalData)
+}
+
+func (a *faultyAEAD) EncryptContext(ctx context.Context, plaintext, additionalData []byte) ([]byte, error) {
+	if err := a.faults.next(ctx); err != nil {
+		return nil, err
+	}
+	return a.a.Encrypt(plaintext, additionalData)
+}
+
+func (a *faultyAEAD) DecryptContext(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error) {
+	if err := a.faults.next(ctx); err != nil {
+		return nil, err
+	}
+	return a.a.Decrypt(ciphertext, additionalData)
 }
 
 // NewKeyURI returns a new, random fake KMS key URI.
@@ -18,9 +18,12 @@ package fakekms_test
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/google/tink/go/testing/fakekms"
+	"github.com/google/tink/go/tink"
 )
 
 const keyURI = "fake-kms://CM2b3_MDElQKSAowdHlwZS5nb29nbGVhcGlzLmNvbS9nb29nbGUuY3J5cHRvLnRpbmsuQWVzR2NtS2V5EhIaEIK75t5L-adlUwVhWvRuWUwYARABGM2b3_MDIAE"
@@ -114,3 +117,182 @@ func TestGetAeadFailsWithBadKeysetEncoding(t *testing.T) {
 		t.Fatalf("client.GetAEAD('fake-kms://badencoding') succeeded, want fail")
 	}
 }
+
+func TestWithLatency(t *testing.T) {
+	latency := 20 * time.Millisecond
+	client, err := fakekms.NewClient(keyURI, fakekms.WithLatency(latency))
+	if err != nil {
+		t.Fatalf("fakekms.NewClient(keyURI, fakekms.WithLatency(latency)) failed: %v", err)
+	}
+	primitive, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	start := time.Now()
+	ciphertext, err := primitive.Encrypt([]byte("plaintext"), []byte("aad"))
+	if err != nil {
+		t.Fatalf("primitive.Encrypt(plaintext, aad) failed: %v", err)
+	}
+	if _, err := primitive.Decrypt(ciphertext, []byte("aad")); err != nil {
+		t.Fatalf("primitive.Decrypt(ciphertext, aad) failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*latency {
+		t.Errorf("Encrypt and Decrypt took %v, want at least %v", elapsed, 2*latency)
+	}
+}
+
+func TestWithLatencyHonorsContext(t *testing.T) {
+	latency := 10 * time.Second
+	client, err := fakekms.NewClient(keyURI, fakekms.WithLatency(latency))
+	if err != nil {
+		t.Fatalf("fakekms.NewClient(keyURI, fakekms.WithLatency(latency)) failed: %v", err)
+	}
+	primitive, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	a, ok := primitive.(tink.AEADWithContext)
+	if !ok {
+		t.Fatalf("client.GetAEAD(keyURI) = %T, want a tink.AEADWithContext", primitive)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := a.EncryptContext(ctx, []byte("plaintext"), []byte("aad")); err != context.DeadlineExceeded {
+		t.Errorf("a.EncryptContext() err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed >= latency {
+		t.Errorf("a.EncryptContext() took %v, want less than %v", elapsed, latency)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	start = time.Now()
+	if _, err := a.DecryptContext(ctx, []byte("ciphertext"), []byte("aad")); err != context.Canceled {
+		t.Errorf("a.DecryptContext() err = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed >= latency {
+		t.Errorf("a.DecryptContext() took %v, want less than %v", elapsed, latency)
+	}
+}
+
+func TestContextVariantsWithoutFaults(t *testing.T) {
+	client, err := fakekms.NewClient(keyURI)
+	if err != nil {
+		t.Fatalf("fakekms.NewClient(keyURI) failed: %v", err)
+	}
+	primitive, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	a, ok := primitive.(tink.AEADWithContext)
+	if !ok {
+		t.Fatalf("client.GetAEAD(keyURI) = %T, want a tink.AEADWithContext", primitive)
+	}
+	ciphertext, err := a.EncryptContext(context.Background(), []byte("plaintext"), []byte("aad"))
+	if err != nil {
+		t.Fatalf("a.EncryptContext() failed: %v", err)
+	}
+	if _, err := a.Decrypt(ciphertext, []byte("aad")); err != nil {
+		t.Errorf("a.Decrypt() failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.DecryptContext(ctx, ciphertext, []byte("aad")); err != context.Canceled {
+		t.Errorf("a.DecryptContext() with cancelled context err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithFailureSequence(t *testing.T) {
+	client, err := fakekms.NewClient(keyURI, fakekms.WithFailureSequence([]bool{true, false, true}))
+	if err != nil {
+		t.Fatalf("fakekms.NewClient(keyURI, fakekms.WithFailureSequence(...)) failed: %v", err)
+	}
+	primitive, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	aad := []byte("aad")
+	if _, err := primitive.Encrypt(plaintext, aad); err != fakekms.ErrInjectedFailure {
+		t.Fatalf("first primitive.Encrypt(plaintext, aad) err = %v, want %v", err, fakekms.ErrInjectedFailure)
+	}
+	ciphertext, err := primitive.Encrypt(plaintext, aad)
+	if err != nil {
+		t.Fatalf("second primitive.Encrypt(plaintext, aad) failed: %v", err)
+	}
+	if _, err := primitive.Decrypt(ciphertext, aad); err != fakekms.ErrInjectedFailure {
+		t.Fatalf("first primitive.Decrypt(ciphertext, aad) err = %v, want %v", err, fakekms.ErrInjectedFailure)
+	}
+	// The script is exhausted, every further call succeeds.
+	for i := 0; i < 5; i++ {
+		decrypted, err := primitive.Decrypt(ciphertext, aad)
+		if err != nil {
+			t.Fatalf("primitive.Decrypt(ciphertext, aad) failed: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("decrypted data doesn't match plaintext, got: %q, want: %q", decrypted, plaintext)
+		}
+	}
+}
+
+func failurePattern(t *testing.T, p float64, seed int64, n int) []bool {
+	t.Helper()
+	client, err := fakekms.NewClient(keyURI, fakekms.WithFailureRate(p, seed))
+	if err != nil {
+		t.Fatalf("fakekms.NewClient(keyURI, fakekms.WithFailureRate(%f, %d)) failed: %v", p, seed, err)
+	}
+	primitive, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) failed: %v", err)
+	}
+	pattern := make([]bool, n)
+	for i := range pattern {
+		_, err := primitive.Encrypt([]byte("plaintext"), []byte("aad"))
+		if err != nil && err != fakekms.ErrInjectedFailure {
+			t.Fatalf("primitive.Encrypt(plaintext, aad) failed: %v", err)
+		}
+		pattern[i] = err != nil
+	}
+	return pattern
+}
+
+func TestWithFailureRateIsDeterministic(t *testing.T) {
+	const n = 100
+	p1 := failurePattern(t, 0.5, 42, n)
+	p2 := failurePattern(t, 0.5, 42, n)
+	failures := 0
+	for i := 0; i < n; i++ {
+		if p1[i] != p2[i] {
+			t.Fatalf("failure patterns with the same seed differ at call %d", i)
+		}
+		if p1[i] {
+			failures++
+		}
+	}
+	if failures == 0 || failures == n {
+		t.Errorf("got %d failures out of %d calls with failure rate 0.5", failures, n)
+	}
+	for _, f := range failurePattern(t, 0, 42, n) {
+		if f {
+			t.Fatalf("got a failure with failure rate 0")
+		}
+	}
+	for _, f := range failurePattern(t, 1, 42, n) {
+		if !f {
+			t.Fatalf("got a success with failure rate 1")
+		}
+	}
+}
+
+func TestInvalidFailureRate(t *testing.T) {
+	for _, p := range []float64{-0.1, 1.1} {
+		if _, err := fakekms.NewClient(keyURI, fakekms.WithFailureRate(p, 1)); err == nil {
+			t.Errorf("fakekms.NewClient(keyURI, fakekms.WithFailureRate(%f, 1)) succeeded, want fail", p)
+		}
+	}
+}
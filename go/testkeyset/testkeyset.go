@@ -20,6 +20,7 @@ package testkeyset
 import (
 	"errors"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/google/tink/go/internal"
 	"github.com/google/tink/go/keyset"
 	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
@@ -69,3 +70,31 @@ func Write(h *keyset.Handle, w keyset.Writer) error {
 	}
 	return w.Write(KeysetMaterial(h))
 }
+
+// Equal reports whether a and b hold the same keys, including their key
+// material, and the same primary key ID. The order of keys in the keysets is
+// ignored.
+func Equal(a, b *keyset.Handle) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ksA, ksB := KeysetMaterial(a), KeysetMaterial(b)
+	if ksA.PrimaryKeyId != ksB.PrimaryKeyId || len(ksA.Key) != len(ksB.Key) {
+		return false
+	}
+	matched := make([]bool, len(ksB.Key))
+	for _, keyA := range ksA.Key {
+		found := false
+		for j, keyB := range ksB.Key {
+			if !matched[j] && proto.Equal(keyA, keyB) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
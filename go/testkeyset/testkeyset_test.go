@@ -0,0 +1,130 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+////////////////////////////////////////////////////////////////////////////////
+
+package testkeyset_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
+	"github.com/google/tink/go/testkeyset"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+)
+
+func TestKeysetMaterialMatchesBinaryWriter(t *testing.T) {
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() failed: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := testkeyset.Write(h, keyset.NewBinaryWriter(buf)); err != nil {
+		t.Fatalf("testkeyset.Write() failed: %v", err)
+	}
+	serialized, err := proto.Marshal(testkeyset.KeysetMaterial(h))
+	if err != nil {
+		t.Fatalf("proto.Marshal() failed: %v", err)
+	}
+	if !bytes.Equal(serialized, buf.Bytes()) {
+		t.Errorf("serialized KeysetMaterial(h) = %x, want %x", serialized, buf.Bytes())
+	}
+}
+
+func TestKeysetMaterialRoundTrip(t *testing.T) {
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() failed: %v", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+	ks.Key[0].OutputPrefixType = tinkpb.OutputPrefixType_RAW
+	h2, err := testkeyset.NewHandle(ks)
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() failed: %v", err)
+	}
+	if got := h2.KeysetInfo().KeyInfo[0].OutputPrefixType; got != tinkpb.OutputPrefixType_RAW {
+		t.Errorf("OutputPrefixType = %v, want %v", got, tinkpb.OutputPrefixType_RAW)
+	}
+	if _, err := mac.New(h2); err != nil {
+		t.Errorf("mac.New() on the modified keyset failed: %v", err)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	ksm := keyset.NewManager()
+	for i := 0; i < 3; i++ {
+		if err := ksm.Rotate(mac.HMACSHA256Tag128KeyTemplate()); err != nil {
+			t.Fatalf("ksm.Rotate() failed: %v", err)
+		}
+	}
+	h, err := ksm.Handle()
+	if err != nil {
+		t.Fatalf("ksm.Handle() failed: %v", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+
+	same, err := testkeyset.NewHandle(proto.Clone(ks).(*tinkpb.Keyset))
+	if err != nil {
+		t.Fatalf("testkeyset.NewHandle() failed: %v", err)
+	}
+	if !testkeyset.Equal(h, same) {
+		t.Error("testkeyset.Equal() = false for a copy of the keyset")
+	}
+
+	reordered := proto.Clone(ks).(*tinkpb.Keyset)
+	reordered.Key[0], reordered.Key[2] = reordered.Key[2], reordered.Key[0]
+	if !testkeyset.Equal(h, testkeyset.KeysetHandle(reordered)) {
+		t.Error("testkeyset.Equal() = false for a keyset with reordered keys")
+	}
+	if !testkeyset.Equal(nil, nil) {
+		t.Error("testkeyset.Equal(nil, nil) = false")
+	}
+}
+
+func TestEqualWithDifferentKeysets(t *testing.T) {
+	h, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() failed: %v", err)
+	}
+	ks := testkeyset.KeysetMaterial(h)
+
+	otherPrimary := proto.Clone(ks).(*tinkpb.Keyset)
+	otherPrimary.PrimaryKeyId++
+	otherMaterial := proto.Clone(ks).(*tinkpb.Keyset)
+	otherMaterial.Key[0].KeyData.Value = append(otherMaterial.Key[0].KeyData.Value, 0)
+	otherStatus := proto.Clone(ks).(*tinkpb.Keyset)
+	otherStatus.Key[0].Status = tinkpb.KeyStatusType_DISABLED
+	extraKey := proto.Clone(ks).(*tinkpb.Keyset)
+	extraKey.Key = append(extraKey.Key, extraKey.Key[0])
+	fresh, err := keyset.NewHandle(mac.HMACSHA256Tag128KeyTemplate())
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() failed: %v", err)
+	}
+
+	for name, other := range map[string]*keyset.Handle{
+		"primary key id": testkeyset.KeysetHandle(otherPrimary),
+		"key material":   testkeyset.KeysetHandle(otherMaterial),
+		"key status":     testkeyset.KeysetHandle(otherStatus),
+		"number of keys": testkeyset.KeysetHandle(extraKey),
+		"fresh keyset":   fresh,
+		"nil":            nil,
+	} {
+		if testkeyset.Equal(h, other) {
+			t.Errorf("testkeyset.Equal() = true for keysets with different %s", name)
+		}
+	}
+}
@@ -17,6 +17,8 @@
 // Package tink provides the abstract interfaces of the primitives which Tink supports.
 package tink
 
+import "context"
+
 /*
 AEAD is the interface for authenticated encryption with additional authenticated data.
 Implementations of this interface are secure against adaptive chosen ciphertext attacks.
@@ -35,3 +37,19 @@ type AEAD interface {
 	// of the additional data, but there are no guarantees wrt. secrecy of that data.
 	Decrypt(ciphertext, additionalData []byte) ([]byte, error)
 }
+
+// AEADWithContext is implemented by AEADs whose operations may block, e.g.
+// because they call a remote KMS. EncryptContext and DecryptContext are like
+// Encrypt and Decrypt, but give up as soon as possible once ctx is done and
+// then return an error that wraps or equals ctx.Err().
+type AEADWithContext interface {
+	AEAD
+
+	// EncryptContext is like Encrypt, but honors the cancellation and the
+	// deadline of ctx.
+	EncryptContext(ctx context.Context, plaintext, additionalData []byte) ([]byte, error)
+
+	// DecryptContext is like Decrypt, but honors the cancellation and the
+	// deadline of ctx.
+	DecryptContext(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error)
+}